@@ -0,0 +1,85 @@
+package emul
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrAccountExists  = errors.New("account already exists")
+	ErrUnknownAccount = errors.New("unknown account")
+)
+
+// AddAccount registers an isolated Exchange under key (e.g. an API key). Every account shares
+// the emulator's bar feed and cost settings but keeps its own balance, positions and orders.
+func (e *Emulator) AddAccount(key string, startUSD float64) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return errors.New("account key is empty")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.accounts[key]; ok {
+		return ErrAccountExists
+	}
+	ex := NewExchangeFromProfile(startUSD, e.costs)
+	if e.spec != nil {
+		ex.SetSymbol(*e.spec)
+	}
+	ex.SetRunID(accountRunID(e.runID, key))
+	ex.SetClock(e.clock)
+	// Accounts added mid-replay start from the bar the feed is currently on.
+	if e.index > 0 {
+		ex.tick = e.ex.tick
+		ex.lastPrice = e.ex.lastPrice
+		ex.prevPrice = e.ex.prevPrice
+		ex.spreadPct = e.ex.spreadPct
+		ex.lastBar = e.ex.lastBar
+		ex.hasLastBar = e.ex.hasLastBar
+	}
+	e.accounts[key] = ex
+	return nil
+}
+
+func (e *Emulator) RemoveAccount(key string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.accounts[key]; !ok {
+		return ErrUnknownAccount
+	}
+	delete(e.accounts, key)
+	return nil
+}
+
+// Accounts returns registered account keys in sorted order.
+func (e *Emulator) Accounts() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	keys := make([]string, 0, len(e.accounts))
+	for k := range e.accounts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HasAccount reports whether key names a registered account.
+func (e *Emulator) HasAccount(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.accounts[key]
+	return ok
+}
+
+// WithAccount runs fn against the account's Exchange while holding the emulator lock,
+// so bots driving different accounts from separate goroutines never race with Next().
+func (e *Emulator) WithAccount(key string, fn func(ex *Exchange) error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ex, ok := e.accounts[key]
+	if !ok {
+		return ErrUnknownAccount
+	}
+	return fn(ex)
+}
@@ -0,0 +1,95 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// ReasonPerpADL marks a perp fill forced by auto-deleveraging.
+const ReasonPerpADL = "perp-adl"
+
+// ADLConfig models auto-deleveraging of the perp leg. On a bar whose mark moves by
+// MoveThreshold or more (0.1 = 10%) a profitable position may be partly closed at the mark, as
+// when a venue's insurance fund cannot absorb the liquidations on the other side. The chance is
+// Probability and the closed share Fraction, both scaled by the ADL level over 5, so the most
+// profitable positions are deleveraged first and hardest. ADL fills pay no fee.
+type ADLConfig struct {
+	MoveThreshold float64
+	Probability   float64
+	Fraction      float64
+	Seed          uint64
+}
+
+func (c ADLConfig) validate() error {
+	if c.MoveThreshold < 0 {
+		return fmt.Errorf("adl move threshold must not be negative")
+	}
+	if c.Probability < 0 || c.Probability > 1 {
+		return fmt.Errorf("adl probability must be within [0, 1]")
+	}
+	if c.Fraction <= 0 || c.Fraction > 1 {
+		return fmt.Errorf("adl fraction must be within (0, 1]")
+	}
+	return nil
+}
+
+// adlBands are the returns on margin where the ADL level steps up, like the five-light
+// indicator of Binance futures; the perp leg runs at 1x, so the ranking is its PnL ratio.
+var adlBands = [...]float64{0.05, 0.1, 0.2, 0.4}
+
+// adlLevel is 0 for a flat or losing position and 1 to 5 by return on margin otherwise.
+func (p *perpLeg) adlLevel() int {
+	pnl := p.unrealized()
+	if p.qty == 0 || pnl <= 0 || p.margin <= 0 {
+		return 0
+	}
+	level := 1
+	for _, band := range adlBands {
+		if pnl/p.margin > band {
+			level++
+		}
+	}
+	return level
+}
+
+// applyADL deleverages the perp position after the mark moved from prev.
+func (e *Exchange) applyADL(prev float64) {
+	p := e.perp
+	cfg := p.cfg.ADL
+	if cfg == nil || p.qty == 0 || prev <= 0 || math.Abs(p.mark/prev-1) < cfg.MoveThreshold {
+		return
+	}
+	level := p.adlLevel()
+	if level == 0 {
+		return
+	}
+	scale := float64(level) / 5
+	if p.adl.Float64() >= cfg.Probability*scale {
+		return
+	}
+	equityBefore := e.Balance().Equity
+	share := cfg.Fraction * scale
+	qty := p.qty * share
+	pnl := qty * (p.mark - p.entry)
+	margin := p.margin * share
+	*e.perpCash() += margin + pnl
+	p.realized += pnl
+	p.margin -= margin
+	p.qty -= qty
+	if share >= 1 {
+		p.qty, p.entry, p.margin = 0, 0, 0
+	}
+	side := SideSell
+	if qty < 0 {
+		side = SideBuy
+	}
+	e.recordPerpOrder(side, math.Abs(qty), 0, equityBefore, ReasonPerpADL)
+}
+
+func newADLRand(cfg *ADLConfig) *rand.Rand {
+	if cfg == nil {
+		return nil
+	}
+	return rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))
+}
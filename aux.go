@@ -0,0 +1,140 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadAuxCSV reads auxiliary series such as funding rates, on-chain metrics or sentiment scores.
+// A header "time,name1,name2,..." names the columns; without one the file holds "time,value"
+// rows and the series is named after the file. Timestamps follow the same rules as bar files.
+// Blank or unparsable cells are skipped.
+func LoadAuxCSV(path string) (map[string][]SeriesPoint, error) {
+	return LoadAuxCSVWithOptions(path, CSVReaderOptions{})
+}
+
+// LoadAuxCSVWithOptions is LoadAuxCSV with explicit reader options.
+func LoadAuxCSVWithOptions(path string, opts CSVReaderOptions) (map[string][]SeriesPoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	unit := opts.EpochUnit
+	scanner := newCSVScanner(file, path, opts)
+	var names []string
+	out := make(map[string][]SeriesPoint)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		ts, ok := parseCSVTime(fields[0], unit)
+		if !ok {
+			if names == nil && len(out) == 0 && len(fields) > 1 {
+				names = make([]string, len(fields)-1)
+				for i, f := range fields[1:] {
+					names[i] = strings.ToLower(strings.Trim(strings.TrimSpace(f), "\""))
+				}
+			}
+			continue
+		}
+		if names == nil {
+			names = []string{strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+		}
+		for i, name := range names {
+			if i+1 >= len(fields) || name == "" {
+				break
+			}
+			if v, ok := parseCSVFloat(fields[i+1]); ok {
+				out[name] = append(out[name], SeriesPoint{Time: ts, Value: v})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, errNoDataRows)
+	}
+	return out, nil
+}
+
+// AlignSeries maps points onto bars as of each bar's time: the value is the last point at or
+// before the bar, and NaN before the first point, so no future value leaks into a bar.
+func AlignSeries(bars []OHLCBar, points []SeriesPoint) ([]float64, error) {
+	sorted := append([]SeriesPoint(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	out := make([]float64, len(bars))
+	j := -1
+	for i, bar := range bars {
+		if bar.Time.IsZero() {
+			return nil, fmt.Errorf("bar %d has no time", i)
+		}
+		if i > 0 && bar.Time.Before(bars[i-1].Time) {
+			return nil, fmt.Errorf("bar %d is out of order", i)
+		}
+		for j+1 < len(sorted) && !sorted[j+1].Time.After(bar.Time) {
+			j++
+		}
+		if j < 0 {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = sorted[j].Value
+	}
+	return out, nil
+}
+
+// AddAux aligns points to the emulator's bars (see AlignSeries) under name, replacing any
+// series of that name.
+func (e *Emulator) AddAux(name string, points []SeriesPoint) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("aux series name is empty")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	values, err := AlignSeries(e.bars, points)
+	if err != nil {
+		return fmt.Errorf("aux %q: %w", name, err)
+	}
+	if e.aux == nil {
+		e.aux = make(map[string][]float64)
+	}
+	e.aux[name] = values
+	return nil
+}
+
+// Aux returns the named series' value at the bar last returned by Next; ok is false before the
+// first bar, for unknown names and before the series starts.
+func (e *Emulator) Aux(name string) (float64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	values, ok := e.aux[name]
+	if !ok || e.index == 0 || e.index > len(values) {
+		return 0, false
+	}
+	v := values[e.index-1]
+	if math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// AuxNames lists the aux series in name order.
+func (e *Emulator) AuxNames() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, 0, len(e.aux))
+	for name := range e.aux {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
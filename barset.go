@@ -0,0 +1,83 @@
+package emul
+
+import (
+	"sync"
+)
+
+// BarSet is a read-only, reference-counted bar series shared by many emulators. The creator
+// holds the first reference; every emulator built from the set retains one more and drops it
+// in Close. When the count reaches zero the bars are released for the garbage collector.
+type BarSet struct {
+	mu     sync.Mutex
+	bars   []OHLCBar
+	refs   int
+	values []float64
+	series *OHLCSeries
+}
+
+// NewBarSet takes ownership of bars; callers must not modify the slice afterwards.
+func NewBarSet(bars []OHLCBar) *BarSet {
+	return &BarSet{
+		bars: bars,
+		refs: 1,
+	}
+}
+
+func (s *BarSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bars)
+}
+
+func (s *BarSet) At(i int) OHLCBar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bars[i]
+}
+
+// Retain adds a reference and returns the set's bars; it returns nil once the set is released.
+func (s *BarSet) Retain() []OHLCBar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs <= 0 {
+		return nil
+	}
+	s.refs++
+	return s.bars
+}
+
+func (s *BarSet) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs <= 0 {
+		return
+	}
+	s.refs--
+	if s.refs == 0 {
+		s.bars = nil
+		s.values = nil
+		s.series = nil
+	}
+}
+
+// Series returns a columnar view (Average values plus OHLC columns) built on first use and shared
+// afterwards; callers must not modify the returned slices.
+func (s *BarSet) Series() ([]float64, OHLCSeries) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.series == nil && s.bars != nil {
+		values, series := SeriesFromBars(s.bars)
+		s.values = values
+		s.series = &series
+	}
+	if s.series == nil {
+		return nil, OHLCSeries{}
+	}
+	return s.values, *s.series
+}
+
+func (s *BarSet) Refs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refs
+}
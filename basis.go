@@ -0,0 +1,364 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	ReasonPerpOpen  = "perp-open"
+	ReasonPerpClose = "perp-close"
+)
+
+var ErrPerpDisabled = errors.New("perp leg not enabled")
+
+// PerpConfig adds a perpetual-futures leg next to the spot position. Bars is the perp series
+// aligned bar-for-bar with the spot series. Every FundingEvery bars the open perp position pays
+// (long) or receives (short) FundingRate times its notional at the mark; a negative rate flips
+// the direction. FundingInterval, when set, replaces FundingEvery with settlements at each
+// interval boundary on the exchange clock (8h settles at 00:00, 08:00 and 16:00 UTC). A zero
+// FundingRate, and a schedule left unset, default to the symbol's (see SymbolSpec). ADL, when
+// set, enables auto-deleveraging.
+type PerpConfig struct {
+	Bars            []OHLCBar
+	FundingRate     float64
+	FundingEvery    int
+	FundingInterval time.Duration
+	Fee             float64
+	ADL             *ADLConfig
+}
+
+// PerpPosition is the perp leg's state. Margin is the USD posted at 1x; UnrealizedPnL is
+// marked at the perp close. ADLLevel ranks the position for auto-deleveraging from 0 (losing
+// or flat) to 5 (first in line). FundingShortfall is funding owed that neither the wallet's
+// cash nor the margin could cover, written off when the leg was liquidated.
+type PerpPosition struct {
+	Qty              float64
+	EntryPrice       float64
+	Mark             float64
+	Margin           float64
+	UnrealizedPnL    float64
+	Funding          float64
+	ADLLevel         int
+	FundingShortfall float64
+}
+
+// BasisReport splits the PnL of a spot/perp book into its legs. BasisPnL is the part explained
+// by the change of the basis (perp minus spot) on the hedged quantity since the perp entry.
+type BasisReport struct {
+	SpotUnrealized float64
+	PerpRealized   float64
+	PerpUnrealized float64
+	Funding        float64
+	PerpFees       float64
+	EntryBasis     float64
+	Basis          float64
+	BasisPnL       float64
+}
+
+type perpLeg struct {
+	cfg        PerpConfig
+	ownsBars   bool
+	qty        float64
+	entry      float64
+	margin     float64
+	mark       float64
+	entryBasis float64
+	funding    float64
+	realized   float64
+	fees       float64
+	shortfall  float64
+	bars       int
+	settled    time.Time
+	adl        *rand.Rand
+	orders     []Order
+}
+
+// EnablePerp attaches a perp series to the emulator's primary exchange; it must be called
+// before the first Next.
+func (e *Emulator) EnablePerp(cfg PerpConfig) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.index > 0 {
+		return fmt.Errorf("perp leg must be enabled before the replay starts")
+	}
+	if len(cfg.Bars) != len(e.bars) {
+		return fmt.Errorf("perp series has %d bars, spot has %d", len(cfg.Bars), len(e.bars))
+	}
+	if cfg.Fee < 0 || cfg.FundingEvery < 0 || cfg.FundingInterval < 0 {
+		return fmt.Errorf("perp fee and funding interval must not be negative")
+	}
+	if cfg.ADL != nil {
+		if err := cfg.ADL.validate(); err != nil {
+			return err
+		}
+	}
+	if cfg.FundingRate == 0 {
+		cfg.FundingRate = e.ex.fundingRate
+	}
+	if cfg.FundingEvery == 0 && cfg.FundingInterval == 0 {
+		cfg.FundingInterval = e.ex.fundingPeriod
+	}
+	e.ex.perp = &perpLeg{cfg: cfg, adl: newADLRand(cfg.ADL)}
+	return nil
+}
+
+// markPerp moves the perp mark to bar, applies auto-deleveraging and settles funding when an
+// interval completes.
+func (e *Exchange) markPerp(bar OHLCBar) {
+	p := e.perp
+	prev := p.mark
+	p.mark = bar.Close
+	p.bars++
+	e.applyADL(prev)
+	e.liquidateMarginWallet()
+	if !p.fundingDue(e.Now()) {
+		return
+	}
+	payment := -p.qty * p.mark * p.cfg.FundingRate
+	if payment >= 0 {
+		*e.perpCash() += payment
+	} else {
+		payment = -e.payPerpFunding(-payment)
+	}
+	p.funding += payment
+	e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerFunding, Amount: payment})
+	e.chargeFlowFee(LedgerFunding, payment, e.perpCash())
+	if e.marginWallet {
+		e.liquidateMarginWallet()
+	} else if p.qty != 0 && p.margin+p.unrealized() <= 0 {
+		e.liquidatePerp()
+	}
+}
+
+// payPerpFunding pays due funding from the perp wallet's cash, then from the posted margin, and
+// returns what was paid. Cash never goes negative: what neither covers is recorded as a
+// shortfall and the leg is left for liquidation.
+func (e *Exchange) payPerpFunding(due float64) float64 {
+	p := e.perp
+	paid := debit(due, e.perpCash())
+	fromMargin := math.Min(due-paid, p.margin)
+	p.margin -= fromMargin
+	p.shortfall += due - paid - fromMargin
+	return paid + fromMargin
+}
+
+// liquidatePerp closes a perp drawing on the spot wallet at the mark once its margin and PnL are
+// gone; what is left of the margin is forfeited.
+func (e *Exchange) liquidatePerp() {
+	p := e.perp
+	equityBefore := e.Balance().Equity
+	side := SideBuy
+	if p.qty > 0 {
+		side = SideSell
+	}
+	qty := math.Abs(p.qty)
+	p.realized -= p.margin
+	p.qty, p.entry, p.margin = 0, 0, 0
+	e.recordPerpOrder(side, qty, 0, equityBefore, ReasonLiquidate)
+}
+
+// fundingDue reports whether a funding interval completed at now. The first time-based check
+// only records the current interval.
+func (p *perpLeg) fundingDue(now time.Time) bool {
+	if p.cfg.FundingInterval > 0 {
+		boundary := now.Truncate(p.cfg.FundingInterval)
+		due := !p.settled.IsZero() && boundary.After(p.settled)
+		if p.settled.IsZero() || due {
+			p.settled = boundary
+		}
+		return due && p.qty != 0
+	}
+	return p.qty != 0 && p.cfg.FundingEvery > 0 && p.bars%p.cfg.FundingEvery == 0
+}
+
+// OpenPerp opens a perp position at the current mark using fraction of the free USD as 1x
+// margin. It may be held together with a spot position in either direction.
+func (e *Exchange) OpenPerp(side OrderSide, fraction float64) (*Order, error) {
+	p := e.perp
+	if p == nil {
+		return nil, ErrPerpDisabled
+	}
+	if p.qty != 0 {
+		return nil, ErrPositionOpen
+	}
+	if p.mark <= 0 {
+		return nil, ErrPriceNotSet
+	}
+	if fraction <= 0 || fraction > 1 {
+		return nil, ErrInvalidFraction
+	}
+	if side != SideBuy && side != SideSell {
+		return nil, fmt.Errorf("invalid side %q", side)
+	}
+	equityBefore := e.Balance().Equity
+	cash := e.perpCash()
+	notional := *cash * fraction
+	fee := notional * p.cfg.Fee
+	margin := notional - fee
+	qty := margin / p.mark
+	if qty <= 0 {
+		return nil, ErrInvalidFraction
+	}
+	if side == SideSell {
+		qty = -qty
+	}
+	*cash -= notional
+	p.qty, p.entry, p.margin = qty, p.mark, margin
+	p.fees += fee
+	if e.lastPrice > 0 {
+		p.entryBasis = p.mark - e.lastPrice
+	}
+	order := e.recordPerpOrder(side, math.Abs(qty), fee, equityBefore, ReasonPerpOpen)
+	return &order, e.checkInvariants()
+}
+
+// ClosePerp closes the perp position at the current mark and returns margin plus PnL to USD.
+func (e *Exchange) ClosePerp() (*Order, error) {
+	p := e.perp
+	if p == nil {
+		return nil, ErrPerpDisabled
+	}
+	if p.qty == 0 {
+		return nil, ErrNoPosition
+	}
+	equityBefore := e.Balance().Equity
+	pnl := p.qty * (p.mark - p.entry)
+	fee := math.Abs(p.qty) * p.mark * p.cfg.Fee
+	side := SideBuy
+	if p.qty > 0 {
+		side = SideSell
+	}
+	qty := math.Abs(p.qty)
+	// A loss beyond the margin is capped: the leg is liquidated, not the spot account. In a
+	// margin wallet the loss is covered by the wallet's free cash first.
+	cash := e.perpCash()
+	if e.marginWallet {
+		*cash = math.Max(*cash+p.margin+pnl-fee, 0)
+	} else {
+		*cash += math.Max(p.margin+pnl-fee, 0)
+	}
+	p.realized += pnl
+	p.fees += fee
+	p.qty, p.entry, p.margin = 0, 0, 0
+	order := e.recordPerpOrder(side, qty, fee, equityBefore, ReasonPerpClose)
+	return &order, e.checkInvariants()
+}
+
+func (e *Exchange) PerpPosition() (PerpPosition, error) {
+	p := e.perp
+	if p == nil {
+		return PerpPosition{}, ErrPerpDisabled
+	}
+	return PerpPosition{
+		Qty:              p.qty,
+		EntryPrice:       p.entry,
+		Mark:             p.mark,
+		Margin:           p.margin,
+		UnrealizedPnL:    p.unrealized(),
+		Funding:          p.funding,
+		ADLLevel:         p.adlLevel(),
+		FundingShortfall: p.shortfall,
+	}, nil
+}
+
+// PerpOrders returns the perp leg's fills; they are kept apart from the spot order history.
+func (e *Exchange) PerpOrders() []Order {
+	if e.perp == nil {
+		return nil
+	}
+	return append([]Order(nil), e.perp.orders...)
+}
+
+func (e *Exchange) BasisReport() (BasisReport, error) {
+	p := e.perp
+	if p == nil {
+		return BasisReport{}, ErrPerpDisabled
+	}
+	r := BasisReport{
+		PerpRealized:   p.realized,
+		PerpUnrealized: p.unrealized(),
+		Funding:        p.funding,
+		PerpFees:       p.fees,
+		EntryBasis:     p.entryBasis,
+	}
+	if e.position != 0 && e.lastPrice > 0 {
+		r.SpotUnrealized = e.position * (e.lastPrice - e.entryPrice)
+	}
+	if e.lastPrice > 0 && p.mark > 0 {
+		r.Basis = p.mark - e.lastPrice
+	}
+	if p.qty != 0 {
+		// Only the quantity offset by an opposite spot position is a basis trade.
+		hedged := math.Min(math.Abs(p.qty), math.Abs(e.position))
+		if p.qty*e.position < 0 {
+			sign := 1.0
+			if p.qty < 0 {
+				sign = -1
+			}
+			r.BasisPnL = sign * hedged * (r.Basis - r.EntryBasis)
+		}
+	}
+	return r, nil
+}
+
+func (p *perpLeg) unrealized() float64 {
+	if p.qty == 0 {
+		return 0
+	}
+	return p.qty * (p.mark - p.entry)
+}
+
+func (e *Exchange) recordPerpOrder(side OrderSide, qty float64, fee float64, equityBefore float64, reason string) Order {
+	e.nextID++
+	bal := e.Balance()
+	order := Order{
+		ID:            e.nextID,
+		RunID:         e.runID,
+		Symbol:        e.symbol,
+		Tag:           e.tag,
+		Side:          side,
+		Qty:           qty,
+		MidPrice:      e.perp.mark,
+		Price:         e.perp.mark,
+		Fee:           fee,
+		EquityBefore:  equityBefore,
+		Reason:        reason,
+		PositionAfter: e.perp.qty,
+		USD:           e.usd,
+		Equity:        bal.Equity,
+		EntryPrice:    e.perp.entry,
+		Tick:          e.tick,
+		PlacedTick:    e.tick,
+	}
+	e.perp.orders = append(e.perp.orders, order)
+	e.recordFee(order.ID, fee)
+	return order
+}
+
+// AlignBars keeps the bars whose timestamps appear in both series, e.g. a spot file and a perp
+// file with different gaps, so they can be replayed together.
+func AlignBars(a []OHLCBar, b []OHLCBar) ([]OHLCBar, []OHLCBar, error) {
+	index := make(map[int64]int, len(b))
+	for i, bar := range b {
+		if bar.Time.IsZero() {
+			return nil, nil, fmt.Errorf("bar %d of the second series has no time", i)
+		}
+		index[bar.Time.UnixNano()] = i
+	}
+	outA := make([]OHLCBar, 0, min(len(a), len(b)))
+	outB := make([]OHLCBar, 0, min(len(a), len(b)))
+	for i, bar := range a {
+		if bar.Time.IsZero() {
+			return nil, nil, fmt.Errorf("bar %d of the first series has no time", i)
+		}
+		if j, ok := index[bar.Time.UnixNano()]; ok {
+			outA = append(outA, bar)
+			outB = append(outB, b[j])
+		}
+	}
+	return outA, outB, nil
+}
@@ -0,0 +1,125 @@
+package emul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchJob is one (config, strategy) combination. Config.Bars and Config.BarSet may be left
+// empty to use the runner's shared bars. Each job needs its own Strategy value since
+// strategies keep state. Prune, when set, stops the job early (see RunPruned); it keeps state
+// too, so every job needs its own.
+type BatchJob struct {
+	Name     string
+	Config   EmulatorConfig
+	Strategy Strategy
+	Prune    PruneFunc
+}
+
+type BatchResult struct {
+	Index   int
+	Name    string
+	Equity  []EquityPoint
+	Orders  []Order
+	Balance Balance
+	// PruneReason is set when the job was stopped early; Err is then nil.
+	PruneReason string
+	Err         error
+}
+
+// BatchRunner executes jobs on a worker pool. Bars are loaded once by the caller and shared
+// read-only by every emulator through a BarSet, so a sweep never re-parses or copies them.
+type BatchRunner struct {
+	set     *BarSet
+	workers int
+}
+
+func NewBatchRunner(bars []OHLCBar, workers int) *BatchRunner {
+	return NewBatchRunnerFromBarSet(NewBarSet(bars), workers)
+}
+
+func NewBatchRunnerFromBarSet(set *BarSet, workers int) *BatchRunner {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &BatchRunner{
+		set:     set,
+		workers: workers,
+	}
+}
+
+// Run starts the jobs and streams results in completion order; the channel is closed when all
+// jobs finished or ctx was canceled (jobs not yet started are then skipped).
+func (r *BatchRunner) Run(ctx context.Context, jobs []BatchJob) <-chan BatchResult {
+	out := make(chan BatchResult, r.workers)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < r.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				res := r.runJob(i, jobs[i])
+				select {
+				case out <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer func() {
+			close(next)
+			wg.Wait()
+			close(out)
+		}()
+		for i := range jobs {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// RunAll runs every job and returns results ordered by job index.
+func (r *BatchRunner) RunAll(ctx context.Context, jobs []BatchJob) []BatchResult {
+	results := make([]BatchResult, len(jobs))
+	for res := range r.Run(ctx, jobs) {
+		results[res.Index] = res
+	}
+	return results
+}
+
+func (r *BatchRunner) runJob(index int, job BatchJob) BatchResult {
+	res := BatchResult{Index: index, Name: job.Name}
+	if job.Strategy == nil {
+		res.Err = fmt.Errorf("job %d has no strategy", index)
+		return res
+	}
+	cfg := job.Config
+	if len(cfg.Bars) == 0 && cfg.BarSet == nil {
+		cfg.BarSet = r.set
+	}
+	emu, err := NewEmulatorFromConfig(cfg)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer emu.Close()
+	res.Equity, res.Err = RunPruned(emu, job.Strategy, job.Prune)
+	if errors.Is(res.Err, ErrPruned) {
+		res.PruneReason = strings.TrimPrefix(res.Err.Error(), ErrPruned.Error()+": ")
+		res.Err = nil
+	}
+	res.Orders = emu.ex.Orders()
+	res.Balance = emu.ex.Balance()
+	return res
+}
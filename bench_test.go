@@ -0,0 +1,117 @@
+package emul
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func benchBars(n int) []OHLCBar {
+	bars := make([]OHLCBar, n)
+	price := 100.0
+	for i := range bars {
+		open := price
+		if i%2 == 0 {
+			price *= 1.001
+		} else {
+			price *= 0.9995
+		}
+		bars[i] = OHLCBar{
+			Open:    open,
+			High:    max(open, price) * 1.002,
+			Low:     min(open, price) * 0.998,
+			Close:   price,
+			Average: (open + price) / 2,
+		}
+	}
+	return bars
+}
+
+func writeBenchCSV(b *testing.B, rows int) string {
+	b.Helper()
+	var sb strings.Builder
+	ts := int64(1_700_000_000)
+	for i, bar := range benchBars(rows) {
+		fmt.Fprintf(&sb, "%d,%.8f,%.8f,%.8f,%.8f,%d\n", ts+int64(i)*60, bar.Open, bar.High, bar.Low, bar.Close, 1000+i)
+	}
+	path := filepath.Join(b.TempDir(), "bench.csv")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkLoadBarsFromCSV(b *testing.B) {
+	path := writeBenchCSV(b, 100_000)
+	info, _ := os.Stat(path)
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadBarsFromCSV(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEmulatorNext(b *testing.B) {
+	bars := benchBars(1 << 16)
+	b.ReportAllocs()
+	emu, _ := NewEmulator(1000, 0.001, 0, 0, bars)
+	for b.Loop() {
+		if _, _, err := emu.Next(); err == ErrNoMoreBars {
+			emu, _ = NewEmulator(1000, 0.001, 0, 0, bars)
+		}
+	}
+}
+
+func BenchmarkEmulatorNextWithTrading(b *testing.B) {
+	bars := benchBars(1 << 16)
+	b.ReportAllocs()
+	emu, _ := NewEmulator(1000, 0.001, 0, 0, bars)
+	for b.Loop() {
+		_, _, err := emu.Next()
+		if err == ErrNoMoreBars {
+			emu, _ = NewEmulator(1000, 0.001, 0, 0, bars)
+			continue
+		}
+		ex := emu.Exchange()
+		if ex.Balance().Position == 0 {
+			_, _ = ex.LongLimit(0, 1)
+		} else {
+			_, _ = ex.CloseLimit(0, ReasonExit, "")
+		}
+	}
+}
+
+func BenchmarkProcessPending(b *testing.B) {
+	bar := benchBars(1)[0]
+	for _, size := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("queue=%d", size), func(b *testing.B) {
+			ex := NewExchange(1000, 0.001, 0, 0)
+			ex.lastPrice = bar.Close
+			// Entries and exits alternate inside the bar's range, so every order fills.
+			queue := make([]pendingOrder, size)
+			for i := range queue {
+				queue[i] = pendingOrder{id: int64(i + 1), kind: pendingOpenLong, price: bar.Close, fraction: 0.5}
+				if i%2 == 1 {
+					queue[i] = pendingOrder{id: int64(i + 1), kind: pendingClose, price: bar.Close, reason: ReasonExit}
+				}
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				ex.usd, ex.position, ex.entryPrice = 1000, 0, 0
+				ex.orders, ex.ledger = ex.orders[:0], ex.ledger[:0]
+				clear(ex.executedByID)
+				clear(ex.limitDone)
+				ex.pending = append(ex.pending[:0], queue...)
+				ex.tick = 1
+				ex.processPending(bar)
+				if len(ex.orders) != size {
+					b.Fatalf("%d of %d queued orders filled", len(ex.orders), size)
+				}
+			}
+		})
+	}
+}
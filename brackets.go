@@ -0,0 +1,186 @@
+package emul
+
+import (
+	"errors"
+	"math"
+)
+
+// Stop kinds of the orders closed by a bracket (see ExitBreakdown.ByStopKind).
+const (
+	StopKindStop   = "stop"
+	StopKindTarget = "target"
+)
+
+var ErrInvalidBracket = errors.New("bracket stop must be on the losing side and target on the winning side of the price")
+
+// Bracket is a protective stop and a take-profit target attached to the open position; a zero
+// level is not set. The exchange checks them on every bar after the one they were set on: a
+// stop fills at its price when the bar trades through it (at the open when the bar gaps past it)
+// with the taker fee and ReasonStopLoss; a target fills likewise with the maker fee and
+// ReasonExit. When a bar touches both, the stop is assumed to have traded first. The bracket is
+// removed when the position closes, however it closes.
+type Bracket struct {
+	Stop   float64
+	Target float64
+}
+
+type activeBracket struct {
+	Bracket
+	setTick int64
+	rules   *StopRules
+	best    float64
+	ladder  []LadderLevel
+}
+
+// SetBracket attaches b to the open position, replacing the levels of any bracket already set;
+// levels must be on the right side of the last price.
+func (e *Exchange) SetBracket(b Bracket) error {
+	if e.position == 0 {
+		return ErrNoPosition
+	}
+	if b.Stop < 0 || b.Target < 0 {
+		return ErrInvalidBracket
+	}
+	long := e.position > 0
+	if b.Stop > 0 && (long && b.Stop >= e.lastPrice || !long && b.Stop <= e.lastPrice) {
+		return ErrInvalidBracket
+	}
+	if b.Target > 0 && (long && b.Target <= e.lastPrice || !long && b.Target >= e.lastPrice) {
+		return ErrInvalidBracket
+	}
+	next := &activeBracket{Bracket: b, setTick: e.tick}
+	if e.bracket != nil {
+		next.rules, next.best, next.ladder = e.bracket.rules, e.bracket.best, e.bracket.ladder
+	}
+	e.bracket = next
+	return nil
+}
+
+// Bracket returns the bracket of the open position; ok is false when none is set.
+func (e *Exchange) Bracket() (Bracket, bool) {
+	if e.bracket == nil {
+		return Bracket{}, false
+	}
+	return e.bracket.Bracket, true
+}
+
+func (e *Exchange) ClearBracket() {
+	e.bracket = nil
+}
+
+// checkBracket closes the position when bar reaches a level of its bracket.
+func (e *Exchange) checkBracket(bar OHLCBar) *Order {
+	b := e.bracket
+	if b == nil || e.position == 0 || e.tick <= b.setTick {
+		return nil
+	}
+	long := e.position > 0
+	if price, ok := levelReached(bar, b.Stop, long); ok {
+		order := e.closeAtPrice(price, ReasonStopLoss, StopKindStop, e.fee)
+		return &order
+	}
+	if price, ok := levelReached(bar, b.Target, !long); ok {
+		order := e.closeAtPrice(price, ReasonExit, StopKindTarget, e.makerFee)
+		return &order
+	}
+	return e.checkLadder(bar)
+}
+
+// levelReached reports whether bar traded down (below) or up to level and the price it filled
+// at: the open when the bar gapped past the level.
+func levelReached(bar OHLCBar, level float64, below bool) (float64, bool) {
+	if level <= 0 {
+		return 0, false
+	}
+	if below {
+		if bar.Open > 0 && bar.Open <= level {
+			return bar.Open, true
+		}
+		return level, bar.Low <= level
+	}
+	if bar.Open >= level {
+		return bar.Open, true
+	}
+	return level, bar.High >= level
+}
+
+// ATRBracket places the stop stopATR average true ranges (see ATR) against a position entered
+// on side at entry and the target targetATR ranges in its favour; a zero multiple leaves that
+// level unset. ok is false until bars hold period+1 bars.
+func ATRBracket(bars []OHLCBar, period int, side OrderSide, entry float64, stopATR float64, targetATR float64) (Bracket, bool) {
+	atr := ATR(bars, period)
+	if atr <= 0 || entry <= 0 || stopATR < 0 || targetATR < 0 {
+		return Bracket{}, false
+	}
+	dir := 1.0
+	if side == SideSell {
+		dir = -1
+	}
+	var b Bracket
+	if stopATR > 0 {
+		b.Stop = math.Max(entry-dir*stopATR*atr, 0)
+	}
+	if targetATR > 0 {
+		b.Target = math.Max(entry+dir*targetATR*atr, 0)
+	}
+	return b, b.Stop > 0 || b.Target > 0
+}
+
+// SwingBracket places the stop at the lowest low (highest high for shorts) of the last lookback
+// bars and the target rewardRisk times that distance beyond entry; rewardRisk 0 leaves the
+// target unset. ok is false when the swing is not beyond entry.
+func SwingBracket(bars []OHLCBar, lookback int, side OrderSide, entry float64, rewardRisk float64) (Bracket, bool) {
+	if lookback <= 0 || len(bars) < lookback || entry <= 0 || rewardRisk < 0 {
+		return Bracket{}, false
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, bar := range bars[len(bars)-lookback:] {
+		low, high = math.Min(low, bar.Low), math.Max(high, bar.High)
+	}
+	b := Bracket{Stop: low}
+	if side == SideSell {
+		b.Stop = high
+	}
+	risk := entry - b.Stop
+	if side == SideSell {
+		risk = -risk
+	}
+	if risk <= 0 {
+		return Bracket{}, false
+	}
+	if rewardRisk > 0 {
+		if side == SideSell {
+			b.Target = math.Max(entry-rewardRisk*risk, 0)
+		} else {
+			b.Target = entry + rewardRisk*risk
+		}
+	}
+	return b, true
+}
+
+// ProtectATR sets an ATRBracket over the context window on the open position, measured from its
+// entry price.
+func (c *StrategyContext) ProtectATR(period int, stopATR float64, targetATR float64) (Bracket, error) {
+	pos := c.Position()
+	if pos.Qty == 0 {
+		return Bracket{}, ErrNoPosition
+	}
+	b, ok := ATRBracket(c.history, period, pos.Side, pos.EntryPrice, stopATR, targetATR)
+	if !ok {
+		return Bracket{}, ErrInvalidBracket
+	}
+	return b, c.ex.SetBracket(b)
+}
+
+// ProtectSwing sets a SwingBracket over the context window on the open position.
+func (c *StrategyContext) ProtectSwing(lookback int, rewardRisk float64) (Bracket, error) {
+	pos := c.Position()
+	if pos.Qty == 0 {
+		return Bracket{}, ErrNoPosition
+	}
+	b, ok := SwingBracket(c.history, lookback, pos.Side, pos.EntryPrice, rewardRisk)
+	if !ok {
+		return Bracket{}, ErrInvalidBracket
+	}
+	return b, c.ex.SetBracket(b)
+}
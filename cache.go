@@ -0,0 +1,181 @@
+package emul
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BarCache is an in-process LRU of loaded bar sets keyed by (data root, coin, interval, years,
+// months). An entry is reused only while every backing file keeps its size and modification time;
+// a changed, added or removed file makes the next Load re-parse.
+//
+// The cache holds the creator reference of each BarSet and releases it on eviction, so emulators
+// built from a set, and callers that have not released it yet, keep their bars even after the
+// entry is gone.
+type BarCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	hits     int
+	misses   int
+	opts     CSVReaderOptions
+}
+
+type barCacheEntry struct {
+	key    string
+	stamps []fileStamp
+	set    *BarSet
+}
+
+type fileStamp struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// NewBarCache keeps at most capacity bar sets (minimum 1).
+func NewBarCache(capacity int) *BarCache {
+	return NewBarCacheWithOptions(capacity, CSVReaderOptions{})
+}
+
+// NewBarCacheWithOptions is NewBarCache parsing files with opts.
+func NewBarCacheWithOptions(capacity int, opts CSVReaderOptions) *BarCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &BarCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		opts:     opts,
+	}
+}
+
+// Load returns the cached set for the request or parses the files and caches the result. Empty
+// years selects every file in the interval directory; empty months disables the month filter.
+// The set comes with a reference retained for the caller, so a concurrent eviction cannot free
+// it; callers must Release it once their emulators are built.
+func (c *BarCache) Load(dataRoot string, coin string, interval string, years []int, months []int) (*BarSet, error) {
+	coin = strings.ToLower(strings.TrimSpace(coin))
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(strings.TrimSpace(dataRoot), coin, interval)
+	files, err := listCSVFilesForYears(dir, coin, years)
+	if err != nil {
+		return nil, err
+	}
+	stamps, err := statFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s|%s|%s|%v|%v", filepath.Clean(dataRoot), coin, interval, years, months)
+
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*barCacheEntry)
+		if sameStamps(entry.stamps, stamps) {
+			c.order.MoveToFront(el)
+			c.hits++
+			entry.set.Retain()
+			c.mu.Unlock()
+			return entry.set, nil
+		}
+		c.remove(el)
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	values, ohlc, _, err := LoadSeriesWithOHLCFromDataRootWithOptions(dataRoot, coin, interval, years, months, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	bars, err := BarsFromSeries(values, ohlc)
+	if err != nil {
+		return nil, err
+	}
+	set := NewBarSet(bars)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		// Another caller loaded the same key concurrently; keep the entry already cached.
+		set.Release()
+		c.order.MoveToFront(el)
+		cached := el.Value.(*barCacheEntry).set
+		cached.Retain()
+		return cached, nil
+	}
+	set.Retain()
+	c.entries[key] = c.order.PushFront(&barCacheEntry{key: key, stamps: stamps, set: set})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return set, nil
+}
+
+// Invalidate drops every entry loaded from dataRoot (all entries when dataRoot is empty).
+func (c *BarCache) Invalidate(dataRoot string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prefix := ""
+	if strings.TrimSpace(dataRoot) != "" {
+		prefix = filepath.Clean(dataRoot) + "|"
+	}
+	for key, el := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(el)
+		}
+	}
+}
+
+func (c *BarCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats reports cache hits and misses since creation.
+func (c *BarCache) Stats() (hits int, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *BarCache) remove(el *list.Element) {
+	entry := el.Value.(*barCacheEntry)
+	c.order.Remove(el)
+	delete(c.entries, entry.key)
+	entry.set.Release()
+}
+
+func statFiles(files []string) ([]fileStamp, error) {
+	stamps := make([]fileStamp, len(files))
+	for i, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps[i] = fileStamp{path: path, size: info.Size(), modTime: info.ModTime()}
+	}
+	return stamps, nil
+}
+
+func sameStamps(a []fileStamp, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].path != b[i].path || a[i].size != b[i].size || !a[i].modTime.Equal(b[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PeriodReturn is the equity change over one calendar month or year (Month is 0 for years).
+type PeriodReturn struct {
+	Year        int
+	Month       time.Month
+	StartEquity float64
+	EndEquity   float64
+	Return      float64
+}
+
+type CalendarReturns struct {
+	Monthly    []PeriodReturn
+	Yearly     []PeriodReturn
+	BestMonth  PeriodReturn
+	WorstMonth PeriodReturn
+}
+
+type SeriesPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// CalendarBreakdown buckets an equity curve into UTC calendar months and years. Each period
+// starts from the last equity of the previous period, so returns chain into the total.
+func CalendarBreakdown(curve []EquityPoint) (CalendarReturns, error) {
+	if err := requireCurveTimes(curve); err != nil {
+		return CalendarReturns{}, err
+	}
+	out := CalendarReturns{
+		Monthly: bucketReturns(curve, func(t time.Time) (int, time.Month) { return t.Year(), t.Month() }),
+		Yearly:  bucketReturns(curve, func(t time.Time) (int, time.Month) { return t.Year(), 0 }),
+	}
+	for i, m := range out.Monthly {
+		if i == 0 || m.Return > out.BestMonth.Return {
+			out.BestMonth = m
+		}
+		if i == 0 || m.Return < out.WorstMonth.Return {
+			out.WorstMonth = m
+		}
+	}
+	return out, nil
+}
+
+func bucketReturns(curve []EquityPoint, key func(time.Time) (int, time.Month)) []PeriodReturn {
+	out := make([]PeriodReturn, 0)
+	start := curve[0].Equity
+	for i, p := range curve {
+		year, month := key(p.Time.UTC())
+		if i > 0 {
+			prevYear, prevMonth := key(curve[i-1].Time.UTC())
+			if prevYear != year || prevMonth != month {
+				start = curve[i-1].Equity
+			}
+		}
+		n := len(out)
+		if n == 0 || out[n-1].Year != year || out[n-1].Month != month {
+			out = append(out, PeriodReturn{Year: year, Month: month, StartEquity: start})
+			n++
+		}
+		out[n-1].EndEquity = p.Equity
+		if start > 0 {
+			out[n-1].Return = p.Equity/start - 1
+		}
+	}
+	return out
+}
+
+// DailyEquity resamples the curve to the last equity of each UTC day.
+func DailyEquity(curve []EquityPoint) []SeriesPoint {
+	out := make([]SeriesPoint, 0)
+	for _, p := range curve {
+		day := p.Time.UTC().Truncate(24 * time.Hour)
+		if n := len(out); n > 0 && out[n-1].Time.Equal(day) {
+			out[n-1].Value = p.Equity
+			continue
+		}
+		out = append(out, SeriesPoint{Time: day, Value: p.Equity})
+	}
+	return out
+}
+
+// RollingSharpe computes the annualized Sharpe ratio (365 trading days, zero risk-free rate)
+// of daily returns over a trailing window of days; points start once the window is full.
+func RollingSharpe(curve []EquityPoint, days int) ([]SeriesPoint, error) {
+	if err := requireCurveTimes(curve); err != nil {
+		return nil, err
+	}
+	if days < 2 {
+		return nil, fmt.Errorf("window must be at least 2 days")
+	}
+	daily := DailyEquity(curve)
+	values := make([]float64, len(daily))
+	for i, p := range daily {
+		values[i] = p.Value
+	}
+	rets := Returns(nil, values)
+	means := RollingMean(nil, rets[1:], days)
+	stds := RollingStd(nil, rets[1:], days)
+	out := make([]SeriesPoint, 0, len(means))
+	for i := range means {
+		if math.IsNaN(means[i]) {
+			continue
+		}
+		sharpe := 0.0
+		if stds[i] > 0 {
+			sharpe = means[i] / stds[i] * math.Sqrt(365)
+		}
+		out = append(out, SeriesPoint{Time: daily[i+1].Time, Value: sharpe})
+	}
+	return out, nil
+}
+
+func requireCurveTimes(curve []EquityPoint) error {
+	if len(curve) == 0 {
+		return fmt.Errorf("equity curve is empty")
+	}
+	for _, p := range curve {
+		if p.Time.IsZero() {
+			return fmt.Errorf("equity curve has points without timestamps")
+		}
+	}
+	return nil
+}
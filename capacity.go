@@ -0,0 +1,99 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+)
+
+// SetMaxParticipation caps every entry and scale-in at pct of the current bar's volume (0.1 =
+// 10%); the rest of the requested USD stays free. Zero disables the cap. Closes always flatten
+// the whole position and are not capped; bars without volume are not capped either.
+func (e *Exchange) SetMaxParticipation(pct float64) error {
+	if pct < 0 || math.IsNaN(pct) {
+		return fmt.Errorf("participation must not be negative")
+	}
+	e.participation = pct
+	return nil
+}
+
+// capByVolume limits a USD notional at price to the participation cap.
+func (e *Exchange) capByVolume(notional float64, price float64) float64 {
+	if e.participation <= 0 || e.barVolume <= 0 || price <= 0 {
+		return notional
+	}
+	return math.Min(notional, e.participation*e.barVolume*price)
+}
+
+// CapacityConfig describes a capacity sweep: the strategy is replayed with StartUSD times each
+// multiple under the same costs, impact model and participation cap.
+type CapacityConfig struct {
+	StartUSD         float64
+	Multiples        []float64
+	Costs            CostProfile
+	Impact           ImpactModel
+	MaxParticipation float64
+}
+
+// CapacityPoint is one run of the sweep. Decay is the run's return relative to the first
+// multiple's return (1 = no decay); it is 0 when the first return is 0.
+type CapacityPoint struct {
+	Multiple    float64
+	StartUSD    float64
+	FinalEquity float64
+	Return      float64
+	MaxDrawdown float64
+	Trades      int
+	Decay       float64
+}
+
+// CapacitySweep re-runs a strategy at growing capital to show how returns decay with size.
+// newStrategy must return a fresh strategy for every run since strategies keep state.
+func CapacitySweep(bars []OHLCBar, newStrategy func() Strategy, cfg CapacityConfig) ([]CapacityPoint, error) {
+	if newStrategy == nil {
+		return nil, fmt.Errorf("strategy factory is nil")
+	}
+	if cfg.StartUSD <= 0 {
+		return nil, fmt.Errorf("start USD must be positive")
+	}
+	multiples := cfg.Multiples
+	if len(multiples) == 0 {
+		multiples = []float64{1, 10, 100}
+	}
+	points := make([]CapacityPoint, 0, len(multiples))
+	for _, m := range multiples {
+		if m <= 0 {
+			return nil, fmt.Errorf("capital multiple %v must be positive", m)
+		}
+		start := cfg.StartUSD * m
+		emu, err := NewEmulatorWithProfile(start, cfg.Costs, bars)
+		if err != nil {
+			return nil, err
+		}
+		if err := emu.ex.SetImpactModel(cfg.Impact); err != nil {
+			return nil, err
+		}
+		if err := emu.ex.SetMaxParticipation(cfg.MaxParticipation); err != nil {
+			return nil, err
+		}
+		curve, err := Run(emu, newStrategy())
+		if err != nil {
+			return nil, fmt.Errorf("multiple %v: %w", m, err)
+		}
+		final := emu.ex.Balance().Equity
+		p := CapacityPoint{
+			Multiple:    m,
+			StartUSD:    start,
+			FinalEquity: final,
+			Return:      final/start - 1,
+			MaxDrawdown: MaxDrawdown(curve),
+			Trades:      len(PairTrades(emu.ex.orders)),
+		}
+		if len(points) > 0 && points[0].Return != 0 {
+			p.Decay = p.Return / points[0].Return
+		} else if len(points) == 0 && p.Return != 0 {
+			p.Decay = 1
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
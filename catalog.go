@@ -0,0 +1,219 @@
+package emul
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DataFile struct {
+	Path  string
+	Year  int
+	Size  int64
+	First time.Time
+	Last  time.Time
+}
+
+type IntervalCatalog struct {
+	Interval string
+	Files    []DataFile
+	Years    []int
+	First    time.Time
+	Last     time.Time
+}
+
+type CoinCatalog struct {
+	Coin      string
+	Intervals []IntervalCatalog
+}
+
+// DataCatalog describes what a data root holds, as found on disk.
+type DataCatalog struct {
+	Root  string
+	Coins []CoinCatalog
+}
+
+var yearInName = regexp.MustCompile(`(19|20)\d{2}`)
+
+// coverageTailBytes is how far from the end of a file DiscoverDataRoot looks for the last row.
+const coverageTailBytes = 64 * 1024
+
+// DiscoverDataRoot walks <dataRoot>/<coin>/<interval>/*.csv and reports, per coin and interval,
+// the files found, the years they cover (from file names) and the first/last bar timestamps.
+// Only the head and tail of each file are read.
+func DiscoverDataRoot(dataRoot string) (DataCatalog, error) {
+	return DiscoverDataRootWithOptions(dataRoot, CSVReaderOptions{})
+}
+
+// DiscoverDataRootWithOptions is DiscoverDataRoot with explicit reader options.
+func DiscoverDataRootWithOptions(dataRoot string, opts CSVReaderOptions) (DataCatalog, error) {
+	root := strings.TrimSpace(dataRoot)
+	if root == "" {
+		return DataCatalog{}, fmt.Errorf("data root is empty")
+	}
+	coinDirs, err := os.ReadDir(root)
+	if err != nil {
+		return DataCatalog{}, err
+	}
+	catalog := DataCatalog{Root: root}
+	for _, coinDir := range coinDirs {
+		if !coinDir.IsDir() || strings.HasPrefix(coinDir.Name(), ".") {
+			continue
+		}
+		coin := CoinCatalog{Coin: strings.ToLower(coinDir.Name())}
+		intervalDirs, err := os.ReadDir(filepath.Join(root, coinDir.Name()))
+		if err != nil {
+			return DataCatalog{}, err
+		}
+		for _, intervalDir := range intervalDirs {
+			interval, err := ParseInterval(intervalDir.Name())
+			if !intervalDir.IsDir() || err != nil || interval != intervalDir.Name() {
+				continue
+			}
+			dir := filepath.Join(root, coinDir.Name(), interval)
+			ic, err := discoverInterval(dir, interval, opts)
+			if err != nil {
+				return DataCatalog{}, err
+			}
+			if len(ic.Files) > 0 {
+				coin.Intervals = append(coin.Intervals, ic)
+			}
+		}
+		sort.Slice(coin.Intervals, func(i, j int) bool {
+			return IntervalDuration(coin.Intervals[i].Interval) < IntervalDuration(coin.Intervals[j].Interval)
+		})
+		if len(coin.Intervals) > 0 {
+			catalog.Coins = append(catalog.Coins, coin)
+		}
+	}
+	sort.Slice(catalog.Coins, func(i, j int) bool { return catalog.Coins[i].Coin < catalog.Coins[j].Coin })
+	return catalog, nil
+}
+
+func discoverInterval(dir string, interval string, opts CSVReaderOptions) (IntervalCatalog, error) {
+	ic := IntervalCatalog{Interval: interval}
+	files, err := listCSVFiles(dir)
+	if err != nil {
+		return ic, err
+	}
+	years := make(map[int]bool)
+	for _, path := range files {
+		df, err := describeDataFile(path, opts)
+		if err != nil {
+			return ic, err
+		}
+		ic.Files = append(ic.Files, df)
+		if df.Year > 0 {
+			years[df.Year] = true
+		}
+		if !df.First.IsZero() && (ic.First.IsZero() || df.First.Before(ic.First)) {
+			ic.First = df.First
+		}
+		if df.Last.After(ic.Last) {
+			ic.Last = df.Last
+		}
+	}
+	for y := range years {
+		ic.Years = append(ic.Years, y)
+	}
+	sort.Ints(ic.Years)
+	return ic, nil
+}
+
+func describeDataFile(path string, opts CSVReaderOptions) (DataFile, error) {
+	df := DataFile{Path: path}
+	if m := yearInName.FindAllString(filepath.Base(path), -1); len(m) > 0 {
+		df.Year, _ = strconv.Atoi(m[len(m)-1])
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return df, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return df, err
+	}
+	df.Size = info.Size()
+
+	scanner := newCSVScanner(file, path, opts)
+	rows := newCSVRowParser(opts)
+	for scanner.Scan() {
+		if bar, ok := rows.parse(scanner.Text(), nil); ok && !bar.Time.IsZero() {
+			df.First = bar.Time
+			break
+		}
+	}
+	offset := max(df.Size-coverageTailBytes, 0)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return df, err
+	}
+	tail, err := io.ReadAll(file)
+	if err != nil {
+		return df, err
+	}
+	lines := strings.Split(string(tail), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		// The first line of a mid-file chunk is usually cut; it only counts when it starts the file.
+		if i == 0 && offset > 0 {
+			break
+		}
+		if bar, ok := parseCSVBarLayout(lines[i], rows.layout, nil); ok && !bar.Time.IsZero() {
+			df.Last = bar.Time
+			break
+		}
+	}
+	return df, nil
+}
+
+func (c DataCatalog) Coin(name string) (CoinCatalog, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, coin := range c.Coins {
+		if coin.Coin == name {
+			return coin, true
+		}
+	}
+	return CoinCatalog{}, false
+}
+
+func (c CoinCatalog) Interval(interval string) (IntervalCatalog, bool) {
+	interval = strings.ToLower(strings.TrimSpace(interval))
+	for _, ic := range c.Intervals {
+		if ic.Interval == interval {
+			return ic, true
+		}
+	}
+	return IntervalCatalog{}, false
+}
+
+// Validate checks that coin/interval exist and every requested year has a file, before loading.
+func (c DataCatalog) Validate(coin string, interval string, years []int) error {
+	cc, ok := c.Coin(coin)
+	if !ok {
+		return fmt.Errorf("coin %q not found in %s", coin, c.Root)
+	}
+	ic, ok := cc.Interval(interval)
+	if !ok {
+		return fmt.Errorf("interval %q not available for %s", interval, cc.Coin)
+	}
+	have := make(map[int]bool, len(ic.Years))
+	for _, y := range ic.Years {
+		have[y] = true
+	}
+	missing := make([]string, 0)
+	for _, y := range years {
+		if !have[y] {
+			missing = append(missing, strconv.Itoa(y))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s/%s has no data for years %s", cc.Coin, ic.Interval, strings.Join(missing, ", "))
+	}
+	return nil
+}
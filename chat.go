@@ -0,0 +1,98 @@
+package emul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FormatEvent renders ev as a one-line chat message.
+func FormatEvent(ev Event) string {
+	switch ev.Kind {
+	case EventFill, EventLiquidation:
+		msg := fmt.Sprintf("%s #%d %s %g @ %.2f fee %.2f, equity %.2f", strings.ReplaceAll(ev.Kind, "_", " "), ev.OrderID, ev.Side, ev.Qty, ev.Price, ev.Fee, ev.Equity)
+		if ev.Reason != "" {
+			msg += " (" + ev.Reason + ")"
+		}
+		return msg
+	case EventRiskBreach:
+		return fmt.Sprintf("risk breach %s at %s, equity %.2f", ev.Rule, ev.BarTime.UTC().Format(time.DateTime), ev.Equity)
+	case EventDailyPnL:
+		return fmt.Sprintf("daily pnl %s: %+.2f, equity %.2f", ev.BarTime.UTC().Format(time.DateOnly), ev.PnL, ev.Equity)
+	}
+	return fmt.Sprintf("%s at tick %d, equity %.2f", ev.Kind, ev.Tick, ev.Equity)
+}
+
+// Telegram sends events as FormatEvent messages to a chat through a bot. BaseURL defaults to
+// the public Bot API; Kinds, Timeout, Client and OnError work as in Webhook.
+type Telegram struct {
+	Token   string
+	ChatID  string
+	BaseURL string
+	Kinds   []string
+	Timeout time.Duration
+	Client  *http.Client
+	OnError func(ev Event, err error)
+}
+
+func (t *Telegram) Notify(ctx context.Context, ev Event) error {
+	if len(t.Kinds) > 0 && !slices.Contains(t.Kinds, ev.Kind) {
+		return nil
+	}
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	body, err := json.Marshal(map[string]string{"chat_id": t.ChatID, "text": FormatEvent(ev)})
+	if err == nil {
+		err = postJSON(ctx, t.Client, strings.TrimRight(base, "/")+"/bot"+t.Token+"/sendMessage", nil, t.Timeout, body)
+	}
+	if err != nil {
+		// The token is part of the URL; keep it out of errors that end up in logs.
+		msg := err.Error()
+		if t.Token != "" {
+			msg = strings.ReplaceAll(msg, t.Token, "<token>")
+		}
+		err = fmt.Errorf("telegram: %s", msg)
+		if t.OnError != nil {
+			t.OnError(ev, err)
+		}
+	}
+	return err
+}
+
+// Discord sends events as FormatEvent messages to a channel webhook URL. Kinds, Timeout, Client
+// and OnError work as in Webhook.
+type Discord struct {
+	WebhookURL string
+	Username   string
+	Kinds      []string
+	Timeout    time.Duration
+	Client     *http.Client
+	OnError    func(ev Event, err error)
+}
+
+func (d *Discord) Notify(ctx context.Context, ev Event) error {
+	if len(d.Kinds) > 0 && !slices.Contains(d.Kinds, ev.Kind) {
+		return nil
+	}
+	msg := map[string]string{"content": FormatEvent(ev)}
+	if d.Username != "" {
+		msg["username"] = d.Username
+	}
+	body, err := json.Marshal(msg)
+	if err == nil {
+		err = postJSON(ctx, d.Client, d.WebhookURL, nil, d.Timeout, body)
+	}
+	if err != nil {
+		err = fmt.Errorf("discord: %w", err)
+		if d.OnError != nil {
+			d.OnError(ev, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package emul
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells time-dependent logic (funding intervals, rate-limit windows, notification
+// delivery) what time it is, so the same code runs on replayed bar times in a backtest and on
+// the wall clock when paper trading in real time.
+type Clock interface {
+	Now() time.Time
+}
+
+// WallClock is the real time.
+type WallClock struct{}
+
+func (WallClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimClock is a manually driven clock, safe for concurrent use.
+type SimClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *SimClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func (c *SimClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SetClock makes the exchange read time from c; nil restores the default, the time of the
+// current bar.
+func (e *Exchange) SetClock(c Clock) {
+	e.clock = c
+}
+
+// Now is the exchange's current time: its clock's, or the current bar's time without one.
+func (e *Exchange) Now() time.Time {
+	if e.clock != nil {
+		return e.clock.Now()
+	}
+	if e.hasLastBar {
+		return e.lastBar.Time
+	}
+	return time.Time{}
+}
+
+// SetClock sets the clock of the emulator's exchange and of every account, including accounts
+// added later. With WallClock, time-based logic follows real time while bars are fed live. Set
+// before the replay starts, it also restarts the session's uptime (see SessionStats) on c.
+func (e *Emulator) SetClock(c Clock) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clock = c
+	if e.index == 0 {
+		e.started = e.now()
+	}
+	e.ex.SetClock(c)
+	for _, ex := range e.accounts {
+		ex.SetClock(c)
+	}
+}
+
+// now is the emulator's clock time, or the wall clock without a clock.
+func (e *Emulator) now() time.Time {
+	if e.clock != nil {
+		return e.clock.Now()
+	}
+	return time.Now()
+}
+
+// SetClock makes the limiter's windows follow c instead of the wall clock; nil restores the wall
+// clock.
+func (r *RateLimiter) SetClock(c Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c == nil {
+		r.now = time.Now
+		return
+	}
+	r.now = c.Now
+}
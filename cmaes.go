@@ -0,0 +1,114 @@
+package emul
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// OptimizeCMAES searches the parameters with the separable CMA-ES (diagonal covariance), which
+// adapts a per-parameter step size and suits noisy backtest objectives with a few to a few
+// dozen continuous parameters. The search runs in the unit cube mapped onto the Param ranges;
+// candidates outside it are clipped. Population defaults to 4+3ln(n).
+func (r *BatchRunner) OptimizeCMAES(ctx context.Context, cfg OptimizeConfig) (OptimizeResult, error) {
+	if err := cfg.validate(); err != nil {
+		return OptimizeResult{}, err
+	}
+	n := len(cfg.Params)
+	nf := float64(n)
+	lambda := cfg.Population
+	if lambda <= 0 {
+		lambda = 4 + int(3*math.Log(nf))
+	}
+	lambda = max(lambda, 2)
+	mu := lambda / 2
+	weights := make([]float64, mu)
+	sum := 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sum += weights[i]
+	}
+	sumSq := 0.0
+	for i := range weights {
+		weights[i] /= sum
+		sumSq += weights[i] * weights[i]
+	}
+	mueff := 1 / sumSq
+
+	cs := (mueff + 2) / (nf + mueff + 5)
+	ds := 1 + 2*math.Max(0, math.Sqrt((mueff-1)/(nf+1))-1) + cs
+	cc := (4 + mueff/nf) / (nf + 4 + 2*mueff/nf)
+	c1 := 2 / ((nf+1.3)*(nf+1.3) + mueff) * (nf + 2) / 3
+	cmu := math.Min(1-c1, 2*(mueff-2+1/mueff)/((nf+2)*(nf+2)+mueff)*(nf+2)/3)
+	chiN := math.Sqrt(nf) * (1 - 1/(4*nf) + 1/(21*nf*nf))
+
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))
+	mean := make([]float64, n)
+	diag := make([]float64, n)
+	pc := make([]float64, n)
+	ps := make([]float64, n)
+	for i := range mean {
+		mean[i], diag[i] = 0.5, 1
+	}
+	sigma := 0.3
+
+	var res OptimizeResult
+	for gen := 0; len(res.Trials) < cfg.Budget; gen++ {
+		count := min(lambda, cfg.Budget-len(res.Trials))
+		ys := make([][]float64, count)
+		points := make([][]float64, count)
+		for k := range points {
+			ys[k] = make([]float64, n)
+			points[k] = make([]float64, n)
+			for i := range n {
+				u := mean[i] + sigma*math.Sqrt(diag[i])*rng.NormFloat64()
+				points[k][i] = math.Min(math.Max(u, 0), 1)
+				// Steps are taken from the clipped point so the update matches what was evaluated.
+				ys[k][i] = (points[k][i] - mean[i]) / sigma
+			}
+		}
+		scores, err := cfg.evaluate(ctx, r, gen, points, &res)
+		if err != nil {
+			return res, err
+		}
+		if count < mu {
+			break
+		}
+		order := make([]int, count)
+		for k := range order {
+			order[k] = k
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+		yw := make([]float64, n)
+		for k, w := range weights {
+			for i := range n {
+				yw[i] += w * ys[order[k]][i]
+			}
+		}
+		psNorm := 0.0
+		for i := range n {
+			mean[i] += sigma * yw[i]
+			ps[i] = (1-cs)*ps[i] + math.Sqrt(cs*(2-cs)*mueff)*yw[i]/math.Sqrt(diag[i])
+			psNorm += ps[i] * ps[i]
+		}
+		psNorm = math.Sqrt(psNorm)
+		hsig := 0.0
+		if psNorm/math.Sqrt(1-math.Pow(1-cs, float64(2*(gen+1))))/chiN < 1.4+2/(nf+1) {
+			hsig = 1
+		}
+		for i := range n {
+			pc[i] = (1-cc)*pc[i] + hsig*math.Sqrt(cc*(2-cc)*mueff)*yw[i]
+			rankMu := 0.0
+			for k, w := range weights {
+				rankMu += w * ys[order[k]][i] * ys[order[k]][i]
+			}
+			diag[i] = (1-c1-cmu)*diag[i] + c1*(pc[i]*pc[i]+(1-hsig)*cc*(2-cc)*diag[i]) + cmu*rankMu
+			diag[i] = math.Max(diag[i], 1e-12)
+		}
+		sigma *= math.Exp(cs / ds * (psNorm/chiN - 1))
+		sigma = math.Min(sigma, 1)
+	}
+	return res, nil
+}
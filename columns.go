@@ -0,0 +1,161 @@
+package emul
+
+import (
+	"math"
+	"time"
+)
+
+// SeriesFromBars is the inverse of BarsFromSeries: it returns the Average column and the OHLC columns.
+func SeriesFromBars(bars []OHLCBar) ([]float64, OHLCSeries) {
+	n := len(bars)
+	values := make([]float64, n)
+	ohlc := OHLCSeries{
+		Time:   make([]time.Time, n),
+		Open:   make([]float64, n),
+		High:   make([]float64, n),
+		Low:    make([]float64, n),
+		Close:  make([]float64, n),
+		Volume: make([]float64, n),
+	}
+	for i, b := range bars {
+		values[i] = b.Average
+		ohlc.Time[i] = b.Time
+		ohlc.Open[i] = b.Open
+		ohlc.High[i] = b.High
+		ohlc.Low[i] = b.Low
+		ohlc.Close[i] = b.Close
+		ohlc.Volume[i] = b.Volume
+	}
+	return values, ohlc
+}
+
+// The helpers below work on plain columns and write into dst (reused when it has capacity),
+// so indicator passes over millions of bars stay sequential in memory and allocation-free.
+// Positions before the first full window are NaN.
+
+func RollingMax(dst []float64, src []float64, window int) []float64 {
+	return rollingExtreme(dst, src, window, func(a float64, b float64) bool { return a >= b })
+}
+
+func RollingMin(dst []float64, src []float64, window int) []float64 {
+	return rollingExtreme(dst, src, window, func(a float64, b float64) bool { return a <= b })
+}
+
+// rollingExtreme keeps a monotonic deque of indexes in a ring of window slots,
+// giving O(n) regardless of window size.
+func rollingExtreme(dst []float64, src []float64, window int, dominates func(float64, float64) bool) []float64 {
+	dst = resize(dst, len(src))
+	if window <= 0 {
+		fillNaN(dst)
+		return dst
+	}
+	ring := make([]int, window)
+	head, size := 0, 0
+	for i, v := range src {
+		for size > 0 && dominates(v, src[ring[(head+size-1)%window]]) {
+			size--
+		}
+		if size > 0 && ring[head] <= i-window {
+			head = (head + 1) % window
+			size--
+		}
+		ring[(head+size)%window] = i
+		size++
+		if i+1 < window {
+			dst[i] = math.NaN()
+			continue
+		}
+		dst[i] = src[ring[head]]
+	}
+	return dst
+}
+
+func RollingMean(dst []float64, src []float64, window int) []float64 {
+	dst = resize(dst, len(src))
+	if window <= 0 {
+		fillNaN(dst)
+		return dst
+	}
+	sum := 0.0
+	for i, v := range src {
+		sum += v
+		if i >= window {
+			sum -= src[i-window]
+		}
+		if i+1 < window {
+			dst[i] = math.NaN()
+			continue
+		}
+		dst[i] = sum / float64(window)
+	}
+	return dst
+}
+
+// RollingStd is the sample standard deviation over the window.
+func RollingStd(dst []float64, src []float64, window int) []float64 {
+	dst = resize(dst, len(src))
+	if window <= 1 {
+		fillNaN(dst)
+		return dst
+	}
+	sum, sumSq := 0.0, 0.0
+	for i, v := range src {
+		sum += v
+		sumSq += v * v
+		if i >= window {
+			old := src[i-window]
+			sum -= old
+			sumSq -= old * old
+		}
+		if i+1 < window {
+			dst[i] = math.NaN()
+			continue
+		}
+		n := float64(window)
+		variance := (sumSq - sum*sum/n) / (n - 1)
+		if variance < 0 {
+			variance = 0
+		}
+		dst[i] = math.Sqrt(variance)
+	}
+	return dst
+}
+
+// Returns writes simple returns src[i]/src[i-1]-1; the first element is NaN.
+func Returns(dst []float64, src []float64) []float64 {
+	dst = resize(dst, len(src))
+	for i := range src {
+		if i == 0 || src[i-1] == 0 {
+			dst[i] = math.NaN()
+			continue
+		}
+		dst[i] = src[i]/src[i-1] - 1
+	}
+	return dst
+}
+
+// LogReturns writes log(src[i]/src[i-1]); the first element is NaN.
+func LogReturns(dst []float64, src []float64) []float64 {
+	dst = resize(dst, len(src))
+	for i := range src {
+		if i == 0 || src[i-1] <= 0 || src[i] <= 0 {
+			dst[i] = math.NaN()
+			continue
+		}
+		dst[i] = math.Log(src[i] / src[i-1])
+	}
+	return dst
+}
+
+func resize(dst []float64, n int) []float64 {
+	if cap(dst) < n {
+		return make([]float64, n)
+	}
+	return dst[:n]
+}
+
+func fillNaN(dst []float64) {
+	for i := range dst {
+		dst[i] = math.NaN()
+	}
+}
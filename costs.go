@@ -0,0 +1,102 @@
+package emul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CostProfile bundles a venue's fee schedule, spread model and slippage model.
+// SpreadPct < 0 selects the dynamic spread model (see updateSpread).
+type CostProfile struct {
+	Name        string
+	MakerFee    float64
+	TakerFee    float64
+	SpreadPct   float64
+	SlippagePct float64
+}
+
+// Preset names; the numbers are typical retail tiers and can be overridden with RegisterCostProfile.
+const (
+	CostBinanceSpot      = "binance-spot"
+	CostBybitPerp        = "bybit-perp"
+	CostSpreadOnlyBroker = "spread-only-broker"
+	CostZero             = "zero"
+)
+
+var costProfiles = struct {
+	sync.RWMutex
+	byName map[string]CostProfile
+}{
+	byName: map[string]CostProfile{
+		CostBinanceSpot:      {Name: CostBinanceSpot, MakerFee: 0.001, TakerFee: 0.001, SpreadPct: -1, SlippagePct: 0.0002},
+		CostBybitPerp:        {Name: CostBybitPerp, MakerFee: 0.0002, TakerFee: 0.00055, SpreadPct: -1, SlippagePct: 0.0002},
+		CostSpreadOnlyBroker: {Name: CostSpreadOnlyBroker, SpreadPct: 0.002},
+		CostZero:             {Name: CostZero},
+	},
+}
+
+// RegisterCostProfile adds or replaces a named profile.
+func RegisterCostProfile(p CostProfile) error {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	if p.Name == "" {
+		return fmt.Errorf("cost profile name is empty")
+	}
+	costProfiles.Lock()
+	defer costProfiles.Unlock()
+	costProfiles.byName[p.Name] = p
+	return nil
+}
+
+func CostProfileByName(name string) (CostProfile, error) {
+	costProfiles.RLock()
+	defer costProfiles.RUnlock()
+	p, ok := costProfiles.byName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return CostProfile{}, fmt.Errorf("unknown cost profile %q", name)
+	}
+	return p, nil
+}
+
+func CostProfileNames() []string {
+	costProfiles.RLock()
+	defer costProfiles.RUnlock()
+	names := make([]string, 0, len(costProfiles.byName))
+	for name := range costProfiles.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewExchangeFromProfile creates an exchange charging p.TakerFee on market orders and on limit
+// orders filled away from their price, and p.MakerFee on limits filled at their price.
+func NewExchangeFromProfile(startUSD float64, p CostProfile) *Exchange {
+	ex := NewExchange(startUSD, p.TakerFee, p.SlippagePct, p.SpreadPct)
+	ex.SetMakerFee(p.MakerFee)
+	return ex
+}
+
+// SetMakerFee sets the fee for limits filled at their price. A negative fee is a maker rebate
+// paid in cash and recorded in the ledger; rebates of 100% or more are ignored.
+func (e *Exchange) SetMakerFee(fee float64) {
+	if fee <= -1 {
+		fee = 0
+	}
+	e.makerFee = fee
+}
+
+// CostProfile reports the costs the exchange is configured with.
+func (e *Exchange) CostProfile() CostProfile {
+	spread := e.spreadPct
+	if !e.spreadManual {
+		spread = -1
+	}
+	return CostProfile{
+		MakerFee:    e.makerFee,
+		TakerFee:    e.fee,
+		SpreadPct:   spread,
+		SlippagePct: e.slippagePct,
+	}
+}
@@ -0,0 +1,114 @@
+package emul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Conversion re-denominates USD results in another accounting currency. Each point is the USD
+// price of one unit of Currency at that time (EURUSD for EUR, BTCUSD for BTC); a lookup uses the
+// last point at or before the time, and the first point before the series starts.
+type Conversion struct {
+	Currency string
+	times    []int64
+	rates    []float64
+}
+
+func NewConversion(currency string, points []SeriesPoint) (*Conversion, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return nil, fmt.Errorf("currency is empty")
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("conversion series for %s is empty", currency)
+	}
+	sorted := append([]SeriesPoint(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	c := &Conversion{
+		Currency: currency,
+		times:    make([]int64, len(sorted)),
+		rates:    make([]float64, len(sorted)),
+	}
+	for i, p := range sorted {
+		if p.Time.IsZero() {
+			return nil, fmt.Errorf("conversion point %d has no time", i)
+		}
+		if p.Value <= 0 {
+			return nil, fmt.Errorf("conversion rate at %s must be positive", p.Time.Format(time.RFC3339))
+		}
+		c.times[i] = p.Time.UnixNano()
+		c.rates[i] = p.Value
+	}
+	return c, nil
+}
+
+// ConversionFromBars uses bar closes as rates, e.g. a EURUSD series loaded with LoadBarsFromCSV.
+func ConversionFromBars(currency string, bars []OHLCBar) (*Conversion, error) {
+	points := make([]SeriesPoint, len(bars))
+	for i, b := range bars {
+		points[i] = SeriesPoint{Time: b.Time, Value: b.Close}
+	}
+	return NewConversion(currency, points)
+}
+
+// Rate returns the USD price of one unit of the currency at t.
+func (c *Conversion) Rate(t time.Time) float64 {
+	ns := t.UnixNano()
+	i := sort.Search(len(c.times), func(i int) bool { return c.times[i] > ns })
+	return c.rates[max(i-1, 0)]
+}
+
+// FromUSD converts a USD amount at t.
+func (c *Conversion) FromUSD(usd float64, t time.Time) float64 {
+	return usd / c.Rate(t)
+}
+
+// ConvertCurve converts every equity point at its own time; the curve must carry timestamps.
+func ConvertCurve(curve []EquityPoint, c *Conversion) ([]EquityPoint, error) {
+	if c == nil {
+		return nil, fmt.Errorf("conversion is nil")
+	}
+	if err := requireCurveTimes(curve); err != nil {
+		return nil, err
+	}
+	out := make([]EquityPoint, len(curve))
+	for i, p := range curve {
+		out[i] = EquityPoint{Tick: p.Tick, Time: p.Time, Equity: c.FromUSD(p.Equity, p.Time)}
+	}
+	return out, nil
+}
+
+// ConvertTrades re-denominates trades using the bars they were replayed on to date their ticks
+// (tick k is bars[k-1], as in Emulator.Next). PnL is the exit equity at the exit rate minus the
+// entry equity at the entry rate, so it includes the currency move on the whole account; prices
+// and fees are converted at the rate of the fill they belong to.
+func ConvertTrades(trades []Trade, bars []OHLCBar, c *Conversion) ([]Trade, error) {
+	if c == nil {
+		return nil, fmt.Errorf("conversion is nil")
+	}
+	out := make([]Trade, len(trades))
+	for i, t := range trades {
+		entryAt, err := barTime(bars, t.EntryTick)
+		if err != nil {
+			return nil, err
+		}
+		exitAt, err := barTime(bars, t.ExitTick)
+		if err != nil {
+			return nil, err
+		}
+		entryRate, exitRate := c.Rate(entryAt), c.Rate(exitAt)
+		t.EntryPrice /= entryRate
+		t.ExitPrice /= exitRate
+		t.Fees = t.Entry.Fee/entryRate + (t.Fees-t.Entry.Fee)/exitRate
+		before := t.Entry.EquityBefore / entryRate
+		t.PnL = t.Exit.Equity/exitRate - before
+		t.Return = 0
+		if before > 0 {
+			t.Return = t.PnL / before
+		}
+		out[i] = t
+	}
+	return out, nil
+}
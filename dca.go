@@ -0,0 +1,165 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+)
+
+const ReasonDCATakeProfit = "dca-take-profit"
+
+// DCAConfig follows the usual DCA-bot settings: a base order at market, then safety orders
+// resting at growing distances from the base price, and a take-profit measured from the
+// average entry. Amounts are in USD.
+type DCAConfig struct {
+	// Side is SideBuy for a long bot (default) or SideSell for a short one.
+	Side        OrderSide
+	BaseUSD     float64
+	SafetyUSD   float64
+	SafetyCount int
+	// StepPct is the price deviation from the base price to the first safety order.
+	StepPct float64
+	// StepScale multiplies each further deviation step (1 keeps steps even).
+	StepScale float64
+	// VolumeScale multiplies the size of each further safety order.
+	VolumeScale   float64
+	TakeProfitPct float64
+}
+
+// DCABot runs DCAConfig deals back to back on an exchange it owns. It switches the exchange to
+// LimitsRest and keeps one safety order and one take-profit on the book; call Update after
+// every bar.
+type DCABot struct {
+	ex       *Exchange
+	cfg      DCAConfig
+	active   bool
+	base     float64
+	safeties int
+	safetyID int64
+	tpID     int64
+	tpEntry  float64
+	deals    int
+}
+
+func NewDCABot(ex *Exchange, cfg DCAConfig) (*DCABot, error) {
+	if ex == nil {
+		return nil, fmt.Errorf("exchange is nil")
+	}
+	if cfg.Side == "" {
+		cfg.Side = SideBuy
+	}
+	if cfg.Side != SideBuy && cfg.Side != SideSell {
+		return nil, fmt.Errorf("invalid side %q", cfg.Side)
+	}
+	if cfg.BaseUSD <= 0 || cfg.TakeProfitPct <= 0 {
+		return nil, fmt.Errorf("base order and take-profit must be positive")
+	}
+	if cfg.SafetyCount < 0 || (cfg.SafetyCount > 0 && (cfg.SafetyUSD <= 0 || cfg.StepPct <= 0)) {
+		return nil, fmt.Errorf("safety orders need a positive size and step")
+	}
+	if cfg.StepScale <= 0 {
+		cfg.StepScale = 1
+	}
+	if cfg.VolumeScale <= 0 {
+		cfg.VolumeScale = 1
+	}
+	ex.SetLimitMode(LimitsRest)
+	return &DCABot{ex: ex, cfg: cfg}, nil
+}
+
+// Deals returns the number of completed deals (position flattened by any exit).
+func (b *DCABot) Deals() int {
+	return b.deals
+}
+
+// SafetyOrdersFilled returns how many safety orders the current deal has used.
+func (b *DCABot) SafetyOrdersFilled() int {
+	return b.safeties
+}
+
+// SafetyPrice returns the trigger price of safety order k (0-based) for the current base price.
+func (b *DCABot) SafetyPrice(k int) float64 {
+	deviation := 0.0
+	step := b.cfg.StepPct
+	for i := 0; i <= k; i++ {
+		deviation += step
+		step *= b.cfg.StepScale
+	}
+	if b.cfg.Side == SideSell {
+		return b.base * (1 + deviation)
+	}
+	return b.base * (1 - deviation)
+}
+
+// Update starts a deal when flat, tracks safety fills and keeps the take-profit at the current
+// average entry.
+func (b *DCABot) Update() error {
+	if b.active && b.ex.position == 0 {
+		b.ex.CancelLimit(b.safetyID)
+		b.active, b.safetyID, b.tpID = false, 0, 0
+		b.deals++
+	}
+	if !b.active {
+		return b.startDeal()
+	}
+	if b.safetyID != 0 && !b.pending(b.safetyID) {
+		if _, ok := b.ex.executedByID[b.safetyID]; ok {
+			b.safeties++
+		}
+		b.safetyID = 0
+	}
+	if b.safetyID == 0 && b.safeties < b.cfg.SafetyCount && b.ex.usd > 0 {
+		amount := b.cfg.SafetyUSD * math.Pow(b.cfg.VolumeScale, float64(b.safeties))
+		id, err := b.ex.ScaleInLimit(b.SafetyPrice(b.safeties), math.Min(amount/b.ex.usd, 1))
+		if err != nil {
+			return err
+		}
+		b.safetyID = id
+	}
+	if b.tpID != 0 && b.tpEntry != b.ex.entryPrice {
+		b.ex.CancelLimit(b.tpID)
+		b.tpID = 0
+	}
+	if b.tpID == 0 {
+		target := b.ex.entryPrice * (1 + b.cfg.TakeProfitPct)
+		if b.cfg.Side == SideSell {
+			target = b.ex.entryPrice * (1 - b.cfg.TakeProfitPct)
+		}
+		id, err := b.ex.CloseLimit(target, ReasonDCATakeProfit, "")
+		if err != nil {
+			return err
+		}
+		b.tpID, b.tpEntry = id, b.ex.entryPrice
+	}
+	return nil
+}
+
+func (b *DCABot) startDeal() error {
+	if b.ex.position != 0 {
+		return ErrPositionOpen
+	}
+	if b.ex.usd <= 0 {
+		return ErrInvalidFraction
+	}
+	fraction := math.Min(b.cfg.BaseUSD/b.ex.usd, 1)
+	var order *Order
+	var err error
+	if b.cfg.Side == SideSell {
+		order, err = b.ex.OpenShort(fraction)
+	} else {
+		order, err = b.ex.OpenLong(fraction)
+	}
+	if err != nil {
+		return err
+	}
+	b.active, b.base, b.safeties = true, order.MidPrice, 0
+	return b.Update()
+}
+
+func (b *DCABot) pending(id int64) bool {
+	for _, p := range b.ex.pending {
+		if p.id == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package emul
+
+import "fmt"
+
+// DiagnosticsLevel selects how much limit-order diagnostics an Exchange records.
+type DiagnosticsLevel uint8
+
+const (
+	// DiagnosticsFull records miss entries (subject to MissRetention), per-reason counters and
+	// limit timelines (the default).
+	DiagnosticsFull DiagnosticsLevel = iota
+	// DiagnosticsCounters keeps only the per-reason miss counters, e.g. for paper-trading
+	// servers that run for weeks.
+	DiagnosticsCounters
+	// DiagnosticsOff records no miss diagnostics or timelines. LimitDiagnostics still reports
+	// the pending queue and rejection counts, and LimitStatus keeps working.
+	DiagnosticsOff
+)
+
+func (l DiagnosticsLevel) String() string {
+	switch l {
+	case DiagnosticsFull:
+		return "full"
+	case DiagnosticsCounters:
+		return "counters"
+	case DiagnosticsOff:
+		return "off"
+	}
+	return "unknown"
+}
+
+// SetDiagnosticsLevel changes what is recorded from now on; diagnostics already recorded are
+// kept.
+func (e *Exchange) SetDiagnosticsLevel(level DiagnosticsLevel) error {
+	if level > DiagnosticsOff {
+		return fmt.Errorf("unknown diagnostics level %d", level)
+	}
+	e.diagnostics = level
+	return nil
+}
+
+func (e *Exchange) DiagnosticsLevel() DiagnosticsLevel {
+	return e.diagnostics
+}
@@ -0,0 +1,94 @@
+package emul
+
+import (
+	"time"
+)
+
+// DrawdownEpisode runs from the last equity peak to the point equity regains it. Recovery is
+// zero when the curve ends underwater.
+type DrawdownEpisode struct {
+	Start        time.Time
+	Trough       time.Time
+	Recovery     time.Time
+	StartTick    int64
+	TroughTick   int64
+	RecoveryTick int64
+	Peak         float64
+	Low          float64
+	Depth        float64
+	Recovered    bool
+}
+
+// Underwater returns the drawdown from the running peak at every point (0 at new highs,
+// -0.25 when 25% below the peak).
+func Underwater(curve []EquityPoint) []SeriesPoint {
+	out := make([]SeriesPoint, len(curve))
+	peak := 0.0
+	for i, p := range curve {
+		if p.Equity > peak {
+			peak = p.Equity
+		}
+		dd := 0.0
+		if peak > 0 {
+			dd = p.Equity/peak - 1
+		}
+		out[i] = SeriesPoint{Time: p.Time, Value: dd}
+	}
+	return out
+}
+
+// MaxDrawdown returns the deepest drawdown of the curve as a positive fraction.
+func MaxDrawdown(curve []EquityPoint) float64 {
+	worst := 0.0
+	for _, p := range Underwater(curve) {
+		if -p.Value > worst {
+			worst = -p.Value
+		}
+	}
+	return worst
+}
+
+func DrawdownEpisodes(curve []EquityPoint) []DrawdownEpisode {
+	out := make([]DrawdownEpisode, 0)
+	if len(curve) == 0 {
+		return out
+	}
+	peakIdx := 0
+	var cur *DrawdownEpisode
+	for i, p := range curve {
+		if p.Equity >= curve[peakIdx].Equity {
+			if cur != nil {
+				cur.Recovery = p.Time
+				cur.RecoveryTick = p.Tick
+				cur.Recovered = true
+				out = append(out, *cur)
+				cur = nil
+			}
+			peakIdx = i
+			continue
+		}
+		if cur == nil {
+			peak := curve[peakIdx]
+			cur = &DrawdownEpisode{
+				Start:      peak.Time,
+				StartTick:  peak.Tick,
+				Trough:     p.Time,
+				TroughTick: p.Tick,
+				Peak:       peak.Equity,
+				Low:        p.Equity,
+			}
+		}
+		if p.Equity <= cur.Low {
+			cur.Low = p.Equity
+			cur.Trough = p.Time
+			cur.TroughTick = p.Tick
+		}
+		if cur.Peak > 0 {
+			cur.Depth = 1 - cur.Low/cur.Peak
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
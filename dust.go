@@ -0,0 +1,68 @@
+package emul
+
+import "math"
+
+// LedgerDust books dust converted to USD.
+const LedgerDust = "dust"
+
+type DustPolicy uint8
+
+const (
+	// DustSellAll closes the whole long position regardless of lot rules (the default).
+	DustSellAll DustPolicy = iota
+	// DustConvert sells whole lots on close and converts the remainder to USD at the close
+	// price, so the position always ends flat.
+	DustConvert
+	// DustTrack sells whole lots on close and moves the remainder to a dust balance, valued at
+	// the last price until SweepDust converts it.
+	DustTrack
+)
+
+// SetDustPolicy selects how long closes treat a remainder below the lot size or the minimum
+// quantity. It only matters when the symbol has lot rules (see SetSymbol).
+func (e *Exchange) SetDustPolicy(p DustPolicy) {
+	e.dustPolicy = p
+}
+
+// Dust returns the tracked dust quantity.
+func (e *Exchange) Dust() float64 {
+	return e.dust
+}
+
+// SweepDust converts the tracked dust to USD at the last price and returns the USD credited.
+func (e *Exchange) SweepDust() float64 {
+	if e.dust <= 0 || e.lastPrice <= 0 {
+		return 0
+	}
+	usd := e.dust * e.lastPrice
+	e.usd += usd
+	e.dust = 0
+	e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerDust, Amount: usd})
+	return usd
+}
+
+// splitDust returns the sellable part of a long quantity and the dust left over.
+func (e *Exchange) splitDust(qty float64) (float64, float64) {
+	if e.dustPolicy == DustSellAll || e.lotSize <= 0 {
+		return qty, 0
+	}
+	sell := roundDownToStep(qty, e.lotSize)
+	if e.minQty > 0 && sell < e.minQty {
+		sell = 0
+	}
+	return sell, math.Max(qty-sell, 0)
+}
+
+// keepDust applies the policy to a remainder left by a close at price.
+func (e *Exchange) keepDust(dust float64, price float64) {
+	if dust <= 0 {
+		return
+	}
+	if e.dustPolicy == DustTrack {
+		e.dust += dust
+		return
+	}
+	usd := dust * price
+	e.usd += usd
+	e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerDust, Amount: usd})
+}
@@ -5,15 +5,34 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 var ErrNoMoreBars = errors.New("no more bars")
 
 // Emulator replays historical bars one-by-one and applies them to Exchange.
 type Emulator struct {
-	bars  []OHLCBar
-	index int
-	ex    *Exchange
+	mu       sync.Mutex
+	bars     []OHLCBar
+	ownsBars bool
+	index    int
+	ex       *Exchange
+	costs    CostProfile
+	spec     *SymbolSpec
+	accounts map[string]*Exchange
+	limiter  *RateLimiter
+	set      *BarSet
+	startUSD float64
+	files    []string
+	seed     *uint64
+	runID    string
+	iterErr  error
+	clock    Clock
+	timing   DecisionTiming
+	aux      map[string][]float64
+	started  time.Time
+	peak     float64
 }
 
 type EmulatorConfig struct {
@@ -22,15 +41,45 @@ type EmulatorConfig struct {
 	SlippagePct float64
 	SpreadPct   float64
 	Bars        []OHLCBar
+	// BarSet, when set, is used instead of Bars and shared with other emulators.
+	BarSet *BarSet
+	// CostProfile, when set, selects a named profile (e.g. "binance-spot") and overrides
+	// Fee, SlippagePct and SpreadPct.
+	CostProfile string
+	// Symbol is looked up in Symbols (loaded once with LoadSymbolRegistry) for tick/lot rules
+	// and per-symbol fees, which override the cost profile.
+	Symbol  string
+	Symbols *SymbolRegistry
 }
 
+// NewEmulator keeps a reference to bars without copying; the replay never modifies them,
+// so one slice can back any number of emulators.
 func NewEmulator(startUSD float64, fee float64, slippagePct float64, spreadPct float64, bars []OHLCBar) (*Emulator, error) {
+	return newEmulator(startUSD, CostProfile{
+		MakerFee:    fee,
+		TakerFee:    fee,
+		SpreadPct:   spreadPct,
+		SlippagePct: slippagePct,
+	}, bars)
+}
+
+// NewEmulatorWithProfile uses a named or custom cost profile instead of a flat fee.
+func NewEmulatorWithProfile(startUSD float64, costs CostProfile, bars []OHLCBar) (*Emulator, error) {
+	return newEmulator(startUSD, costs, bars)
+}
+
+func newEmulator(startUSD float64, costs CostProfile, bars []OHLCBar) (*Emulator, error) {
 	if len(bars) == 0 {
 		return nil, fmt.Errorf("bars are empty")
 	}
 	return &Emulator{
-		bars: bars,
-		ex:   NewExchange(startUSD, fee, slippagePct, spreadPct),
+		bars:     bars,
+		ex:       NewExchangeFromProfile(startUSD, costs),
+		costs:    costs,
+		accounts: make(map[string]*Exchange),
+		startUSD: max(startUSD, 0),
+		started:  time.Now(),
+		peak:     max(startUSD, 0),
 	}, nil
 }
 
@@ -39,21 +88,86 @@ func NewEmulatorFromCSV(startUSD float64, fee float64, slippagePct float64, spre
 	if err != nil {
 		return nil, err
 	}
-	return NewEmulator(startUSD, fee, slippagePct, spreadPct, bars)
+	emu, err := NewEmulator(startUSD, fee, slippagePct, spreadPct, bars)
+	if err != nil {
+		return nil, err
+	}
+	emu.files = []string{csvPath}
+	return emu, nil
+}
+
+// NewEmulatorFromBarSet retains set for the emulator's lifetime; call Close to release it.
+func NewEmulatorFromBarSet(startUSD float64, fee float64, slippagePct float64, spreadPct float64, set *BarSet) (*Emulator, error) {
+	return newEmulatorFromBarSet(startUSD, CostProfile{
+		MakerFee:    fee,
+		TakerFee:    fee,
+		SpreadPct:   spreadPct,
+		SlippagePct: slippagePct,
+	}, set)
+}
+
+func newEmulatorFromBarSet(startUSD float64, costs CostProfile, set *BarSet) (*Emulator, error) {
+	if set == nil {
+		return nil, fmt.Errorf("bar set is nil")
+	}
+	bars := set.Retain()
+	emu, err := newEmulator(startUSD, costs, bars)
+	if err != nil {
+		if bars != nil {
+			set.Release()
+		}
+		return nil, err
+	}
+	emu.set = set
+	return emu, nil
 }
 
 // NewEmulatorFromConfig consumes prepared bars (no file I/O in production code paths).
 func NewEmulatorFromConfig(cfg EmulatorConfig) (*Emulator, error) {
-	return NewEmulator(
-		cfg.StartUSD,
-		cfg.Fee,
-		cfg.SlippagePct,
-		cfg.SpreadPct,
-		cfg.Bars,
-	)
+	costs := CostProfile{
+		MakerFee:    cfg.Fee,
+		TakerFee:    cfg.Fee,
+		SpreadPct:   cfg.SpreadPct,
+		SlippagePct: cfg.SlippagePct,
+	}
+	if cfg.CostProfile != "" {
+		var err error
+		if costs, err = CostProfileByName(cfg.CostProfile); err != nil {
+			return nil, err
+		}
+	}
+	var spec *SymbolSpec
+	if cfg.Symbol != "" && cfg.Symbols != nil {
+		found, ok := cfg.Symbols.Lookup(cfg.Symbol)
+		if !ok {
+			return nil, fmt.Errorf("symbol %q not in registry", cfg.Symbol)
+		}
+		spec = &found
+		costs = found.CostProfile(costs)
+	}
+	var emu *Emulator
+	var err error
+	if cfg.BarSet != nil {
+		emu, err = newEmulatorFromBarSet(cfg.StartUSD, costs, cfg.BarSet)
+	} else {
+		emu, err = newEmulator(cfg.StartUSD, costs, cfg.Bars)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if spec != nil {
+		emu.spec = spec
+		emu.ex.SetSymbol(*spec)
+	}
+	return emu, nil
 }
 
 func LoadBarsFromCSV(csvPath string) ([]OHLCBar, error) {
+	return LoadBarsFromCSVWithOptions(csvPath, CSVReaderOptions{})
+}
+
+// LoadBarsFromCSVWithOptions is LoadBarsFromCSV with explicit reader options.
+func LoadBarsFromCSVWithOptions(csvPath string, opts CSVReaderOptions) ([]OHLCBar, error) {
 	path := strings.TrimSpace(csvPath)
 	if path == "" {
 		return nil, fmt.Errorf("csv path is empty")
@@ -61,36 +175,85 @@ func LoadBarsFromCSV(csvPath string) ([]OHLCBar, error) {
 	if strings.ToLower(filepath.Ext(path)) != ".csv" {
 		return nil, fmt.Errorf("csv path must end with .csv")
 	}
-	values, ohlc, _, err := loadSeriesFromCSVWithOHLC(path, nil)
+	values, ohlc, _, err := loadSeriesFromCSVWithOHLC(path, nil, opts)
 	if err != nil {
 		return nil, err
 	}
 	return BarsFromSeries(values, ohlc)
 }
 
+// Next applies the next bar and returns the orders executed on it. The returned slice shares
+// memory with the exchange history and must be treated as read-only. An error raised after the
+// bar was applied, e.g. a failed invariant check, is returned with the bar and its fills.
 func (e *Emulator) Next() (OHLCBar, []Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if e.index >= len(e.bars) {
 		return OHLCBar{}, nil, ErrNoMoreBars
 	}
+	if p := e.ex.perp; p != nil && e.index >= len(p.cfg.Bars) {
+		return OHLCBar{}, nil, fmt.Errorf("perp series ends at bar %d", len(p.cfg.Bars))
+	}
 	bar := e.bars[e.index]
-	before := e.ex.Orders()
+	if bar.Close <= 0 {
+		return OHLCBar{}, nil, fmt.Errorf("bar %d: price must be positive", e.index)
+	}
+	before := len(e.ex.orders)
+	// A failed check after the bar was applied does not stop the bar: the perp leg and every
+	// account still get it and the replay moves past it, so it is never applied twice. The first
+	// error is returned with the bar.
 	_, err := e.ex.tickBarAt(int64(e.index+1), bar)
-	if err != nil {
-		return OHLCBar{}, nil, err
+	if p := e.ex.perp; p != nil {
+		e.ex.markPerp(p.cfg.Bars[e.index])
 	}
-	after := e.ex.Orders()
-	executed := make([]Order, 0)
-	if len(after) > len(before) {
-		executed = append(executed, after[len(before):]...)
+	for _, ex := range e.accounts {
+		if _, accountErr := ex.tickBarAt(int64(e.index+1), bar); err == nil {
+			err = accountErr
+		}
 	}
+	// Orders are only ever appended, so the fills of this bar are the history tail. The capped
+	// sub-slice avoids copying on the hot path while keeping appends from clobbering it.
+	after := len(e.ex.orders)
+	executed := e.ex.orders[before:after:after]
 	e.index++
-	return bar, executed, nil
+	e.peak = max(e.peak, e.ex.Balance().Equity)
+	if e.ex.impact != nil {
+		// Return the bar as the exchange saw it, shifted by the account's own impact.
+		bar = e.ex.lastBar
+	}
+	return bar, executed, err
+}
+
+// NextWithRejections is Next that also returns the pending limits the exchange dropped on the
+// bar instead of filling (see Exchange.Rejections).
+func (e *Emulator) NextWithRejections() (OHLCBar, []Order, []Rejection, error) {
+	bar, executed, err := e.Next()
+	if err != nil {
+		return bar, executed, nil, err
+	}
+	return bar, executed, e.ex.Rejections(), nil
 }
 
 func (e *Emulator) Exchange() *Exchange {
 	return e.ex
 }
 
+// Close releases the emulator's BarSet reference, if any. The emulator must not be used afterwards.
+func (e *Emulator) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.set != nil {
+		e.set.Release()
+		e.set = nil
+	}
+}
+
+// BarSet returns the shared set backing the emulator, or nil when it was built from a plain slice.
+func (e *Emulator) BarSet() *BarSet {
+	return e.set
+}
+
+// Bars returns a copy of the replayed bars; prefer BarSet for read-only access to large series.
 func (e *Emulator) Bars() []OHLCBar {
 	out := make([]OHLCBar, len(e.bars))
 	copy(out, e.bars)
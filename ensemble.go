@@ -0,0 +1,176 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Allocation uint8
+
+const (
+	// AllocationFixed splits capital proportionally to Sleeve.Weight.
+	AllocationFixed Allocation = iota
+	// AllocationInverseVol splits capital proportionally to 1/Sleeve.Vol, estimated from
+	// Sleeve.Returns when Vol is 0.
+	AllocationInverseVol
+)
+
+// Sleeve is one strategy of an ensemble. Returns holds per-period returns of the sleeve, e.g.
+// from a previous run, and is only used to estimate Vol when Vol is 0.
+type Sleeve struct {
+	Name     string
+	Strategy Strategy
+	Weight   float64
+	Vol      float64
+	Returns  []float64
+}
+
+type EnsembleConfig struct {
+	StartUSD    float64
+	Fee         float64
+	SlippagePct float64
+	SpreadPct   float64
+	Bars        []OHLCBar
+	Allocation  Allocation
+	Sleeves     []Sleeve
+}
+
+type SleeveResult struct {
+	Name         string
+	StartUSD     float64
+	FinalEquity  float64
+	PnL          float64
+	Contribution float64
+	Equity       []EquityPoint
+	Orders       []Order
+}
+
+type EnsembleResult struct {
+	Equity  []EquityPoint
+	Sleeves []SleeveResult
+}
+
+// RunEnsemble runs every sleeve on its own virtual sub-balance fed by the same bars and costs.
+// Each sleeve is an isolated account of one Emulator (see AddAccount), so sleeves cannot touch
+// each other's positions; Contribution is the sleeve's PnL as a fraction of the gross PnL, the
+// sum of every sleeve's absolute PnL.
+func RunEnsemble(cfg EnsembleConfig) (*EnsembleResult, error) {
+	if len(cfg.Sleeves) == 0 {
+		return nil, fmt.Errorf("ensemble has no sleeves")
+	}
+	weights, err := sleeveWeights(cfg.Allocation, cfg.Sleeves)
+	if err != nil {
+		return nil, err
+	}
+	emu, err := NewEmulator(0, cfg.Fee, cfg.SlippagePct, cfg.SpreadPct, cfg.Bars)
+	if err != nil {
+		return nil, err
+	}
+	res := &EnsembleResult{
+		Equity:  make([]EquityPoint, 0, len(cfg.Bars)),
+		Sleeves: make([]SleeveResult, len(cfg.Sleeves)),
+	}
+	keys := make([]string, len(cfg.Sleeves))
+	for i, s := range cfg.Sleeves {
+		if s.Strategy == nil {
+			return nil, fmt.Errorf("sleeve %d has no strategy", i)
+		}
+		keys[i] = fmt.Sprintf("sleeve-%d", i)
+		startUSD := cfg.StartUSD * weights[i]
+		if err := emu.AddAccount(keys[i], startUSD); err != nil {
+			return nil, err
+		}
+		_ = emu.WithAccount(keys[i], func(ex *Exchange) error {
+			ex.SetTag(s.Name)
+			return nil
+		})
+		res.Sleeves[i] = SleeveResult{
+			Name:     s.Name,
+			StartUSD: startUSD,
+			Equity:   make([]EquityPoint, 0, len(cfg.Bars)),
+		}
+	}
+	seen := make([]int, len(cfg.Sleeves))
+	for {
+		bar, _, err := emu.Next()
+		if errors.Is(err, ErrNoMoreBars) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		total := EquityPoint{Time: bar.Time}
+		for i, s := range cfg.Sleeves {
+			err := emu.WithAccount(keys[i], func(ex *Exchange) error {
+				executed := append([]Order(nil), ex.orders[seen[i]:]...)
+				if err := s.Strategy.OnBar(ex, bar, executed); err != nil {
+					return fmt.Errorf("sleeve %q: %w", s.Name, err)
+				}
+				seen[i] = len(ex.orders)
+				point := EquityPoint{Tick: ex.tick, Time: bar.Time, Equity: ex.Balance().Equity}
+				res.Sleeves[i].Equity = append(res.Sleeves[i].Equity, point)
+				total.Tick = point.Tick
+				total.Equity += point.Equity
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		res.Equity = append(res.Equity, total)
+	}
+	gross := 0.0
+	for i := range res.Sleeves {
+		sr := &res.Sleeves[i]
+		_ = emu.WithAccount(keys[i], func(ex *Exchange) error {
+			sr.FinalEquity = ex.Balance().Equity
+			sr.Orders = ex.Orders()
+			return nil
+		})
+		sr.PnL = sr.FinalEquity - sr.StartUSD
+		gross += math.Abs(sr.PnL)
+	}
+	if gross != 0 {
+		for i := range res.Sleeves {
+			res.Sleeves[i].Contribution = res.Sleeves[i].PnL / gross
+		}
+	}
+	return res, nil
+}
+
+func sleeveWeights(alloc Allocation, sleeves []Sleeve) ([]float64, error) {
+	raw := make([]float64, len(sleeves))
+	for i, s := range sleeves {
+		switch alloc {
+		case AllocationFixed:
+			raw[i] = s.Weight
+		case AllocationInverseVol:
+			vol := s.Vol
+			if vol == 0 && len(s.Returns) >= 2 {
+				_, variance := meanVariance(s.Returns)
+				vol = math.Sqrt(variance)
+			}
+			if !(vol > 0) || math.IsInf(vol, 0) {
+				return nil, fmt.Errorf("sleeve %q needs a positive Vol or varying Returns for inverse-vol allocation", s.Name)
+			}
+			raw[i] = 1 / vol
+		default:
+			return nil, fmt.Errorf("unknown allocation %d", alloc)
+		}
+		if raw[i] < 0 {
+			return nil, fmt.Errorf("sleeve %q has negative weight", s.Name)
+		}
+	}
+	sum := 0.0
+	for _, w := range raw {
+		sum += w
+	}
+	if sum <= 0 {
+		return nil, fmt.Errorf("sleeve weights sum to zero")
+	}
+	for i := range raw {
+		raw[i] /= sum
+	}
+	return raw, nil
+}
@@ -0,0 +1,95 @@
+// Command profile replays bars through a simple strategy with CPU/heap profiling enabled.
+//
+//	go run ./examples/profile -csv data/btc/m/btc2024.csv -cpuprofile cpu.out
+//	go tool pprof cpu.out
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func main() {
+	csvPath := flag.String("csv", "", "csv file to replay (synthetic bars when empty)")
+	synthetic := flag.Int("bars", 1_000_000, "number of synthetic bars when -csv is empty")
+	cpuProfile := flag.String("cpuprofile", "", "write cpu profile to file")
+	memProfile := flag.String("memprofile", "", "write heap profile to file")
+	flag.Parse()
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatal(err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	start := time.Now()
+	bars, err := loadBars(*csvPath, *synthetic)
+	if err != nil {
+		log.Fatal(err)
+	}
+	loaded := time.Since(start)
+
+	emu, err := emul.NewEmulator(1000, 0.001, 0, -1, bars)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start = time.Now()
+	curve, err := emul.Run(emu, alternating())
+	if err != nil {
+		log.Fatal(err)
+	}
+	replayed := time.Since(start)
+
+	fmt.Printf("bars=%d load=%s replay=%s (%.0f bars/s) orders=%d equity=%.2f\n",
+		len(bars), loaded, replayed, float64(len(bars))/replayed.Seconds(),
+		len(emu.Exchange().Orders()), curve[len(curve)-1].Equity)
+
+	if *memProfile != "" {
+		f, err := os.Create(*memProfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func loadBars(path string, n int) ([]emul.OHLCBar, error) {
+	if path != "" {
+		return emul.LoadBarsFromCSV(path)
+	}
+	return emul.GenerateGARCH(emul.GARCHParams{Omega: 1e-7, Alpha: 0.05, Beta: 0.9}, 100, n, 1)
+}
+
+// alternating flips between a long limit and a close limit every 10 bars.
+func alternating() emul.Strategy {
+	count := 0
+	return emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, executed []emul.Order) error {
+		count++
+		if count%10 != 0 {
+			return nil
+		}
+		if ex.Balance().Position == 0 {
+			_, err := ex.LongLimit(0, 1)
+			return err
+		}
+		_, err := ex.CloseLimit(0, emul.ReasonExit, "")
+		return err
+	})
+}
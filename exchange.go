@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 )
 
 type OrderSide string
@@ -19,10 +20,16 @@ const (
 	ReasonExit       = "exit"
 	ReasonStopLoss   = "stop-loss"
 	ReasonLiquidate  = "liquidation"
+	ReasonScaleIn    = "scale-in"
+	// ReasonBorrowRecall marks a short force-closed because the lender recalled the borrow.
+	ReasonBorrowRecall = "borrow-recall"
+	// ReasonTimeExit marks a position closed by a TimeExit rule.
+	ReasonTimeExit = "time-exit"
 )
 
 type Order struct {
 	ID            int64
+	RunID         string
 	Symbol        string
 	Side          OrderSide
 	Qty           float64
@@ -43,6 +50,10 @@ type Order struct {
 	PlacedTick    int64
 	SpreadPct     float64
 	SlippagePct   float64
+	// Tag names the strategy or signal that placed the order (see SetTag).
+	Tag string
+	// Meta is the metadata attached to the placement (see WithMeta); treat it as read-only.
+	Meta map[string]string
 }
 
 type Balance struct {
@@ -53,6 +64,19 @@ type Balance struct {
 	Equity      float64
 	EntryPrice  float64
 	LastPrice   float64
+	// PerpMargin and PerpPnL are the perp leg's posted margin and unrealized PnL (see EnablePerp).
+	PerpMargin float64
+	PerpPnL    float64
+	// MarginUSD is the margin wallet's free cash (see EnableMarginWallet), included in Equity.
+	MarginUSD float64
+	// Dust is the tracked sub-lot remainder of closed longs (see DustTrack), valued in Equity.
+	Dust float64
+	// OptionValue marks open options to model; OptionCollateral is cash locked by written puts.
+	OptionValue      float64
+	OptionCollateral float64
+	// LiquidationPrice is where the open position would be liquidated, 0 when it cannot be
+	// (see SetLiquidationConfig).
+	LiquidationPrice float64
 }
 
 type PositionInfo struct {
@@ -67,27 +91,84 @@ type WalletSnapshot struct {
 }
 
 type Exchange struct {
-	fee          float64
-	slippagePct  float64
-	spreadPct    float64
-	spreadManual bool
-	prevPrice    float64
-	usd          float64
-	position     float64
-	entryPrice   float64
-	shortCash    float64
-	shortMargin  float64
-	lastPrice    float64
-	tick         int64
-	orders       []Order
-	nextID       int64
-	nextLimitID  int64
-	pending      []pendingOrder
-	executedByID map[int64]Order
-	limitFailed  map[string]int
-	misses       []LimitMiss
-	lastBar      OHLCBar
-	hasLastBar   bool
+	fee           float64
+	makerFee      float64
+	slippagePct   float64
+	spreadPct     float64
+	spreadManual  bool
+	prevPrice     float64
+	usd           float64
+	position      float64
+	entryPrice    float64
+	shortCash     float64
+	shortMargin   float64
+	lastPrice     float64
+	tick          int64
+	orders        []Order
+	nextID        int64
+	runID         string
+	clock         Clock
+	liquidation   LiquidationConfig
+	precision     Precision
+	nextLimitID   int64
+	pending       []pendingOrder
+	executedByID  map[int64]Order
+	limitDone     map[int64]LimitState
+	limitFailed   map[string]int
+	rejected      []Rejection
+	misses        []LimitMiss
+	missStart     int
+	missCounts    map[string]int
+	missDropped   int
+	missRetention MissRetention
+	diagnostics   DiagnosticsLevel
+	fillTiming    FillTiming
+	deferred      []pendingOrder
+	sizeBuckets   []SizeBucket
+	staking       StakingConfig
+	stakedAt      time.Time
+	marginWallet  bool
+	marginUSD     float64
+	tag           string
+	meta          map[string]string
+	timelines     map[int64]*LimitTimeline
+	timelineDone  []int64
+	bracket       *activeBracket
+	timeExit      TimeExit
+	openedTick    int64
+	sessionLimit  *SessionLimit
+	sessions      []SessionPnL
+	riskEvents    []Event
+	flowFees      map[string]FlowFee
+	borrowAPR     float64
+	borrowedAt    time.Time
+	lastBar       OHLCBar
+	hasLastBar    bool
+	invariants    InvariantMode
+	limitMode     LimitMode
+	eligibility   LimitEligibility
+	fillModel     *fillModel
+	impact        *impactState
+	participation float64
+	barVolume     float64
+	ledger        []LedgerEntry
+	intents       []Intent
+	reversals     []Reversal
+	orderLimits   OrderLimits
+	placedTick    int64
+	placedOnBar   int
+	dustPolicy    DustPolicy
+	dust          float64
+	recall        *borrowRecall
+	perp          *perpLeg
+	options       *optionBook
+	symbol        string
+	tickSize      float64
+	lotSize       float64
+	minQty        float64
+	maxLeverage   float64
+	fundingRate   float64
+	fundingPeriod time.Duration
 }
 
 type pendingKind uint8
@@ -96,6 +177,8 @@ const (
 	pendingOpenLong pendingKind = iota + 1
 	pendingOpenShort
 	pendingClose
+	pendingScaleIn
+	pendingReverse
 )
 
 type pendingOrder struct {
@@ -108,6 +191,8 @@ type pendingOrder struct {
 	placedAtTick int64
 	lastReason   string
 	placedBar    OHLCBar
+	tag          string
+	meta         map[string]string
 }
 
 type LimitMiss struct {
@@ -120,10 +205,15 @@ type LimitMiss struct {
 	CurrBar    OHLCBar
 }
 
+// LimitDiagnostics summarizes why limits did not fill at their price. MissCounts counts every
+// miss by reason whatever the MissRetention; Misses holds the retained entries oldest first and
+// MissesDropped the ones the policy discarded.
 type LimitDiagnostics struct {
-	PendingTotal int
-	Reasons      map[string]int
-	Misses       []LimitMiss
+	PendingTotal  int
+	Reasons       map[string]int
+	Misses        []LimitMiss
+	MissCounts    map[string]int
+	MissesDropped int
 }
 
 var (
@@ -131,6 +221,7 @@ var (
 	ErrPositionOpen    = errors.New("position already open")
 	ErrNoPosition      = errors.New("no open position")
 	ErrInvalidFraction = errors.New("fraction must be in (0, 1]")
+	ErrBelowMinQty     = errors.New("order quantity below minimum lot")
 )
 
 func NewExchange(startUSD float64, fee float64, slippagePct float64, spreadPct float64) *Exchange {
@@ -151,11 +242,13 @@ func NewExchange(startUSD float64, fee float64, slippagePct float64, spreadPct f
 	}
 	return &Exchange{
 		fee:          fee,
+		makerFee:     fee,
 		usd:          startUSD,
 		slippagePct:  slippagePct,
 		spreadPct:    spreadPct,
 		spreadManual: spreadManual,
 		executedByID: make(map[int64]Order),
+		limitDone:    make(map[int64]LimitState),
 		limitFailed:  make(map[string]int),
 	}
 }
@@ -165,19 +258,36 @@ func (e *Exchange) Balance() Balance {
 	if price <= 0 {
 		price = e.entryPrice
 	}
-	equity := e.usd + e.shortCash + e.shortMargin
+	equity := e.usd + e.marginUSD + e.shortCash + e.shortMargin
 	if price > 0 {
 		equity += e.position * price
 	}
-	return Balance{
+	bal := Balance{
 		USD:         e.usd,
+		MarginUSD:   e.marginUSD,
 		Position:    e.position,
 		ShortCash:   e.shortCash,
 		ShortMargin: e.shortMargin,
-		Equity:      equity,
 		EntryPrice:  e.entryPrice,
 		LastPrice:   e.lastPrice,
 	}
+	bal.LiquidationPrice = e.liquidationPrice()
+	if e.dust > 0 && price > 0 {
+		bal.Dust = e.dust
+		equity += e.dust * price
+	}
+	if e.perp != nil {
+		bal.PerpMargin = e.perp.margin
+		bal.PerpPnL = e.perp.unrealized()
+		equity += bal.PerpMargin + bal.PerpPnL
+	}
+	if e.options != nil {
+		bal.OptionValue = e.options.value()
+		bal.OptionCollateral = e.options.collateral
+		equity += bal.OptionValue + bal.OptionCollateral
+	}
+	bal.Equity = equity
+	return bal
 }
 
 func (e *Exchange) Orders() []Order {
@@ -209,6 +319,8 @@ func (e *Exchange) Wallet() WalletSnapshot {
 
 // tick is internal; external callers advance bars via Emulator.Next().
 func (e *Exchange) tickBarAt(tick int64, bar OHLCBar) (*Order, error) {
+	bar = e.applyImpact(bar)
+	e.barVolume = bar.Volume
 	price := bar.Close
 	if price <= 0 {
 		return nil, fmt.Errorf("price must be positive")
@@ -217,11 +329,41 @@ func (e *Exchange) tickBarAt(tick int64, bar OHLCBar) (*Order, error) {
 		tick = 0
 	}
 	e.tick = tick
+	e.rejected = e.rejected[:0]
+	e.startSession(bar)
+	e.accrueStaking(bar)
+	e.accrueBorrow(bar)
+	var executed *Order
+	if len(e.deferred) > 0 && bar.Open > 0 {
+		executed = e.fillDeferred(bar)
+	}
+	if closed := e.checkTimeOfDay(bar); executed == nil {
+		executed = closed
+	}
 	e.updateSpread(price)
 	e.lastPrice = price
-	executed := e.processPending(bar)
+	if filled := e.processPending(bar); executed == nil {
+		executed = filled
+	}
+	if closed := e.checkBracket(bar); executed == nil {
+		executed = closed
+	}
+	e.applyStopRules(bar)
+	if closed := e.checkMaxBars(); executed == nil {
+		executed = closed
+	}
+	e.markSession(bar)
+	if recalled := e.applyBorrowRecall(bar); executed == nil {
+		executed = recalled
+	}
+	if assigned := e.settleOptions(bar); executed == nil {
+		executed = assigned
+	}
 	e.lastBar = bar
 	e.hasLastBar = true
+	if err := e.checkInvariants(); err != nil {
+		return executed, err
+	}
 	if executed != nil {
 		return executed, nil
 	}
@@ -230,7 +372,18 @@ func (e *Exchange) tickBarAt(tick int64, bar OHLCBar) (*Order, error) {
 }
 
 func (e *Exchange) OpenLong(fraction float64) (*Order, error) {
-	return e.openLongAtPrice(e.lastPrice, fraction, e.tick)
+	var order *Order
+	err := e.admitOrder(false)
+	if err == nil && e.fillTiming == FillAtNextOpen {
+		err = e.deferMarket(pendingOrder{kind: pendingOpenLong, fraction: fraction})
+	} else if err == nil {
+		order, err = e.openLongAtPrice(e.lastPrice, fraction, e.fee, e.tick)
+	}
+	e.recordIntent(Intent{Action: IntentOpenLong, Fraction: fraction}, order, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return order, e.checkInvariants()
 }
 
 func (e *Exchange) OpenLongLimit(price float64, fraction float64) (*Order, error) {
@@ -240,6 +393,15 @@ func (e *Exchange) OpenLongLimit(price float64, fraction float64) (*Order, error
 
 // LongLimit places a limit order and returns its limit-order ID.
 func (e *Exchange) LongLimit(price float64, fraction float64) (int64, error) {
+	id, err := e.longLimit(price, fraction)
+	e.recordIntent(Intent{Action: IntentLongLimit, Price: price, Fraction: fraction}, nil, id, err)
+	return id, err
+}
+
+func (e *Exchange) longLimit(price float64, fraction float64) (int64, error) {
+	if err := e.admitOrder(true); err != nil {
+		return 0, err
+	}
 	if price <= 0 {
 		price = e.lastPrice
 	}
@@ -249,22 +411,26 @@ func (e *Exchange) LongLimit(price float64, fraction float64) (int64, error) {
 	if fraction <= 0 || fraction > 1 {
 		return 0, ErrInvalidFraction
 	}
-	e.nextLimitID++
-	id := e.nextLimitID
-	e.pending = append(e.pending, pendingOrder{
-		id:           id,
-		kind:         pendingOpenLong,
-		price:        price,
-		fraction:     fraction,
-		placedAtTick: e.tick,
-		lastReason:   "await_next_candle",
-		placedBar:    e.lastBar,
-	})
-	return id, nil
+	return e.queueLimit(pendingOrder{
+		kind:     pendingOpenLong,
+		price:    price,
+		fraction: fraction,
+	}), nil
 }
 
 func (e *Exchange) OpenShort(fraction float64) (*Order, error) {
-	return e.openShortAtPrice(e.lastPrice, fraction, e.tick)
+	var order *Order
+	err := e.admitOrder(false)
+	if err == nil && e.fillTiming == FillAtNextOpen {
+		err = e.deferMarket(pendingOrder{kind: pendingOpenShort, fraction: fraction})
+	} else if err == nil {
+		order, err = e.openShortAtPrice(e.lastPrice, fraction, e.fee, e.tick)
+	}
+	e.recordIntent(Intent{Action: IntentOpenShort, Fraction: fraction}, order, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return order, e.checkInvariants()
 }
 
 func (e *Exchange) OpenShortLimit(price float64, fraction float64) (*Order, error) {
@@ -273,6 +439,15 @@ func (e *Exchange) OpenShortLimit(price float64, fraction float64) (*Order, erro
 }
 
 func (e *Exchange) ShortLimit(price float64, fraction float64) (int64, error) {
+	id, err := e.shortLimit(price, fraction)
+	e.recordIntent(Intent{Action: IntentShortLimit, Price: price, Fraction: fraction}, nil, id, err)
+	return id, err
+}
+
+func (e *Exchange) shortLimit(price float64, fraction float64) (int64, error) {
+	if err := e.admitOrder(true); err != nil {
+		return 0, err
+	}
 	if price <= 0 {
 		price = e.lastPrice
 	}
@@ -282,21 +457,23 @@ func (e *Exchange) ShortLimit(price float64, fraction float64) (int64, error) {
 	if fraction <= 0 || fraction > 1 {
 		return 0, ErrInvalidFraction
 	}
-	e.nextLimitID++
-	id := e.nextLimitID
-	e.pending = append(e.pending, pendingOrder{
-		id:           id,
-		kind:         pendingOpenShort,
-		price:        price,
-		fraction:     fraction,
-		placedAtTick: e.tick,
-		lastReason:   "await_next_candle",
-		placedBar:    e.lastBar,
-	})
-	return id, nil
+	return e.queueLimit(pendingOrder{
+		kind:     pendingOpenShort,
+		price:    price,
+		fraction: fraction,
+	}), nil
 }
 
 func (e *Exchange) CloseDeal(reason string) (*Order, error) {
+	order, err := e.closeDeal(reason)
+	e.recordIntent(Intent{Action: IntentClose, Reason: reason}, order, 0, err)
+	return order, err
+}
+
+func (e *Exchange) closeDeal(reason string) (*Order, error) {
+	if err := e.admitOrder(false); err != nil {
+		return nil, err
+	}
 	if e.position == 0 {
 		return nil, ErrNoPosition
 	}
@@ -306,9 +483,12 @@ func (e *Exchange) CloseDeal(reason string) (*Order, error) {
 	if reason == "" {
 		reason = ReasonExit
 	}
-	order := e.closeAtPrice(e.lastPrice, reason, "")
+	if e.fillTiming == FillAtNextOpen {
+		return nil, e.deferMarket(pendingOrder{kind: pendingClose, reason: reason})
+	}
+	order := e.closeAtPrice(e.lastPrice, reason, "", e.fee)
 	order.PlacedTick = e.tick
-	return &order, nil
+	return &order, e.checkInvariants()
 }
 
 // CloseDealLimit closes the current position using a caller-specified execution price (e.g. stop/limit level).
@@ -319,6 +499,15 @@ func (e *Exchange) CloseDealLimit(price float64, reason string, stopKind string)
 }
 
 func (e *Exchange) CloseLimit(price float64, reason string, stopKind string) (int64, error) {
+	id, err := e.closeLimit(price, reason, stopKind)
+	e.recordIntent(Intent{Action: IntentCloseLimit, Price: price, Reason: reason, StopKind: stopKind}, nil, id, err)
+	return id, err
+}
+
+func (e *Exchange) closeLimit(price float64, reason string, stopKind string) (int64, error) {
+	if err := e.admitOrder(true); err != nil {
+		return 0, err
+	}
 	if price <= 0 {
 		price = e.lastPrice
 	}
@@ -328,19 +517,12 @@ func (e *Exchange) CloseLimit(price float64, reason string, stopKind string) (in
 	if reason == "" {
 		reason = ReasonExit
 	}
-	e.nextLimitID++
-	id := e.nextLimitID
-	e.pending = append(e.pending, pendingOrder{
-		id:           id,
-		kind:         pendingClose,
-		price:        price,
-		reason:       reason,
-		stopKind:     stopKind,
-		placedAtTick: e.tick,
-		lastReason:   "await_next_candle",
-		placedBar:    e.lastBar,
-	})
-	return id, nil
+	return e.queueLimit(pendingOrder{
+		kind:     pendingClose,
+		price:    price,
+		reason:   reason,
+		stopKind: stopKind,
+	}), nil
 }
 
 func (e *Exchange) LimitDiagnostics() LimitDiagnostics {
@@ -360,14 +542,22 @@ func (e *Exchange) LimitDiagnostics() LimitDiagnostics {
 		out.Reasons[reason]++
 		out.PendingTotal++
 	}
-	out.Misses = append(out.Misses, e.misses...)
+	out.Misses = e.missLog()
+	out.MissCounts = make(map[string]int, len(e.missCounts))
+	for k, v := range e.missCounts {
+		out.MissCounts[k] = v
+	}
+	out.MissesDropped = e.missDropped
 	return out
 }
 
-func (e *Exchange) openLongAtPrice(price float64, fraction float64, placedTick int64) (*Order, error) {
+func (e *Exchange) openLongAtPrice(price float64, fraction float64, fee float64, placedTick int64) (*Order, error) {
 	if e.position != 0 {
 		return nil, ErrPositionOpen
 	}
+	if e.sessionLocked() {
+		return nil, ErrSessionLossLimit
+	}
 	if e.lastPrice <= 0 {
 		return nil, ErrPriceNotSet
 	}
@@ -379,29 +569,111 @@ func (e *Exchange) openLongAtPrice(price float64, fraction float64, placedTick i
 	}
 	equityBefore := e.Balance().Equity
 	mid := price
-	notional := e.usd * fraction
-	if notional <= 0 {
-		return nil, ErrInvalidFraction
+	f, err := e.sizeOpen(SideBuy, e.usd, price, fraction, fee)
+	if err != nil {
+		return nil, err
+	}
+	execPnL := f.qty * (mid - f.exec)
+	e.usd -= f.notional
+	e.position = f.qty
+	e.entryPrice = f.exec
+	e.openedTick = e.tick
+	order := e.recordOrder(SideBuy, f.qty, mid, f.exec, f.fee, execPnL, equityBefore, ReasonEntryLong, "", placedTick)
+	return &order, nil
+}
+
+// openSize is the sizing of an entry: the execution price, the quantity, the USD spent (or
+// posted as short margin), the fee and, for shorts, the proceeds net of the fee.
+type openSize struct {
+	exec     float64
+	qty      float64
+	notional float64
+	fee      float64
+	net      float64
+}
+
+// sizeOpen sizes an entry on side with fraction of usd at price without changing the exchange.
+func (e *Exchange) sizeOpen(side OrderSide, usd float64, price float64, fraction float64, fee float64) (openSize, error) {
+	notional := e.capByVolume(usd*fraction, price)
+	if notional <= 0 || notional-notional*fee <= 0 {
+		return openSize{}, ErrInvalidFraction
 	}
-	feeUSD := notional * e.fee
+	f := openSize{exec: e.execPrice(side, price, notional)}
+	if side == SideBuy {
+		f.qty, f.notional, f.fee = e.buyQty(notional, fee, f.exec)
+	} else {
+		f.qty, f.notional, f.fee, f.net = e.sellQty(notional, fee, f.exec)
+	}
+	if f.qty <= 0 || (e.minQty > 0 && f.qty < e.minQty) {
+		return openSize{}, ErrBelowMinQty
+	}
+	return f, nil
+}
+
+// buyQty returns the quantity bought for a USD notional at execPrice, the USD actually spent
+// and the fee. Only whole lots are bought and the fee is charged on what is actually spent. A
+// negative fee (maker rebate) is paid back in cash instead of buying extra quantity.
+func (e *Exchange) buyQty(notional float64, fee float64, execPrice float64) (float64, float64, float64) {
+	budget := notional
+	feeUSD := notional * fee
 	net := notional - feeUSD
-	if net <= 0 {
-		return nil, ErrInvalidFraction
+	if fee < 0 {
+		net = notional
 	}
-	execPrice := e.execPrice(SideBuy, price)
 	qty := net / execPrice
-	execPnL := qty * (mid - execPrice)
-	e.usd -= notional
-	e.position = qty
-	e.entryPrice = execPrice
-	order := e.recordOrder(SideBuy, qty, mid, execPrice, feeUSD, execPnL, equityBefore, ReasonEntryLong, "", placedTick)
-	return &order, nil
+	if e.lotSize > 0 {
+		qty = roundDownToStep(qty, e.lotSize)
+		net = qty * execPrice
+		if fee >= 0 {
+			notional = net / (1 - fee)
+			feeUSD = notional - net
+		}
+	}
+	if fee < 0 {
+		feeUSD = net * fee
+		notional = net + feeUSD
+	}
+	if e.roundsQuote() {
+		feeUSD = e.roundQuote(feeUSD)
+		if e.lotSize <= 0 {
+			net = e.floorQuote(min(net, budget-max(feeUSD, 0)))
+			qty = net / execPrice
+		} else {
+			net = e.floorQuote(net)
+		}
+		notional = net + feeUSD
+		if notional > budget {
+			feeUSD, notional = budget-net, budget
+		}
+	}
+	return qty, notional, feeUSD
+}
+
+// sellQty returns the quantity sold short for a USD notional at execPrice, the notional posted
+// as margin, the fee and the proceeds net of the fee.
+func (e *Exchange) sellQty(notional float64, fee float64, execPrice float64) (float64, float64, float64, float64) {
+	qty := notional / execPrice
+	if e.lotSize > 0 {
+		qty = roundDownToStep(qty, e.lotSize)
+		notional = qty * execPrice
+	}
+	if e.roundsQuote() {
+		notional = e.floorQuote(notional)
+		if e.lotSize <= 0 {
+			qty = notional / execPrice
+		}
+	}
+	feeUSD := e.roundQuote(notional * fee)
+	return qty, notional, feeUSD, notional - feeUSD
 }
 
-func (e *Exchange) openShortAtPrice(price float64, fraction float64, placedTick int64) (*Order, error) {
+func (e *Exchange) openShortAtPrice(price float64, fraction float64, fee float64, placedTick int64) (*Order, error) {
 	if e.position != 0 {
 		return nil, ErrPositionOpen
 	}
+	if e.sessionLocked() {
+		return nil, ErrSessionLossLimit
+	}
 	if e.lastPrice <= 0 {
 		return nil, ErrPriceNotSet
 	}
@@ -413,24 +685,18 @@ func (e *Exchange) openShortAtPrice(price float64, fraction float64, placedTick
 	}
 	equityBefore := e.Balance().Equity
 	mid := price
-	notional := e.usd * fraction
-	if notional <= 0 {
-		return nil, ErrInvalidFraction
+	f, err := e.sizeOpen(SideSell, e.usd, price, fraction, fee)
+	if err != nil {
+		return nil, err
 	}
-	feeUSD := notional * e.fee
-	net := notional - feeUSD
-	if net <= 0 {
-		return nil, ErrInvalidFraction
-	}
-	execPrice := e.execPrice(SideSell, price)
-	qty := notional / execPrice
-	execPnL := qty * (execPrice - mid)
-	e.usd -= notional
-	e.shortMargin += notional
-	e.shortCash += net
-	e.position = -qty
-	e.entryPrice = execPrice
-	order := e.recordOrder(SideSell, qty, mid, execPrice, feeUSD, execPnL, equityBefore, ReasonEntryShort, "", placedTick)
+	execPnL := f.qty * (f.exec - mid)
+	e.usd -= f.notional
+	e.shortMargin += f.notional
+	e.shortCash += f.net
+	e.position = -f.qty
+	e.entryPrice = f.exec
+	e.openedTick = e.tick
+	order := e.recordOrder(SideSell, f.qty, mid, f.exec, f.fee, execPnL, equityBefore, ReasonEntryShort, "", placedTick)
 	return &order, nil
 }
 
@@ -438,6 +704,9 @@ func (e *Exchange) processPending(bar OHLCBar) *Order {
 	if len(e.pending) == 0 {
 		return nil
 	}
+	if e.limitMode == LimitsRest {
+		return e.processResting(bar)
+	}
 	var firstExecuted *Order
 	for len(e.pending) > 0 {
 		p := e.pending[0]
@@ -445,10 +714,20 @@ func (e *Exchange) processPending(bar OHLCBar) *Order {
 			break
 		}
 		fillPrice := p.price
+		// Resting limits that trade at their price pay the maker fee; the fallback fill at the
+		// close crosses the book and pays taker.
+		fee := e.makerFee
+		missReason := ""
 		if !priceInRange(p.price, bar.Low, bar.High) {
+			missReason = "price_not_in_hl_filled_at_close"
+		} else if !e.queueFilled(p, bar) {
+			missReason = "queue_not_filled_filled_at_close"
+		}
+		if missReason != "" {
 			fillPrice = bar.Close
-			e.misses = append(e.misses, LimitMiss{
-				Reason:     "price_not_in_hl_filled_at_close",
+			fee = e.fee
+			e.recordMiss(LimitMiss{
+				Reason:     missReason,
 				Kind:       pendingKindName(p.kind),
 				LimitPrice: p.price,
 				PlacedTick: p.placedAtTick,
@@ -456,42 +735,21 @@ func (e *Exchange) processPending(bar OHLCBar) *Order {
 				PrevBar:    p.placedBar,
 				CurrBar:    bar,
 			})
+			e.trackLimit(p.id, LimitEvent{Kind: LimitEventEvaluated, Reason: missReason})
 		}
-		var executed *Order
-		switch p.kind {
-		case pendingOpenLong:
-			if e.position != 0 {
-				e.limitFailed["position_state_mismatch"]++
-				e.pending = e.pending[1:]
-				continue
-			}
-			executed, _ = e.openLongAtPrice(fillPrice, p.fraction, p.placedAtTick)
-		case pendingOpenShort:
-			if e.position != 0 {
-				e.limitFailed["position_state_mismatch"]++
-				e.pending = e.pending[1:]
-				continue
-			}
-			executed, _ = e.openShortAtPrice(fillPrice, p.fraction, p.placedAtTick)
-		case pendingClose:
-			if e.position == 0 {
-				e.limitFailed["position_state_mismatch"]++
-				e.pending = e.pending[1:]
-				continue
-			}
-			order := e.closeAtPrice(fillPrice, p.reason, p.stopKind)
-			order.PlacedTick = p.placedAtTick
-			// closeAtPrice already appends order into e.orders with PlacedTick=e.tick;
-			// keep emitted order and stored history consistent with original pending placement tick.
-			if n := len(e.orders); n > 0 {
-				e.orders[n-1].PlacedTick = p.placedAtTick
-			}
-			executed = &order
+		if !e.pendingMatchesPosition(p.kind) {
+			e.limitFailed["position_state_mismatch"]++
+			e.limitDone[p.id] = LimitExpired
+			e.reject(p, "position_state_mismatch", nil)
+			e.pending = e.pending[1:]
+			continue
 		}
-		e.pending = e.pending[1:]
-		if executed != nil {
-			e.executedByID[p.id] = *executed
+		executed, err := e.fillPending(p, fillPrice, fee)
+		if executed == nil {
+			e.limitFailed["open_rejected"]++
+			e.reject(p, "open_rejected", err)
 		}
+		e.pending = e.pending[1:]
 		if firstExecuted == nil && executed != nil {
 			firstExecuted = executed
 		}
@@ -499,7 +757,7 @@ func (e *Exchange) processPending(bar OHLCBar) *Order {
 	for i := 1; i < len(e.pending); i++ {
 		if e.tick > e.pending[i].placedAtTick {
 			e.pending[i].lastReason = "blocked_by_fifo_head"
-			e.misses = append(e.misses, LimitMiss{
+			e.recordMiss(LimitMiss{
 				Reason:     "blocked_by_fifo_head",
 				Kind:       pendingKindName(e.pending[i].kind),
 				LimitPrice: e.pending[i].price,
@@ -508,11 +766,63 @@ func (e *Exchange) processPending(bar OHLCBar) *Order {
 				PrevBar:    e.pending[i].placedBar,
 				CurrBar:    bar,
 			})
+			e.trackLimit(e.pending[i].id, LimitEvent{Kind: LimitEventEvaluated, Reason: "blocked_by_fifo_head"})
 		}
 	}
 	return firstExecuted
 }
 
+// pendingMatchesPosition reports whether a pending order can execute in the current position
+// state: entries need a flat book, closes need an open position.
+func (e *Exchange) pendingMatchesPosition(kind pendingKind) bool {
+	if kind == pendingClose || kind == pendingScaleIn || kind == pendingReverse {
+		return e.position != 0
+	}
+	return e.position == 0
+}
+
+// fillPending executes p at price and records it under its limit ID. A nil result means the
+// open was rejected (e.g. below the minimum lot) with the returned error.
+func (e *Exchange) fillPending(p pendingOrder, price float64, fee float64) (*Order, error) {
+	var executed *Order
+	var err error
+	// The fill carries the tag the order was placed under.
+	savedTag, savedMeta := e.tag, e.meta
+	e.tag, e.meta = p.tag, p.meta
+	defer func() { e.tag, e.meta = savedTag, savedMeta }()
+	switch p.kind {
+	case pendingOpenLong:
+		executed, err = e.openLongAtPrice(price, p.fraction, fee, p.placedAtTick)
+	case pendingOpenShort:
+		executed, err = e.openShortAtPrice(price, p.fraction, fee, p.placedAtTick)
+	case pendingScaleIn:
+		executed, err = e.scaleInAtPrice(price, p.fraction, fee, p.placedAtTick)
+	case pendingReverse:
+		var r *Reversal
+		if r, err = e.reverseAtPrice(price, p.fraction, fee, p.placedAtTick); r != nil {
+			r.LimitID = p.id
+			e.reversals = append(e.reversals, *r)
+			executed = &r.Open
+		}
+	case pendingClose:
+		order := e.closeAtPrice(price, p.reason, p.stopKind, fee)
+		order.PlacedTick = p.placedAtTick
+		// closeAtPrice already appends order into e.orders with PlacedTick=e.tick;
+		// keep emitted order and stored history consistent with original pending placement tick.
+		if n := len(e.orders); n > 0 {
+			e.orders[n-1].PlacedTick = p.placedAtTick
+		}
+		executed = &order
+	}
+	if executed != nil {
+		e.executedByID[p.id] = *executed
+		e.trackLimit(p.id, LimitEvent{Kind: LimitEventFilled, Price: executed.Price, OrderID: executed.ID})
+	} else {
+		e.limitDone[p.id] = LimitExpired
+	}
+	return executed, err
+}
+
 func pendingKindName(kind pendingKind) string {
 	switch kind {
 	case pendingOpenLong:
@@ -521,6 +831,10 @@ func pendingKindName(kind pendingKind) string {
 		return "open_short"
 	case pendingClose:
 		return "close"
+	case pendingScaleIn:
+		return "scale_in"
+	case pendingReverse:
+		return "reverse"
 	default:
 		return "unknown"
 	}
@@ -536,20 +850,22 @@ func priceInRange(price float64, low float64, high float64) bool {
 	return price >= low && price <= high
 }
 
-func (e *Exchange) closeAtPrice(price float64, reason string, stopKind string) Order {
+func (e *Exchange) closeAtPrice(price float64, reason string, stopKind string, fee float64) Order {
 	// For stop closes we may execute at a synthetic "mid" (e.g., stop price) while lastPrice
 	// still points to the bar's close; value equityBefore at the provided mid for consistency.
 	savedLast := e.lastPrice
 	e.lastPrice = price
+	e.bracket = nil
 	equityBefore := e.Balance().Equity
 	mid := price
 	if e.position > 0 {
-		execPrice := e.execPrice(SideSell, price)
-		qty := e.position
-		revenue := qty * execPrice
-		feeUSD := revenue * e.fee
+		execPrice := e.execPrice(SideSell, price, e.position*price)
+		qty, dust := e.splitDust(e.position)
+		revenue := e.roundQuote(qty * execPrice)
+		feeUSD := e.roundQuote(revenue * fee)
 		execPnL := qty * (execPrice - mid)
 		e.usd += revenue - feeUSD
+		e.keepDust(dust, mid)
 		e.position = 0
 		e.entryPrice = 0
 		order := e.recordOrder(SideSell, qty, mid, execPrice, feeUSD, execPnL, equityBefore, reason, stopKind, e.tick)
@@ -557,10 +873,10 @@ func (e *Exchange) closeAtPrice(price float64, reason string, stopKind string) O
 		return order
 	}
 	if e.position < 0 {
-		execPrice := e.execPrice(SideBuy, price)
+		execPrice := e.execPrice(SideBuy, price, -e.position*price)
 		qty := -e.position
-		cost := qty * execPrice
-		feeUSD := cost * e.fee
+		cost := e.roundQuote(qty * execPrice)
+		feeUSD := e.roundQuote(cost * fee)
 		execPnL := qty * (mid - execPrice)
 		total := cost + feeUSD
 		available := e.shortCash + e.shortMargin
@@ -602,14 +918,14 @@ func (e *Exchange) closeAtPrice(price float64, reason string, stopKind string) O
 	return order
 }
 
-func (e *Exchange) applySpread(side OrderSide, price float64) float64 {
+func (e *Exchange) applySpread(side OrderSide, price float64, spreadPct float64) float64 {
 	if price <= 0 {
 		return price
 	}
-	if e.spreadPct <= 0 {
+	if spreadPct <= 0 {
 		return price
 	}
-	half := e.spreadPct / 2
+	half := spreadPct / 2
 	switch side {
 	case SideBuy:
 		return price * (1 + half)
@@ -620,26 +936,29 @@ func (e *Exchange) applySpread(side OrderSide, price float64) float64 {
 	}
 }
 
-func (e *Exchange) applySlippage(side OrderSide, price float64) float64 {
+func (e *Exchange) applySlippage(side OrderSide, price float64, slippagePct float64) float64 {
 	if price <= 0 {
 		return price
 	}
-	if e.slippagePct <= 0 {
+	if slippagePct <= 0 {
 		return price
 	}
 	switch side {
 	case SideBuy:
-		return price * (1 + e.slippagePct)
+		return price * (1 + slippagePct)
 	case SideSell:
-		return price * (1 - e.slippagePct)
+		return price * (1 - slippagePct)
 	default:
 		return price
 	}
 }
 
-func (e *Exchange) execPrice(side OrderSide, mid float64) float64 {
-	withSpread := e.applySpread(side, mid)
-	return e.applySlippage(side, withSpread)
+// execPrice applies spread, slippage (by size bucket when set), tick size and price decimals to
+// an order of notional USD at mid.
+func (e *Exchange) execPrice(side OrderSide, mid float64, notional float64) float64 {
+	spread, slippage := e.sizeCosts(side, notional)
+	withSpread := e.applySpread(side, mid, spread)
+	return e.applyPriceDecimals(side, e.applyTickSize(side, e.applySlippage(side, withSpread, slippage)))
 }
 
 func (e *Exchange) updateSpread(price float64) {
@@ -676,7 +995,10 @@ func (e *Exchange) recordOrder(side OrderSide, qty float64, mid float64, exec fl
 	bal := e.Balance()
 	order := Order{
 		ID:            e.nextID,
-		Symbol:        "",
+		RunID:         e.runID,
+		Symbol:        e.symbol,
+		Tag:           e.tag,
+		Meta:          e.meta,
 		Side:          side,
 		Qty:           qty,
 		MidPrice:      mid,
@@ -698,5 +1020,7 @@ func (e *Exchange) recordOrder(side OrderSide, qty float64, mid float64, exec fl
 		SlippagePct:   e.slippagePct,
 	}
 	e.orders = append(e.orders, order)
+	e.recordFee(order.ID, feeUSD)
+	e.recordImpact(side, qty)
 	return order
 }
@@ -0,0 +1,68 @@
+package emul
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// WriteEquityCSV writes the equity curve with its underwater series:
+// time,tick,equity,drawdown (time as RFC3339, empty when bars carry no timestamps).
+func WriteEquityCSV(w io.Writer, curve []EquityPoint) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "tick", "equity", "drawdown"}); err != nil {
+		return err
+	}
+	underwater := Underwater(curve)
+	for i, p := range curve {
+		ts := ""
+		if !p.Time.IsZero() {
+			ts = p.Time.UTC().Format(time.RFC3339)
+		}
+		row := []string{
+			ts,
+			strconv.FormatInt(p.Tick, 10),
+			strconv.FormatFloat(p.Equity, 'f', -1, 64),
+			strconv.FormatFloat(underwater[i].Value, 'f', -1, 64),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportRun writes a run into dir: equity.csv (see WriteEquityCSV), run.json with the orders and
+// curve in the golden format, and manifest.json (see Emulator.Manifest).
+func ExportRun(dir string, emu *Emulator, curve []EquityPoint) error {
+	manifest, err := emu.Manifest()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, "equity.csv"), func(w io.Writer) error { return WriteEquityCSV(w, curve) }); err != nil {
+		return err
+	}
+	if err := WriteGolden(filepath.Join(dir, "run.json"), GoldenFromEmulator(emu, curve)); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "manifest.json"), func(w io.Writer) error { return WriteManifest(w, manifest) })
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,82 @@
+package emul
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+type FillModelKind uint8
+
+const (
+	// FillOnTouch fills a limit whenever the bar reaches its price (the default).
+	FillOnTouch FillModelKind = iota
+	// FillWithProbability fills a touched limit with Probability, modelling an unknown queue
+	// position at the price level.
+	FillWithProbability
+	// FillOnTradeThrough fills only when the bar trades TradeThroughTicks ticks beyond the limit,
+	// i.e. the whole queue at the price was consumed.
+	FillOnTradeThrough
+)
+
+// FillModel decides whether a touched limit actually fills. TickSize overrides the symbol's
+// tick size for FillOnTradeThrough; Seed makes FillWithProbability reproducible.
+type FillModel struct {
+	Kind              FillModelKind
+	Probability       float64
+	TradeThroughTicks int
+	TickSize          float64
+	Seed              uint64
+}
+
+type fillModel struct {
+	cfg FillModel
+	rng *rand.Rand
+}
+
+// SetFillModel applies m to pending limits in both limit modes. A limit that is touched but not
+// filled stays queued under LimitsRest and falls back to the close under LimitsFillOrClose.
+func (e *Exchange) SetFillModel(m FillModel) error {
+	switch m.Kind {
+	case FillOnTouch:
+		e.fillModel = nil
+		return nil
+	case FillWithProbability:
+		if m.Probability < 0 || m.Probability > 1 {
+			return fmt.Errorf("fill probability must be within [0, 1]")
+		}
+	case FillOnTradeThrough:
+		if m.TradeThroughTicks < 0 {
+			return fmt.Errorf("trade-through ticks must not be negative")
+		}
+		if m.TickSize <= 0 && e.tickSize <= 0 {
+			return fmt.Errorf("trade-through fills need a tick size")
+		}
+	default:
+		return fmt.Errorf("unknown fill model %d", m.Kind)
+	}
+	e.fillModel = &fillModel{cfg: m, rng: rand.New(rand.NewPCG(m.Seed, m.Seed^0x9e3779b97f4a7c15))}
+	return nil
+}
+
+// queueFilled reports whether a limit already touched by bar gets its fill.
+func (e *Exchange) queueFilled(p pendingOrder, bar OHLCBar) bool {
+	m := e.fillModel
+	if m == nil {
+		return true
+	}
+	switch m.cfg.Kind {
+	case FillWithProbability:
+		return m.rng.Float64() < m.cfg.Probability
+	case FillOnTradeThrough:
+		tick := m.cfg.TickSize
+		if tick <= 0 {
+			tick = e.tickSize
+		}
+		through := float64(m.cfg.TradeThroughTicks) * tick
+		if e.pendingSide(p.kind) == SideBuy {
+			return bar.Low <= p.price-through+invariantEpsilon
+		}
+		return bar.High >= p.price+through-invariantEpsilon
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+)
+
+// Ledger kinds of the cash flows added with flow fees.
+const (
+	// LedgerBorrow books interest paid on the coin borrowed for a short (see SetBorrowRate).
+	LedgerBorrow = "borrow"
+	// LedgerWithdrawal books USD taken out of the account (see Withdraw).
+	LedgerWithdrawal = "withdrawal"
+)
+
+// FlowFee is a commission on a cash flow that is not a fill: Pct of the flow's absolute amount
+// plus Fixed USD per flow.
+type FlowFee struct {
+	Pct   float64
+	Fixed float64
+}
+
+// SetFlowFee charges fee on every later flow of kind: LedgerFunding, LedgerBorrow,
+// LedgerStaking, LedgerTransfer (between wallets) or LedgerWithdrawal. The fee is booked in the
+// ledger as LedgerFee with Flow set to kind, and comes out of the wallet the flow touched; it is
+// capped at the cash there, except for transfers and withdrawals, which fail without enough
+// cash for amount and fee. A zero fee removes the commission.
+func (e *Exchange) SetFlowFee(kind string, fee FlowFee) error {
+	switch kind {
+	case LedgerFunding, LedgerBorrow, LedgerStaking, LedgerTransfer, LedgerWithdrawal:
+	default:
+		return fmt.Errorf("no flow fee for ledger kind %q", kind)
+	}
+	if fee.Pct < 0 || fee.Fixed < 0 {
+		return fmt.Errorf("flow fee must not be negative")
+	}
+	if fee == (FlowFee{}) {
+		delete(e.flowFees, kind)
+		return nil
+	}
+	if e.flowFees == nil {
+		e.flowFees = make(map[string]FlowFee)
+	}
+	e.flowFees[kind] = fee
+	return nil
+}
+
+// FlowFees returns the flow fees in force by ledger kind.
+func (e *Exchange) FlowFees() map[string]FlowFee {
+	out := make(map[string]FlowFee, len(e.flowFees))
+	for k, v := range e.flowFees {
+		out[k] = v
+	}
+	return out
+}
+
+// flowFee is the fee due on a flow of amount.
+func (e *Exchange) flowFee(kind string, amount float64) float64 {
+	fee, ok := e.flowFees[kind]
+	if !ok || amount == 0 {
+		return 0
+	}
+	return e.roundQuote(math.Abs(amount)*fee.Pct + fee.Fixed)
+}
+
+// chargeFlowFee takes the fee on a flow of amount from wallets and books what was collected.
+func (e *Exchange) chargeFlowFee(kind string, amount float64, wallets ...*float64) {
+	e.bookFlowFee(kind, debit(e.flowFee(kind, amount), wallets...))
+}
+
+// debit takes due from the cash of wallets in order, never below zero, and returns what it
+// collected.
+func debit(due float64, wallets ...*float64) float64 {
+	collected := 0.0
+	for _, cash := range wallets {
+		take := math.Min(due-collected, math.Max(*cash, 0))
+		*cash -= take
+		collected += take
+	}
+	return collected
+}
+
+func (e *Exchange) bookFlowFee(kind string, fee float64) {
+	if fee > 0 {
+		e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerFee, Amount: -fee, Flow: kind})
+	}
+}
+
+// SetBorrowRate charges interest on shorts at the simple annual rate apr (0.1 for 10%) of the
+// borrowed coin's value, accrued over the time between bars like staking; 0 disables it. The
+// interest is paid from the short's proceeds, then from free USD.
+func (e *Exchange) SetBorrowRate(apr float64) error {
+	if apr < 0 || math.IsNaN(apr) || math.IsInf(apr, 0) {
+		return fmt.Errorf("borrow rate must be a non-negative number")
+	}
+	e.borrowAPR = apr
+	return nil
+}
+
+func (e *Exchange) BorrowRate() float64 {
+	return e.borrowAPR
+}
+
+// accrueBorrow charges the interest of the short held from the previous bar up to bar.
+func (e *Exchange) accrueBorrow(bar OHLCBar) {
+	now := bar.Time
+	if e.clock != nil {
+		now = e.clock.Now()
+	}
+	since := e.borrowedAt
+	e.borrowedAt = now
+	if e.borrowAPR <= 0 || e.position >= 0 || since.IsZero() || !now.After(since) || e.lastPrice <= 0 {
+		return
+	}
+	years := float64(now.Sub(since)) / float64(stakingYear)
+	paid := debit(-e.position*e.lastPrice*e.borrowAPR*years, &e.shortCash, &e.usd)
+	e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerBorrow, Amount: -paid})
+	e.chargeFlowFee(LedgerBorrow, paid, &e.shortCash, &e.usd)
+}
+
+// Withdraw takes amount USD of free cash out of the spot wallet, plus the withdrawal fee. The
+// withdrawn cash leaves the account, so it lowers equity.
+func (e *Exchange) Withdraw(amount float64) error {
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return fmt.Errorf("withdrawal amount must be positive")
+	}
+	fee := e.flowFee(LedgerWithdrawal, amount)
+	if e.usd < amount+fee {
+		return fmt.Errorf("%w: %.2f for %.2f plus fee %.2f", ErrInsufficientFunds, e.usd, amount, fee)
+	}
+	e.usd -= amount + fee
+	e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerWithdrawal, Amount: -amount})
+	e.bookFlowFee(LedgerWithdrawal, fee)
+	return e.checkInvariants()
+}
+
+// CostReport gathers every cost in the ledger, as positive USD paid: order fees net of maker
+// rebates, funding and borrow interest paid net of what was received, and flow fees by the
+// flow they were charged on. Total sums them.
+type CostReport struct {
+	OrderFees float64
+	Rebates   float64
+	Funding   float64
+	Borrow    float64
+	FlowFees  map[string]float64
+	Total     float64
+}
+
+func (e *Exchange) CostReport() CostReport {
+	out := CostReport{FlowFees: make(map[string]float64)}
+	for _, entry := range e.ledger {
+		switch {
+		case entry.Kind == LedgerFee && entry.Flow != "":
+			out.FlowFees[entry.Flow] -= entry.Amount
+		case entry.Kind == LedgerFee:
+			out.OrderFees -= entry.Amount
+		case entry.Kind == LedgerRebate:
+			out.Rebates += entry.Amount
+		case entry.Kind == LedgerFunding:
+			out.Funding -= entry.Amount
+		case entry.Kind == LedgerBorrow:
+			out.Borrow -= entry.Amount
+		}
+	}
+	out.Total = out.OrderFees - out.Rebates + out.Funding + out.Borrow
+	for _, v := range out.FlowFees {
+		out.Total += v
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+package emul
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CoinSweepConfig describes a run of one strategy over many coins. Coins defaults to every coin
+// in the data root that has Interval.
+type CoinSweepConfig struct {
+	DataRoot string
+	Interval string
+	Coins    []string
+	StartUSD float64
+	Costs    CostProfile
+	// CSV holds the reader options the coins are discovered and loaded with.
+	CSV CSVReaderOptions
+}
+
+// CoinRun is one coin's replay; Err is set when the coin could not be loaded or replayed.
+type CoinRun struct {
+	Coin        string
+	Bars        int
+	Curve       []EquityPoint
+	Stats       TradeStats
+	FinalEquity float64
+	Return      float64
+	MaxDrawdown float64
+	Err         error
+}
+
+// CoinReport aggregates a sweep. Pooled is an equal-weight portfolio of the successful runs: at
+// every timestamp it is the mean equity of the coins, each carried as of its last bar (StartUSD
+// before its first). Correlation is the correlation of per-bar equity returns on common bar times,
+// in the order of Coins; pairs without variance are 0.
+type CoinReport struct {
+	Interval    string
+	Runs        []CoinRun
+	Coins       []string
+	Pooled      []EquityPoint
+	Correlation [][]float64
+}
+
+// SweepCoins runs a fresh strategy from newStrategy over every coin, to check whether a result
+// generalizes beyond the coin it was tuned on.
+func SweepCoins(cfg CoinSweepConfig, newStrategy func(coin string) Strategy) (CoinReport, error) {
+	if newStrategy == nil {
+		return CoinReport{}, fmt.Errorf("strategy factory is nil")
+	}
+	if cfg.StartUSD <= 0 {
+		return CoinReport{}, fmt.Errorf("start USD must be positive")
+	}
+	interval, err := ParseInterval(cfg.Interval)
+	if err != nil {
+		return CoinReport{}, err
+	}
+	coins := cfg.Coins
+	if len(coins) == 0 {
+		catalog, err := DiscoverDataRootWithOptions(cfg.DataRoot, cfg.CSV)
+		if err != nil {
+			return CoinReport{}, err
+		}
+		for _, c := range catalog.Coins {
+			if _, ok := c.Interval(interval); ok {
+				coins = append(coins, c.Coin)
+			}
+		}
+	}
+	if len(coins) == 0 {
+		return CoinReport{}, fmt.Errorf("no coins with interval %s in %s", interval, cfg.DataRoot)
+	}
+	report := CoinReport{Interval: interval}
+	var curves [][]EquityPoint
+	for _, coin := range coins {
+		run := runCoin(cfg, interval, strings.ToLower(strings.TrimSpace(coin)), newStrategy)
+		report.Runs = append(report.Runs, run)
+		if run.Err == nil && len(run.Curve) > 0 {
+			report.Coins = append(report.Coins, run.Coin)
+			curves = append(curves, run.Curve)
+		}
+	}
+	report.Pooled = poolCurves(curves, cfg.StartUSD)
+	report.Correlation = curveCorrelation(curves)
+	return report, nil
+}
+
+func runCoin(cfg CoinSweepConfig, interval string, coin string, newStrategy func(coin string) Strategy) CoinRun {
+	run := CoinRun{Coin: coin}
+	values, ohlc, _, err := LoadSeriesWithOHLCFromDataRootWithOptions(cfg.DataRoot, coin, interval, nil, nil, cfg.CSV)
+	if err != nil {
+		run.Err = err
+		return run
+	}
+	bars, err := BarsFromSeries(values, ohlc)
+	if err != nil {
+		run.Err = err
+		return run
+	}
+	emu, err := NewEmulatorWithProfile(cfg.StartUSD, cfg.Costs, bars)
+	if err != nil {
+		run.Err = err
+		return run
+	}
+	defer emu.Close()
+	run.Bars = len(bars)
+	if run.Curve, run.Err = Run(emu, newStrategy(coin)); run.Err != nil {
+		return run
+	}
+	run.Stats = ComputeTradeStats(PairTrades(emu.ex.orders))
+	run.FinalEquity = emu.ex.Balance().Equity
+	run.Return = run.FinalEquity/cfg.StartUSD - 1
+	run.MaxDrawdown = MaxDrawdown(run.Curve)
+	return run
+}
+
+func poolCurves(curves [][]EquityPoint, start float64) []EquityPoint {
+	if len(curves) == 0 {
+		return nil
+	}
+	seen := make(map[int64]time.Time)
+	for _, c := range curves {
+		for _, p := range c {
+			seen[p.Time.UnixNano()] = p.Time
+		}
+	}
+	times := make([]time.Time, 0, len(seen))
+	for _, t := range seen {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	pos := make([]int, len(curves))
+	pooled := make([]EquityPoint, len(times))
+	for i, t := range times {
+		sum := 0.0
+		for k, c := range curves {
+			for pos[k] < len(c) && !c[pos[k]].Time.After(t) {
+				pos[k]++
+			}
+			if pos[k] == 0 {
+				sum += start
+				continue
+			}
+			sum += c[pos[k]-1].Equity
+		}
+		pooled[i] = EquityPoint{Tick: int64(i + 1), Time: t, Equity: sum / float64(len(curves))}
+	}
+	return pooled
+}
+
+func curveCorrelation(curves [][]EquityPoint) [][]float64 {
+	k := len(curves)
+	common := make(map[int64]int)
+	for _, c := range curves {
+		for _, p := range c {
+			common[p.Time.UnixNano()]++
+		}
+	}
+	rets := make([][]float64, k)
+	for i, c := range curves {
+		prev := math.NaN()
+		for _, p := range c {
+			if common[p.Time.UnixNano()] != k {
+				continue
+			}
+			if !math.IsNaN(prev) && prev > 0 {
+				rets[i] = append(rets[i], p.Equity/prev-1)
+			}
+			prev = p.Equity
+		}
+	}
+	corr := make([][]float64, k)
+	for i := range corr {
+		corr[i] = make([]float64, k)
+		corr[i][i] = 1
+		for j := range i {
+			corr[i][j] = pearson(rets[i], rets[j])
+			corr[j][i] = corr[i][j]
+		}
+	}
+	return corr
+}
+
+func pearson(a []float64, b []float64) float64 {
+	n := min(len(a), len(b))
+	if n < 2 {
+		return 0
+	}
+	ma, va := meanVariance(a[:n])
+	mb, vb := meanVariance(b[:n])
+	if va <= 0 || vb <= 0 {
+		return 0
+	}
+	cov := 0.0
+	for t := 0; t < n; t++ {
+		cov += (a[t] - ma) * (b[t] - mb)
+	}
+	return cov / float64(n-1) / math.Sqrt(va*vb)
+}
+
+// WriteCoinReport writes the per-coin table as CSV:
+// coin,bars,trades,win_rate,net_pnl,return,max_drawdown,final_equity,error.
+func WriteCoinReport(w io.Writer, r CoinReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"coin", "bars", "trades", "win_rate", "net_pnl", "return", "max_drawdown", "final_equity", "error"}); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, run := range r.Runs {
+		msg := ""
+		if run.Err != nil {
+			msg = run.Err.Error()
+		}
+		row := []string{
+			run.Coin,
+			strconv.Itoa(run.Bars),
+			strconv.Itoa(run.Stats.Trades),
+			f(run.Stats.WinRate),
+			f(run.Stats.NetPnL),
+			f(run.Return),
+			f(run.MaxDrawdown),
+			f(run.FinalEquity),
+			msg,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,113 @@
+package emul
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// GAConfig tunes OptimizeGA. Elite individuals pass to the next generation unchanged (default 2);
+// parents are picked by tournaments of TournamentSize (default 3). Crossover is uniform with
+// probability CrossoverRate (default 0.9), and each gene mutates with probability MutationRate
+// (default 1/n) by a Gaussian step of MutationScale of its range (default 0.1).
+type GAConfig struct {
+	Elite          int
+	TournamentSize int
+	CrossoverRate  float64
+	MutationRate   float64
+	MutationScale  float64
+}
+
+// OptimizeGA evolves parameter vectors over generations of Population (default 20) until the
+// budget is spent. Runs are reproducible for a given Seed.
+func (r *BatchRunner) OptimizeGA(ctx context.Context, cfg OptimizeConfig, ga GAConfig) (OptimizeResult, error) {
+	if err := cfg.validate(); err != nil {
+		return OptimizeResult{}, err
+	}
+	n := len(cfg.Params)
+	size := cfg.Population
+	if size <= 0 {
+		size = 20
+	}
+	size = max(size, 2)
+	if ga.Elite <= 0 {
+		ga.Elite = 2
+	}
+	ga.Elite = min(ga.Elite, size-1)
+	if ga.TournamentSize <= 0 {
+		ga.TournamentSize = 3
+	}
+	if ga.CrossoverRate <= 0 {
+		ga.CrossoverRate = 0.9
+	}
+	if ga.MutationRate <= 0 {
+		ga.MutationRate = 1 / float64(n)
+	}
+	if ga.MutationScale <= 0 {
+		ga.MutationScale = 0.1
+	}
+
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))
+	population := make([][]float64, min(size, cfg.Budget))
+	for k := range population {
+		population[k] = make([]float64, n)
+		for i := range n {
+			population[k][i] = rng.Float64()
+		}
+	}
+	var res OptimizeResult
+	scores, err := cfg.evaluate(ctx, r, 0, population, &res)
+	if err != nil {
+		return res, err
+	}
+	for gen := 1; len(res.Trials) < cfg.Budget; gen++ {
+		order := make([]int, len(population))
+		for k := range order {
+			order[k] = k
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+		// Elites are carried over with their scores, so only children spend budget.
+		next := make([][]float64, 0, size)
+		nextScores := make([]float64, 0, size)
+		for _, k := range order[:min(ga.Elite, len(order))] {
+			next = append(next, population[k])
+			nextScores = append(nextScores, scores[k])
+		}
+		pick := func() []float64 {
+			best := rng.IntN(len(population))
+			for range ga.TournamentSize - 1 {
+				if k := rng.IntN(len(population)); scores[k] > scores[best] {
+					best = k
+				}
+			}
+			return population[best]
+		}
+		children := make([][]float64, min(size-len(next), cfg.Budget-len(res.Trials)))
+		for c := range children {
+			a, b := pick(), pick()
+			child := append([]float64(nil), a...)
+			if rng.Float64() < ga.CrossoverRate {
+				for i := range n {
+					if rng.IntN(2) == 1 {
+						child[i] = b[i]
+					}
+				}
+			}
+			for i := range n {
+				if rng.Float64() < ga.MutationRate {
+					child[i] = math.Min(math.Max(child[i]+ga.MutationScale*rng.NormFloat64(), 0), 1)
+				}
+			}
+			children[c] = child
+		}
+		childScores, err := cfg.evaluate(ctx, r, gen, children, &res)
+		if err != nil {
+			return res, err
+		}
+		population = append(next, children...)
+		scores = append(nextScores, childScores...)
+	}
+	return res, nil
+}
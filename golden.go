@@ -0,0 +1,134 @@
+package emul
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// GoldenRun is the recorded output of a run: every executed order and the equity curve.
+type GoldenRun struct {
+	Orders []Order
+	Equity []EquityPoint
+}
+
+// GoldenTolerance bounds float differences: a value matches when it is within Abs or within
+// Rel of the recorded value. Integers, strings and times must match exactly.
+type GoldenTolerance struct {
+	Abs float64
+	Rel float64
+}
+
+// goldenMaxDiffs caps the number of differences listed in a comparison error.
+const goldenMaxDiffs = 20
+
+func WriteGolden(path string, run GoldenRun) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func ReadGolden(path string) (GoldenRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return GoldenRun{}, err
+	}
+	var run GoldenRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return GoldenRun{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return run, nil
+}
+
+// CompareGolden reports every field that drifted beyond tol (up to a cap) in one error.
+func CompareGolden(want GoldenRun, got GoldenRun, tol GoldenTolerance) error {
+	var diffs []string
+	if len(want.Orders) != len(got.Orders) {
+		diffs = append(diffs, fmt.Sprintf("orders: want %d, got %d", len(want.Orders), len(got.Orders)))
+	}
+	for i := 0; i < min(len(want.Orders), len(got.Orders)); i++ {
+		diffs = goldenDiff(diffs, fmt.Sprintf("orders[%d]", i), reflect.ValueOf(want.Orders[i]), reflect.ValueOf(got.Orders[i]), tol)
+	}
+	if len(want.Equity) != len(got.Equity) {
+		diffs = append(diffs, fmt.Sprintf("equity: want %d points, got %d", len(want.Equity), len(got.Equity)))
+	}
+	for i := 0; i < min(len(want.Equity), len(got.Equity)); i++ {
+		diffs = goldenDiff(diffs, fmt.Sprintf("equity[%d]", i), reflect.ValueOf(want.Equity[i]), reflect.ValueOf(got.Equity[i]), tol)
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	more := ""
+	if len(diffs) > goldenMaxDiffs {
+		more = fmt.Sprintf("\n  ... and %d more", len(diffs)-goldenMaxDiffs)
+		diffs = diffs[:goldenMaxDiffs]
+	}
+	return fmt.Errorf("golden mismatch:\n  %s%s", strings.Join(diffs, "\n  "), more)
+}
+
+// MatchGolden compares got against the golden file at path. With update set (or when rewriting
+// is intended after a deliberate change) the file is written instead.
+func MatchGolden(path string, got GoldenRun, tol GoldenTolerance, update bool) error {
+	if update {
+		return WriteGolden(path, got)
+	}
+	want, err := ReadGolden(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("golden file %s does not exist; rerun with update enabled to record it", path)
+	}
+	if err != nil {
+		return err
+	}
+	return CompareGolden(want, got, tol)
+}
+
+// GoldenFromEmulator captures the emulator's order history together with an equity curve.
+func GoldenFromEmulator(emu *Emulator, equity []EquityPoint) GoldenRun {
+	return GoldenRun{
+		Orders: emu.Exchange().Orders(),
+		Equity: equity,
+	}
+}
+
+func goldenDiff(diffs []string, path string, want reflect.Value, got reflect.Value, tol GoldenTolerance) []string {
+	if want.Type() == reflect.TypeOf(time.Time{}) {
+		if !want.Interface().(time.Time).Equal(got.Interface().(time.Time)) {
+			diffs = append(diffs, fmt.Sprintf("%s: want %v, got %v", path, want.Interface(), got.Interface()))
+		}
+		return diffs
+	}
+	switch want.Kind() {
+	case reflect.Struct:
+		for i := 0; i < want.NumField(); i++ {
+			if !want.Type().Field(i).IsExported() {
+				continue
+			}
+			diffs = goldenDiff(diffs, path+"."+want.Type().Field(i).Name, want.Field(i), got.Field(i), tol)
+		}
+	case reflect.Float32, reflect.Float64:
+		w, g := want.Float(), got.Float()
+		if !floatClose(w, g, tol) {
+			diffs = append(diffs, fmt.Sprintf("%s: want %.10g, got %.10g", path, w, g))
+		}
+	default:
+		if !reflect.DeepEqual(want.Interface(), got.Interface()) {
+			diffs = append(diffs, fmt.Sprintf("%s: want %v, got %v", path, want.Interface(), got.Interface()))
+		}
+	}
+	return diffs
+}
+
+func floatClose(want float64, got float64, tol GoldenTolerance) bool {
+	if want == got || (math.IsNaN(want) && math.IsNaN(got)) {
+		return true
+	}
+	diff := math.Abs(want - got)
+	return diff <= tol.Abs || diff <= tol.Rel*math.Abs(want)
+}
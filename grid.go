@@ -0,0 +1,144 @@
+package emul
+
+import (
+	"fmt"
+)
+
+const ReasonGridExit = "grid-exit"
+
+// GridConfig describes a ladder of limits spaced evenly around Center: entry levels below
+// (longs) and above (shorts), each committing Fraction of the free USD.
+type GridConfig struct {
+	// Center is the middle of the ladder; zero uses the last price at the first Update.
+	Center float64
+	// SpacingPct is the distance between levels as a fraction of Center (0.01 = 1%).
+	SpacingPct float64
+	Levels     int
+	Fraction   float64
+	// LongOnly skips the short entries above the center.
+	LongOnly bool
+}
+
+// Grid maintains a GridConfig on an exchange. The exchange holds a single position, so one
+// entry fills at a time: once a level fills, the grid places an exit one level closer to the
+// center and re-arms the filled level, which can fill again after the exit.
+//
+// NewGrid switches the exchange to LimitsRest so untouched levels stay on the book. Call
+// Update after every bar.
+type Grid struct {
+	ex      *Exchange
+	cfg     GridConfig
+	center  float64
+	entries map[int]int64
+	held    int
+	exitID  int64
+}
+
+func NewGrid(ex *Exchange, cfg GridConfig) (*Grid, error) {
+	if ex == nil {
+		return nil, fmt.Errorf("exchange is nil")
+	}
+	if cfg.Levels <= 0 {
+		return nil, fmt.Errorf("grid needs at least one level")
+	}
+	if cfg.SpacingPct <= 0 || float64(cfg.Levels)*cfg.SpacingPct >= 1 {
+		return nil, fmt.Errorf("grid spacing %v with %d levels is out of range", cfg.SpacingPct, cfg.Levels)
+	}
+	if cfg.Fraction <= 0 || cfg.Fraction > 1 {
+		return nil, ErrInvalidFraction
+	}
+	ex.SetLimitMode(LimitsRest)
+	return &Grid{
+		ex:      ex,
+		cfg:     cfg,
+		center:  cfg.Center,
+		entries: make(map[int]int64),
+	}, nil
+}
+
+// Price returns the price of a level: negative levels are below the center, positive above.
+func (g *Grid) Price(level int) float64 {
+	return g.center * (1 + float64(level)*g.cfg.SpacingPct)
+}
+
+// Held returns the level of the entry currently open, or 0 when flat.
+func (g *Grid) Held() int {
+	return g.held
+}
+
+// Update reconciles the grid with fills from the last bar and replenishes missing orders.
+func (g *Grid) Update() error {
+	if g.center <= 0 {
+		if g.ex.lastPrice <= 0 {
+			return ErrPriceNotSet
+		}
+		g.center = g.ex.lastPrice
+	}
+	live := make(map[int64]bool, len(g.ex.pending))
+	for _, p := range g.ex.pending {
+		live[p.id] = true
+	}
+	for level, id := range g.entries {
+		if id != 0 && !live[id] {
+			if _, filled := g.ex.executedByID[id]; filled {
+				g.held = level
+			}
+			g.entries[level] = 0
+		}
+	}
+	if g.exitID != 0 && !live[g.exitID] {
+		g.exitID = 0
+	}
+	if g.ex.position == 0 {
+		g.held = 0
+	}
+	if g.held != 0 && g.exitID == 0 {
+		exit := g.held + 1
+		if g.held > 0 {
+			exit = g.held - 1
+		}
+		id, err := g.ex.CloseLimit(g.Price(exit), ReasonGridExit, "")
+		if err != nil {
+			return err
+		}
+		g.exitID = id
+	}
+	for k := 1; k <= g.cfg.Levels; k++ {
+		if err := g.arm(-k, g.ex.LongLimit); err != nil {
+			return err
+		}
+		if g.cfg.LongOnly {
+			continue
+		}
+		if err := g.arm(k, g.ex.ShortLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop cancels every order the grid has on the book; the open position, if any, is kept.
+func (g *Grid) Stop() {
+	for level, id := range g.entries {
+		if id != 0 {
+			g.ex.CancelLimit(id)
+		}
+		g.entries[level] = 0
+	}
+	if g.exitID != 0 {
+		g.ex.CancelLimit(g.exitID)
+		g.exitID = 0
+	}
+}
+
+func (g *Grid) arm(level int, place func(price float64, fraction float64) (int64, error)) error {
+	if g.entries[level] != 0 {
+		return nil
+	}
+	id, err := place(g.Price(level), g.cfg.Fraction)
+	if err != nil {
+		return err
+	}
+	g.entries[level] = id
+	return nil
+}
@@ -0,0 +1,87 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+)
+
+// ImpactModel makes the account's own fills move later prices. A fill of qty on a bar with
+// volume V shifts the following bars by Coefficient*(qty/V)^Exponent (up for buys, down for
+// sells), capped per fill at MaxPct when set. The shift halves every HalfLifeBars bars; with a
+// zero half-life it lasts one bar. Bars without volume cause no impact.
+type ImpactModel struct {
+	Coefficient  float64
+	Exponent     float64
+	HalfLifeBars float64
+	MaxPct       float64
+}
+
+type impactState struct {
+	cfg    ImpactModel
+	offset float64
+}
+
+// SetImpactModel enables self-impact; a zero Coefficient disables it. Exponent defaults to 0.5
+// (square-root impact). The impact only affects this exchange, not other accounts of the
+// emulator.
+func (e *Exchange) SetImpactModel(m ImpactModel) error {
+	if m.Coefficient == 0 {
+		e.impact = nil
+		return nil
+	}
+	if m.Coefficient < 0 || m.Exponent < 0 || m.HalfLifeBars < 0 || m.MaxPct < 0 {
+		return fmt.Errorf("impact parameters must not be negative")
+	}
+	if m.Exponent == 0 {
+		m.Exponent = 0.5
+	}
+	e.impact = &impactState{cfg: m}
+	return nil
+}
+
+// ImpactOffset returns the price shift currently applied to bars as a fraction.
+func (e *Exchange) ImpactOffset() float64 {
+	if e.impact == nil {
+		return 0
+	}
+	return e.impact.offset
+}
+
+// applyImpact shifts bar by the accumulated impact and decays it for the next bar.
+func (e *Exchange) applyImpact(bar OHLCBar) OHLCBar {
+	s := e.impact
+	if s == nil {
+		return bar
+	}
+	if s.offset == 0 {
+		return bar
+	}
+	scale := 1 + s.offset
+	bar.Open *= scale
+	bar.High *= scale
+	bar.Low *= scale
+	bar.Close *= scale
+	bar.Average *= scale
+	if s.cfg.HalfLifeBars > 0 {
+		s.offset *= math.Pow(0.5, 1/s.cfg.HalfLifeBars)
+	} else {
+		s.offset = 0
+	}
+	return bar
+}
+
+// recordImpact adds the impact of a fill on the current bar.
+func (e *Exchange) recordImpact(side OrderSide, qty float64) {
+	s := e.impact
+	if s == nil || e.barVolume <= 0 || qty <= 0 {
+		return
+	}
+	pct := s.cfg.Coefficient * math.Pow(qty/e.barVolume, s.cfg.Exponent)
+	if s.cfg.MaxPct > 0 {
+		pct = math.Min(pct, s.cfg.MaxPct)
+	}
+	if side == SideSell {
+		pct = -pct
+	}
+	s.offset = math.Max(s.offset+pct, -0.99)
+}
@@ -0,0 +1,331 @@
+package emul
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Trade log importers map other engines' exports onto Trade, with synthesized Entry and Exit
+// orders, so ComputeTradeStats and BreakdownExits compare them with emulator runs on the same
+// data. PnL, Fees and Return are the source engine's figures, so Return follows its definition
+// (usually relative to the position, not equity). Ticks follow Emulator.Next (tick k is bars[k-1]):
+// where a log only has timestamps, a tick is that of the last bar at or before it, and 0 before the
+// first bar or without bars.
+
+// ImportBacktestingPy reads the trades table of backtesting.py (stats._trades.to_csv()); its
+// 0-based bar indices become ticks EntryBar+1 and ExitBar+1. Size is negative for shorts and
+// Commission, when present, is taken as the trade's fees. The table has no exit reason, so exits
+// are ReasonExit.
+func ImportBacktestingPy(r io.Reader) ([]Trade, error) {
+	rows, err := readTradeCSV(r, "Size", "EntryBar", "ExitBar", "EntryPrice", "ExitPrice", "PnL", "ReturnPct")
+	if err != nil {
+		return nil, fmt.Errorf("backtesting.py: %w", err)
+	}
+	trades := make([]Trade, 0, len(rows))
+	for i, row := range rows {
+		size, err := row.float("Size")
+		if err != nil {
+			return nil, fmt.Errorf("backtesting.py row %d: %w", i+1, err)
+		}
+		t := importedTrade{short: size < 0, qty: math.Abs(size)}
+		for _, f := range []struct {
+			col string
+			dst *float64
+		}{{"EntryPrice", &t.entryPrice}, {"ExitPrice", &t.exitPrice}, {"PnL", &t.pnl}, {"ReturnPct", &t.ret}} {
+			if *f.dst, err = row.float(f.col); err != nil {
+				return nil, fmt.Errorf("backtesting.py row %d: %w", i+1, err)
+			}
+		}
+		if row.get("Commission") != "" {
+			if t.exitFee, err = row.float("Commission"); err != nil {
+				return nil, fmt.Errorf("backtesting.py row %d: %w", i+1, err)
+			}
+		}
+		entryBar, err1 := row.float("EntryBar")
+		exitBar, err2 := row.float("ExitBar")
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, fmt.Errorf("backtesting.py row %d: %w", i+1, err)
+		}
+		t.entryTick, t.exitTick = int64(entryBar)+1, int64(exitBar)+1
+		trades = append(trades, t.trade(i))
+	}
+	return trades, nil
+}
+
+// ImportFreqtrade reads a freqtrade backtest result (backtest-result-*.json). strategy selects
+// the strategy and may be empty when the file holds one. Fees are derived from fee_open and
+// fee_close; stop-loss and liquidation exits map to ReasonStopLoss and ReasonLiquidate, other exit
+// reasons are kept as they are.
+func ImportFreqtrade(r io.Reader, strategy string, bars []OHLCBar) ([]Trade, error) {
+	var doc struct {
+		Strategy map[string]struct {
+			Trades []freqtradeTrade `json:"trades"`
+		} `json:"strategy"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("freqtrade: %w", err)
+	}
+	if strategy == "" {
+		if len(doc.Strategy) != 1 {
+			return nil, fmt.Errorf("freqtrade: %d strategies in result, name one", len(doc.Strategy))
+		}
+		for name := range doc.Strategy {
+			strategy = name
+		}
+	}
+	res, ok := doc.Strategy[strategy]
+	if !ok {
+		return nil, fmt.Errorf("freqtrade: strategy %q not in result", strategy)
+	}
+	trades := make([]Trade, 0, len(res.Trades))
+	for i, ft := range res.Trades {
+		opened, err1 := ft.time(ft.OpenTimestamp, ft.OpenDate)
+		closed, err2 := ft.time(ft.CloseTimestamp, ft.CloseDate)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, fmt.Errorf("freqtrade trade %d: %w", i+1, err)
+		}
+		reason := ft.ExitReason
+		if reason == "" {
+			reason = ft.SellReason
+		}
+		switch reason {
+		case "stop_loss", "stoploss", "stoploss_on_exchange", "trailing_stop_loss":
+			reason = ReasonStopLoss
+		case "liquidation":
+			reason = ReasonLiquidate
+		}
+		t := importedTrade{
+			symbol:     ft.Pair,
+			short:      ft.IsShort,
+			qty:        ft.Amount,
+			entryPrice: ft.OpenRate,
+			exitPrice:  ft.CloseRate,
+			entryFee:   ft.Amount * ft.OpenRate * ft.FeeOpen,
+			exitFee:    ft.Amount * ft.CloseRate * ft.FeeClose,
+			pnl:        ft.ProfitAbs,
+			ret:        ft.ProfitRatio,
+			reason:     reason,
+			entryTick:  tickAt(bars, opened),
+			exitTick:   tickAt(bars, closed),
+		}
+		trades = append(trades, t.trade(i))
+	}
+	return trades, nil
+}
+
+type freqtradeTrade struct {
+	Pair           string  `json:"pair"`
+	Amount         float64 `json:"amount"`
+	OpenDate       string  `json:"open_date"`
+	CloseDate      string  `json:"close_date"`
+	OpenTimestamp  int64   `json:"open_timestamp"`
+	CloseTimestamp int64   `json:"close_timestamp"`
+	OpenRate       float64 `json:"open_rate"`
+	CloseRate      float64 `json:"close_rate"`
+	FeeOpen        float64 `json:"fee_open"`
+	FeeClose       float64 `json:"fee_close"`
+	ProfitAbs      float64 `json:"profit_abs"`
+	ProfitRatio    float64 `json:"profit_ratio"`
+	ExitReason     string  `json:"exit_reason"`
+	SellReason     string  `json:"sell_reason"`
+	IsShort        bool    `json:"is_short"`
+}
+
+// time prefers the millisecond timestamp and falls back to the date string.
+func (ft freqtradeTrade) time(ms int64, date string) (time.Time, error) {
+	if ms > 0 {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	ts, ok := parseCSVTime(date, EpochAuto)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date %q", date)
+	}
+	return ts, nil
+}
+
+// ImportVectorbt reads vectorbt's readable trade records (pf.trades.records_readable.to_csv()).
+// Open trades are skipped. Both the "Avg Entry Price" and the older "Entry Price" columns are
+// accepted.
+func ImportVectorbt(r io.Reader, bars []OHLCBar) ([]Trade, error) {
+	rows, err := readTradeCSV(r, "Size", "Entry Timestamp", "Exit Timestamp", "PnL", "Return", "Direction")
+	if err != nil {
+		return nil, fmt.Errorf("vectorbt: %w", err)
+	}
+	trades := make([]Trade, 0, len(rows))
+	for i, row := range rows {
+		if strings.EqualFold(row.get("Status"), "open") {
+			continue
+		}
+		fail := func(err error) ([]Trade, error) { return nil, fmt.Errorf("vectorbt row %d: %w", i+1, err) }
+		t := importedTrade{symbol: row.get("Column"), reason: ReasonExit}
+		switch strings.ToLower(row.get("Direction")) {
+		case "long":
+		case "short":
+			t.short = true
+		default:
+			return fail(fmt.Errorf("unknown direction %q", row.get("Direction")))
+		}
+		for _, f := range []struct {
+			cols []string
+			dst  *float64
+		}{
+			{[]string{"Size"}, &t.qty},
+			{[]string{"Avg Entry Price", "Entry Price"}, &t.entryPrice},
+			{[]string{"Avg Exit Price", "Exit Price"}, &t.exitPrice},
+			{[]string{"Entry Fees"}, &t.entryFee},
+			{[]string{"Exit Fees"}, &t.exitFee},
+			{[]string{"PnL"}, &t.pnl},
+			{[]string{"Return"}, &t.ret},
+		} {
+			if *f.dst, err = row.float(f.cols...); err != nil {
+				return fail(err)
+			}
+		}
+		opened, ok1 := parseCSVTime(row.get("Entry Timestamp"), EpochAuto)
+		closed, ok2 := parseCSVTime(row.get("Exit Timestamp"), EpochAuto)
+		if !ok1 || !ok2 {
+			return fail(fmt.Errorf("invalid timestamps %q, %q", row.get("Entry Timestamp"), row.get("Exit Timestamp")))
+		}
+		t.entryTick, t.exitTick = tickAt(bars, opened), tickAt(bars, closed)
+		trades = append(trades, t.trade(i))
+	}
+	return trades, nil
+}
+
+// importedTrade is the engine-neutral form of a round trip read from a log.
+type importedTrade struct {
+	symbol              string
+	short               bool
+	qty                 float64
+	entryPrice          float64
+	exitPrice           float64
+	entryFee, exitFee   float64
+	pnl, ret            float64
+	reason              string
+	entryTick, exitTick int64
+}
+
+// trade builds the Trade with its entry and exit orders; order IDs are 2i+1 and 2i+2.
+func (t importedTrade) trade(i int) Trade {
+	side, exitSide, entryReason, position := SideBuy, SideSell, ReasonEntryLong, t.qty
+	if t.short {
+		side, exitSide, entryReason, position = SideSell, SideBuy, ReasonEntryShort, -t.qty
+	}
+	if t.reason == "" {
+		t.reason = ReasonExit
+	}
+	entry := Order{
+		ID:            int64(2*i + 1),
+		Symbol:        t.symbol,
+		Side:          side,
+		Qty:           t.qty,
+		MidPrice:      t.entryPrice,
+		Price:         t.entryPrice,
+		Fee:           t.entryFee,
+		Reason:        entryReason,
+		PositionAfter: position,
+		EntryPrice:    t.entryPrice,
+		Tick:          t.entryTick,
+		PlacedTick:    t.entryTick,
+	}
+	exit := Order{
+		ID:         int64(2*i + 2),
+		Symbol:     t.symbol,
+		Side:       exitSide,
+		Qty:        t.qty,
+		MidPrice:   t.exitPrice,
+		Price:      t.exitPrice,
+		Fee:        t.exitFee,
+		Reason:     t.reason,
+		EntryPrice: t.entryPrice,
+		Tick:       t.exitTick,
+		PlacedTick: t.exitTick,
+	}
+	return Trade{
+		Side:         side,
+		Qty:          t.qty,
+		EntryPrice:   t.entryPrice,
+		ExitPrice:    t.exitPrice,
+		EntryTick:    t.entryTick,
+		ExitTick:     t.exitTick,
+		Fees:         t.entryFee + t.exitFee,
+		PnL:          t.pnl,
+		Return:       t.ret,
+		ExitReason:   t.reason,
+		ExitCategory: exit.Category(),
+		Entry:        entry,
+		Exit:         exit,
+	}
+}
+
+// tickAt is the tick of the last bar at or before ts, 0 before the first bar or without bars.
+func tickAt(bars []OHLCBar, ts time.Time) int64 {
+	return int64(sort.Search(len(bars), func(i int) bool { return bars[i].Time.After(ts) }))
+}
+
+// tradeRow is one CSV row keyed by header name.
+type tradeRow map[string]string
+
+func (r tradeRow) get(col string) string {
+	return strings.TrimSpace(r[col])
+}
+
+// float parses the first of cols present in the row.
+func (r tradeRow) float(cols ...string) (float64, error) {
+	for _, col := range cols {
+		raw, ok := r[col]
+		if !ok {
+			continue
+		}
+		v, ok := parseCSVFloat(raw)
+		if !ok {
+			return 0, fmt.Errorf("invalid %s %q", col, raw)
+		}
+		return v, nil
+	}
+	return 0, fmt.Errorf("missing column %s", cols[0])
+}
+
+// readTradeCSV reads a headed CSV, requiring the given columns. A leading unnamed index column,
+// as pandas writes, is kept under "".
+func readTradeCSV(r io.Reader, required ...string) ([]tradeRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	for _, col := range required {
+		if !slices.Contains(header, col) {
+			return nil, fmt.Errorf("missing column %s", col)
+		}
+	}
+	var rows []tradeRow
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(tradeRow, len(header))
+		for i, h := range header {
+			if i < len(rec) {
+				row[h] = rec[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
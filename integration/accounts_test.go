@@ -0,0 +1,77 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func syntheticBars(n int, start float64, step float64) []emul.OHLCBar {
+	bars := make([]emul.OHLCBar, n)
+	price := start
+	for i := range bars {
+		open := price
+		price += step
+		high := open
+		low := price
+		if price > open {
+			high, low = price, open
+		}
+		bars[i] = emul.OHLCBar{
+			Open:    open,
+			High:    high * 1.01,
+			Low:     low * 0.99,
+			Close:   price,
+			Average: (open + price) / 2,
+		}
+	}
+	return bars
+}
+
+func TestAccountsAreIsolated(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0.001, 0, 0, syntheticBars(5, 100, 1))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	if err := emu.AddAccount("bot-a", 500); err != nil {
+		t.Fatalf("add bot-a: %v", err)
+	}
+	if err := emu.AddAccount("bot-b", 2000); err != nil {
+		t.Fatalf("add bot-b: %v", err)
+	}
+	if err := emu.AddAccount("bot-a", 1); err != emul.ErrAccountExists {
+		t.Fatalf("expected ErrAccountExists, got %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	err = emu.WithAccount("bot-a", func(ex *emul.Exchange) error {
+		_, err := ex.OpenLong(1)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("open long on bot-a: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	_ = emu.WithAccount("bot-a", func(ex *emul.Exchange) error {
+		if ex.Balance().Position <= 0 {
+			t.Fatalf("bot-a expected long position")
+		}
+		return nil
+	})
+	_ = emu.WithAccount("bot-b", func(ex *emul.Exchange) error {
+		bal := ex.Balance()
+		if bal.Position != 0 || bal.USD != 2000 {
+			t.Fatalf("bot-b expected untouched balance, got %+v", bal)
+		}
+		return nil
+	})
+	if emu.Exchange().Balance().Position != 0 {
+		t.Fatalf("primary exchange must not see account positions")
+	}
+	if err := emu.WithAccount("missing", func(*emul.Exchange) error { return nil }); err != emul.ErrUnknownAccount {
+		t.Fatalf("expected ErrUnknownAccount, got %v", err)
+	}
+}
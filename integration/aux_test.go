@@ -0,0 +1,60 @@
+package emul_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestAuxSeriesAlignedAsOf(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "signals.csv")
+	writeCSV(t, path, "time,funding,sentiment\n"+
+		"2024-01-01T00:30:00Z,0.01,\n"+
+		"2024-01-01T02:00:00Z,0.02,0.5\n")
+	series, err := emul.LoadAuxCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series["funding"]) != 2 || len(series["sentiment"]) != 1 {
+		t.Fatalf("unexpected series: %v", series)
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, points := range series {
+		if err := emu.AddAux(name, points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := emu.Aux("funding"); ok {
+		t.Fatalf("aux available before the first bar")
+	}
+	want := []float64{0, 0.01, 0.02, 0.02}
+	for i, w := range want {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		v, ok := emu.Aux("funding")
+		if ok != (i > 0) || v != w {
+			t.Fatalf("bar %d: funding=%v ok=%v, want %v", i, v, ok, w)
+		}
+	}
+	if v, ok := emu.Aux("sentiment"); !ok || v != 0.5 {
+		t.Fatalf("sentiment=%v ok=%v", v, ok)
+	}
+}
+
+func TestAuxCSVWithoutHeaderUsesFileName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashrate.csv")
+	writeCSV(t, path, "2024-01-01T00:00:00Z,5\n2024-01-01T01:00:00Z,6\n")
+	series, err := emul.LoadAuxCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series["hashrate"]) != 2 {
+		t.Fatalf("unexpected series: %v", series)
+	}
+}
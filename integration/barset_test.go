@@ -0,0 +1,73 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestBarSetReferenceCounting(t *testing.T) {
+	set := emul.NewBarSet(flatBars(100, 101, 102))
+	if set.Refs() != 1 {
+		t.Fatalf("a new set holds the creator's reference, got %d", set.Refs())
+	}
+	a, err := emul.NewEmulatorFromBarSet(1000, 0, 0, 0, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := emul.NewEmulatorFromBarSet(1000, 0, 0, 0, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Refs() != 3 || a.BarSet() != set {
+		t.Fatalf("refs %d after two emulators", set.Refs())
+	}
+	set.Release()
+	a.Close()
+	if set.Refs() != 1 || set.Len() != 3 {
+		t.Fatalf("bars must stay while an emulator holds them: refs %d, len %d", set.Refs(), set.Len())
+	}
+	if bar, _, err := b.Next(); err != nil || bar.Close != 100 {
+		t.Fatalf("last holder replays the set: %+v, %v", bar, err)
+	}
+	if values, series := set.Series(); len(values) != 3 || len(series.Close) != 3 {
+		t.Fatalf("series %d/%d", len(values), len(series.Close))
+	}
+	b.Close()
+	if set.Refs() != 0 || set.Len() != 0 {
+		t.Fatalf("the last release must free the bars: refs %d, len %d", set.Refs(), set.Len())
+	}
+	if values, series := set.Series(); values != nil || series.Close != nil {
+		t.Fatal("a released set has no series")
+	}
+}
+
+func TestBarSetMisuseAfterRelease(t *testing.T) {
+	set := emul.NewBarSet(flatBars(100, 101))
+	emu, err := emul.NewEmulatorFromBarSet(1000, 0, 0, 0, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emu.Close()
+	emu.Close()
+	if set.Refs() != 1 {
+		t.Fatalf("closing an emulator twice must release once, refs %d", set.Refs())
+	}
+	set.Release()
+	set.Release()
+	if set.Refs() != 0 {
+		t.Fatalf("a double release must not go negative, refs %d", set.Refs())
+	}
+	if bars := set.Retain(); bars != nil || set.Refs() != 0 {
+		t.Fatalf("retain after release returned %d bars, refs %d", len(bars), set.Refs())
+	}
+	if _, err := emul.NewEmulatorFromBarSet(1000, 0, 0, 0, set); err == nil {
+		t.Fatal("an emulator cannot be built from a released set")
+	}
+	if _, err := emul.NewEmulatorFromConfig(emul.EmulatorConfig{StartUSD: 1000, BarSet: set}); err == nil {
+		t.Fatal("a config cannot use a released set")
+	}
+	if set.Refs() != 0 {
+		t.Fatalf("failed builds must not change the count, refs %d", set.Refs())
+	}
+}
@@ -0,0 +1,173 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func flatBars(closes ...float64) []emul.OHLCBar {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bars := make([]emul.OHLCBar, len(closes))
+	for i, c := range closes {
+		bars[i] = emul.OHLCBar{Time: start.Add(time.Duration(i) * time.Hour), Open: c, High: c, Low: c, Close: c, Average: c}
+	}
+	return bars
+}
+
+func TestSpotPerpBasisTrade(t *testing.T) {
+	spot := flatBars(100, 100, 110, 110)
+	perp := flatBars(101, 101, 110.5, 110.5)
+	emu, err := emul.NewEmulator(2000, 0, 0, 0, spot)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: perp, FundingRate: 0.001, FundingEvery: 1}); err != nil {
+		t.Fatalf("enable perp: %v", err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(0.5); err != nil {
+		t.Fatalf("spot long: %v", err)
+	}
+	if _, err := ex.OpenPerp(emul.SideSell, 1); err != nil {
+		t.Fatalf("perp short: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos, _ := ex.PerpPosition()
+	if pos.Qty >= 0 || math.Abs(pos.Funding-0.001*(101+110.5+110.5)*(-pos.Qty)) > 1e-9 {
+		t.Fatalf("unexpected perp position %+v", pos)
+	}
+	rep, err := ex.BasisReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spot qty 10, perp qty 1000/101: hedged 9.90 units, basis narrowed from 1 to 0.5.
+	if math.Abs(rep.BasisPnL-(1000.0/101)*0.5) > 1e-9 || rep.Funding <= 0 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if _, err := ex.ClosePerp(); err != nil {
+		t.Fatalf("close perp: %v", err)
+	}
+	if len(ex.PerpOrders()) != 2 || len(ex.Orders()) != 1 {
+		t.Fatalf("perp fills must stay out of the spot history")
+	}
+	bal := ex.Balance()
+	if want := 2000 + 10*10 - (1000.0/101)*9.5 + rep.Funding; math.Abs(bal.Equity-want) > 1e-9 {
+		t.Fatalf("equity %v, want %v", bal.Equity, want)
+	}
+
+	a, b, err := emul.AlignBars(spot, perp[1:])
+	if err != nil || len(a) != 3 || !a[0].Time.Equal(b[0].Time) {
+		t.Fatalf("align: %v %d", err, len(a))
+	}
+}
+
+func TestPerpAutoDeleveraging(t *testing.T) {
+	spot := flatBars(100, 100, 100, 50)
+	perp := flatBars(100, 100, 97, 50)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, spot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adl := &emul.ADLConfig{MoveThreshold: 0.1, Probability: 1, Fraction: 0.5, Seed: 1}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: perp, ADL: adl}); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenPerp(emul.SideSell, 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A 3% move is below the threshold: the position is ranked but untouched.
+	pos, _ := ex.PerpPosition()
+	if pos.Qty != -10 || pos.ADLLevel != 1 {
+		t.Fatalf("unexpected position before the crash %+v", pos)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// The 48% crash puts the short at level 5: half of it is closed at the mark, without fee.
+	pos, _ = ex.PerpPosition()
+	orders := ex.PerpOrders()
+	last := orders[len(orders)-1]
+	if last.Reason != emul.ReasonPerpADL || last.Side != emul.SideBuy || last.Qty != 5 || last.Fee != 0 || pos.Qty != -5 || pos.Margin != 500 {
+		t.Fatalf("unexpected deleveraging: %+v, position %+v", last, pos)
+	}
+	if bal := ex.Balance(); math.Abs(bal.USD-(500+5*50)) > 1e-9 || math.Abs(bal.Equity-1500) > 1e-9 {
+		t.Fatalf("unexpected balance %+v", bal)
+	}
+
+	fresh, err := emul.NewEmulator(1000, 0, 0, 0, spot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fresh.EnablePerp(emul.PerpConfig{Bars: perp, ADL: &emul.ADLConfig{Fraction: 2}}); err == nil {
+		t.Fatal("expected an error for an invalid ADL fraction")
+	}
+}
+
+func TestMarginWalletIsolatesPerpLosses(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: flatBars(100, 100, 150, 350)}); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.Transfer(emul.WalletSpot, emul.WalletMargin, 100); !errors.Is(err, emul.ErrMarginWalletDisabled) {
+		t.Fatalf("transfer before enabling: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.EnableMarginWallet(300); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.Transfer(emul.WalletMargin, emul.WalletSpot, 1000); !errors.Is(err, emul.ErrInsufficientFunds) {
+		t.Fatalf("overdrawn transfer: %v", err)
+	}
+	if _, err := ex.OpenPerp(emul.SideSell, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Short 1.5 from 100 marked at 150: the margin wallet holds 150 free + 150 posted - 75.
+	spot, _ := ex.WalletBalance(emul.WalletSpot)
+	margin, _ := ex.WalletBalance(emul.WalletMargin)
+	if spot.Equity != 700 || margin.Cash != 150 || margin.Equity != 225 || ex.Balance().Equity != 925 {
+		t.Fatalf("wallets spot %+v margin %+v", spot, margin)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// At 350 the loss exceeds the wallet: the perp is liquidated and the spot wallet survives.
+	orders := ex.PerpOrders()
+	if last := orders[len(orders)-1]; last.Reason != emul.ReasonLiquidate || last.Side != emul.SideBuy {
+		t.Fatalf("last perp order %+v", last)
+	}
+	margin, _ = ex.WalletBalance(emul.WalletMargin)
+	if margin.Equity != 0 || ex.Balance().Equity != 700 {
+		t.Fatalf("after liquidation margin %+v equity %v", margin, ex.Balance().Equity)
+	}
+}
@@ -0,0 +1,100 @@
+package emul_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+// holdFor buys on bar entry and sells hold bars later; errAt, when positive, fails that bar.
+func holdFor(entry, hold, errAt int) emul.Strategy {
+	bar := 0
+	return emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		bar++
+		switch {
+		case bar == errAt:
+			return fmt.Errorf("strategy failed on bar %d", bar)
+		case bar == entry:
+			_, err := ex.OpenLong(1)
+			return err
+		case bar == entry+hold && ex.Balance().Position > 0:
+			_, err := ex.CloseDeal("")
+			return err
+		}
+		return nil
+	})
+}
+
+func TestBatchRunnerMatchesSequentialRuns(t *testing.T) {
+	bars := syntheticBars(60, 100, 0.5)
+	const n = 12
+	jobs := make([]emul.BatchJob, n)
+	for i := range jobs {
+		errAt := 0
+		if i == 5 {
+			errAt = 7
+		}
+		jobs[i] = emul.BatchJob{
+			Name:     fmt.Sprintf("job-%d", i),
+			Config:   emul.EmulatorConfig{StartUSD: 1000, Fee: 0.001},
+			Strategy: holdFor(1+i, 5+i%4, errAt),
+		}
+	}
+	results := emul.NewBatchRunner(bars, 4).RunAll(context.Background(), jobs)
+	if len(results) != n {
+		t.Fatalf("got %d results", len(results))
+	}
+	for i, res := range results {
+		if res.Index != i || res.Name != jobs[i].Name {
+			t.Fatalf("result %d is %d/%q", i, res.Index, res.Name)
+		}
+		if i == 5 {
+			if res.Err == nil || res.Err.Error() != "strategy failed on bar 7" {
+				t.Fatalf("failing job returned %v", res.Err)
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Fatalf("%s: %v", res.Name, res.Err)
+		}
+		emu, err := emul.NewEmulator(1000, 0.001, 0, 0, bars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curve, err := emul.Run(emu, holdFor(1+i, 5+i%4, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Equity, curve) || !reflect.DeepEqual(res.Orders, emu.Exchange().Orders()) || res.Balance != emu.Exchange().Balance() {
+			t.Fatalf("%s differs from a sequential run", res.Name)
+		}
+	}
+
+	missing := emul.NewBatchRunner(bars, 2).RunAll(context.Background(), []emul.BatchJob{{Name: "nil"}})
+	if missing[0].Err == nil {
+		t.Fatal("a job without a strategy must fail")
+	}
+}
+
+func TestBatchRunnerStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobs := make([]emul.BatchJob, 50)
+	for i := range jobs {
+		jobs[i] = emul.BatchJob{Config: emul.EmulatorConfig{StartUSD: 1000}, Strategy: holdFor(1, 1, 0)}
+	}
+	got := 0
+	for res := range emul.NewBatchRunner(flatBars(100, 100), 2).Run(ctx, jobs) {
+		if res.Err != nil && !errors.Is(res.Err, context.Canceled) {
+			t.Fatal(res.Err)
+		}
+		got++
+	}
+	if got == len(jobs) {
+		t.Fatal("a canceled batch must skip jobs")
+	}
+}
@@ -0,0 +1,82 @@
+package emul_test
+
+import (
+	"errors"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestBracketStopsAndTargets(t *testing.T) {
+	bars := flatBars(100, 100, 100, 100, 92, 91)
+	for i := range bars {
+		bars[i].High, bars[i].Low = bars[i].Close+2, bars[i].Close-2
+	}
+	bars[3].High, bars[3].Low = 105, 95
+	bars[4].Open, bars[4].High, bars[4].Low = 100, 101, 90
+	bars[5].Open = 89
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	for i := 0; i < 3; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ex.SetBracket(emul.Bracket{Stop: 95}); !errors.Is(err, emul.ErrNoPosition) {
+		t.Fatalf("bracket while flat: %v", err)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	// ATR over the last 2 bars is 4: stop 1.5 ATR below and target 3 ATR above the entry.
+	b, ok := emul.ATRBracket(bars[:3], 2, emul.SideBuy, 100, 1.5, 3)
+	if !ok || b.Stop != 94 || b.Target != 112 {
+		t.Fatalf("unexpected ATR bracket %+v", b)
+	}
+	if err := ex.SetBracket(emul.Bracket{Stop: 101}); !errors.Is(err, emul.ErrInvalidBracket) {
+		t.Fatalf("stop above a long entry: %v", err)
+	}
+	if err := ex.SetBracket(b); err != nil {
+		t.Fatal(err)
+	}
+	// The 95 low stays above the stop; the next bar trades through it.
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ex.Bracket(); !ok {
+		t.Fatal("bracket must survive a bar that does not reach it")
+	}
+	_, fills, err := emu.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 1 || fills[0].Reason != emul.ReasonStopLoss || fills[0].StopKind != emul.StopKindStop || fills[0].MidPrice != 94 {
+		t.Fatalf("unexpected stop fill %+v", fills)
+	}
+	if _, ok := ex.Bracket(); ok {
+		t.Fatal("closing the position must remove the bracket")
+	}
+
+	// A short whose target is gapped through fills at the open.
+	if _, err := ex.OpenShort(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetBracket(emul.Bracket{Stop: 99, Target: 90}); err != nil {
+		t.Fatal(err)
+	}
+	_, fills, err = emu.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 1 || fills[0].Reason != emul.ReasonExit || fills[0].StopKind != emul.StopKindTarget || fills[0].MidPrice != 89 {
+		t.Fatalf("unexpected target fill %+v", fills)
+	}
+
+	swing, ok := emul.SwingBracket(bars[:4], 3, emul.SideBuy, 100, 2)
+	if !ok || swing.Stop != 95 || swing.Target != 110 {
+		t.Fatalf("unexpected swing bracket %+v", swing)
+	}
+}
@@ -0,0 +1,122 @@
+package emul_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestBarCacheReusesAndInvalidates(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "btc", "d", "2024.csv")
+	writeCSV(t, path, "1704067200,1,2,0.5,1.5,10\n1704153600,1,2,0.5,1.5,10\n")
+
+	cache := emul.NewBarCache(2)
+	first, err := cache.Load(root, "btc", "d", []int{2024}, nil)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	second, err := cache.Load(root, "BTC", "d", []int{2024}, nil)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if first != second || first.Len() != 2 || first.Refs() != 3 {
+		t.Fatalf("expected cached set with 2 bars retained by the cache and both callers, refs=%d", first.Refs())
+	}
+	first.Release()
+	second.Release()
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("unexpected stats hits=%d misses=%d", hits, misses)
+	}
+
+	writeCSV(t, path, "1704067200,1,2,0.5,1.5,10\n1704153600,1,2,0.5,1.5,10\n1704240000,1,2,0.5,1.5,10\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	third, err := cache.Load(root, "btc", "d", []int{2024}, nil)
+	if err != nil {
+		t.Fatalf("load after change: %v", err)
+	}
+	if third == first || third.Len() != 3 {
+		t.Fatalf("expected re-parse after file change, got %d bars", third.Len())
+	}
+	third.Release()
+	if first.Refs() != 0 {
+		t.Fatalf("stale entry should be released, refs=%d", first.Refs())
+	}
+
+	cache.Invalidate(root)
+	if cache.Len() != 0 {
+		t.Fatalf("expected empty cache after invalidate")
+	}
+}
+
+func TestBarCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	root := t.TempDir()
+	for _, coin := range []string{"btc", "eth", "sol"} {
+		writeCSV(t, filepath.Join(root, coin, "d", "2024.csv"), "1704067200,1,2,0.5,1.5,10\n")
+	}
+	cache := emul.NewBarCache(2)
+	btc, _ := cache.Load(root, "btc", "d", nil, nil)
+	emu, err := emul.NewEmulatorFromBarSet(1000, 0, 0, 0, btc)
+	btc.Release()
+	if err != nil {
+		t.Fatalf("emulator: %v", err)
+	}
+	defer emu.Close()
+	for _, coin := range []string{"eth", "sol"} {
+		set, err := cache.Load(root, coin, "d", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.Release()
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+	if btc.Refs() != 1 || btc.Len() != 1 {
+		t.Fatalf("evicted set should stay alive for its emulator, refs=%d", btc.Refs())
+	}
+}
+
+func TestBarCacheLoadSurvivesConcurrentEviction(t *testing.T) {
+	root := t.TempDir()
+	coins := []string{"btc", "eth", "sol"}
+	for _, coin := range coins {
+		writeCSV(t, filepath.Join(root, coin, "d", "2024.csv"), "1704067200,1,2,0.5,1.5,10\n")
+	}
+	// One entry for three coins: every load of another coin evicts the previous set.
+	cache := emul.NewBarCache(1)
+	var wg sync.WaitGroup
+	errs := make(chan error, len(coins)*50)
+	for _, coin := range coins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				set, err := cache.Load(root, coin, "d", nil, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				emu, err := emul.NewEmulatorFromBarSet(1000, 0, 0, 0, set)
+				set.Release()
+				if err != nil {
+					errs <- err
+					return
+				}
+				emu.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("load under eviction: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func dailyCurve(start time.Time, equities ...float64) []emul.EquityPoint {
+	curve := make([]emul.EquityPoint, len(equities))
+	for i, e := range equities {
+		curve[i] = emul.EquityPoint{Tick: int64(i + 1), Time: start.AddDate(0, 0, i), Equity: e}
+	}
+	return curve
+}
+
+func TestCalendarBreakdownChainsMonths(t *testing.T) {
+	start := time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC)
+	curve := dailyCurve(start, 100, 110, 121, 90.75, 99.825)
+	cal, err := emul.CalendarBreakdown(curve)
+	if err != nil {
+		t.Fatalf("breakdown: %v", err)
+	}
+	if len(cal.Monthly) != 2 || len(cal.Yearly) != 2 {
+		t.Fatalf("expected 2 months and 2 years, got %d/%d", len(cal.Monthly), len(cal.Yearly))
+	}
+	if math.Abs(cal.Monthly[0].Return-0.10) > 1e-9 {
+		t.Fatalf("december return: %v", cal.Monthly[0].Return)
+	}
+	if math.Abs(cal.Monthly[1].Return-(99.825/110-1)) > 1e-9 {
+		t.Fatalf("january must start from december's close: %v", cal.Monthly[1].Return)
+	}
+	total := (1 + cal.Yearly[0].Return) * (1 + cal.Yearly[1].Return)
+	if math.Abs(total-0.99825) > 1e-9 {
+		t.Fatalf("yearly returns must chain to the total, got %v", total)
+	}
+	if cal.BestMonth.Month != time.December || cal.WorstMonth.Month != time.January {
+		t.Fatalf("unexpected best/worst: %+v %+v", cal.BestMonth, cal.WorstMonth)
+	}
+}
+
+func TestDrawdownEpisodes(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	curve := dailyCurve(start, 100, 120, 90, 60, 100, 125, 110, 115)
+	eps := emul.DrawdownEpisodes(curve)
+	if len(eps) != 2 {
+		t.Fatalf("expected 2 episodes, got %d", len(eps))
+	}
+	first := eps[0]
+	if !first.Recovered || first.Depth != 0.5 || !first.Trough.Equal(start.AddDate(0, 0, 3)) || !first.Recovery.Equal(start.AddDate(0, 0, 5)) {
+		t.Fatalf("unexpected first episode: %+v", first)
+	}
+	if eps[1].Recovered || eps[1].Low != 110 {
+		t.Fatalf("unexpected open episode: %+v", eps[1])
+	}
+	if dd := emul.MaxDrawdown(curve); dd != 0.5 {
+		t.Fatalf("max drawdown: %v", dd)
+	}
+}
@@ -0,0 +1,45 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestCapacitySweepDecaysWithSize(t *testing.T) {
+	bars := flatBars(100, 100, 110, 120)
+	for i := range bars {
+		bars[i].Volume = 50
+	}
+	newStrategy := func() emul.Strategy {
+		return emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+			var err error
+			switch {
+			case len(ex.Orders()) == 0:
+				_, err = ex.OpenLong(1)
+			case bar.Close >= 120 && ex.Balance().Position > 0:
+				_, err = ex.CloseDeal(emul.ReasonExit)
+			}
+			return err
+		})
+	}
+	points, err := emul.CapacitySweep(bars, newStrategy, emul.CapacityConfig{
+		StartUSD:         1000,
+		Impact:           emul.ImpactModel{Coefficient: 0.05},
+		MaxParticipation: 0.2,
+	})
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if len(points) != 3 || points[0].Multiple != 1 || points[2].StartUSD != 100000 {
+		t.Fatalf("unexpected points %+v", points)
+	}
+	if points[0].Decay != 1 || points[1].Return >= points[0].Return || points[2].Decay >= points[1].Decay {
+		t.Fatalf("returns should decay with size: %+v", points)
+	}
+	for _, p := range points {
+		if p.Trades != 1 {
+			t.Fatalf("expected one trade per run, got %+v", p)
+		}
+	}
+}
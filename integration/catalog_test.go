@@ -0,0 +1,55 @@
+package emul_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func writeCSV(t *testing.T, path string, rows string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverDataRoot(t *testing.T) {
+	root := t.TempDir()
+	writeCSV(t, filepath.Join(root, "btc", "d", "btc2023.csv"), "1672531200,1,2,0.5,1.5,10\n1703980800,1,2,0.5,1.5,10\n")
+	writeCSV(t, filepath.Join(root, "btc", "d", "btc2024.csv"), "1704067200,1,2,0.5,1.5,10\n1704153600,1,2,0.5,1.5,10\n")
+	writeCSV(t, filepath.Join(root, "eth", "h", "2024.csv"), "1704067200000,1,2,0.5,1.5,10\n")
+	if err := os.MkdirAll(filepath.Join(root, "eth", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cat, err := emul.DiscoverDataRoot(root)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(cat.Coins) != 2 || cat.Coins[0].Coin != "btc" {
+		t.Fatalf("unexpected coins: %+v", cat.Coins)
+	}
+	btc, _ := cat.Coin("BTC")
+	daily, ok := btc.Interval("d")
+	if !ok || len(daily.Years) != 2 || daily.Years[0] != 2023 {
+		t.Fatalf("unexpected btc daily catalog: %+v", daily)
+	}
+	if !daily.First.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) || !daily.Last.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected coverage %s..%s", daily.First, daily.Last)
+	}
+	if err := cat.Validate("btc", "d", []int{2023, 2024}); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := cat.Validate("btc", "d", []int{2022}); err == nil {
+		t.Fatalf("expected missing year to be reported")
+	}
+	if err := cat.Validate("eth", "d", nil); err == nil {
+		t.Fatalf("expected missing interval to be reported")
+	}
+}
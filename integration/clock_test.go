@@ -0,0 +1,117 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestFundingIntervalFollowsClock(t *testing.T) {
+	closes := make([]float64, 20)
+	for i := range closes {
+		closes[i] = 100
+	}
+	bars := flatBars(closes...)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: flatBars(closes...), FundingRate: 0.001, FundingInterval: 8 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if !ex.Now().Equal(bars[0].Time) {
+		t.Fatalf("the default clock is the bar time, got %v", ex.Now())
+	}
+	if _, err := ex.OpenPerp(emul.SideSell, 1); err != nil {
+		t.Fatal(err)
+	}
+	for range emu.All() {
+	}
+	// Bars run from 00:00 to 19:00: funding settles at 08:00 and 16:00.
+	pos, _ := ex.PerpPosition()
+	if want := 2 * 0.001 * 100 * -pos.Qty; math.Abs(pos.Funding-want) > 1e-9 {
+		t.Fatalf("funding %v, want %v", pos.Funding, want)
+	}
+
+	clock := emul.NewSimClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	emu.SetClock(clock)
+	if err := emu.AddAccount("bob", 100); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	err = emu.WithAccount("bob", func(acct *emul.Exchange) error {
+		if !acct.Now().Equal(clock.Now()) || !ex.Now().Equal(clock.Now()) {
+			t.Fatalf("exchanges must follow the clock: %v, %v", acct.Now(), ex.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRateLimiterFollowsClock(t *testing.T) {
+	clock := emul.NewSimClock(time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC))
+	rl := emul.NewRateLimiter(1, time.Minute)
+	rl.SetClock(clock)
+	if _, err := rl.Allow("k", 1); err != nil {
+		t.Fatal(err)
+	}
+	var rle *emul.RateLimitError
+	if _, err := rl.Allow("k", 1); !errors.As(err, &rle) || rle.RetryAfter != 30*time.Second {
+		t.Fatalf("expected a 30s back-off, got %v", err)
+	}
+	clock.Advance(30 * time.Second)
+	if _, err := rl.Allow("k", 1); err != nil {
+		t.Fatalf("a new window must start on the simulated clock: %v", err)
+	}
+}
+
+func TestSymbolSpecSetsLeverageCapAndFunding(t *testing.T) {
+	if _, err := emul.NewSymbolRegistry(emul.SymbolSpec{Symbol: "btc", MaxLeverage: 0.5}); err == nil {
+		t.Fatalf("max leverage below 1 must be rejected")
+	}
+	reg, err := emul.NewSymbolRegistry(emul.SymbolSpec{Symbol: "btc", MaxLeverage: 5, FundingRate: 0.001, FundingInterval: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closes := make([]float64, 20)
+	for i := range closes {
+		closes[i] = 100
+	}
+	emu, err := emul.NewEmulatorFromConfig(emul.EmulatorConfig{StartUSD: 1000, Bars: flatBars(closes...), Symbol: "btc", Symbols: reg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetLiquidationConfig(emul.LiquidationConfig{Leverage: 10}); err == nil {
+		t.Fatalf("leverage above the symbol's cap must be rejected")
+	}
+	if err := ex.SetLiquidationConfig(emul.LiquidationConfig{Leverage: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without funding terms of its own the perp leg settles on the symbol's 8h schedule.
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: flatBars(closes...)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenPerp(emul.SideSell, 1); err != nil {
+		t.Fatal(err)
+	}
+	for range emu.All() {
+	}
+	pos, _ := ex.PerpPosition()
+	if want := 2 * 0.001 * 100 * -pos.Qty; math.Abs(pos.Funding-want) > 1e-9 {
+		t.Fatalf("funding %v, want %v", pos.Funding, want)
+	}
+}
@@ -0,0 +1,50 @@
+package emul_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestRollingExtremesMatchNaive(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	src := make([]float64, 500)
+	for i := range src {
+		src[i] = rng.Float64() * 100
+	}
+	const window = 17
+	maxes := emul.RollingMax(nil, src, window)
+	mins := emul.RollingMin(nil, src, window)
+	for i := range src {
+		if i+1 < window {
+			if !math.IsNaN(maxes[i]) || !math.IsNaN(mins[i]) {
+				t.Fatalf("expected NaN before first full window at %d", i)
+			}
+			continue
+		}
+		hi, lo := math.Inf(-1), math.Inf(1)
+		for _, v := range src[i+1-window : i+1] {
+			hi = math.Max(hi, v)
+			lo = math.Min(lo, v)
+		}
+		if maxes[i] != hi || mins[i] != lo {
+			t.Fatalf("window ending at %d: got max=%v min=%v, want %v %v", i, maxes[i], mins[i], hi, lo)
+		}
+	}
+}
+
+func TestSeriesFromBarsRoundTrip(t *testing.T) {
+	bars := syntheticBars(10, 100, 1)
+	values, ohlc := emul.SeriesFromBars(bars)
+	back, err := emul.BarsFromSeries(values, ohlc)
+	if err != nil {
+		t.Fatalf("bars from series: %v", err)
+	}
+	for i := range bars {
+		if back[i] != bars[i] {
+			t.Fatalf("bar %d differs after round trip", i)
+		}
+	}
+}
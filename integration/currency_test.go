@@ -0,0 +1,55 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestConvertResultsToEUR(t *testing.T) {
+	bars := flatBars(100, 100, 120, 120)
+	fx, err := emul.ConversionFromBars("eur", flatBars(1.0, 1.25, 1.25, 1.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fx.Currency != "EUR" || fx.Rate(bars[0].Time.Add(-1)) != 1.0 || fx.Rate(bars[3].Time.Add(1)) != 1.5 {
+		t.Fatalf("unexpected lookup")
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve, err := emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		switch bar.Time {
+		case bars[1].Time:
+			_, err := ex.OpenLong(1)
+			return err
+		case bars[2].Time:
+			_, err := ex.CloseDeal(emul.ReasonExit)
+			return err
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eur, err := emul.ConvertCurve(curve, fx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eur[0].Equity != 1000 || eur[3].Equity != 800 {
+		t.Fatalf("unexpected EUR curve %+v", eur)
+	}
+	trades, err := emul.ConvertTrades(emul.PairTrades(emu.Exchange().Orders()), bars, fx)
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("convert trades: %v %d", err, len(trades))
+	}
+	// 1000 USD = 800 EUR at entry, 1200 USD = 960 EUR at exit.
+	if tr := trades[0]; tr.PnL != 160 || math.Abs(tr.Return-0.2) > 1e-12 || tr.EntryPrice != 80 {
+		t.Fatalf("unexpected trade %+v", tr)
+	}
+	if _, err := emul.ConvertTrades(emul.PairTrades(emu.Exchange().Orders()), bars[:1], fx); err == nil {
+		t.Fatalf("trades outside the bars must be rejected")
+	}
+}
@@ -0,0 +1,60 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestDCABotAveragesDownAndTakesProfit(t *testing.T) {
+	bars := []emul.OHLCBar{
+		{Open: 100, High: 100, Low: 100, Close: 100},
+		{Open: 100, High: 100, Low: 97.5, Close: 98},
+		{Open: 98, High: 100.5, Low: 98, Close: 100},
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	bot, err := emul.NewDCABot(emu.Exchange(), emul.DCAConfig{
+		BaseUSD:       100,
+		SafetyUSD:     200,
+		SafetyCount:   2,
+		StepPct:       0.02,
+		StepScale:     2,
+		VolumeScale:   2,
+		TakeProfitPct: 0.01,
+	})
+	if err != nil {
+		t.Fatalf("new bot: %v", err)
+	}
+	for {
+		if _, _, err := emu.Next(); err != nil {
+			break
+		}
+		if err := bot.Update(); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if emu.Exchange().Balance().Position != 0 && bot.SafetyOrdersFilled() == 0 {
+			if p := bot.SafetyPrice(1); math.Abs(p-94) > 1e-9 {
+				t.Fatalf("second safety order at %v, want 94", p)
+			}
+		}
+	}
+	if bot.Deals() != 1 {
+		t.Fatalf("expected one closed deal, got %d", bot.Deals())
+	}
+	trades := emul.PairTrades(emu.Exchange().Orders())
+	if len(trades) != 1 {
+		t.Fatalf("expected one trade, got %d", len(trades))
+	}
+	tr := trades[0]
+	wantAvg := 300 / (1 + 200.0/98)
+	if math.Abs(tr.EntryPrice-wantAvg) > 1e-9 || tr.ExitReason != emul.ReasonDCATakeProfit {
+		t.Fatalf("unexpected trade %+v", tr)
+	}
+	if math.Abs(tr.ExitPrice-wantAvg*1.01) > 1e-9 || tr.PnL <= 0 {
+		t.Fatalf("unexpected exit %v pnl %v", tr.ExitPrice, tr.PnL)
+	}
+}
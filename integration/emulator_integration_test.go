@@ -1,8 +1,10 @@
 package emul_test
 
 import (
-	emul "github.com/svanichkin/ExchangeEmulator"
+	"os"
 	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
 )
 
 const integrationCSVPath = "/Users/alien/Vault/Projects/Self/Trading/PriceLoader/data/enj/h/enj2026.csv"
@@ -14,7 +16,15 @@ func inRange(price float64, low float64, high float64) bool {
 	return price >= low && price <= high
 }
 
+func requireIntegrationCSV(t *testing.T) {
+	t.Helper()
+	if _, err := os.Stat(integrationCSVPath); err != nil {
+		t.Skipf("integration csv not available: %v", err)
+	}
+}
+
 func TestIntegrationNextLogsTenBars(t *testing.T) {
+	requireIntegrationCSV(t)
 	bars, err := emul.LoadBarsFromCSV(integrationCSVPath)
 	if err != nil {
 		t.Fatalf("load csv: %v", err)
@@ -44,6 +54,7 @@ func TestIntegrationNextLogsTenBars(t *testing.T) {
 }
 
 func TestIntegrationLimitAndOppositeOrder(t *testing.T) {
+	requireIntegrationCSV(t)
 	bars, err := emul.LoadBarsFromCSV(integrationCSVPath)
 	if err != nil {
 		t.Fatalf("load csv: %v", err)
@@ -60,7 +71,7 @@ func TestIntegrationLimitAndOppositeOrder(t *testing.T) {
 		t.Fatalf("new emulator: %v", err)
 	}
 
-	bars := emu.Bars()
+	bars = emu.Bars()
 	if len(bars) < 4 {
 		t.Fatalf("need at least 4 bars, got %d", len(bars))
 	}
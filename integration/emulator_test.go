@@ -0,0 +1,47 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestNextDoesNotAllocate(t *testing.T) {
+	bars := syntheticBars(1000, 100, 0.1)
+	emu, err := emul.NewEmulator(1000, 0.001, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	allocs := testing.AllocsPerRun(500, func() {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatalf("next: %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected allocation-free Next, got %.1f allocs per call", allocs)
+	}
+}
+
+func TestNextReturnsBarExecutions(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0.001, 0, 0, syntheticBars(4, 100, 1))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if _, err := emu.Exchange().LongLimit(0, 1); err != nil {
+		t.Fatalf("long limit: %v", err)
+	}
+	_, executed, err := emu.Next()
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if len(executed) != 1 || executed[0].Reason != emul.ReasonEntryLong {
+		t.Fatalf("expected one entry fill, got %+v", executed)
+	}
+	_, executed, _ = emu.Next()
+	if len(executed) != 0 {
+		t.Fatalf("expected no fills, got %d", len(executed))
+	}
+}
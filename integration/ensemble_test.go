@@ -0,0 +1,110 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func buyAndHold() emul.Strategy {
+	return emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, executed []emul.Order) error {
+		if ex.Balance().Position == 0 && len(ex.Orders()) == 0 {
+			_, err := ex.OpenLong(1)
+			return err
+		}
+		return nil
+	})
+}
+
+func idle() emul.Strategy {
+	return emul.StrategyFunc(func(*emul.Exchange, emul.OHLCBar, []emul.Order) error { return nil })
+}
+
+func TestRunEnsembleSplitsCapital(t *testing.T) {
+	res, err := emul.RunEnsemble(emul.EnsembleConfig{
+		StartUSD:   1000,
+		Bars:       syntheticBars(20, 100, 1),
+		Allocation: emul.AllocationFixed,
+		Sleeves: []emul.Sleeve{
+			{Name: "trend", Strategy: buyAndHold(), Weight: 3},
+			{Name: "cash", Strategy: idle(), Weight: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("run ensemble: %v", err)
+	}
+	if res.Sleeves[0].StartUSD != 750 || res.Sleeves[1].StartUSD != 250 {
+		t.Fatalf("unexpected allocation: %+v %+v", res.Sleeves[0].StartUSD, res.Sleeves[1].StartUSD)
+	}
+	if res.Sleeves[1].PnL != 0 || res.Sleeves[0].PnL <= 0 {
+		t.Fatalf("unexpected sleeve pnl: %v / %v", res.Sleeves[0].PnL, res.Sleeves[1].PnL)
+	}
+	if math.Abs(res.Sleeves[0].Contribution-1) > 1e-9 {
+		t.Fatalf("trend sleeve should carry all pnl, got %.3f", res.Sleeves[0].Contribution)
+	}
+	last := res.Equity[len(res.Equity)-1].Equity
+	if math.Abs(last-(res.Sleeves[0].FinalEquity+res.Sleeves[1].FinalEquity)) > 1e-9 {
+		t.Fatalf("combined equity must equal the sum of sleeves")
+	}
+}
+
+func TestRunEnsembleInverseVolFromReturns(t *testing.T) {
+	cfg := emul.EnsembleConfig{
+		StartUSD:   900,
+		Bars:       syntheticBars(5, 100, 1),
+		Allocation: emul.AllocationInverseVol,
+		Sleeves: []emul.Sleeve{
+			{Name: "calm", Strategy: idle(), Returns: []float64{0.01, -0.01, 0.01, -0.01}},
+			{Name: "wild", Strategy: idle(), Returns: []float64{0.02, -0.02, 0.02, -0.02}},
+			{Name: "given", Strategy: idle(), Vol: 0.0115470053837925},
+		},
+	}
+	res, err := emul.RunEnsemble(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sample vols 0.01155, 0.02309 and 0.01155 give weights 2:1:2.
+	for i, want := range []float64{360, 180, 360} {
+		if math.Abs(res.Sleeves[i].StartUSD-want) > 1e-6 {
+			t.Fatalf("sleeve %d starts with %v, want %v", i, res.Sleeves[i].StartUSD, want)
+		}
+	}
+	cfg.Sleeves[1].Returns = []float64{0.01, 0.01, 0.01}
+	if _, err := emul.RunEnsemble(cfg); err == nil {
+		t.Fatal("returns without variance must be rejected")
+	}
+	cfg.Sleeves[1].Returns = nil
+	if _, err := emul.RunEnsemble(cfg); err == nil {
+		t.Fatal("a sleeve without Vol or Returns must be rejected")
+	}
+}
+
+func TestRunEnsembleOffsettingSleeves(t *testing.T) {
+	short := emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		if ex.Balance().Position == 0 && len(ex.Orders()) == 0 {
+			_, err := ex.OpenShort(1)
+			return err
+		}
+		return nil
+	})
+	res, err := emul.RunEnsemble(emul.EnsembleConfig{
+		StartUSD:   2000,
+		Bars:       flatBars(100, 110),
+		Allocation: emul.AllocationFixed,
+		Sleeves: []emul.Sleeve{
+			{Name: "long", Strategy: buyAndHold(), Weight: 1},
+			{Name: "short", Strategy: short, Weight: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, shrt := res.Sleeves[0], res.Sleeves[1]
+	if math.Abs(long.PnL+shrt.PnL) > 1e-9 || long.PnL <= 0 {
+		t.Fatalf("sleeves should offset: %v / %v", long.PnL, shrt.PnL)
+	}
+	if math.Abs(long.Contribution-0.5) > 1e-9 || math.Abs(shrt.Contribution+0.5) > 1e-9 {
+		t.Fatalf("contributions %v / %v", long.Contribution, shrt.Contribution)
+	}
+}
@@ -0,0 +1,1048 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestCostProfileMakerAndTakerFees(t *testing.T) {
+	bars := syntheticBars(5, 100, 1)
+	emu, err := emul.NewEmulatorFromConfig(emul.EmulatorConfig{
+		StartUSD:    1000,
+		Bars:        bars,
+		CostProfile: emul.CostBybitPerp,
+	})
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if _, err := ex.LongLimit(bars[1].Average, 1); err != nil {
+		t.Fatalf("long limit: %v", err)
+	}
+	_, fills, _ := emu.Next()
+	if len(fills) != 1 || math.Abs(fills[0].Fee-1000*0.0002) > 1e-9 {
+		t.Fatalf("expected maker fee on in-range limit fill, got %+v", fills)
+	}
+	order, err := ex.CloseDeal("")
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if want := order.Qty * order.Price * 0.00055; math.Abs(order.Fee-want) > 1e-9 {
+		t.Fatalf("expected taker fee %.8f on market close, got %.8f", want, order.Fee)
+	}
+	if _, err := emul.NewEmulatorFromConfig(emul.EmulatorConfig{Bars: bars, CostProfile: "nope"}); err == nil {
+		t.Fatalf("unknown profile must be rejected")
+	}
+}
+
+func TestSymbolRegistryAppliesLotAndTick(t *testing.T) {
+	path := t.TempDir() + "/symbols.json"
+	data := `{"symbols":[{"symbol":"BTC","tick_size":0.5,"lot_size":0.01,"min_qty":0.01,"costs":"binance-spot","taker_fee":0.002}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := emul.LoadSymbolRegistry(path)
+	if err != nil {
+		t.Fatalf("load registry: %v", err)
+	}
+	emu, err := emul.NewEmulatorFromConfig(emul.EmulatorConfig{
+		StartUSD:  1000,
+		Bars:      syntheticBars(3, 100.3, 0),
+		Symbol:    "btc",
+		Symbols:   reg,
+		SpreadPct: 0,
+	})
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	if got := ex.CostProfile(); got.TakerFee != 0.002 || got.MakerFee != 0.001 {
+		t.Fatalf("unexpected costs: %+v", got)
+	}
+	_, _, _ = emu.Next()
+	order, err := ex.OpenLong(1)
+	if err != nil {
+		t.Fatalf("open long: %v", err)
+	}
+	if order.Symbol != "btc" {
+		t.Fatalf("expected symbol on order, got %q", order.Symbol)
+	}
+	if math.Mod(order.Price, 0.5) != 0 {
+		t.Fatalf("price %.8f not on tick", order.Price)
+	}
+	if lots := order.Qty / 0.01; math.Abs(lots-math.Round(lots)) > 1e-9 {
+		t.Fatalf("qty %.8f not on lot", order.Qty)
+	}
+	if ex.Balance().USD <= 0 {
+		t.Fatalf("lot rounding must leave the unspent remainder in USD")
+	}
+}
+
+func TestOpenOrdersGroupsByPriceLevel(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, syntheticBars(3, 100, 0))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	ex := emu.Exchange()
+	for _, price := range []float64{95, 90, 95} {
+		if _, err := ex.LongLimit(price, 0.1); err != nil {
+			t.Fatalf("long limit: %v", err)
+		}
+	}
+	if _, err := ex.ShortLimit(110, 0.2); err != nil {
+		t.Fatalf("short limit: %v", err)
+	}
+	view := ex.OpenOrders()
+	if view.LastPrice != 100 || len(view.Buys) != 2 || len(view.Sells) != 1 {
+		t.Fatalf("unexpected view %+v", view)
+	}
+	top := view.Buys[0]
+	if top.Price != 95 || len(top.Orders) != 2 || math.Abs(top.Fraction-0.2) > 1e-12 {
+		t.Fatalf("unexpected top buy level %+v", top)
+	}
+	if math.Abs(top.DistancePct+0.05) > 1e-12 || math.Abs(view.Sells[0].DistancePct-0.1) > 1e-12 {
+		t.Fatalf("unexpected distances %v %v", top.DistancePct, view.Sells[0].DistancePct)
+	}
+	if pending := ex.PendingOrders(); len(pending) != 4 || pending[3].Side != emul.SideSell {
+		t.Fatalf("unexpected pending list %+v", pending)
+	}
+}
+
+func TestBorrowRecallForceClosesShorts(t *testing.T) {
+	bars := syntheticBars(4, 100, 1)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	ex.SetBorrowRecall(emul.BorrowRecallConfig{Ticks: []int64{3}, PenaltyPct: 0.01})
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if _, err := ex.OpenShort(0.5); err != nil {
+		t.Fatalf("open short: %v", err)
+	}
+	if _, fills, _ := emu.Next(); len(fills) != 0 {
+		t.Fatalf("unexpected fills before the recall: %+v", fills)
+	}
+	_, fills, err := emu.Next()
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if len(fills) != 1 || fills[0].Reason != emul.ReasonBorrowRecall {
+		t.Fatalf("expected a recall fill, got %+v", fills)
+	}
+	if want := bars[2].High * 1.01; math.Abs(fills[0].MidPrice-want) > 1e-9 {
+		t.Fatalf("recall at %v, want %v", fills[0].MidPrice, want)
+	}
+	if ex.Balance().Position != 0 {
+		t.Fatalf("short should be closed")
+	}
+}
+
+func TestFillModelTradeThroughAndProbability(t *testing.T) {
+	bars := flatBars(100, 100, 100, 100)
+	bars[1].Low, bars[2].Low, bars[3].Low = 95, 94, 94
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	ex.SetLimitMode(emul.LimitsRest)
+	if err := ex.SetFillModel(emul.FillModel{Kind: emul.FillOnTradeThrough, TradeThroughTicks: 2}); err == nil {
+		t.Fatalf("trade-through without a tick size must be rejected")
+	}
+	if err := ex.SetFillModel(emul.FillModel{Kind: emul.FillOnTradeThrough, TradeThroughTicks: 2, TickSize: 0.5}); err != nil {
+		t.Fatalf("set fill model: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if _, err := ex.LongLimit(95, 0.5); err != nil {
+		t.Fatalf("long limit: %v", err)
+	}
+	if _, fills, _ := emu.Next(); len(fills) != 0 || len(ex.PendingOrders()) != 1 {
+		t.Fatalf("a touch without trade-through must not fill: %+v", fills)
+	}
+	_, fills, err := emu.Next()
+	if err != nil || len(fills) != 1 || fills[0].Price != 95 {
+		t.Fatalf("expected a fill at 95 once traded through, got %+v (%v)", fills, err)
+	}
+
+	if err := ex.SetFillModel(emul.FillModel{Kind: emul.FillWithProbability, Probability: 0}); err != nil {
+		t.Fatalf("set fill model: %v", err)
+	}
+	if _, err := ex.CloseLimit(94, emul.ReasonExit, ""); err != nil {
+		t.Fatalf("close limit: %v", err)
+	}
+	if _, fills, _ := emu.Next(); len(fills) != 0 || len(ex.PendingOrders()) != 1 {
+		t.Fatalf("probability 0 must never fill: %+v", fills)
+	}
+}
+
+func TestSelfImpactShiftsFollowingBars(t *testing.T) {
+	bars := flatBars(100, 100, 100, 100)
+	for i := range bars {
+		bars[i].Volume = 20
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetImpactModel(emul.ImpactModel{Coefficient: 0.1, HalfLifeBars: 1}); err != nil {
+		t.Fatalf("set impact: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if _, err := ex.OpenLong(0.5); err != nil {
+		t.Fatalf("open long: %v", err)
+	}
+	// 5 units against a volume of 20: 0.1*sqrt(0.25) = 5%, halving every bar.
+	for _, want := range []float64{105, 102.5, 101.25} {
+		bar, _, err := emu.Next()
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		if math.Abs(bar.Close-want) > 1e-9 || math.Abs(ex.Balance().LastPrice-want) > 1e-9 {
+			t.Fatalf("close %v, want %v", bar.Close, want)
+		}
+	}
+}
+
+func TestMakerRebatePaidInCash(t *testing.T) {
+	emu, err := emul.NewEmulatorWithProfile(1000, emul.CostProfile{MakerFee: -0.001, TakerFee: 0.002}, flatBars(100, 100, 100, 100))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	ex.SetLimitMode(emul.LimitsRest)
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if _, err := ex.LongLimit(100, 1); err != nil {
+		t.Fatalf("long limit: %v", err)
+	}
+	_, fills, err := emu.Next()
+	if err != nil || len(fills) != 1 {
+		t.Fatalf("expected the limit to fill: %+v %v", fills, err)
+	}
+	// The whole notional buys quantity; the rebate comes back as cash.
+	if bal := ex.Balance(); bal.Position != 10 || math.Abs(bal.USD-1) > 1e-9 || fills[0].Fee >= 0 {
+		t.Fatalf("unexpected balance %+v fee %v", bal, fills[0].Fee)
+	}
+	if _, err := ex.CloseDeal(emul.ReasonExit); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	totals := ex.LedgerTotals()
+	if math.Abs(totals[emul.LedgerRebate]-1) > 1e-9 || math.Abs(totals[emul.LedgerFee]+2) > 1e-9 {
+		t.Fatalf("unexpected ledger %+v", ex.Ledger())
+	}
+	if math.Abs(ex.Balance().Equity-999) > 1e-9 {
+		t.Fatalf("equity %v, want 999", ex.Balance().Equity)
+	}
+}
+
+func TestDustPolicies(t *testing.T) {
+	for _, policy := range []emul.DustPolicy{emul.DustConvert, emul.DustTrack} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100))
+		if err != nil {
+			t.Fatalf("new emulator: %v", err)
+		}
+		ex := emu.Exchange()
+		ex.SetDustPolicy(policy)
+		_, _, _ = emu.Next()
+		if _, err := ex.OpenLong(0.955); err != nil {
+			t.Fatalf("open long: %v", err)
+		}
+		// Lot rules applied after the entry leave 0.55 units that cannot be sold.
+		ex.SetSymbol(emul.SymbolSpec{Symbol: "X", LotSize: 1, MinQty: 1})
+		_, _, _ = emu.Next()
+		order, err := ex.CloseDeal(emul.ReasonExit)
+		if err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		bal := ex.Balance()
+		if order.Qty != 9 || bal.Position != 0 || math.Abs(bal.Equity-1000) > 1e-9 {
+			t.Fatalf("policy %d: order qty %v, balance %+v", policy, order.Qty, bal)
+		}
+		switch policy {
+		case emul.DustConvert:
+			if bal.Dust != 0 || math.Abs(ex.LedgerTotals()[emul.LedgerDust]-55) > 1e-9 {
+				t.Fatalf("dust should be converted: %+v", ex.Ledger())
+			}
+		case emul.DustTrack:
+			if math.Abs(ex.Dust()-0.55) > 1e-9 {
+				t.Fatalf("dust %v, want 0.55", ex.Dust())
+			}
+			if usd := ex.SweepDust(); math.Abs(usd-55) > 1e-9 || ex.Dust() != 0 {
+				t.Fatalf("sweep returned %v", usd)
+			}
+		}
+	}
+}
+
+func TestIntentsRecordRejectedAndUnfilledCalls(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	order, err := ex.OpenLong(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenShort(0.5); !errors.Is(err, emul.ErrPositionOpen) {
+		t.Fatalf("expected position open, got %v", err)
+	}
+	id, err := ex.CloseLimit(90, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.CancelLimit(id)
+	ex.CancelLimit(id)
+
+	intents := ex.Intents()
+	want := []struct{ action, outcome string }{
+		{emul.IntentOpenLong, emul.IntentFilled},
+		{emul.IntentOpenShort, emul.IntentRejected},
+		{emul.IntentCloseLimit, emul.IntentPlaced},
+		{emul.IntentCancel, emul.IntentCanceled},
+		{emul.IntentCancel, emul.IntentNotPending},
+	}
+	if len(intents) != len(want) {
+		t.Fatalf("unexpected intents %+v", intents)
+	}
+	for i, w := range want {
+		if intents[i].Action != w.action || intents[i].Outcome != w.outcome || intents[i].Tick != 1 {
+			t.Fatalf("intent %d: %+v, want %s/%s", i, intents[i], w.action, w.outcome)
+		}
+	}
+	if intents[0].OrderID != order.ID || intents[0].Fraction != 0.5 || !errors.Is(intents[1].Err, emul.ErrPositionOpen) {
+		t.Fatalf("unexpected details %+v %+v", intents[0], intents[1])
+	}
+	if intents[2].LimitID != id || intents[2].Price != 90 || intents[3].LimitID != id {
+		t.Fatalf("unexpected limit intents %+v", intents[2:])
+	}
+}
+
+func TestLimitStatusLifecycle(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	exitID, err := ex.CloseLimit(100, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryID, err := ex.LongLimit(100, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceledID, err := ex.ShortLimit(100, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, order := ex.LimitStatus(entryID); state != emul.LimitPending || order != nil {
+		t.Fatalf("before the bar: %s %v", state, order)
+	}
+	ex.CancelLimit(canceledID)
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	state, order := ex.LimitStatus(entryID)
+	if state != emul.LimitFilled || order == nil || order.Side != emul.SideBuy {
+		t.Fatalf("entry: %s %+v", state, order)
+	}
+	if lookup, ok := ex.ExecutedOrder(entryID); !ok || lookup.ID != order.ID {
+		t.Fatalf("executed lookup %+v %v", lookup, ok)
+	}
+	for id, want := range map[int64]emul.LimitState{exitID: emul.LimitExpired, canceledID: emul.LimitCanceled, 99: emul.LimitUnknown} {
+		if state, order := ex.LimitStatus(id); state != want || order != nil {
+			t.Fatalf("limit %d: %s, want %s", id, state, want)
+		}
+	}
+}
+
+func TestNextReturnsRejectedLimits(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	exitID, err := ex.CloseLimit(100, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.LongLimit(100, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	_, executed, rejected, err := emu.NextWithRejections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(executed) != 1 || len(rejected) != 1 {
+		t.Fatalf("executed %d, rejected %+v", len(executed), rejected)
+	}
+	if r := rejected[0]; r.LimitID != exitID || r.Kind != "close" || r.Reason != "position_state_mismatch" || r.Tick != 2 {
+		t.Fatalf("unexpected rejection %+v", r)
+	}
+	if _, _, rejected, err := emu.NextWithRejections(); err != nil || len(rejected) != 0 {
+		t.Fatalf("rejections must reset per bar: %+v %v", rejected, err)
+	}
+}
+
+func TestLimitSameBarEligibility(t *testing.T) {
+	bars := []emul.OHLCBar{
+		{Open: 100, High: 102, Low: 98, Close: 100, Average: 100},
+		{Open: 100, High: 100, Low: 100, Close: 100, Average: 100},
+	}
+	for _, tc := range []struct {
+		mode   emul.LimitEligibility
+		price  float64
+		filled bool
+	}{
+		{emul.LimitNextBar, 99, false},
+		{emul.LimitSameBar, 99, true},
+		{emul.LimitSameBar, 97, false},
+	} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		ex.SetLimitMode(emul.LimitsRest)
+		ex.SetLimitEligibility(tc.mode)
+		id, err := ex.LongLimit(tc.price, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, order := ex.LimitStatus(id)
+		if (state == emul.LimitFilled) != tc.filled {
+			t.Fatalf("mode %d at %v: state %s", tc.mode, tc.price, state)
+		}
+		if tc.filled && (order.Price != tc.price || order.Tick != 1 || ex.Intents()[0].Outcome != emul.IntentFilled) {
+			t.Fatalf("unexpected fill %+v", order)
+		}
+	}
+}
+
+func TestMarketableLimitExecutesImmediately(t *testing.T) {
+	for _, tc := range []struct {
+		short  bool
+		price  float64
+		filled bool
+	}{
+		{false, 105, true},
+		{false, 95, false},
+		{true, 95, true},
+		{true, 105, false},
+	} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		ex.SetLimitEligibility(emul.LimitImmediateIfMarketable)
+		place := ex.LongLimit
+		if tc.short {
+			place = ex.ShortLimit
+		}
+		id, err := place(tc.price, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, order := ex.LimitStatus(id)
+		if (state == emul.LimitFilled) != tc.filled {
+			t.Fatalf("short=%v at %v: state %s", tc.short, tc.price, state)
+		}
+		if tc.filled && order.Price != 100 {
+			t.Fatalf("marketable limit filled at %v, want the market price", order.Price)
+		}
+		if !tc.filled && len(ex.PendingOrders()) != 1 {
+			t.Fatalf("non-marketable limit must rest")
+		}
+	}
+}
+
+func TestReverseFlipsAtomically(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 110, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.Reverse(1); !errors.Is(err, emul.ErrNoPosition) {
+		t.Fatalf("reverse while flat: %v", err)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ex.Reverse(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Close.Reason != emul.ReasonReverse || r.Open.Side != emul.SideSell || ex.Balance().Position >= 0 || math.Abs(r.PnL-100) > 1e-9 {
+		t.Fatalf("unexpected reversal %+v", r)
+	}
+
+	// A rejected open rolls the close back.
+	ex.SetSymbol(emul.SymbolSpec{MinQty: 1e9})
+	before, orders := ex.Balance(), len(ex.Orders())
+	if _, err := ex.Reverse(1); !errors.Is(err, emul.ErrBelowMinQty) {
+		t.Fatalf("expected min qty rejection, got %v", err)
+	}
+	if ex.Balance() != before || len(ex.Orders()) != orders {
+		t.Fatalf("failed reverse changed state: %+v vs %+v", ex.Balance(), before)
+	}
+	ex.SetSymbol(emul.SymbolSpec{})
+
+	id, err := ex.ReverseLimit(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, executed, err := emu.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(executed) != 2 || ex.Balance().Position <= 0 {
+		t.Fatalf("reverse limit executed %+v", executed)
+	}
+	if rs := ex.Reversals(); len(rs) != 2 || rs[1].LimitID != id || rs[1].Open.ID != executed[1].ID {
+		t.Fatalf("unexpected reversals %+v", rs)
+	}
+}
+
+func TestFailedReverseKeepsQueuedLimits(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	ex.SetLimitMode(emul.LimitsRest)
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.ScaleInLimit(50, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	id, err := ex.CloseLimit(120, emul.ReasonExit, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverseID, err := ex.ReverseLimit(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.SetSymbol(emul.SymbolSpec{MinQty: 1e9})
+
+	// The reverse limit is touched and rejected while the other limits stay queued.
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := ex.LimitStatus(reverseID); state != emul.LimitExpired || ex.Balance().Position <= 0 {
+		t.Fatalf("rejected reverse limit: %s, position %v", state, ex.Balance().Position)
+	}
+	if len(ex.PendingOrders()) != 2 {
+		t.Fatalf("queued limits after rejected reverse limit: %+v", ex.PendingOrders())
+	}
+
+	pending, timelines, ledger := ex.PendingOrders(), ex.LimitTimelines(), ex.Ledger()
+	if _, err := ex.Reverse(1); !errors.Is(err, emul.ErrBelowMinQty) {
+		t.Fatalf("expected min qty rejection, got %v", err)
+	}
+	ex.SetSymbol(emul.SymbolSpec{})
+	if !reflect.DeepEqual(ex.PendingOrders(), pending) || !reflect.DeepEqual(ex.LimitTimelines(), timelines) || !reflect.DeepEqual(ex.Ledger(), ledger) {
+		t.Fatalf("failed reverse changed the queue: %+v vs %+v", ex.PendingOrders(), pending)
+	}
+
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if state, order := ex.LimitStatus(id); state != emul.LimitFilled || order.Price != 120 {
+		t.Fatalf("queued close limit after rollback: %s %+v", state, order)
+	}
+}
+
+func TestOrderLimitsRejectRunawayPlacement(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	ex.SetLimitMode(emul.LimitsRest)
+	if err := ex.SetOrderLimits(emul.OrderLimits{MaxOpenOrders: 2, MaxOrdersPerBar: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := ex.LongLimit(90-float64(i), 0.1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ex.LongLimit(80, 0.1); !errors.Is(err, emul.ErrTooManyOpenOrders) {
+		t.Fatalf("expected open-order cap, got %v", err)
+	}
+	if _, err := ex.OpenLong(0.1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.CloseDeal(""); !errors.Is(err, emul.ErrTooManyOrdersPerBar) {
+		t.Fatalf("expected per-bar cap, got %v", err)
+	}
+	if intents := ex.Intents(); intents[len(intents)-1].Outcome != emul.IntentRejected {
+		t.Fatalf("rejection not recorded: %+v", intents[len(intents)-1])
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.CloseDeal(""); err != nil {
+		t.Fatalf("per-bar count must reset on the next bar: %v", err)
+	}
+}
+
+func TestLiquidationPriceInBalance(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 120, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenShort(1); err != nil {
+		t.Fatal(err)
+	}
+	// 10 short against 1000 margin and 1000 proceeds: buying back at 200 uses all of it.
+	if liq := ex.Balance().LiquidationPrice; math.Abs(liq-200) > 1e-9 {
+		t.Fatalf("short liquidation price %v, want 200", liq)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if liq := ex.Balance().LiquidationPrice; math.Abs(liq-200) > 1e-9 {
+		t.Fatalf("short liquidation price %v after a bar, want 200", liq)
+	}
+
+	if err := ex.SetLiquidationConfig(emul.LiquidationConfig{Leverage: 10, MaintenanceMargin: 0.005}); err != nil {
+		t.Fatal(err)
+	}
+	if liq, want := ex.Balance().LiquidationPrice, 100*1.1/1.005; math.Abs(liq-want) > 1e-9 {
+		t.Fatalf("leveraged short liquidation price %v, want %v", liq, want)
+	}
+	if _, err := ex.CloseDeal(emul.ReasonExit); err != nil {
+		t.Fatal(err)
+	}
+	if liq := ex.Balance().LiquidationPrice; liq != 0 {
+		t.Fatalf("flat account reports liquidation price %v", liq)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	if liq, want := ex.Balance().LiquidationPrice, 120*0.9/0.995; math.Abs(liq-want) > 1e-9 {
+		t.Fatalf("leveraged long liquidation price %v, want %v", liq, want)
+	}
+	if err := ex.SetLiquidationConfig(emul.LiquidationConfig{Leverage: 0.5}); err == nil {
+		t.Fatal("expected an error for leverage below 1")
+	}
+}
+
+func TestPrecisionRoundsFillsToCents(t *testing.T) {
+	cents := func(v float64) bool { return math.Abs(v*100-math.Round(v*100)) < 1e-6 }
+	for _, short := range []bool{false, true} {
+		emu, err := emul.NewEmulator(1000.004, 0.001, 0, 0, flatBars(123.456789, 130.98765))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		if err := ex.SetPrecision(emul.Precision{PriceDecimals: emul.Decimals(2), QuoteDecimals: emul.Decimals(2)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		open := ex.OpenLong
+		if short {
+			open = ex.OpenShort
+		}
+		if _, err := open(1); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ex.CloseDeal(emul.ReasonExit); err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range ex.Orders() {
+			if !cents(o.Price) || !cents(o.Fee) {
+				t.Fatalf("short=%v: order not rounded to cents: %+v", short, o)
+			}
+		}
+		orders := ex.Orders()
+		if want := 123.46; !short && orders[0].Price != want {
+			t.Fatalf("buy price %v, want %v rounded up", orders[0].Price, want)
+		}
+		if want := 130.98; !short && orders[1].Price != want {
+			t.Fatalf("sell price %v, want %v rounded down", orders[1].Price, want)
+		}
+		if bal := ex.Balance(); !cents(bal.USD-0.004) || bal.USD <= 0 {
+			t.Fatalf("short=%v: cash moved by amounts that are not whole cents: %v", short, bal.USD)
+		}
+	}
+
+	dec := 2
+	reg, err := emul.NewSymbolRegistry(emul.SymbolSpec{Symbol: "X", PriceDecimals: &dec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := reg.Lookup("x")
+	ex := emul.NewExchange(100, 0, 0, 0)
+	ex.SetSymbol(spec)
+	if p := ex.Precision(); p.PriceDecimals == nil || *p.PriceDecimals != 2 || p.QuoteDecimals != nil {
+		t.Fatalf("unexpected precision from spec %+v", p)
+	}
+}
+
+func TestPrecisionZeroValueLeavesSideUnrounded(t *testing.T) {
+	emu, err := emul.NewEmulator(1000.123, 0.001, 0, 0, flatBars(123.456789, 130.98765))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetPrecision(emul.Precision{PriceDecimals: emul.Decimals(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	order, err := ex.OpenLong(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Price != 123.46 {
+		t.Fatalf("price %v, want 123.46", order.Price)
+	}
+	if math.Abs(order.Fee*100-math.Round(order.Fee*100)) < 1e-6 {
+		t.Fatalf("an unset QuoteDecimals must leave the fee unrounded, got %v", order.Fee)
+	}
+	if err := ex.SetPrecision(emul.Precision{QuoteDecimals: emul.Decimals(-1)}); err == nil {
+		t.Fatal("negative decimals must be rejected")
+	}
+	if err := ex.SetPrecision(emul.NoPrecision); err != nil {
+		t.Fatal(err)
+	}
+	if p := ex.Precision(); p.PriceDecimals != nil || p.QuoteDecimals != nil {
+		t.Fatalf("NoPrecision must clear rounding, got %+v", p)
+	}
+}
+
+func TestLimitTimelineTracksChanges(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 95, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	ex.SetLimitMode(emul.LimitsRest)
+	entryID, err := ex.LongLimit(90, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceledID, err := ex.CloseLimit(200, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.CancelLimit(canceledID)
+	for i := 0; i < 3; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tl, ok := ex.LimitTimeline(entryID)
+	if !ok || tl.State != "filled" || tl.Kind != "open_long" || tl.LimitPrice != 90 || tl.PlacedTick != 1 {
+		t.Fatalf("entry timeline %+v %v", tl, ok)
+	}
+	order, _ := ex.ExecutedOrder(entryID)
+	want := []emul.LimitEvent{
+		{Tick: 1, Kind: emul.LimitEventPlaced, Price: 90},
+		{Tick: 2, Kind: emul.LimitEventEvaluated, Reason: "price_not_touched", Repeats: 1, LastTick: 3},
+		{Tick: 4, Kind: emul.LimitEventFilled, Price: order.Price, OrderID: order.ID},
+	}
+	if len(tl.Events) != len(want) {
+		t.Fatalf("entry events %+v", tl.Events)
+	}
+	for i, w := range want {
+		if tl.Events[i] != w {
+			t.Fatalf("event %d: %+v, want %+v", i, tl.Events[i], w)
+		}
+	}
+
+	canceled, ok := ex.LimitTimeline(canceledID)
+	if !ok || canceled.State != "canceled" || len(canceled.Events) != 2 || canceled.Events[1].Kind != emul.LimitEventCanceled {
+		t.Fatalf("canceled timeline %+v %v", canceled, ok)
+	}
+	if all := ex.LimitTimelines(); len(all) != 2 || all[0].ID != entryID || all[1].ID != canceledID {
+		t.Fatalf("timelines %+v", all)
+	}
+	if _, ok := ex.LimitTimeline(99); ok {
+		t.Fatal("unknown limit has a timeline")
+	}
+}
+
+func TestMissRetentionBoundsLog(t *testing.T) {
+	cases := []struct {
+		retention emul.MissRetention
+		ticks     []int64
+	}{
+		{emul.MissRetention{}, []int64{2, 3, 4, 5, 6}},
+		{emul.MissRetention{Mode: emul.MissKeepFirst, MaxEntries: 2}, []int64{2, 3}},
+		{emul.MissRetention{Mode: emul.MissRingBuffer, MaxEntries: 2}, []int64{5, 6}},
+		{emul.MissRetention{Mode: emul.MissAggregateOnly}, nil},
+	}
+	for _, tc := range cases {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100, 100, 100, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		if err := ex.SetMissRetention(tc.retention); err != nil {
+			t.Fatal(err)
+		}
+		// Far-off limits fall back to the close on the next bar and log one miss each.
+		for {
+			if _, _, err := emu.Next(); errors.Is(err, emul.ErrNoMoreBars) {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if ex.Balance().Position == 0 {
+				_, err = ex.LongLimit(50, 0.5)
+			} else {
+				_, err = ex.CloseLimit(200, "", "")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		diag := ex.LimitDiagnostics()
+		if diag.MissCounts["price_not_in_hl_filled_at_close"] != 5 || diag.MissesDropped != 5-len(tc.ticks) {
+			t.Fatalf("%+v: counts %v dropped %d", tc.retention, diag.MissCounts, diag.MissesDropped)
+		}
+		if len(diag.Misses) != len(tc.ticks) {
+			t.Fatalf("%+v: misses %+v", tc.retention, diag.Misses)
+		}
+		for i, tick := range tc.ticks {
+			if diag.Misses[i].CheckTick != tick {
+				t.Fatalf("%+v: miss %d at tick %d, want %d", tc.retention, i, diag.Misses[i].CheckTick, tick)
+			}
+		}
+	}
+
+	ex := emul.NewExchange(1000, 0, 0, 0)
+	if err := ex.SetMissRetention(emul.MissRetention{Mode: emul.MissRingBuffer}); err == nil {
+		t.Fatal("ring buffer without max entries accepted")
+	}
+}
+
+func TestBoundedRetentionFoldsTimelineEvaluations(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 95, 95, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	ex.SetLimitMode(emul.LimitsRest)
+	if err := ex.SetMissRetention(emul.MissRetention{Mode: emul.MissAggregateOnly}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	id, err := ex.LongLimit(90, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tl, _ := ex.LimitTimeline(id)
+	if len(tl.Events) != 3 || tl.Events[1].Tick != 2 || tl.Events[1].Repeats != 2 || tl.Events[2].Kind != emul.LimitEventFilled {
+		t.Fatalf("events %+v", tl.Events)
+	}
+}
+
+func TestBoundedRetentionTrimsFinishedTimelines(t *testing.T) {
+	for _, tc := range []struct {
+		retention emul.MissRetention
+		kept      []int
+	}{
+		{emul.MissRetention{}, []int{0, 1, 2, 3}},
+		{emul.MissRetention{Mode: emul.MissKeepFirst, MaxEntries: 1}, []int{0, 3}},
+		{emul.MissRetention{Mode: emul.MissRingBuffer, MaxEntries: 1}, []int{2, 3}},
+	} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		ex.SetLimitMode(emul.LimitsRest)
+		var ids []int64
+		for i := 0; i < 4; i++ {
+			id, err := ex.LongLimit(50, 0.1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		for _, id := range ids[:3] {
+			ex.CancelLimit(id)
+		}
+		if err := ex.SetMissRetention(tc.retention); err != nil {
+			t.Fatal(err)
+		}
+		// The pending limit keeps its timeline whatever the retention.
+		all := ex.LimitTimelines()
+		if len(all) != len(tc.kept) {
+			t.Fatalf("%+v: timelines %+v", tc.retention, all)
+		}
+		for i, k := range tc.kept {
+			if all[i].ID != ids[k] {
+				t.Fatalf("%+v: timeline %d is %d, want %d", tc.retention, i, all[i].ID, ids[k])
+			}
+		}
+	}
+}
+
+func TestDiagnosticsLevels(t *testing.T) {
+	for _, level := range []emul.DiagnosticsLevel{emul.DiagnosticsFull, emul.DiagnosticsCounters, emul.DiagnosticsOff} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		if err := ex.SetDiagnosticsLevel(level); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		id, err := ex.LongLimit(50, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		diag := ex.LimitDiagnostics()
+		_, hasTimeline := ex.LimitTimeline(id)
+		misses, counted := len(diag.Misses), diag.MissCounts["price_not_in_hl_filled_at_close"]
+		switch {
+		case level == emul.DiagnosticsFull && (misses != 1 || counted != 1 || !hasTimeline),
+			level == emul.DiagnosticsCounters && (misses != 0 || counted != 1 || hasTimeline),
+			level == emul.DiagnosticsOff && (misses != 0 || counted != 0 || hasTimeline):
+			t.Fatalf("%s: misses %d counted %d timeline %v", level, misses, counted, hasTimeline)
+		}
+		if state, _ := ex.LimitStatus(id); state != emul.LimitFilled {
+			t.Fatalf("%s: limit state %s", level, state)
+		}
+	}
+	if err := emul.NewExchange(1000, 0, 0, 0).SetDiagnosticsLevel(9); err == nil {
+		t.Fatal("unknown level accepted")
+	}
+}
+
+func TestSizeBucketsScaleSlippageWithNotional(t *testing.T) {
+	buckets := []emul.SizeBucket{
+		{MinNotional: 0, SpreadPct: -1, BuySlippagePct: 0.001, SellSlippagePct: 0.001},
+		{MinNotional: 5000, SpreadPct: 0.002, BuySlippagePct: 0.01, SellSlippagePct: 0.005},
+	}
+	for _, tc := range []struct {
+		start      float64
+		buy, sell  float64
+		spreadUsed bool
+	}{
+		{1000, 100 * 1.001, 100 * 0.999, false},
+		{10000, 100 * 1.001 * 1.01, 0, true},
+	} {
+		emu, err := emul.NewEmulator(tc.start, 0, 0, 0, flatBars(100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		if err := ex.SetSizeBuckets(buckets); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		open, err := ex.OpenLong(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(open.Price-tc.buy) > 1e-9 {
+			t.Fatalf("start %v: bought at %v, want %v", tc.start, open.Price, tc.buy)
+		}
+		closed, err := ex.CloseDeal(emul.ReasonExit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The sell notional follows the position, so the large account sells in the large bucket.
+		want := tc.sell
+		if tc.spreadUsed {
+			want = 100 * 0.999 * 0.995
+		}
+		if math.Abs(closed.Price-want) > 1e-9 {
+			t.Fatalf("start %v: sold at %v, want %v", tc.start, closed.Price, want)
+		}
+	}
+
+	ex := emul.NewExchange(1000, 0, 0, 0)
+	if err := ex.SetSizeBuckets([]emul.SizeBucket{{MinNotional: 10}, {MinNotional: 5}}); err == nil {
+		t.Fatal("unordered buckets accepted")
+	}
+}
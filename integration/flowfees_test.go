@@ -0,0 +1,116 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestFlowFeesRouteThroughLedger(t *testing.T) {
+	bars := flatBars(100, 100, 100)
+	emu, err := emul.NewEmulator(2000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: bars, FundingRate: 0.001, FundingEvery: 1}); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetFlowFee(emul.LedgerFee, emul.FlowFee{Pct: 0.1}); err == nil {
+		t.Fatal("expected an error for a fee on order fees")
+	}
+	if err := ex.SetFlowFee(emul.LedgerFunding, emul.FlowFee{Pct: 0.1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetFlowFee(emul.LedgerWithdrawal, emul.FlowFee{Pct: 0.001, Fixed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetBorrowRate(0.876); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.Withdraw(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.Withdraw(5000); !errors.Is(err, emul.ErrInsufficientFunds) {
+		t.Fatalf("overdrawn withdrawal: %v", err)
+	}
+	if _, err := ex.OpenShort(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenPerp(emul.SideBuy, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Short 949.45/100 units: an hour at 87.6% APR costs 0.01% of 949.45 per bar. The perp
+	// long of 474.725/100 units pays 0.1% funding per bar and 10% of that again as a fee.
+	short := (2000 - 100 - 1.1) / 2
+	perpQty := (2000 - 100 - 1.1 - short) / 2 / 100
+	funding := 2 * perpQty * 100 * 0.001
+	rep := ex.CostReport()
+	if math.Abs(rep.Borrow-2*short*0.0001) > 1e-9 || math.Abs(rep.Funding-funding) > 1e-9 {
+		t.Fatalf("unexpected borrow/funding %+v", rep)
+	}
+	if math.Abs(rep.FlowFees[emul.LedgerWithdrawal]-1.1) > 1e-9 || math.Abs(rep.FlowFees[emul.LedgerFunding]-0.1*funding) > 1e-9 {
+		t.Fatalf("unexpected flow fees %+v", rep.FlowFees)
+	}
+	if want := rep.Borrow + rep.Funding + 1.1 + 0.1*funding; math.Abs(rep.Total-want) > 1e-9 {
+		t.Fatalf("total %v, want %v", rep.Total, want)
+	}
+	if bal := ex.Balance(); math.Abs(bal.Equity-(2000-100-rep.Total)) > 1e-9 {
+		t.Fatalf("equity %v does not reflect the costs %v", bal.Equity, rep.Total)
+	}
+}
+
+func TestFundingNeverDrivesCashNegative(t *testing.T) {
+	bars := flatBars(100, 100, 100, 100, 100, 100)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: bars, FundingRate: 0.3, FundingEvery: 1}); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetFlowFee(emul.LedgerFunding, emul.FlowFee{Fixed: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenPerp(emul.SideBuy, 1); err != nil {
+		t.Fatal(err)
+	}
+	// The long pays 300 a bar out of its 1000 margin: three payments leave 100, the fourth
+	// pays that and falls 200 short, and the leg is liquidated.
+	for i := 0; i < 5; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		if bal := ex.Balance(); bal.USD < 0 || bal.Equity < 0 {
+			t.Fatalf("bar %d: cash %v, equity %v", i+2, bal.USD, bal.Equity)
+		}
+	}
+	pos, err := ex.PerpPosition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Qty != 0 || math.Abs(pos.Funding+1000) > 1e-9 || math.Abs(pos.FundingShortfall-200) > 1e-9 {
+		t.Fatalf("perp after funding ran out %+v", pos)
+	}
+	orders := ex.PerpOrders()
+	if last := orders[len(orders)-1]; last.Reason != emul.ReasonLiquidate || last.Tick != 5 {
+		t.Fatalf("expected a liquidation on bar 5, got %+v", last)
+	}
+	if rep := ex.CostReport(); rep.FlowFees[emul.LedgerFunding] != 0 {
+		t.Fatalf("flow fees cannot be collected from an empty wallet, got %v", rep.FlowFees)
+	}
+}
@@ -0,0 +1,28 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func FuzzSimulate(f *testing.F) {
+	f.Add([]byte{0, 200, 10, 1, 255, 0, 0, 90, 20, 0, 30, 40, 3, 0, 0})
+	f.Add([]byte{0, 120, 5, 2, 255, 128, 0, 250, 60, 0, 250, 60, 3, 0, 0})
+	f.Add([]byte{0, 128, 0, 4, 100, 128, 6, 0, 128, 0, 60, 200, 0, 10, 255})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cfg := emul.SimConfig{StartUSD: 1000, Fee: 0.001, SlippagePct: 0.0005, SpreadPct: 0.001}
+		if _, err := emul.Simulate(cfg, emul.DecodeActions(data)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSimulateRandomSequences(t *testing.T) {
+	for seed := uint64(0); seed < 200; seed++ {
+		cfg := emul.SimConfig{StartUSD: 1000, Fee: 0.001, SpreadPct: -1}
+		if _, err := emul.Simulate(cfg, emul.RandomActions(300, seed)); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
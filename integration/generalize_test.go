@@ -0,0 +1,60 @@
+package emul_test
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestSweepCoinsAggregates(t *testing.T) {
+	root := t.TempDir()
+	rows := func(closes ...float64) string {
+		var sb strings.Builder
+		for i, c := range closes {
+			fmt.Fprintf(&sb, "%d,%v,%v,%v,%v,10\n", 1704067200+i*86400, c, c, c, c)
+		}
+		return sb.String()
+	}
+	writeCSV(t, filepath.Join(root, "btc", "d", "2024.csv"), rows(100, 110, 121, 110))
+	writeCSV(t, filepath.Join(root, "eth", "d", "2024.csv"), rows(10, 11, 12.1, 11))
+	writeCSV(t, filepath.Join(root, "sol", "h", "2024.csv"), rows(1, 2))
+
+	report, err := emul.SweepCoins(emul.CoinSweepConfig{DataRoot: root, Interval: "d", StartUSD: 1000}, func(string) emul.Strategy {
+		return emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+			if ex.Balance().Position == 0 && len(ex.Orders()) == 0 {
+				_, err := ex.OpenLong(1)
+				return err
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Runs) != 2 || report.Coins[0] != "btc" || report.Coins[1] != "eth" {
+		t.Fatalf("unexpected runs: %+v", report.Runs)
+	}
+	for _, run := range report.Runs {
+		if run.Err != nil || run.Bars != 4 || run.Return <= 0 {
+			t.Fatalf("unexpected run %+v", run)
+		}
+	}
+	if len(report.Pooled) != 4 || report.Pooled[3].Equity != (report.Runs[0].FinalEquity+report.Runs[1].FinalEquity)/2 {
+		t.Fatalf("unexpected pooled curve %+v", report.Pooled)
+	}
+	if c := report.Correlation[0][1]; c < 0.99 {
+		t.Fatalf("identical paths should correlate, got %v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := emul.WriteCoinReport(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "btc,4,") {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+}
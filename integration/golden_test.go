@@ -0,0 +1,51 @@
+package emul_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func goldenRun(t *testing.T, fee float64) emul.GoldenRun {
+	t.Helper()
+	emu, err := emul.NewEmulator(1000, fee, 0, 0, emul.RandomBars(200, 100, 0.02, 9))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	n := 0
+	curve, err := emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, executed []emul.Order) error {
+		n++
+		if n%7 != 0 {
+			return nil
+		}
+		if ex.Balance().Position == 0 {
+			_, err := ex.LongLimit(0, 1)
+			return err
+		}
+		_, err := ex.CloseLimit(0, "", "")
+		return err
+	}))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	return emul.GoldenFromEmulator(emu, curve)
+}
+
+func TestGoldenRoundTripAndDrift(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.golden.json")
+	if err := emul.MatchGolden(path, goldenRun(t, 0.001), emul.GoldenTolerance{}, true); err != nil {
+		t.Fatalf("record golden: %v", err)
+	}
+	if err := emul.MatchGolden(path, goldenRun(t, 0.001), emul.GoldenTolerance{Abs: 1e-9}, false); err != nil {
+		t.Fatalf("identical run must match: %v", err)
+	}
+	err := emul.MatchGolden(path, goldenRun(t, 0.0011), emul.GoldenTolerance{Abs: 1e-9}, false)
+	if err == nil || !strings.Contains(err.Error(), ".Fee") {
+		t.Fatalf("expected fee drift to be reported, got %v", err)
+	}
+	if err := emul.MatchGolden(path, goldenRun(t, 0.0011), emul.GoldenTolerance{Rel: 0.2}, false); err != nil {
+		t.Fatalf("drift within tolerance must match: %v", err)
+	}
+}
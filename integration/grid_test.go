@@ -0,0 +1,52 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestGridFillsAndReplenishes(t *testing.T) {
+	bars := []emul.OHLCBar{
+		{Open: 100, High: 100.5, Low: 99.5, Close: 100},
+		{Open: 100, High: 100, Low: 97.5, Close: 98},
+		{Open: 98, High: 100.5, Low: 98.5, Close: 100},
+		{Open: 100, High: 100.2, Low: 99.8, Close: 100},
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	grid, err := emul.NewGrid(emu.Exchange(), emul.GridConfig{SpacingPct: 0.02, Levels: 2, Fraction: 0.5})
+	if err != nil {
+		t.Fatalf("new grid: %v", err)
+	}
+	var fills []emul.Order
+	for {
+		_, executed, err := emu.Next()
+		if err != nil {
+			break
+		}
+		fills = append(fills, executed...)
+		if err := grid.Update(); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	if len(fills) != 2 {
+		t.Fatalf("expected entry and exit, got %+v", fills)
+	}
+	if fills[0].Side != emul.SideBuy || fills[0].Price != 98 || fills[1].Price != 100 || fills[1].Reason != emul.ReasonGridExit {
+		t.Fatalf("unexpected fills %+v", fills)
+	}
+	if grid.Held() != 0 {
+		t.Fatalf("grid should be flat, held %d", grid.Held())
+	}
+	view := emu.Exchange().OpenOrders()
+	if len(view.Buys) != 2 || len(view.Sells) != 2 {
+		t.Fatalf("expected the full ladder to be re-armed, got %+v", view)
+	}
+	grid.Stop()
+	if n := len(emu.Exchange().PendingOrders()); n != 0 {
+		t.Fatalf("stop left %d orders", n)
+	}
+}
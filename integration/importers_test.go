@@ -0,0 +1,85 @@
+package emul_test
+
+import (
+	"strings"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestImportBacktestingPy(t *testing.T) {
+	log := `,Size,EntryBar,ExitBar,EntryPrice,ExitPrice,PnL,ReturnPct,EntryTime,ExitTime,Duration,Commission
+0,10,1,3,100.0,110.0,98.0,0.098,2024-01-01 01:00:00,2024-01-01 03:00:00,0 days 02:00:00,2.0
+1,-5,4,6,110.0,115.0,-26.0,-0.047,2024-01-01 04:00:00,2024-01-01 06:00:00,0 days 02:00:00,1.0
+`
+	trades, err := emul.ImportBacktestingPy(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("expected 2 trades, got %d", len(trades))
+	}
+	long, short := trades[0], trades[1]
+	if long.Side != emul.SideBuy || long.Qty != 10 || long.EntryTick != 2 || long.ExitTick != 4 || long.Fees != 2 || long.Exit.Side != emul.SideSell {
+		t.Fatalf("unexpected long trade %+v", long)
+	}
+	if short.Side != emul.SideSell || short.Qty != 5 || short.Entry.PositionAfter != -5 || short.ExitCategory != emul.ReasonCategoryExit {
+		t.Fatalf("unexpected short trade %+v", short)
+	}
+	stats := emul.ComputeTradeStats(trades)
+	if stats.Wins != 1 || stats.Losses != 1 || stats.NetPnL != 72 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestImportFreqtrade(t *testing.T) {
+	result := `{"strategy": {"Sample": {"trades": [
+		{"pair": "BTC/USDT", "amount": 0.5, "open_date": "2024-01-01 01:00:00+00:00", "close_date": "2024-01-01 02:30:00+00:00",
+		 "open_rate": 100, "close_rate": 90, "fee_open": 0.001, "fee_close": 0.001, "profit_abs": -5.095,
+		 "profit_ratio": -0.1019, "exit_reason": "stop_loss", "is_short": false},
+		{"pair": "BTC/USDT", "amount": 1, "open_timestamp": 1704081600000, "close_timestamp": 1704085200000,
+		 "open_rate": 90, "close_rate": 80, "fee_open": 0, "fee_close": 0, "profit_abs": 10,
+		 "profit_ratio": 0.111, "exit_reason": "roi", "is_short": true}
+	]}}}`
+	trades, err := emul.ImportFreqtrade(strings.NewReader(result), "", flatBars(100, 100, 100, 100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("expected 2 trades, got %d", len(trades))
+	}
+	stop := trades[0]
+	if stop.ExitReason != emul.ReasonStopLoss || stop.ExitCategory != emul.ReasonCategoryStop || stop.EntryTick != 2 || stop.ExitTick != 3 || stop.Entry.Symbol != "BTC/USDT" {
+		t.Fatalf("unexpected stop trade %+v", stop)
+	}
+	if fees := stop.Fees; fees < 0.0949 || fees > 0.0951 {
+		t.Fatalf("unexpected fees %v", fees)
+	}
+	if short := trades[1]; short.Side != emul.SideSell || short.ExitReason != "roi" || short.EntryTick != 5 || short.ExitTick != 6 {
+		t.Fatalf("unexpected short trade %+v", short)
+	}
+	if _, err := emul.ImportFreqtrade(strings.NewReader(result), "Other", nil); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+}
+
+func TestImportVectorbt(t *testing.T) {
+	log := `Exit Trade Id,Column,Size,Entry Timestamp,Avg Entry Price,Entry Fees,Exit Timestamp,Avg Exit Price,Exit Fees,PnL,Return,Direction,Status,Position Id
+0,BTC,2.0,2024-01-01 00:00:00+00:00,100.0,0.2,2024-01-01 03:00:00+00:00,105.0,0.21,9.59,0.0479,Long,Closed,0
+1,BTC,1.0,2024-01-01 04:00:00+00:00,105.0,0.1,2024-01-01 05:00:00+00:00,104.0,0.0,-0.1,-0.001,Long,Open,1
+`
+	trades, err := emul.ImportVectorbt(strings.NewReader(log), flatBars(100, 100, 100, 100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("open trades must be skipped, got %d trades", len(trades))
+	}
+	tr := trades[0]
+	if tr.Qty != 2 || tr.EntryPrice != 100 || tr.ExitPrice != 105 || tr.EntryTick != 1 || tr.ExitTick != 4 || tr.PnL != 9.59 || tr.Fees < 0.40999 || tr.Fees > 0.41001 {
+		t.Fatalf("unexpected trade %+v", tr)
+	}
+	if _, err := emul.ImportVectorbt(strings.NewReader("Size,PnL\n1,2\n"), nil); err == nil {
+		t.Fatal("expected an error for missing columns")
+	}
+}
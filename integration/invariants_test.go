@@ -0,0 +1,83 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestInvariantsHoldThroughLongAndShortCycles(t *testing.T) {
+	bars := syntheticBars(60, 100, 0.7)
+	for i := 30; i < len(bars); i++ {
+		bars[i] = syntheticBars(1, bars[i-1].Close, -1.3)[0]
+	}
+	emu, err := emul.NewEmulator(1000, 0.001, 0.0005, -1, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	ex.SetInvariantMode(emul.InvariantsPanic)
+	for i := 0; ; i++ {
+		if _, _, err := emu.Next(); err == emul.ErrNoMoreBars {
+			break
+		} else if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		switch {
+		case ex.Balance().Position == 0 && i%6 == 0:
+			_, err = ex.OpenLong(0.5)
+		case ex.Balance().Position == 0 && i%6 == 3:
+			_, err = ex.ShortLimit(0, 1)
+		case ex.Balance().Position != 0 && i%4 == 0:
+			_, err = ex.CloseDeal("")
+		}
+		if err != nil {
+			t.Fatalf("bar %d: %v", i, err)
+		}
+	}
+	if err := ex.CheckInvariants(); err != nil {
+		t.Fatalf("final state: %v", err)
+	}
+}
+
+func TestNextMovesPastBarThatFailsInvariants(t *testing.T) {
+	bars := flatBars(100, 100, 105)
+	bars[1].Close = math.Inf(1)
+	perp := flatBars(100, 101, 102)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: perp}); err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.AddAccount("bot", 1000); err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	ex.SetInvariantMode(emul.InvariantsError)
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); !errors.Is(err, emul.ErrInvariant) {
+		t.Fatalf("expected an invariant error, got %v", err)
+	}
+	if p, _ := ex.PerpPosition(); p.Mark != 101 {
+		t.Fatalf("perp must be marked on the failing bar, mark %v", p.Mark)
+	}
+	bar, _, _ := emu.Next()
+	if bar.Close != 105 {
+		t.Fatalf("next bar after the failure is %+v, want the third bar", bar)
+	}
+	if p, _ := ex.PerpPosition(); p.Mark != 102 {
+		t.Fatalf("perp mark %v after the third bar", p.Mark)
+	}
+	if _, _, err := emu.Next(); err != emul.ErrNoMoreBars {
+		t.Fatalf("expected the end of the replay, got %v", err)
+	}
+}
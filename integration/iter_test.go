@@ -0,0 +1,87 @@
+package emul_test
+
+import (
+	"errors"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestEmulatorAllIterates(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 110, 110, 90, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	bars, fills := 0, 0
+	for bar, executed := range emu.All() {
+		bars++
+		fills += len(executed)
+		if bars == 2 {
+			break
+		}
+		if _, err := ex.LongLimit(bar.Close, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for bar, executed := range emu.All() {
+		bars++
+		fills += len(executed)
+		switch bar.Close {
+		case 110:
+			if ex.Balance().Position > 0 {
+				if _, err := ex.CloseDeal(emul.ReasonExit); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := ex.OpenShort(1); err != nil {
+					t.Fatal(err)
+				}
+			}
+		case 90:
+			if ex.Balance().Position < 0 {
+				if _, err := ex.CloseDeal(emul.ReasonStopLoss); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if err := emu.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if bars != 6 || fills != 1 {
+		t.Fatalf("expected 6 bars and the limit fill, got %d bars, %d fills", bars, fills)
+	}
+
+	var sells int
+	for range ex.OrdersSeq(emul.OrdersOnSide(emul.SideSell)) {
+		sells++
+	}
+	var stops []emul.Order
+	for o := range ex.OrdersSeq(emul.OrdersInCategory(emul.ReasonCategoryStop)) {
+		stops = append(stops, o)
+	}
+	if sells != 2 || len(stops) != 1 || stops[0].Side != emul.SideBuy {
+		t.Fatalf("unexpected order filters: %d sells, stops %+v", sells, stops)
+	}
+	var wins []emul.Trade
+	for tr := range ex.TradesSeq(emul.WinningTrades) {
+		wins = append(wins, tr)
+	}
+	if len(wins) != 2 {
+		t.Fatalf("expected both trades to win, got %+v", wins)
+	}
+}
+
+func TestEmulatorAllReportsErrors(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bars := 0
+	for range emu.All() {
+		bars++
+	}
+	if err := emu.Err(); err == nil || errors.Is(err, emul.ErrNoMoreBars) || bars != 1 {
+		t.Fatalf("expected the bad bar to stop iteration after 1 bar, got %d bars, %v", bars, err)
+	}
+}
@@ -0,0 +1,88 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestTakeProfitLadderScalesOutUntilStopped(t *testing.T) {
+	bars := flatBars(100, 101, 103, 100)
+	bars[1].High = 102.5
+	bars[2].High = 105
+	bars[3].Low = 98
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetTakeProfitLadder(emul.TakeProfitRung{Gain: 0.02, Fraction: 0.6}, emul.TakeProfitRung{Gain: 0.04, Fraction: 0.6}); !errors.Is(err, emul.ErrInvalidLadder) {
+		t.Fatalf("ladder over the whole position: %v", err)
+	}
+	rungs := []emul.TakeProfitRung{{Gain: 0.02, Fraction: 0.25}, {Gain: 0.04, Fraction: 0.25}, {Gain: 0.08, Fraction: 0.5}}
+	if err := ex.SetTakeProfitLadder(rungs...); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetBracket(emul.Bracket{Stop: 99}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		_, fills, err := emu.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fills) != 1 || fills[0].Qty != 2.5 || fills[0].StopKind != emul.StopKindTarget || fills[0].PositionAfter != 7.5-2.5*float64(i) {
+			t.Fatalf("rung %d: %+v", i+1, fills)
+		}
+	}
+	// The stop closes what is left and cancels the +8% rung.
+	_, fills, err := emu.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 1 || fills[0].Reason != emul.ReasonStopLoss || fills[0].Qty != 5 || ex.TakeProfitLadder() != nil {
+		t.Fatalf("unexpected stop %+v", fills)
+	}
+	trades := emul.PairTrades(ex.Orders())
+	if len(trades) != 1 || trades[0].Qty != 10 || math.Abs(trades[0].ExitPrice-101) > 1e-9 || math.Abs(trades[0].PnL-10) > 1e-9 {
+		t.Fatalf("unexpected trade %+v", trades)
+	}
+}
+
+func TestTakeProfitLadderOnShort(t *testing.T) {
+	bars := flatBars(100, 96, 96)
+	bars[1].Low = 94
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenShort(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetTakeProfitLadder(emul.TakeProfitRung{Gain: 0.05, Fraction: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// Half covered at 95, half still short at 96: 1000 + 5*5 + 5*4.
+	bal := ex.Balance()
+	if bal.Position != -5 || math.Abs(bal.Equity-1045) > 1e-9 {
+		t.Fatalf("unexpected balance %+v", bal)
+	}
+	if levels := ex.TakeProfitLadder(); len(levels) != 1 || !levels[0].Filled || levels[0].Price != 95 {
+		t.Fatalf("unexpected ladder %+v", levels)
+	}
+}
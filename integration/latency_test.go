@@ -0,0 +1,62 @@
+package emul_test
+
+import (
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestNotificationsArriveOutOfOrder(t *testing.T) {
+	model, err := emul.NewLatencyModel(emul.LatencyConfig{
+		Base:             10 * time.Millisecond,
+		Jitter:           5 * time.Millisecond,
+		DelayProbability: 1,
+		Delay:            time.Second,
+		Seed:             7,
+	})
+	if err != nil {
+		t.Fatalf("new model: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if d := model.ResponseDelay(); d < 10*time.Millisecond || d >= 15*time.Millisecond {
+			t.Fatalf("response delay %v out of range", d)
+		}
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := emul.NewNotificationQueue(model)
+	q.Push(start, emul.Order{ID: 1})
+
+	fast := emul.NewNotificationQueue(nil)
+	fast.Push(start, emul.Order{ID: 1}, emul.Order{ID: 2})
+	if due := fast.Due(start); len(due) != 2 || due[0].Seq != 1 {
+		t.Fatalf("without a model notifications are immediate: %+v", due)
+	}
+
+	if due := q.Due(start.Add(500 * time.Millisecond)); len(due) != 0 || q.Pending() != 1 {
+		t.Fatalf("delayed notification delivered early: %+v", due)
+	}
+	due := q.Due(start.Add(2 * time.Second))
+	if len(due) != 1 || !due[0].Delayed || due[0].Deliver.Sub(start) < time.Second {
+		t.Fatalf("unexpected delivery %+v", due)
+	}
+
+	mixed, _ := emul.NewLatencyModel(emul.LatencyConfig{DelayProbability: 0.5, Delay: time.Second, Seed: 1})
+	q = emul.NewNotificationQueue(mixed)
+	for i := int64(1); i <= 20; i++ {
+		q.Push(start.Add(time.Duration(i)*time.Millisecond), emul.Order{ID: i})
+	}
+	delivered := append(q.Due(start.Add(100*time.Millisecond)), q.Due(start.Add(2*time.Second))...)
+	if len(delivered) != 20 {
+		t.Fatalf("delivered %d notifications", len(delivered))
+	}
+	outOfOrder := false
+	for i := 1; i < len(delivered); i++ {
+		if delivered[i].Seq < delivered[i-1].Seq {
+			outOfOrder = true
+		}
+	}
+	if !outOfOrder {
+		t.Fatalf("expected some fills to arrive after later ones")
+	}
+}
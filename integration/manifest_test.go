@@ -0,0 +1,61 @@
+package emul_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestManifestAndExport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "btc.csv")
+	writeCSV(t, path, "1704067200,1,2,0.5,1.5,10\n1704070800,1.5,2,1,1.8,10\n")
+	emu, err := emul.NewEmulatorFromCSV(1000, 0.001, 0, 0, path)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	emu.SetSeed(42)
+	m, err := emu.Manifest()
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if m.Config.StartUSD != 1000 || m.Config.Bars != 2 || m.Config.Seeds["run"] != 42 || len(m.ConfigHash) != 64 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	if len(m.Files) != 1 || m.Files[0].Size == 0 || len(m.Files[0].SHA256) != 64 {
+		t.Fatalf("unexpected files %+v", m.Files)
+	}
+	again, _ := emu.Manifest()
+	if again.ConfigHash != m.ConfigHash {
+		t.Fatalf("config hash is not stable")
+	}
+	emu.Exchange().SetLimitMode(emul.LimitsRest)
+	if changed, _ := emu.Manifest(); changed.ConfigHash == m.ConfigHash {
+		t.Fatalf("config hash must change with the config")
+	}
+
+	curve, err := emul.Run(emu, emul.StrategyFunc(func(*emul.Exchange, emul.OHLCBar, []emul.Order) error { return nil }))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := emul.ExportRun(out, emu, curve); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded emul.Manifest
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Files[0].SHA256 != m.Files[0].SHA256 {
+		t.Fatalf("manifest.json: %v %+v", err, decoded)
+	}
+	for _, name := range []string{"equity.csv", "run.json"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Fatalf("missing %s: %v", name, err)
+		}
+	}
+}
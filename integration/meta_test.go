@@ -0,0 +1,75 @@
+package emul_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestOrderMetaCarriedToFillsAndExports(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 110, 90, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	meta := map[string]string{"signal": "breakout"}
+	if _, err := ex.WithMeta(meta).OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	meta["signal"] = "changed"
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// A limit placed with metadata carries it to the fill on a later bar.
+	if _, err := ex.WithMeta(map[string]string{"note": "take profit"}).CloseLimit(110, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.WithMeta(map[string]string{"signal": "dip"}).OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.CloseDeal(emul.ReasonExit); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := ex.Orders()
+	want := []string{"signal=breakout", "note=take profit", "signal=dip", ""}
+	for i, o := range orders {
+		got := ""
+		for k, v := range o.Meta {
+			got = k + "=" + v
+		}
+		if got != want[i] {
+			t.Fatalf("order %d meta %v, want %q", i, o.Meta, want[i])
+		}
+	}
+	if intents := ex.Intents(); intents[0].Meta["signal"] != "breakout" || intents[len(intents)-1].Meta != nil {
+		t.Fatalf("intents %+v", intents)
+	}
+
+	groups := emul.GroupTradesByMeta(emul.PairTrades(orders), "signal")
+	if len(groups) != 2 || groups[0].Value != "breakout" || groups[0].Stats.Wins != 1 || groups[1].Value != "dip" || groups[1].Stats.Losses != 1 {
+		t.Fatalf("groups %+v", groups)
+	}
+
+	dir := t.TempDir()
+	if err := emul.ExportRun(dir, emu, nil); err != nil {
+		t.Fatal(err)
+	}
+	run, err := emul.ReadGolden(filepath.Join(dir, "run.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Orders[1].Meta["note"] != "take profit" {
+		t.Fatalf("exported meta %v", run.Orders[1].Meta)
+	}
+}
@@ -0,0 +1,90 @@
+package emul_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+// fractionJob buys x[0] of the cash on the first bar; on flatBars(100, ..., 200) the final
+// equity is 1000+1000*x[0], so targeting 1400 puts the optimum at 0.4.
+func fractionJob(bars []emul.OHLCBar) func(x []float64) emul.BatchJob {
+	return func(x []float64) emul.BatchJob {
+		return emul.BatchJob{
+			Config: emul.EmulatorConfig{StartUSD: 1000, Bars: bars},
+			Strategy: emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+				if len(ex.Orders()) == 0 && x[0] > 0 {
+					_, err := ex.OpenLong(x[0])
+					return err
+				}
+				return nil
+			}),
+		}
+	}
+}
+
+var targetEquity = emul.ObjectiveFunc(func(res emul.BatchResult) float64 {
+	d := res.Balance.Equity - 1400
+	return -d * d
+})
+
+func TestOptimizeCMAESFindsOptimum(t *testing.T) {
+	bars := flatBars(100, 150, 200)
+	cfg := emul.OptimizeConfig{
+		Params:    []emul.Param{{Name: "fraction", Min: 0, Max: 1}},
+		Job:       fractionJob(bars),
+		Objective: targetEquity,
+		Budget:    120,
+		Seed:      7,
+	}
+	runner := emul.NewBatchRunner(bars, 4)
+	res, err := runner.OptimizeCMAES(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Trials) != cfg.Budget {
+		t.Fatalf("ran %d trials, want %d", len(res.Trials), cfg.Budget)
+	}
+	if math.Abs(res.Best.Params[0]-0.4) > 0.01 {
+		t.Fatalf("best fraction %v, want about 0.4", res.Best.Params[0])
+	}
+	again, err := runner.OptimizeCMAES(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Best.Params[0] != res.Best.Params[0] {
+		t.Fatalf("same seed gave %v and %v", res.Best.Params[0], again.Best.Params[0])
+	}
+}
+
+func TestOptimizeGAEvolves(t *testing.T) {
+	bars := flatBars(100, 150, 200)
+	cfg := emul.OptimizeConfig{
+		Params:     []emul.Param{{Name: "fraction", Min: 0, Max: 1}},
+		Job:        fractionJob(bars),
+		Objective:  targetEquity,
+		Budget:     200,
+		Population: 20,
+		Seed:       3,
+	}
+	runner := emul.NewBatchRunner(bars, 4)
+	res, err := runner.OptimizeGA(context.Background(), cfg, emul.GAConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Trials) != cfg.Budget || res.Trials[len(res.Trials)-1].Generation == 0 {
+		t.Fatalf("ran %d trials", len(res.Trials))
+	}
+	if math.Abs(res.Best.Params[0]-0.4) > 0.02 {
+		t.Fatalf("best fraction %v, want about 0.4", res.Best.Params[0])
+	}
+	again, err := runner.OptimizeGA(context.Background(), cfg, emul.GAConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Best.Params[0] != res.Best.Params[0] {
+		t.Fatalf("same seed gave %v and %v", res.Best.Params[0], again.Best.Params[0])
+	}
+}
@@ -0,0 +1,113 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestBlackScholesParity(t *testing.T) {
+	spot, strike, years, vol, rate := 100.0, 105.0, 0.5, 0.6, 0.03
+	call := emul.BlackScholes(emul.OptionCall, spot, strike, years, vol, rate)
+	put := emul.BlackScholes(emul.OptionPut, spot, strike, years, vol, rate)
+	if parity := call - put - (spot - strike*math.Exp(-rate*years)); math.Abs(parity) > 1e-9 {
+		t.Fatalf("put-call parity off by %v (call %v put %v)", parity, call, put)
+	}
+	if got := emul.BlackScholes(emul.OptionPut, 90, 100, 0, vol, rate); got != 10 {
+		t.Fatalf("expired put = %v, want intrinsic 10", got)
+	}
+}
+
+func TestCoveredCallAssigned(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 105, 120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.EnableOptions(emul.OptionsConfig{IV: 0.8, BarsPerYear: 365}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.WriteOption(emul.OptionCall, 110, 3, 11); err == nil {
+		t.Fatalf("call larger than the long must be rejected")
+	}
+	pos, err := ex.WriteOption(emul.OptionCall, 110, 3, 10)
+	if err != nil {
+		t.Fatalf("write call: %v", err)
+	}
+	premium := pos.Premium * 10
+	if premium <= 0 || math.Abs(ex.Balance().Equity-1000) > 1e-9 {
+		t.Fatalf("writing at model price must not change equity: premium %v equity %v", premium, ex.Balance().Equity)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ex.OptionPositions()) != 0 || ex.Balance().Position != 0 {
+		t.Fatalf("call should be settled and the long called away")
+	}
+	orders := ex.Orders()
+	if last := orders[len(orders)-1]; last.Reason != emul.ReasonOptionAssigned {
+		t.Fatalf("last order reason %q", last.Reason)
+	}
+	// Upside is capped at the strike: 10 units sold at 120, 10*(120-110) paid back.
+	if want := 1100 + premium; math.Abs(ex.Balance().Equity-want) > 1e-9 {
+		t.Fatalf("equity %v, want %v", ex.Balance().Equity, want)
+	}
+}
+
+func TestProtectivePutAndSecuredPut(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, err := ex.BuyOption(emul.OptionPut, 95, 2, 1); !errors.Is(err, emul.ErrOptionsDisabled) {
+		t.Fatalf("want ErrOptionsDisabled, got %v", err)
+	}
+	if err := ex.EnableOptions(emul.OptionsConfig{IV: 0.5, BarsPerYear: 365}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(0.5); err != nil {
+		t.Fatal(err)
+	}
+	bought, err := ex.BuyOption(emul.OptionPut, 95, 2, 5)
+	if err != nil {
+		t.Fatalf("buy put: %v", err)
+	}
+	if _, err := ex.WriteOption(emul.OptionPut, 90, 2, 100); !errors.Is(err, emul.ErrInsufficientFunds) {
+		t.Fatalf("unsecured put must be rejected, got %v", err)
+	}
+	written, err := ex.WriteOption(emul.OptionPut, 90, 2, 2)
+	if err != nil {
+		t.Fatalf("write put: %v", err)
+	}
+	if bal := ex.Balance(); bal.OptionCollateral != 180 {
+		t.Fatalf("collateral %v, want 180", bal.OptionCollateral)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fills := ex.OptionFills()
+	if len(fills) != 4 || !fills[2].Settlement || fills[2].Cash != 75 || fills[3].Cash != -20 {
+		t.Fatalf("unexpected fills %+v", fills)
+	}
+	bal := ex.Balance()
+	want := 500 + 5*80 - bought.Premium*5 + written.Premium*2 + 75 - 20
+	if bal.OptionCollateral != 0 || math.Abs(bal.Equity-want) > 1e-9 {
+		t.Fatalf("equity %v, want %v (%+v)", bal.Equity, want, bal)
+	}
+}
@@ -0,0 +1,74 @@
+package emul_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+// trialsWithDrift builds one trial per drift: a random walk of per-bar returns with that mean.
+func trialsWithDrift(seed uint64, bars int, drifts ...float64) emul.OptimizeResult {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var res emul.OptimizeResult
+	for k, drift := range drifts {
+		equity := 1000.0
+		curve := make([]emul.EquityPoint, bars)
+		for i := range curve {
+			curve[i] = emul.EquityPoint{Tick: int64(i + 1), Equity: equity}
+			equity *= 1 + drift + 0.01*rng.NormFloat64()
+		}
+		t := emul.Trial{Params: []float64{float64(k)}, Score: drift, Equity: curve}
+		if k == 0 || t.Score > res.Best.Score {
+			res.Best = t
+		}
+		res.Trials = append(res.Trials, t)
+	}
+	return res
+}
+
+func TestOverfitDiagnosticsOnNoise(t *testing.T) {
+	noise := trialsWithDrift(1, 400, make([]float64, 20)...)
+	// With no skill, pick the trial that happened to do best.
+	for _, tr := range noise.Trials {
+		if tr.Equity[len(tr.Equity)-1].Equity > noise.Best.Equity[len(noise.Best.Equity)-1].Equity {
+			noise.Best = tr
+		}
+	}
+	pbo, err := emul.ProbabilityOfOverfitting(noise, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbo.Combinations != 70 || pbo.PBO < 0.2 || pbo.PBO > 0.8 {
+		t.Fatalf("noise: %d combinations, PBO %v", pbo.Combinations, pbo.PBO)
+	}
+	dsr, err := emul.DeflatedSharpe(noise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsr.Probability > 0.95 || dsr.Benchmark <= 0 {
+		t.Fatalf("noise: deflated Sharpe %+v", dsr)
+	}
+}
+
+func TestOverfitDiagnosticsOnSkill(t *testing.T) {
+	drifts := make([]float64, 10)
+	for k := range drifts {
+		drifts[k] = float64(k) * 0.001
+	}
+	skill := trialsWithDrift(2, 400, drifts...)
+	pbo, err := emul.ProbabilityOfOverfitting(skill, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbo.PBO > 0.1 {
+		t.Fatalf("skill: PBO %v", pbo.PBO)
+	}
+	dsr, err := emul.DeflatedSharpe(skill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsr.Probability < 0.95 || dsr.Trials != 10 || dsr.Observations != 399 {
+		t.Fatalf("skill: deflated Sharpe %+v", dsr)
+	}
+}
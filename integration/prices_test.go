@@ -0,0 +1,273 @@
+package emul_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestLoadDataRootKeepsFileOrder(t *testing.T) {
+	root := t.TempDir()
+	for year := 2015; year <= 2026; year++ {
+		var rows strings.Builder
+		for i := 0; i < 50; i++ {
+			ts := int64(year-1970)*365*86400 + int64(i)*86400
+			fmt.Fprintf(&rows, "%d,%d,%d,%d,%d,1\n", ts, year, year, year, year)
+		}
+		writeCSV(t, filepath.Join(root, "btc", "d", fmt.Sprintf("%d.csv", year)), rows.String())
+	}
+	writeCSV(t, filepath.Join(root, "btc", "d", "2014.csv"), "header only\n")
+
+	values, ohlc, maxValue, err := emul.LoadSeriesWithOHLCFromDataRoot(root, "btc", "d")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(values) != 12*50 || len(ohlc.Close) != len(values) {
+		t.Fatalf("unexpected length %d", len(values))
+	}
+	for i, v := range ohlc.Close {
+		if want := float64(2015 + i/50); v != want {
+			t.Fatalf("row %d: close %v, want %v", i, v, want)
+		}
+	}
+	if maxValue != 2026 {
+		t.Fatalf("max %v", maxValue)
+	}
+}
+
+func TestLoadBarsFromCSVFieldCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bars.csv")
+	writeCSV(t, path, strings.Join([]string{
+		"timestamp,open,high,low,close,volume",
+		"1704067200,1,2,0.5,1.5,10",
+		"1704153600,1,2,0.5,1.5",
+		" 1704240000 , 2 , 3 , 1 , 2.5 , 10 ,extra",
+		"1704326400,1,2,0.5,1.5,",
+		"",
+	}, "\n"))
+	bars, err := emul.LoadBarsFromCSV(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(bars) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(bars))
+	}
+	if bars[1].Close != 2.5 || bars[1].Time.Unix() != 1704240000 {
+		t.Fatalf("unexpected padded row %+v", bars[1])
+	}
+}
+
+func TestLoadBarsFromCSVLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bars.csv")
+	writeCSV(t, path, "# "+strings.Repeat("x", 4096)+"\n1704067200,1,2,0.5,1.5,10\n1704153600,1,2,0.5,1.5,10\n")
+	if _, err := emul.LoadBarsFromCSVWithOptions(path, emul.CSVReaderOptions{MaxLineBytes: 1024}); err == nil {
+		t.Fatalf("expected oversized line to fail the load")
+	}
+
+	var skipped []int
+	opts := emul.CSVReaderOptions{
+		MaxLineBytes:  1024,
+		SkipLongLines: true,
+		Warn:          func(_ string, size int) { skipped = append(skipped, size) },
+	}
+	bars, err := emul.LoadBarsFromCSVWithOptions(path, opts)
+	if err != nil {
+		t.Fatalf("load with skip: %v", err)
+	}
+	if len(bars) != 2 {
+		t.Fatalf("expected 2 bars, got %d", len(bars))
+	}
+	if len(skipped) != 1 || skipped[0] != 4098 {
+		t.Fatalf("unexpected skipped lines %v", skipped)
+	}
+}
+
+func TestLoadBarsFromCSVHeaderAndDates(t *testing.T) {
+	dir := t.TempDir()
+	coinbase := filepath.Join(dir, "coinbase.csv")
+	writeCSV(t, coinbase, strings.Join([]string{
+		"time,low,high,open,close,volume",
+		"2024-01-01T00:00:00Z,90,110,100,105,1",
+		"2024-01-01 01:00:00,95,115,105,110,1",
+		"",
+	}, "\n"))
+	bars, err := emul.LoadBarsFromCSV(coinbase)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(bars) != 2 {
+		t.Fatalf("expected 2 bars, got %d", len(bars))
+	}
+	if bars[0].Open != 100 || bars[0].Low != 90 || bars[0].High != 110 || bars[0].Close != 105 {
+		t.Fatalf("columns not mapped by header: %+v", bars[0])
+	}
+	if got := bars[1].Time; !got.Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected time %s", got)
+	}
+
+	kraken := filepath.Join(dir, "kraken.csv")
+	writeCSV(t, kraken, "\"Date\",\"Open\",\"High\",\"Low\",\"Close\",\"Volume\"\n\"2024-03-05\",1,2,0.5,1.5,10\n")
+	bars, err = emul.LoadBarsFromCSV(kraken)
+	if err != nil {
+		t.Fatalf("load quoted: %v", err)
+	}
+	if len(bars) != 1 || !bars[0].Time.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected bars %+v", bars)
+	}
+}
+
+func TestLoadBarsFromCSVEpochUnits(t *testing.T) {
+	want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, ts := range []string{"1704067200", "1704067200000", "1704067200000000", "1704067200000000000"} {
+		path := filepath.Join(t.TempDir(), "bars.csv")
+		writeCSV(t, path, ts+",1,2,0.5,1.5,10\n")
+		bars, err := emul.LoadBarsFromCSV(path)
+		if err != nil {
+			t.Fatalf("%s: %v", ts, err)
+		}
+		if !bars[0].Time.Equal(want) {
+			t.Fatalf("%s parsed as %s", ts, bars[0].Time)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "bars.csv")
+	writeCSV(t, path, "1704067200,1,2,0.5,1.5,10\n")
+	bars, err := emul.LoadBarsFromCSVWithOptions(path, emul.CSVReaderOptions{EpochUnit: emul.EpochMillis})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if want := time.UnixMilli(1704067200).UTC(); !bars[0].Time.Equal(want) {
+		t.Fatalf("explicit unit ignored: %s", bars[0].Time)
+	}
+}
+
+func TestCSVReaderOptionsArePerCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bars.csv")
+	writeCSV(t, path, "1704067200,1,2,0.5,1.5,10\n")
+	units := []emul.EpochUnit{emul.EpochSeconds, emul.EpochMillis}
+	want := []time.Time{time.Unix(1704067200, 0).UTC(), time.UnixMilli(1704067200).UTC()}
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			bars, err := emul.LoadBarsFromCSVWithOptions(path, emul.CSVReaderOptions{EpochUnit: units[k]})
+			if err == nil && !bars[0].Time.Equal(want[k]) {
+				err = fmt.Errorf("unit %d read %s", units[k], bars[0].Time)
+			}
+			errs <- err
+		}(i % 2)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadDataRootDetectsIntervalMismatch(t *testing.T) {
+	root := t.TempDir()
+	writeCSV(t, filepath.Join(root, "btc", "m", "2024.csv"), strings.Join([]string{
+		"1704067200,1,2,0.5,1.5,10",
+		"1704070800,1,2,0.5,1.5,10",
+		"1704074400,1,2,0.5,1.5,10",
+		"1704085200,1,2,0.5,1.5,10",
+		"",
+	}, "\n"))
+	var warned []error
+	opts := emul.CSVReaderOptions{WarnInterval: func(err error) { warned = append(warned, err) }}
+	if _, _, _, err := emul.LoadSeriesWithOHLCFromDataRootWithOptions(root, "btc", "m", nil, nil, opts); err != nil {
+		t.Fatalf("mismatch should only warn by default: %v", err)
+	}
+	if len(warned) != 1 || !errors.Is(warned[0], emul.ErrIntervalMismatch) {
+		t.Fatalf("expected one interval warning, got %v", warned)
+	}
+
+	_, _, _, err := emul.LoadSeriesWithOHLCFromDataRootWithOptions(root, "btc", "m", nil, nil, emul.CSVReaderOptions{StrictIntervals: true})
+	if !errors.Is(err, emul.ErrIntervalMismatch) {
+		t.Fatalf("expected interval mismatch, got %v", err)
+	}
+}
+
+func TestInferInterval(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{start, start.Add(time.Hour), start.Add(2 * time.Hour), start.Add(5 * time.Hour), start.Add(6 * time.Hour)}
+	if d, ok := emul.InferInterval(times); !ok || d != time.Hour {
+		t.Fatalf("got %s %v", d, ok)
+	}
+	if _, ok := emul.InferInterval(times[:1]); ok {
+		t.Fatalf("single timestamp should not infer an interval")
+	}
+}
+
+func TestCustomIntervals(t *testing.T) {
+	cases := map[string]string{"15m": "15m", "4H": "4h", "1h": "h", "1w": "1w", "d": "d", "60m": "60m"}
+	for raw, want := range cases {
+		got, err := emul.ParseInterval(raw)
+		if err != nil || got != want {
+			t.Fatalf("ParseInterval(%q) = %q, %v", raw, got, err)
+		}
+	}
+	for _, raw := range []string{"", "0m", "5x", "m5"} {
+		if _, err := emul.ParseInterval(raw); err == nil {
+			t.Fatalf("ParseInterval(%q) should fail", raw)
+		}
+	}
+	if got := emul.PointsPerDayForInterval("15m"); got != 96 {
+		t.Fatalf("15m points per day = %d", got)
+	}
+	if got := emul.PointsPerDayForInterval("4h"); got != 6 {
+		t.Fatalf("4h points per day = %d", got)
+	}
+
+	root := t.TempDir()
+	var rows strings.Builder
+	for i := 0; i < 8; i++ {
+		fmt.Fprintf(&rows, "%d,%d,%d,%d,%d,1\n", 1704067200+i*900, 10+i, 20+i, 5+i, 11+i)
+	}
+	writeCSV(t, filepath.Join(root, "btc", "15m", "2024.csv"), rows.String())
+	values, ohlc, _, err := emul.LoadSeriesWithOHLCFromDataRoot(root, "btc", "15m")
+	if err != nil {
+		t.Fatalf("load 15m: %v", err)
+	}
+	bars, _ := emul.BarsFromSeries(values, ohlc)
+	hourly, err := emul.Resample(bars, "1h")
+	if err != nil {
+		t.Fatalf("resample: %v", err)
+	}
+	if len(hourly) != 2 {
+		t.Fatalf("expected 2 hourly bars, got %d", len(hourly))
+	}
+	if h := hourly[1]; h.Open != 14 || h.High != 27 || h.Low != 9 || h.Close != 18 {
+		t.Fatalf("unexpected hourly bar %+v", h)
+	}
+	cat, err := emul.DiscoverDataRoot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	btc, _ := cat.Coin("btc")
+	if _, ok := btc.Interval("15m"); !ok {
+		t.Fatalf("15m directory not discovered")
+	}
+}
+
+func TestVolumeColumnIsLoaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "v.csv")
+	writeCSV(t, path, "time,open,high,low,close,vol\n1704067200,1,2,0.5,1.5,42\n1704070800,1,2,0.5,1.5,n/a\n")
+	bars, err := emul.LoadBarsFromCSV(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(bars) != 2 || bars[0].Volume != 42 || bars[1].Volume != 0 {
+		t.Fatalf("unexpected volumes %+v", bars)
+	}
+}
@@ -0,0 +1,41 @@
+package emul_test
+
+import (
+	"context"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestBatchJobsPrunedEarly(t *testing.T) {
+	bars := flatBars(100, 90, 80, 70, 60, 50, 40, 30, 20, 10)
+	buyAndHold := func() emul.Strategy {
+		return emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+			if ex.Balance().Position == 0 && len(ex.Orders()) == 0 {
+				_, err := ex.OpenLong(1)
+				return err
+			}
+			return nil
+		})
+	}
+	jobs := []emul.BatchJob{
+		{Name: "drawdown", Config: emul.EmulatorConfig{StartUSD: 1000}, Strategy: buyAndHold(), Prune: emul.PruneDrawdown(0.25)},
+		{Name: "sharpe", Config: emul.EmulatorConfig{StartUSD: 1000}, Strategy: buyAndHold(), Prune: emul.PruneSharpe(0.4, 0)},
+		{Name: "full", Config: emul.EmulatorConfig{StartUSD: 1000}, Strategy: buyAndHold()},
+	}
+	results := emul.NewBatchRunner(bars, 2).RunAll(context.Background(), jobs)
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatalf("%s: %v", res.Name, res.Err)
+		}
+	}
+	if r := results[0]; r.PruneReason == "" || len(r.Equity) != 4 {
+		t.Fatalf("drawdown job ran %d bars (%q)", len(r.Equity), r.PruneReason)
+	}
+	if r := results[1]; r.PruneReason == "" || len(r.Equity) != 4 {
+		t.Fatalf("sharpe job ran %d bars (%q)", len(r.Equity), r.PruneReason)
+	}
+	if r := results[2]; r.PruneReason != "" || len(r.Equity) != len(bars) {
+		t.Fatalf("unpruned job ran %d bars", len(r.Equity))
+	}
+}
@@ -0,0 +1,108 @@
+package emul_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestRateLimiterWeightAndWindow(t *testing.T) {
+	clock := emul.NewSimClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	rl := emul.NewRateLimiter(10, time.Minute)
+	rl.SetClock(clock)
+	for i, want := range []int{4, 8} {
+		if used, err := rl.Allow("a", 4); err != nil || used != want {
+			t.Fatalf("call %d: used %d, err %v", i, used, err)
+		}
+	}
+	if used, err := rl.Allow("a", 4); !errors.Is(err, emul.ErrRateLimited) || used != 8 {
+		t.Fatalf("over the limit: used %d, err %v", used, err)
+	}
+	if used, err := rl.Allow("b", 10); err != nil || used != 10 {
+		t.Fatalf("keys must be charged separately: used %d, err %v", used, err)
+	}
+	clock.Advance(time.Minute)
+	if rl.Used("a") != 0 {
+		t.Fatalf("weight must reset with the window, got %d", rl.Used("a"))
+	}
+	if used, err := rl.Allow("a", 4); err != nil || used != 4 {
+		t.Fatalf("new window: used %d, err %v", used, err)
+	}
+	rl.SetClock(nil)
+	if _, err := rl.Allow("c", 1); err != nil {
+		t.Fatalf("a nil clock must fall back to the wall clock: %v", err)
+	}
+}
+
+func TestAccountWeightRejectsUnknownKeys(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.AddAccount("bot", 1000); err != nil {
+		t.Fatal(err)
+	}
+	emu.SetRateLimiter(emul.NewRateLimiter(5, time.Minute))
+	called := false
+	if _, err := emu.WithAccountWeight("missing", 1, func(*emul.Exchange) error { called = true; return nil }); err != emul.ErrUnknownAccount || called {
+		t.Fatalf("unknown key: err %v, called %v", err, called)
+	}
+	if used, err := emu.WithAccountWeight("bot", 5, func(*emul.Exchange) error { return nil }); err != nil || used != 5 {
+		t.Fatalf("used %d, err %v", used, err)
+	}
+	if _, err := emu.WithAccountWeight("bot", 1, func(*emul.Exchange) error { called = true; return nil }); !errors.Is(err, emul.ErrRateLimited) || called {
+		t.Fatalf("over the limit: err %v, called %v", err, called)
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	clock := emul.NewSimClock(time.Date(2024, 1, 1, 0, 0, 15, 0, time.UTC))
+	rl := emul.NewRateLimiter(3, time.Minute)
+	rl.SetClock(clock)
+	served := 0
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.AddAccount("k", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h := emul.RateLimitHandler(rl, emu.HasAccount, func(*http.Request) int { return 2 }, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		served++
+	}))
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v3/order", nil)
+		if key != "" {
+			req.Header.Set(emul.HeaderAPIKey, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do(""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing key returned %d", rec.Code)
+	}
+	for _, key := range []string{"x1", "x2"} {
+		if rec := do(key); rec.Code != http.StatusUnauthorized || rl.Used(key) != 0 {
+			t.Fatalf("unknown key %s returned %d and was charged %d", key, rec.Code, rl.Used(key))
+		}
+	}
+	if rec := do("k"); rec.Code != http.StatusOK || rec.Header().Get(emul.HeaderUsedWeight) != "2" {
+		t.Fatalf("first call: %d, weight %q", rec.Code, rec.Header().Get(emul.HeaderUsedWeight))
+	}
+	rec := do("k")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "45" || rec.Header().Get(emul.HeaderUsedWeight) != "2" {
+		t.Fatalf("second call: %d, retry %q, weight %q", rec.Code, rec.Header().Get("Retry-After"), rec.Header().Get(emul.HeaderUsedWeight))
+	}
+	if served != 1 {
+		t.Fatalf("rejected requests must not be served, served %d", served)
+	}
+	clock.Advance(45 * time.Second)
+	if rec := do("k"); rec.Code != http.StatusOK || served != 2 {
+		t.Fatalf("after the window: %d, served %d", rec.Code, served)
+	}
+}
@@ -0,0 +1,66 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestRegimeBreakdownSplitsByMarketState(t *testing.T) {
+	// 40 ranging bars, 40 bars rising 1% each, then 40 bars swinging with a growing amplitude.
+	closes := make([]float64, 0, 120)
+	for i := 0; i < 40; i++ {
+		closes = append(closes, 100+0.1*float64(i%2))
+	}
+	for i := 0; i < 40; i++ {
+		closes = append(closes, closes[len(closes)-1]*1.01)
+	}
+	base := closes[len(closes)-1]
+	for i := 0; i < 40; i++ {
+		swing := 0.02 + 0.002*float64(i)
+		if i%2 == 0 {
+			swing = -swing
+		}
+		closes = append(closes, base*(1+swing))
+	}
+	bars := flatBars(closes...)
+	cfg := emul.RegimeConfig{VolWindow: 5, MAWindow: 5, SlopeBars: 3}
+	labels, err := emul.ClassifyRegimes(bars, cfg)
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if labels[0] != emul.RegimeUnknown || labels[6] != emul.RegimeUnknown {
+		t.Fatalf("warm-up bars must be unknown: %v %v", labels[0], labels[6])
+	}
+	for i, want := range map[int]emul.Regime{20: emul.RegimeRange, 39: emul.RegimeRange, 60: emul.RegimeTrendUp, 79: emul.RegimeTrendUp, 110: emul.RegimeHighVol, 119: emul.RegimeHighVol} {
+		if labels[i] != want {
+			t.Fatalf("bar %d labeled %v, want %v", i+1, labels[i], want)
+		}
+	}
+
+	// The strategy only earns during the trend: +0.5% per trend bar.
+	curve := make([]emul.EquityPoint, len(bars))
+	equity := 1000.0
+	for i := range curve {
+		if labels[i] == emul.RegimeTrendUp {
+			equity *= 1.005
+		}
+		curve[i] = emul.EquityPoint{Tick: int64(i + 1), Time: bars[i].Time, Equity: equity}
+	}
+	trades := []emul.Trade{{EntryTick: 61, ExitTick: 70, PnL: 20}, {EntryTick: 21, ExitTick: 30, PnL: -5}}
+	rep, err := emul.RegimeBreakdown(bars, curve, trades, cfg)
+	if err != nil {
+		t.Fatalf("breakdown: %v", err)
+	}
+	trend, rng := rep.ByRegime[emul.RegimeTrendUp], rep.ByRegime[emul.RegimeRange]
+	if math.Abs(trend.Return-(math.Pow(1.005, float64(trend.Bars))-1)) > 1e-9 || rng.Return != 0 {
+		t.Fatalf("unexpected regime returns: trend %+v range %+v", trend, rng)
+	}
+	if trend.Trades.Wins != 1 || rng.Trades.Losses != 1 || math.Abs(trend.Share-float64(trend.Bars)/120) > 1e-12 {
+		t.Fatalf("unexpected trade split: trend %+v range %+v", trend, rng)
+	}
+	if emul.RegimeHighVol.String() != "high_vol" {
+		t.Fatal("unexpected regime name")
+	}
+}
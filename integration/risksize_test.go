@@ -0,0 +1,57 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestRiskSizeLosesBudgetAtStop(t *testing.T) {
+	emu, err := emul.NewEmulator(10000, 0.001, 0, 0, flatBars(100, 95))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	ex.SetSymbol(emul.SymbolSpec{Symbol: "btcusdt", LotSize: 0.01})
+	if _, err := ex.RiskSize(emul.SideBuy, 0, 95, 0.01); !errors.Is(err, emul.ErrPriceNotSet) {
+		t.Fatalf("sizing before the first bar: %v", err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.RiskSize(emul.SideBuy, 0, 105, 0.01); !errors.Is(err, emul.ErrInvalidStop) {
+		t.Fatalf("stop above a long entry: %v", err)
+	}
+	// Risk 100 USD: 5 of price move plus about 0.1 entry and 0.095 exit fee per unit = 19.24 units.
+	size, err := ex.RiskSize(emul.SideBuy, 0, 95, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(size.Qty-19.24) > 1e-9 || size.Capped || size.Risk > 100 || size.Risk < 99.9 {
+		t.Fatalf("unexpected size %+v", size)
+	}
+	order, err := ex.OpenLong(size.Fraction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(order.Qty-size.Qty) > 1e-9 {
+		t.Fatalf("filled %v, sized %v", order.Qty, size.Qty)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.CloseDeal(emul.ReasonStopLoss); err != nil {
+		t.Fatal(err)
+	}
+	if loss := 10000 - ex.Balance().Equity; math.Abs(loss-size.Risk) > 1e-6 {
+		t.Fatalf("lost %v at the stop, sized for %v", loss, size.Risk)
+	}
+
+	// A tight stop asks for more than the account holds: the size is capped at all the cash.
+	tight, err := ex.RiskSize(emul.SideSell, 0, 95.01, 0.5)
+	if err != nil || !tight.Capped || tight.Fraction != 1 {
+		t.Fatalf("unexpected capped size %+v: %v", tight, err)
+	}
+}
@@ -0,0 +1,47 @@
+package emul_test
+
+import (
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestRollingRobustnessFlagsLosingWindows(t *testing.T) {
+	// 100 days rising by 1, then 100 days falling by 0.5.
+	equities := make([]float64, 200)
+	for i := range equities {
+		if i < 100 {
+			equities[i] = 100 + float64(i)
+		} else {
+			equities[i] = 199 - 0.5*float64(i-99)
+		}
+	}
+	curve := dailyCurve(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), equities...)
+	trades := []emul.Trade{{ExitTick: 20, PnL: 5}, {ExitTick: 150, PnL: -3}}
+	rep, err := emul.RollingRobustness(curve, trades, emul.RobustnessConfig{})
+	if err != nil {
+		t.Fatalf("robustness: %v", err)
+	}
+	// Windows start on days 0, 30, 60, 90 and the last one is pulled back to end on day 199.
+	if len(rep.Windows) != 5 || rep.Losing != 2 || rep.LosingShare != 0.4 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	for i, w := range rep.Windows {
+		if w.Losing != (i >= 3) || (w.Losing && (w.Return >= 0 || w.Sharpe >= 0 || w.MaxDrawdown <= 0)) {
+			t.Fatalf("window %d: %+v", i, w)
+		}
+	}
+	if last := rep.Windows[4]; !last.End.Equal(curve[199].Time) || last.StartEquity != 194 || last.EndEquity != 149 {
+		t.Fatalf("last window %+v", last)
+	}
+	if rep.Windows[0].Trades.Wins != 1 || rep.Windows[3].Trades.Losses != 1 || rep.Windows[1].Trades.Trades != 0 {
+		t.Fatal("trades must be counted in the windows they close in")
+	}
+	if rep.WorstReturn != rep.Windows[4].Return || rep.BestReturn != rep.Windows[0].Return {
+		t.Fatalf("worst %v best %v", rep.WorstReturn, rep.BestReturn)
+	}
+	if _, err := emul.RollingRobustness(curve[:50], nil, emul.RobustnessConfig{}); err == nil {
+		t.Fatal("expected an error for a curve shorter than one window")
+	}
+}
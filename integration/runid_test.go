@@ -0,0 +1,47 @@
+package emul_test
+
+import (
+	"regexp"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestRunIDNamespacesOrders(t *testing.T) {
+	id := emul.NewRunID(7)
+	if id != emul.NewRunID(7) || id == emul.NewRunID(8) {
+		t.Fatalf("run IDs must be deterministic per seed: %s", id)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Fatalf("not a version-4 UUID: %s", id)
+	}
+
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 110, 110))
+	if err != nil {
+		t.Fatal(err)
+	}
+	emu.SetRunID(id)
+	if err := emu.AddAccount("alice", 1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := emul.Run(emu, fractionStrategy(1)); err != nil {
+		t.Fatal(err)
+	}
+	orders := emu.Exchange().Orders()
+	if len(orders) != 2 || orders[0].RunID != id || orders[1].GlobalID() != id+"-2" {
+		t.Fatalf("unexpected orders %+v", orders)
+	}
+	var acctID string
+	if err := emu.WithAccount("alice", func(ex *emul.Exchange) error { acctID = ex.RunID(); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if acctID != id+".alice" {
+		t.Fatalf("unexpected account run ID %q", acctID)
+	}
+	if m, err := emu.Manifest(); err != nil || m.RunID != id {
+		t.Fatalf("manifest run ID %q, %v", m.RunID, err)
+	}
+	if (emul.Order{ID: 3}).GlobalID() != "3" {
+		t.Fatal("orders without a run keep the bare ID")
+	}
+}
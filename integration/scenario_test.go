@@ -0,0 +1,64 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestApplyScenarioFlashCrash(t *testing.T) {
+	bars := syntheticBars(10, 100, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range bars {
+		bars[i].Time = start.Add(time.Duration(i) * time.Hour)
+	}
+	out, err := emul.ApplyScenario(bars, emul.Shock{
+		Kind: emul.ShockPrice,
+		At:   start.Add(2 * time.Hour),
+		Bars: 3,
+		Move: -0.30,
+	})
+	if err != nil {
+		t.Fatalf("apply scenario: %v", err)
+	}
+	if out[1].Close != bars[1].Close {
+		t.Fatalf("bars before the shock must be untouched")
+	}
+	if got := out[4].Close / bars[4].Close; math.Abs(got-0.70) > 1e-9 {
+		t.Fatalf("expected -30%% after the window, got ratio %.6f", got)
+	}
+	if got := out[9].Close / bars[9].Close; math.Abs(got-0.70) > 1e-9 {
+		t.Fatalf("expected shifted level to persist, got ratio %.6f", got)
+	}
+	for i, b := range out {
+		if b.Low > b.Open || b.Low > b.Close || b.High < b.Open || b.High < b.Close {
+			t.Fatalf("bar %d has inconsistent OHLC: %+v", i, b)
+		}
+	}
+	if bars[4].Close != 100 {
+		t.Fatalf("input bars must not be modified")
+	}
+}
+
+func TestApplyScenarioVolatilityIsContinuous(t *testing.T) {
+	bars := syntheticBars(10, 100, 1)
+	out, err := emul.ApplyScenario(bars, emul.Shock{
+		Kind:          emul.ShockVolatility,
+		Index:         3,
+		Bars:          4,
+		VolMultiplier: 2,
+	})
+	if err != nil {
+		t.Fatalf("apply scenario: %v", err)
+	}
+	r := math.Log(bars[4].Close / bars[3].Close)
+	got := math.Log(out[4].Close / out[3].Close)
+	if math.Abs(got-2*r) > 1e-9 {
+		t.Fatalf("expected doubled log return %.6f, got %.6f", 2*r, got)
+	}
+	if out[7].Open != out[6].Close {
+		t.Fatalf("series must stay continuous after the window")
+	}
+}
@@ -0,0 +1,76 @@
+package emul_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestStatsHandlerServesSessionStats(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 120, 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		if ex.Balance().Position == 0 && len(ex.Orders()) == 0 {
+			if _, err := ex.OpenLong(1); err != nil {
+				return err
+			}
+			ex.SetLimitMode(emul.LimitsRest)
+			_, err := ex.CloseLimit(200, "", "")
+			return err
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(emul.StatsHandler(emu))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats emul.SessionStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Tick != 3 || stats.Equity != 900 || stats.PeakEquity != 1200 || math.Abs(stats.Drawdown-0.25) > 1e-12 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.PositionSide != emul.SideBuy || stats.PositionQty != 10 || stats.Fills != 1 || stats.PendingOrders != 1 || stats.StartedAt.IsZero() {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	post, err := http.Post(srv.URL+"/stats", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST returned %d", post.StatusCode)
+	}
+}
+
+func TestSessionUptimeFollowsClock(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := emul.NewSimClock(start)
+	emu.SetClock(clock)
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(90 * time.Minute)
+	if stats := emu.SessionStats(); !stats.StartedAt.Equal(start) || stats.Uptime != 90*time.Minute {
+		t.Fatalf("uptime must follow the clock: %+v", stats)
+	}
+}
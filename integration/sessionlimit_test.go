@@ -0,0 +1,59 @@
+package emul_test
+
+import (
+	"errors"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestSessionLossLimitBlocksEntriesUntilNextDay(t *testing.T) {
+	closes := make([]float64, 26)
+	for i := range closes {
+		closes[i] = 100
+	}
+	closes[2] = 94
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(closes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetSessionLimit(emul.SessionLimit{MaxLossPct: 0.05}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Down 6% on the day: the session locks and a risk event is raised.
+	events := ex.RiskEvents()
+	if len(events) != 1 || events[0].Rule != emul.RuleSessionLoss || events[0].Tick != 3 || events[0].PnL != -60 {
+		t.Fatalf("unexpected risk events %+v", events)
+	}
+	if _, err := ex.CloseDeal(""); err != nil {
+		t.Fatalf("closing must stay allowed: %v", err)
+	}
+	if _, err := ex.OpenLong(1); !errors.Is(err, emul.ErrSessionLossLimit) {
+		t.Fatalf("entry after the breach: %v", err)
+	}
+	// The next UTC day starts a fresh session.
+	for i := 3; i < 25; i++ {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatalf("entry in the next session: %v", err)
+	}
+	sessions := ex.Sessions()
+	if len(sessions) != 2 || !sessions[0].Locked || sessions[0].PnL != -60 || sessions[1].Locked || sessions[1].StartEquity != 940 {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+}
@@ -0,0 +1,43 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestCompareRunsPairedTest(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// A flat, B earns 1%, 2%, 3% per period twice over: mean 2%, sample sd 0.894%.
+	a := dailyCurve(start, 100, 100, 100, 100, 100, 100, 100)
+	b := []float64{100}
+	for _, r := range []float64{0.01, 0.02, 0.03, 0.01, 0.02, 0.03} {
+		b = append(b, b[len(b)-1]*(1+r))
+	}
+	cmp, err := emul.CompareRuns(a, dailyCurve(start, b...), emul.CompareConfig{Seed: 1, Resamples: 999})
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	se := math.Sqrt(0.00008 / 6)
+	if cmp.Periods != 6 || math.Abs(cmp.Diff-0.02) > 1e-12 || math.Abs(cmp.StdErr-se) > 1e-12 {
+		t.Fatalf("unexpected comparison %+v", cmp)
+	}
+	// t = 5.477 with 5 degrees of freedom: two-sided p = 0.00277, t(0.975) = 2.5706.
+	if math.Abs(cmp.PValue-0.00277) > 1e-4 || math.Abs(cmp.CILow-(0.02-2.5706*se)) > 1e-5 {
+		t.Fatalf("unexpected t-test %+v", cmp)
+	}
+	if cmp.BootPValue > 0.01 || cmp.BootLow < 0.01 || cmp.BootHigh > 0.03 || cmp.BootLow >= cmp.BootHigh {
+		t.Fatalf("unexpected bootstrap %+v", cmp)
+	}
+
+	// Swapping the runs flips the sign and keeps the p-value.
+	rev, err := emul.CompareRuns(dailyCurve(start, b...), a, emul.CompareConfig{Seed: 1, Resamples: 999, BlockSize: 2})
+	if err != nil || math.Abs(rev.Diff+0.02) > 1e-12 || math.Abs(rev.PValue-cmp.PValue) > 1e-12 {
+		t.Fatalf("reversed comparison %+v: %v", rev, err)
+	}
+	if _, err := emul.CompareRuns(a[:2], a[:2], emul.CompareConfig{}); err == nil {
+		t.Fatal("expected an error for too few periods")
+	}
+}
@@ -0,0 +1,84 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func tradesWithReturns(returns ...float64) []emul.Trade {
+	trades := make([]emul.Trade, len(returns))
+	for i, r := range returns {
+		trades[i].Return = r
+	}
+	return trades
+}
+
+func TestKellyFraction(t *testing.T) {
+	cases := []struct {
+		name     string
+		trades   []emul.Trade
+		lookback int
+		scale    float64
+		want     float64
+	}{
+		{"edge", tradesWithReturns(0.02, -0.01, 0.02, -0.01, 0.02), 0, 1, 0.4},
+		{"half kelly", tradesWithReturns(0.02, -0.01, 0.02, -0.01, 0.02), 0, 0.5, 0.2},
+		{"even odds", tradesWithReturns(0.01, -0.01, 0.03, -0.03), 0, 1, 0},
+		{"negative edge clamps to zero", tradesWithReturns(0.01, -0.01, -0.01, -0.01), 0, 1, 0},
+		{"capped at one", tradesWithReturns(0.5, 0.5, 0.5, -0.001), 0, 2, 1},
+		{"lookback drops old losses", tradesWithReturns(-0.5, -0.5, 0.02, -0.01, 0.02, -0.01, 0.02), 5, 1, 0.4},
+		{"no losses", tradesWithReturns(0.01, 0.02), 0, 1, 0},
+		{"no trades", nil, 0, 1, 0},
+		{"NaN return", tradesWithReturns(math.NaN(), 0.02, -0.01), 0, 1, 0.25},
+	}
+	for _, tc := range cases {
+		if got := emul.KellyFraction(tc.trades, tc.lookback, tc.scale); math.Abs(got-tc.want) > 1e-12 {
+			t.Fatalf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestVolTargetFraction(t *testing.T) {
+	cases := []struct {
+		name   string
+		target float64
+		asset  float64
+		want   float64
+	}{
+		{"scales down", 0.01, 0.04, 0.25},
+		{"capped at full equity", 0.04, 0.01, 1},
+		{"zero asset vol", 0.01, 0, 0},
+		{"NaN asset vol", 0.01, math.NaN(), 0},
+		{"infinite asset vol", 0.01, math.Inf(1), 0},
+		{"zero target", 0, 0.02, 0},
+	}
+	for _, tc := range cases {
+		if got := emul.VolTargetFraction(tc.target, tc.asset); math.Abs(got-tc.want) > 1e-12 {
+			t.Fatalf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	// A flat series has zero variance and therefore no size.
+	flat := flatBars(100, 100, 100, 100, 100)
+	if vol := emul.RealizedVol(flat, 3); vol != 0 || emul.VolTargetFraction(0.01, vol) != 0 {
+		t.Fatalf("flat series: vol %v", vol)
+	}
+	if vol := emul.RealizedVol(flat, 10); vol != 0 {
+		t.Fatalf("too few bars must give 0, got %v", vol)
+	}
+}
+
+func TestATRRiskFraction(t *testing.T) {
+	bars := flatBars(100, 100, 100)
+	for i := range bars {
+		bars[i].High, bars[i].Low = 101, 99
+	}
+	// ATR 2 on a 100 price: a 2 ATR stop is 4%, so 1% risk buys 25% of equity.
+	if got := emul.ATRRiskFraction(bars, 2, 2, 0.01); math.Abs(got-0.25) > 1e-12 {
+		t.Fatalf("fraction %v", got)
+	}
+	if got := emul.ATRRiskFraction(bars, 5, 2, 0.01); got != 0 {
+		t.Fatalf("not enough bars must give 0, got %v", got)
+	}
+}
@@ -0,0 +1,48 @@
+package emul_test
+
+import (
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestSplitByDateWithEmbargo(t *testing.T) {
+	bars := flatBars(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	splits, err := emul.SplitByDate(bars, emul.SplitConfig{
+		ValidationStart: bars[4].Time,
+		TestStart:       bars[7].Time,
+		Embargo:         time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]float64{
+		emul.SegmentTrain:      {1, 3},
+		emul.SegmentValidation: {5, 6},
+		emul.SegmentTest:       {8, 10},
+	}
+	for name, w := range want {
+		seg, ok := splits.Segment(name)
+		if !ok || seg.Bars[0].Close != w[0] || seg.Bars[len(seg.Bars)-1].Close != w[1] {
+			t.Fatalf("%s: unexpected segment %+v", name, seg)
+		}
+	}
+
+	var seen []float64
+	emu, curve, err := splits.RunSegment(emul.SegmentTest, emul.EmulatorConfig{StartUSD: 1000}, emul.StrategyFunc(func(_ *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		seen = append(seen, bar.Close)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emu.Close()
+	if len(curve) != 3 || seen[0] != 8 {
+		t.Fatalf("test run saw %v", seen)
+	}
+
+	if _, err := emul.SplitByDate(bars, emul.SplitConfig{TestStart: bars[1].Time, Embargo: 2 * time.Hour}); err == nil {
+		t.Fatalf("expected an empty train segment to be rejected")
+	}
+}
@@ -0,0 +1,75 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestStakingAccruesOnLongs(t *testing.T) {
+	closes := make([]float64, 25)
+	for i := range closes {
+		closes[i] = 100
+	}
+	for _, credit := range []emul.StakingCredit{emul.StakeInQuote, emul.StakeInKind} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(closes...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex := emu.Exchange()
+		// 36.5% a year is 0.1% a day: one day on 10 coins earns 0.01 coin.
+		if err := ex.SetStaking(emul.StakingConfig{APR: 0.365, Credit: credit}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ex.OpenLong(1); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 24; i++ {
+			if _, _, err := emu.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Rewards paid in kind compound hourly.
+		wantQty, wantEquity := 10.0, 1001.0
+		if credit == emul.StakeInKind {
+			wantQty = 10 * math.Pow(1+0.001/24, 24)
+			wantEquity = wantQty * 100
+		}
+		bal := ex.Balance()
+		staked := ex.LedgerTotals()[emul.LedgerStaking]
+		if math.Abs(bal.Equity-wantEquity) > 1e-9 || math.Abs(staked-(wantEquity-1000)) > 1e-9 {
+			t.Fatalf("credit %d: equity %v, staking %v", credit, bal.Equity, staked)
+		}
+		if math.Abs(bal.Position-wantQty) > 1e-9 {
+			t.Fatalf("credit %d: position %v, want %v", credit, bal.Position, wantQty)
+		}
+	}
+
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetStaking(emul.StakingConfig{APR: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenShort(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ex.LedgerTotals()[emul.LedgerStaking]; got != 0 {
+		t.Fatalf("short earned staking %v", got)
+	}
+	if err := ex.SetStaking(emul.StakingConfig{APR: -0.1}); err == nil {
+		t.Fatal("negative apr accepted")
+	}
+}
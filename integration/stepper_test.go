@@ -0,0 +1,66 @@
+package emul_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestStepperBreakpoints(t *testing.T) {
+	bars := flatBars(100, 100, 90, 80, 85, 95)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	s := emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		if bar.Time.Equal(bars[0].Time) || bar.Close == 85 {
+			_, err := ex.OpenLong(1)
+			return err
+		}
+		return nil
+	})
+	st := emul.NewStepper(emu, s, emul.Breakpoints{
+		EquityDropPct: 0.15,
+		At:            []time.Time{bars[5].Time},
+		OnRejection:   true,
+		RecentBars:    3,
+	})
+	want := []struct {
+		kind emul.BreakKind
+		tick int64
+	}{
+		{emul.BreakEquityDrop, 4},
+		{emul.BreakRejection, 5},
+		{emul.BreakTime, 6},
+	}
+	for _, w := range want {
+		ev, err := st.Continue()
+		if err != nil || ev == nil {
+			t.Fatalf("continue: %v %v", ev, err)
+		}
+		if ev.Kind != w.kind || ev.Tick != w.tick || len(ev.Recent) != 3 {
+			t.Fatalf("got %s at %d with %d bars, want %s at %d", ev.Kind, ev.Tick, len(ev.Recent), w.kind, w.tick)
+		}
+		if !strings.Contains(ev.String(), "position=") {
+			t.Fatalf("dump lacks exchange state:\n%s", ev)
+		}
+	}
+	if ev, err := st.Continue(); ev != nil || err != nil {
+		t.Fatalf("expected the end of the replay, got %v %v", ev, err)
+	}
+	if len(st.Curve()) != len(bars) {
+		t.Fatalf("curve has %d points", len(st.Curve()))
+	}
+
+	emu, _ = emul.NewEmulator(1000, 0, 0, 0, bars)
+	failing := emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		_, err := ex.CloseDeal(emul.ReasonExit)
+		return err
+	})
+	if _, err := emul.NewStepper(emu, failing, emul.Breakpoints{}).Step(); !errors.Is(err, emul.ErrNoPosition) {
+		t.Fatalf("without OnRejection the error aborts the replay, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package emul_test
+
+import (
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestStopRulesBreakEvenThenTrail(t *testing.T) {
+	bars := flatBars(100, 102, 105, 104, 104)
+	bars[1].High = 103
+	bars[2].High = 106
+	bars[3].High, bars[3].Low = 107, 103.5
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetStopRules(emul.StopRules{BreakEvenAt: 0.02}); err == nil {
+		t.Fatal("expected an error without a position")
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	rules := emul.StopRules{BreakEvenAt: 0.02, BreakEvenOffset: 0.001, TrailAt: 0.05, TrailATR: 2, ATR: 1}
+	if err := ex.SetStopRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	// +3% at the high: the stop moves to the entry plus the offset.
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := ex.Bracket(); !ok || b.Stop != 100.1 {
+		t.Fatalf("stop after +3%%: %+v", b)
+	}
+	// +6%: the stop trails the 106 high by 2 ATR.
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ex.Bracket(); b.Stop != 104 {
+		t.Fatalf("stop after +6%%: %+v", b)
+	}
+	_, fills, err := emu.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 1 || fills[0].StopKind != emul.StopKindStop || fills[0].MidPrice != 104 {
+		t.Fatalf("expected the trailed stop to fill at 104: %+v", fills)
+	}
+	if _, ok := ex.StopRules(); ok {
+		t.Fatal("rules must be removed with the position")
+	}
+}
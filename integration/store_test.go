@@ -0,0 +1,82 @@
+package emul_test
+
+import (
+	"errors"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestResultStoreArchivesAndQueriesRuns(t *testing.T) {
+	dir := t.TempDir()
+	store, err := emul.OpenResultStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fraction := range []float64{0.5, 1} {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 110, 110))
+		if err != nil {
+			t.Fatal(err)
+		}
+		curve, err := emul.Run(emu, fractionStrategy(fraction))
+		if err != nil {
+			t.Fatal(err)
+		}
+		run, err := emul.NewStoredRun("long", emu, curve, "sweep-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.SaveBatch([]emul.BatchResult{{Name: "pruned", PruneReason: "drawdown"}}, "sweep-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = emul.OpenResultStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Query(emul.RunQuery{Tags: []string{"sweep-a"}, SortBy: emul.SortReturn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "run-000002" || got[0].Return <= got[1].Return || got[0].ConfigHash == "" {
+		t.Fatalf("unexpected query result %+v", got)
+	}
+	if all, _ := store.Query(emul.RunQuery{Incomplete: true}); len(all) != 3 {
+		t.Fatalf("expected the pruned run with Incomplete, got %+v", all)
+	}
+	if none, _ := store.Query(emul.RunQuery{Tags: []string{"other"}}); len(none) != 0 {
+		t.Fatalf("unexpected runs for an unknown tag: %+v", none)
+	}
+
+	run, err := store.Load(got[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Manifest == nil || len(run.Trades) != 1 || len(run.Equity) != 4 || run.TradeCount != 1 || run.Stats.Wins != 1 {
+		t.Fatalf("unexpected stored run %+v", run)
+	}
+	if _, err := store.Load("missing"); !errors.Is(err, emul.ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
+}
+
+// fractionStrategy buys fraction of the balance on the first bar and closes on the third.
+func fractionStrategy(fraction float64) emul.Strategy {
+	return emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		switch len(ex.Orders()) {
+		case 0:
+			_, err := ex.OpenLong(fraction)
+			return err
+		case 1:
+			if bar.Close == 110 {
+				_, err := ex.CloseDeal(emul.ReasonExit)
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+package emul_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestContextStrategyIntents(t *testing.T) {
+	bars := flatBars(100, 101, 102, 103, 102, 99, 98, 97)
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	var windows []int
+	s := emul.ContextStrategyFunc(func(ctx *emul.StrategyContext) error {
+		windows = append(windows, len(ctx.History(0)))
+		sma, ok := ctx.SMA(3)
+		if !ok {
+			return nil
+		}
+		if ctx.Bar().Close > sma {
+			_, err := ctx.GoLong(1)
+			return err
+		}
+		_, err := ctx.GoShort(0.5)
+		return err
+	})
+	if _, err := emul.Run(emu, emul.WithContext(s, 3)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if want := []int{1, 2, 3, 3, 3, 3, 3, 3}; !slices.Equal(windows, want) {
+		t.Fatalf("history windows %v, want %v", windows, want)
+	}
+	orders := emu.Exchange().Orders()
+	// Long from bar 3, flipped short on bar 5; repeated intents do not add orders.
+	if len(orders) != 3 || orders[0].Reason != emul.ReasonEntryLong || orders[2].Reason != emul.ReasonEntryShort {
+		t.Fatalf("unexpected orders %+v", orders)
+	}
+}
+
+func TestContextIndicators(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(1, 2, 3, 4))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	var std, high float64
+	s := emul.ContextStrategyFunc(func(ctx *emul.StrategyContext) error {
+		std, _ = ctx.StdDev(4)
+		high, _ = ctx.Highest(2)
+		if ctx.Position().Qty != 0 {
+			t.Fatalf("expected a flat book")
+		}
+		return nil
+	})
+	if _, err := emul.Run(emu, emul.WithContext(s, 10)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if math.Abs(std-math.Sqrt(5.0/3)) > 1e-12 || high != 4 {
+		t.Fatalf("std %v high %v", std, high)
+	}
+}
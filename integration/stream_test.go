@@ -0,0 +1,82 @@
+package emul_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestStreamPipeline(t *testing.T) {
+	closes := []float64{100, 101, 102, 99, 98, 103, 104, 97}
+	emu, err := emul.NewEmulator(1000, 0.001, 0, 0, flatBars(closes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := emu.Stream(context.Background())
+
+	type signal struct {
+		ev   *emul.StreamEvent
+		long bool
+	}
+	signals := make(chan signal)
+	go func() {
+		defer close(signals)
+		prev := 0.0
+		for ev := range stream.Events {
+			signals <- signal{ev: ev, long: prev > 0 && ev.Bar.Close > prev}
+			prev = ev.Bar.Close
+		}
+	}()
+
+	ex := emu.Exchange()
+	bars := 0
+	for sig := range signals {
+		bars++
+		pos := ex.Balance().Position
+		switch {
+		case sig.long && pos == 0:
+			if _, err := ex.OpenLong(1); err != nil {
+				t.Fatal(err)
+			}
+		case !sig.long && pos > 0:
+			if _, err := ex.CloseDeal(emul.ReasonExit); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sig.ev.Done()
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if bars != len(closes) {
+		t.Fatalf("expected %d bars, got %d", len(closes), bars)
+	}
+	// Longs open on the rises at bars 2 and 6 and close on the falls at bars 4 and 8.
+	if orders := ex.Orders(); len(orders) != 4 || orders[0].Tick != 2 || orders[3].Tick != 8 {
+		t.Fatalf("unexpected orders %+v", orders)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := emu.Stream(ctx)
+	ev := <-stream.Events
+	if ev.Bar.Close != 100 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	cancel()
+	for range stream.Events {
+	}
+	if !errors.Is(stream.Err(), context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", stream.Err())
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatalf("the replay must stop after the delivered bar: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestBlockBootstrapIsDeterministicAndContinuous(t *testing.T) {
+	src := syntheticBars(50, 100, 0.5)
+	a, err := emul.BlockBootstrap(src, 5, 80, 7)
+	if err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	b, _ := emul.BlockBootstrap(src, 5, 80, 7)
+	if len(a) != 80 {
+		t.Fatalf("expected 80 bars, got %d", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed must give same path, differs at %d", i)
+		}
+		if i > 0 && math.Abs(a[i].Open-a[i-1].Close) > 1e-9 {
+			t.Fatalf("path must be continuous at %d", i)
+		}
+	}
+}
+
+// walkBars is a gapless hourly zig-zag with wicks, so blocks differ from one another.
+func walkBars(n int) []emul.OHLCBar {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bars := make([]emul.OHLCBar, n)
+	price := 100.0
+	for i := range bars {
+		open := price
+		price *= 1 + 0.01*math.Sin(float64(i)*1.7)
+		bars[i] = emul.OHLCBar{
+			Time:  start.Add(time.Duration(i) * time.Hour),
+			Open:  open,
+			High:  math.Max(open, price) * 1.002,
+			Low:   math.Min(open, price) * 0.998,
+			Close: price,
+		}
+	}
+	return bars
+}
+
+func TestBlockBootstrapKeepsVolume(t *testing.T) {
+	src := walkBars(60)
+	byVolume := make(map[float64]emul.OHLCBar, len(src))
+	for i := range src {
+		src[i].Volume = float64(i+1) * 10
+		byVolume[src[i].Volume] = src[i]
+	}
+	path, err := emul.BlockBootstrap(src, 6, 120, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, bar := range path {
+		orig, ok := byVolume[bar.Volume]
+		if !ok {
+			t.Fatalf("bar %d has volume %v not taken from the source", i, bar.Volume)
+		}
+		if math.Abs(bar.Close/bar.Open-orig.Close/orig.Open) > 1e-9 {
+			t.Fatalf("bar %d carries the volume of a different source bar", i)
+		}
+	}
+}
+
+func TestBlockBootstrapShapes(t *testing.T) {
+	src := walkBars(30)
+	cases := []struct {
+		name      string
+		block     int
+		length    int
+		wantLen   int
+		wantError bool
+	}{
+		{"block of one", 1, 45, 45, false},
+		{"whole series", 30, 60, 60, false},
+		{"default length", 4, 0, 30, false},
+		{"block too long", 31, 10, 0, true},
+		{"zero block", 0, 10, 0, true},
+	}
+	for _, tc := range cases {
+		out, err := emul.BlockBootstrap(src, tc.block, tc.length, 11)
+		if tc.wantError {
+			if err == nil {
+				t.Fatalf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(out) != tc.wantLen {
+			t.Fatalf("%s: %d bars, want %d", tc.name, len(out), tc.wantLen)
+		}
+		again, _ := emul.BlockBootstrap(src, tc.block, tc.length, 11)
+		other, _ := emul.BlockBootstrap(src, tc.block, tc.length, 12)
+		same := true
+		for i := range out {
+			if out[i] != again[i] {
+				t.Fatalf("%s: same seed differs at %d", tc.name, i)
+			}
+			same = same && out[i] == other[i]
+			b := out[i]
+			if b.Low > math.Min(b.Open, b.Close) || b.High < math.Max(b.Open, b.Close) || b.Low <= 0 {
+				t.Fatalf("%s: invalid bar %d %+v", tc.name, i, b)
+			}
+			if i > 0 && (b.Time.Sub(out[i-1].Time) != time.Hour || math.Abs(b.Open-out[i-1].Close) > 1e-9) {
+				t.Fatalf("%s: discontinuity at %d", tc.name, i)
+			}
+		}
+		if !out[0].Time.Equal(src[0].Time) {
+			t.Fatalf("%s: path starts at %v", tc.name, out[0].Time)
+		}
+		if tc.block < len(src) && same {
+			t.Fatalf("%s: different seeds gave the same path", tc.name)
+		}
+	}
+}
+
+func TestGARCHRoundTrip(t *testing.T) {
+	want := emul.GARCHParams{Omega: 1e-6, Alpha: 0.1, Beta: 0.85}
+	bars, err := emul.GenerateGARCH(want, 100, 5000, 1)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got, err := emul.CalibrateGARCH(bars)
+	if err != nil {
+		t.Fatalf("calibrate: %v", err)
+	}
+	if math.Abs(got.Alpha+got.Beta-0.95) > 0.05 {
+		t.Fatalf("expected persistence near 0.95, got alpha=%.3f beta=%.3f", got.Alpha, got.Beta)
+	}
+}
+
+func TestJumpDiffusionCalibrationFindsJumps(t *testing.T) {
+	want := emul.JumpDiffusionParams{Sigma: 0.01, Lambda: 0.02, JumpMean: -0.08, JumpStd: 0.02}
+	bars, err := emul.GenerateJumpDiffusion(want, 100, 5000, 3)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got, err := emul.CalibrateJumpDiffusion(bars)
+	if err != nil {
+		t.Fatalf("calibrate: %v", err)
+	}
+	if got.Lambda < 0.01 || got.Lambda > 0.04 || got.JumpMean > -0.04 {
+		t.Fatalf("unexpected jump calibration: %+v", got)
+	}
+}
+
+func TestGenerateCorrelatedKeepsCorrelation(t *testing.T) {
+	src, err := emul.GenerateGARCH(emul.GARCHParams{Omega: 1e-5, Alpha: 0.05, Beta: 0.9}, 100, 500, 11)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	other, _ := emul.GenerateGARCH(emul.GARCHParams{Omega: 1e-5, Alpha: 0.05, Beta: 0.9}, 50, 500, 12)
+	corr := [][]float64{{1, 0.8}, {0.8, 1}}
+	paths, err := emul.GenerateCorrelated([][]emul.OHLCBar{src, other}, corr, 4000, 5)
+	if err != nil {
+		t.Fatalf("generate correlated: %v", err)
+	}
+	got, err := emul.EstimateCorrelation(paths...)
+	if err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	if math.Abs(got[0][1]-0.8) > 0.05 {
+		t.Fatalf("expected correlation near 0.8, got %.3f", got[0][1])
+	}
+}
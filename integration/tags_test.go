@@ -0,0 +1,107 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestAttributeByTagSplitsSharedAccount(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 110, 105, 120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trend := emul.WithTag(emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		switch bar.Close {
+		case 100:
+			if ex.Balance().Position == 0 {
+				_, err := ex.OpenLong(1)
+				return err
+			}
+		case 110:
+			_, err := ex.CloseDeal(emul.ReasonExit)
+			return err
+		}
+		return nil
+	}), "trend")
+	var limitTag string
+	dip := emul.WithTag(emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		switch bar.Close {
+		case 110:
+			if _, err := ex.LongLimit(105, 1); err != nil {
+				return err
+			}
+			limitTag = ex.PendingOrders()[0].Tag
+		case 120:
+			_, err := ex.CloseDeal(emul.ReasonExit)
+			return err
+		}
+		return nil
+	}), "dip")
+	_, err = emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, executed []emul.Order) error {
+		if err := trend.OnBar(ex, bar, executed); err != nil {
+			return err
+		}
+		return dip.OnBar(ex, bar, executed)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if limitTag != "dip" || ex.Tag() != "" {
+		t.Fatalf("limit tag %q, exchange tag %q", limitTag, ex.Tag())
+	}
+	for _, o := range ex.Orders() {
+		if want := map[bool]string{true: "trend", false: "dip"}[o.Tick <= 3]; o.Tag != want {
+			t.Fatalf("order %d at tick %d tagged %q, want %q", o.ID, o.Tick, o.Tag, want)
+		}
+	}
+
+	got := emul.AttributeByTag(ex.Orders())
+	if len(got) != 2 || got[0].Tag != "dip" || got[1].Tag != "trend" {
+		t.Fatalf("attribution %+v", got)
+	}
+	dipPnL := 1100 * (120.0/105 - 1)
+	if math.Abs(got[0].PnL-dipPnL) > 1e-9 || math.Abs(got[1].PnL-100) > 1e-9 || got[1].ExposureBars != 2 {
+		t.Fatalf("attribution %+v", got)
+	}
+	if math.Abs(got[0].Share+got[1].Share-1) > 1e-9 || got[0].Trades != 1 || got[0].Wins != 1 {
+		t.Fatalf("shares %+v", got)
+	}
+}
+
+func TestAttributeByTagOffsettingTags(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 110, 110, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar := 0
+	_, err = emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		bar++
+		var err error
+		switch bar {
+		case 1:
+			ex.SetTag("win")
+			_, err = ex.OpenLong(1)
+		case 2:
+			_, err = ex.CloseDeal(emul.ReasonExit)
+		case 3:
+			ex.SetTag("lose")
+			_, err = ex.OpenLong(1)
+		case 4:
+			_, err = ex.CloseDeal(emul.ReasonExit)
+		}
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := emul.AttributeByTag(emu.Exchange().Orders())
+	if len(got) != 2 || math.Abs(got[0].PnL+got[1].PnL) > 1e-9 {
+		t.Fatalf("attribution %+v", got)
+	}
+	if math.Abs(got[0].Share+0.5) > 1e-9 || math.Abs(got[1].Share-0.5) > 1e-9 {
+		t.Fatalf("offsetting tags must split the gross PnL, got %v and %v", got[0].Share, got[1].Share)
+	}
+}
@@ -0,0 +1,186 @@
+package emul_test
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestCSVTailFeedsAppendedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "btc2026.csv")
+	rows := "1704067200,100,101,99,100.5,10\n1704070800,100.5,102,100,101,12\n"
+	if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bars, tail, err := emul.LoadBarsAndTail(path)
+	if err != nil {
+		t.Fatalf("load and tail: %v", err)
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	for range bars {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatalf("next: %v", err)
+		}
+	}
+	if _, _, err := emu.Next(); err != emul.ErrNoMoreBars {
+		t.Fatalf("expected ErrNoMoreBars, got %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("1704074400,101,103,100,102,9\n1704078000,102,10")
+	newBars, err := tail.Poll()
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(newBars) != 1 || newBars[0].Close != 102 {
+		t.Fatalf("expected one complete appended bar, got %+v", newBars)
+	}
+	_, _ = f.WriteString("4,101,103,11\n")
+	f.Close()
+	newBars2, err := tail.Poll()
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(newBars2) != 1 || newBars2[0].High != 104 {
+		t.Fatalf("expected the completed partial row, got %+v", newBars2)
+	}
+	if err := emu.AppendBars(append(newBars, newBars2...)...); err != nil {
+		t.Fatalf("append bars: %v", err)
+	}
+	bar, _, err := emu.Next()
+	if err != nil || bar.Close != 102 {
+		t.Fatalf("expected appended bar from next, got %+v (%v)", bar, err)
+	}
+}
+
+func TestAppendBarsWithPerpLeg(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 101))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.EnablePerp(emul.PerpConfig{Bars: flatBars(100, 101)}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	more := flatBars(100, 101, 102, 103)[2:]
+	if err := emu.AppendBars(more...); err == nil {
+		t.Fatal("spot bars must not outgrow the perp series")
+	}
+	if err := emu.AppendPerpBars(flatBars(100, 101, 104, 105)[2:]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.AppendBars(more...); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos, err := emu.Exchange().PerpPosition()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Mark != 105 {
+		t.Fatalf("perp mark %v, want the appended 105", pos.Mark)
+	}
+}
+
+func TestAppendBarsDoesNotTouchSharedSlice(t *testing.T) {
+	shared := make([]emul.OHLCBar, 2, 4)
+	copy(shared, flatBars(100, 101))
+	a, err := emul.NewEmulator(1000, 0, 0, 0, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := emul.NewEmulator(1000, 0, 0, 0, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AppendBars(flatBars(100, 101, 150)[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AppendBars(flatBars(100, 101, 50)[2]); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Bars()[2].Close; got != 150 {
+		t.Fatalf("emulator a sees %v, want its own appended 150", got)
+	}
+	if got := shared[:3][2].Close; got != 0 {
+		t.Fatalf("the shared backing array was written: %v", got)
+	}
+}
+
+func TestAppendBarsOneAtATimeDoesNotCopyHistory(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar := flatBars(100)[0]
+	if err := emu.AppendBars(bar); err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(1000, func() {
+		if err := emu.AppendBars(bar); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs >= 0.1 {
+		t.Fatalf("appending one bar allocates %.2f times per call", allocs)
+	}
+}
+
+func TestCSVTailLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "btc2026.csv")
+	if err := os.WriteFile(path, []byte("1704067200,100,101,99,100.5,10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	strict := emul.NewCSVTailWithOptions(path, emul.CSVReaderOptions{MaxLineBytes: 64})
+	var skipped []int
+	lenient := emul.NewCSVTailWithOptions(path, emul.CSVReaderOptions{
+		MaxLineBytes:  64,
+		SkipLongLines: true,
+		Warn:          func(_ string, size int) { skipped = append(skipped, size) },
+	})
+	for _, tail := range []*emul.CSVTail{strict, lenient} {
+		if bars, err := tail.Poll(); err != nil || len(bars) != 1 {
+			t.Fatalf("first poll: %d bars, %v", len(bars), err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, _ = f.WriteString("# " + strings.Repeat("x", 100))
+	if _, err := strict.Poll(); !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("expected bufio.ErrTooLong for an unfinished long line, got %v", err)
+	}
+	if bars, err := lenient.Poll(); err != nil || len(bars) != 0 {
+		t.Fatalf("lenient poll: %d bars, %v", len(bars), err)
+	}
+	_, _ = f.WriteString(strings.Repeat("x", 100) + "\n1704070800,100.5,102,100,101,12\n")
+	bars, err := lenient.Poll()
+	if err != nil || len(bars) != 1 || bars[0].Close != 101 {
+		t.Fatalf("expected the row after the skipped line, got %+v (%v)", bars, err)
+	}
+	if len(skipped) != 1 {
+		t.Fatalf("unexpected skipped lines %v", skipped)
+	}
+}
@@ -0,0 +1,72 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestTaxLotsAcrossYears(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	closes := map[int]float64{0: 100, 380: 150, 400: 200}
+	bars := make([]emul.OHLCBar, 401)
+	price := 100.0
+	for i := range bars {
+		if c, ok := closes[i]; ok {
+			price = c
+		}
+		bars[i] = emul.OHLCBar{Time: start.AddDate(0, 0, i), Open: price, High: price, Low: price, Close: price, Average: price}
+	}
+	emu, err := emul.NewEmulator(2000, 0.001, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		var err error
+		switch len(ex.Orders()) {
+		case 0:
+			_, err = ex.OpenLong(0.5)
+		case 1:
+			if ex.Balance().LastPrice == 150 {
+				_, err = ex.ScaleIn(1)
+			}
+		case 2:
+			if ex.Balance().LastPrice == 200 {
+				_, err = ex.CloseDeal(emul.ReasonExit)
+			}
+		}
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := emu.Exchange().Orders()
+	fifo, err := emul.ComputeTaxLots(orders, bars, emul.LotsFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifo, err := emul.ComputeTaxLots(orders, bars, emul.LotsLIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fifo.Disposals) != 2 || len(fifo.OpenLots) != 0 || len(fifo.Years) != 1 || fifo.Years[0].Year != 2025 {
+		t.Fatalf("unexpected FIFO report %+v", fifo)
+	}
+	if !fifo.Disposals[0].LongTerm || fifo.Disposals[1].LongTerm || lifo.Disposals[0].LongTerm {
+		t.Fatalf("unexpected disposal order: fifo %+v lifo %+v", fifo.Disposals, lifo.Disposals)
+	}
+	// Realized gain equals the account's PnL: fees are in cost and proceeds.
+	pnl := orders[2].Equity - orders[0].EquityBefore
+	y := fifo.Years[0]
+	if math.Abs(y.Gain-pnl) > 1e-9 || math.Abs(y.LongTerm+y.ShortTerm-y.Gain) > 1e-9 {
+		t.Fatalf("gain %v, want %v (%+v)", y.Gain, pnl, y)
+	}
+	if math.Abs(lifo.Years[0].Gain-y.Gain) > 1e-9 {
+		t.Fatalf("lot method must not change the total gain")
+	}
+	if _, err := emul.ComputeTaxLots(orders, bars[:10], emul.LotsHIFO); err == nil {
+		t.Fatalf("orders outside the bars must be rejected")
+	}
+}
@@ -0,0 +1,75 @@
+package emul_test
+
+import (
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestTimeExitMaxBars(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 101, 102, 103, 104))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetTimeExit(emul.TimeExit{MaxBars: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenLong(1); err != nil {
+		t.Fatal(err)
+	}
+	var fills []emul.Order
+	for i := 0; i < 2; i++ {
+		if _, fills, err = emu.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fills) != 1 || fills[0].Reason != emul.ReasonTimeExit || fills[0].StopKind != emul.StopKindMaxBars || fills[0].Tick != 3 || fills[0].Price != 102 {
+		t.Fatalf("expected a time exit at the close of the second bar: %+v", fills)
+	}
+	stats := emul.BreakdownExits(emul.PairTrades(ex.Orders()))
+	if stats.ByReason[emul.ReasonTimeExit].Trades != 1 || stats.ByCategory[emul.ReasonCategoryExit].Trades != 1 {
+		t.Fatalf("time exits must show up in the exit breakdown: %+v", stats)
+	}
+}
+
+func TestTimeExitTimeOfDay(t *testing.T) {
+	// Bars every 4 hours from midnight: the 10:00 exit falls between the 08:00 and 12:00 bars.
+	bars := flatBars(100, 101, 102, 103, 104)
+	for i := range bars {
+		bars[i].Time = bars[0].Time.Add(time.Duration(i) * 4 * time.Hour)
+		bars[i].Open = bars[i].Close - 0.5
+	}
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetTimeExit(emul.TimeExit{TimeOfDay: 25 * time.Hour}); err == nil {
+		t.Fatal("expected an error for a time of day past 24h")
+	}
+	if err := ex.SetTimeExit(emul.TimeExit{TimeOfDay: 10 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.OpenShort(1); err != nil {
+		t.Fatal(err)
+	}
+	var fills []emul.Order
+	for i := 0; i < 3; i++ {
+		_, f, err := emu.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fills = append(fills, f...)
+	}
+	if len(fills) != 1 || fills[0].StopKind != emul.StopKindTimeOfDay || fills[0].Tick != 4 || fills[0].MidPrice != 102.5 {
+		t.Fatalf("expected a time exit at the open of the 12:00 bar: %+v", fills)
+	}
+}
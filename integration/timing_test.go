@@ -0,0 +1,128 @@
+package emul_test
+
+import (
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+// openCloseBars returns hourly bars whose open and close differ, so fills reveal which one
+// they used.
+func openCloseBars(pairs ...[2]float64) []emul.OHLCBar {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bars := make([]emul.OHLCBar, len(pairs))
+	for i, p := range pairs {
+		bars[i] = emul.OHLCBar{
+			Time:  start.Add(time.Duration(i) * time.Hour),
+			Open:  p[0],
+			High:  max(p[0], p[1]),
+			Low:   min(p[0], p[1]),
+			Close: p[1],
+		}
+	}
+	return bars
+}
+
+func TestDecisionTimingSchedulesStrategy(t *testing.T) {
+	cases := []struct {
+		timing    emul.DecisionTiming
+		calls     int
+		fillTick  int64
+		fillPrice float64
+	}{
+		{emul.DecideOnCloseFillAtClose, 3, 1, 101},
+		{emul.DecideOnCloseFillNextOpen, 2, 2, 102},
+		{emul.DecideOnOpen, 3, 1, 100},
+	}
+	for _, tc := range cases {
+		emu, err := emul.NewEmulator(1000, 0, 0, 0, openCloseBars([2]float64{100, 101}, [2]float64{102, 103}, [2]float64{104, 105}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := emu.SetDecisionTiming(tc.timing); err != nil {
+			t.Fatal(err)
+		}
+		calls := 0
+		var fill *emul.Order
+		curve, err := emul.Run(emu, emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, executed []emul.Order) error {
+			calls++
+			if tc.timing == emul.DecideOnOpen && (bar.Close != bar.Open || bar.High != bar.Open) {
+				t.Fatalf("open decision saw %+v", bar)
+			}
+			if fill == nil {
+				order, err := ex.OpenLong(1)
+				fill = order
+				return err
+			}
+			return nil
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != tc.calls || len(curve) != 3 {
+			t.Fatalf("%s: %d calls, %d points", tc.timing, calls, len(curve))
+		}
+		if fill.Tick != tc.fillTick || fill.Price != tc.fillPrice {
+			t.Fatalf("%s: filled at tick %d price %v", tc.timing, fill.Tick, fill.Price)
+		}
+		if want := 1000 * 105 / tc.fillPrice; curve[2].Equity < want-1e-9 || curve[2].Equity > want+1e-9 {
+			t.Fatalf("%s: final equity %v, want %v", tc.timing, curve[2].Equity, want)
+		}
+	}
+
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := emu.SetDecisionTiming(emul.DecideOnOpen); err == nil {
+		t.Fatal("timing changed after the replay started")
+	}
+}
+
+func TestFillAtNextOpen(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, openCloseBars([2]float64{100, 101}, [2]float64{102, 103}, [2]float64{104, 105}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex := emu.Exchange()
+	if err := ex.SetFillTiming(emul.FillAtNextOpen); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if order, err := ex.OpenLong(1); order != nil || err != nil {
+			t.Fatalf("deferred open returned %+v %v", order, err)
+		}
+	}
+	if ex.Balance().Position != 0 {
+		t.Fatal("deferred open filled at the close")
+	}
+	_, executed, rejections, err := emu.NextWithRejections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(executed) != 1 || executed[0].Price != 102 || executed[0].Tick != 2 || executed[0].PlacedTick != 1 {
+		t.Fatalf("open fills %+v", executed)
+	}
+	if len(rejections) != 1 || rejections[0].Reason != "position_state_mismatch" || rejections[0].Kind != "open_long" {
+		t.Fatalf("rejections %+v", rejections)
+	}
+	if order, err := ex.CloseDeal(emul.ReasonExit); order != nil || err != nil {
+		t.Fatalf("deferred close returned %+v %v", order, err)
+	}
+	if _, executed, err = emu.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if len(executed) != 1 || executed[0].Price != 104 || executed[0].Side != emul.SideSell {
+		t.Fatalf("close fills %+v", executed)
+	}
+	if _, err := ex.CloseDeal(emul.ReasonExit); err != emul.ErrNoPosition {
+		t.Fatalf("close while flat: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package emul_test
+
+import (
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestPairTradesUsesReasonCategories(t *testing.T) {
+	if err := emul.RegisterReason("atr-stop", emul.ReasonCategoryStop); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := emul.RegisterReason(emul.ReasonExit, emul.ReasonCategoryStop); err == nil {
+		t.Fatalf("built-in reasons must not be remapped")
+	}
+	emu, err := emul.NewEmulator(1000, 0.001, 0, 0, syntheticBars(10, 100, 1))
+	if err != nil {
+		t.Fatalf("new emulator: %v", err)
+	}
+	ex := emu.Exchange()
+	step := func() {
+		if _, _, err := emu.Next(); err != nil {
+			t.Fatalf("next: %v", err)
+		}
+	}
+	step()
+	_, _ = ex.OpenLong(1)
+	step()
+	_, _ = ex.CloseDeal("atr-stop")
+	step()
+	_, _ = ex.OpenShort(1)
+	step()
+	_, _ = ex.CloseDeal("flip-close")
+	step()
+	_, _ = ex.OpenLong(1)
+
+	trades := emul.PairTrades(ex.Orders())
+	if len(trades) != 2 {
+		t.Fatalf("expected 2 closed trades, got %d", len(trades))
+	}
+	if trades[0].ExitCategory != emul.ReasonCategoryStop || trades[0].PnL <= 0 {
+		t.Fatalf("unexpected first trade: %+v", trades[0])
+	}
+	if trades[1].ExitCategory != emul.ReasonCategoryExit || trades[1].Side != emul.SideSell || trades[1].PnL >= 0 {
+		t.Fatalf("unexpected second trade: %+v", trades[1])
+	}
+}
+
+func TestBreakdownExitsByStopKind(t *testing.T) {
+	trades := []emul.Trade{
+		{PnL: -10, ExitReason: emul.ReasonStopLoss, ExitCategory: emul.ReasonCategoryStop, StopKind: "sl"},
+		{PnL: -5, ExitReason: emul.ReasonStopLoss, ExitCategory: emul.ReasonCategoryStop, StopKind: "sl"},
+		{PnL: 30, ExitReason: emul.ReasonExit, ExitCategory: emul.ReasonCategoryExit, StopKind: "tp"},
+		{PnL: 4, ExitReason: "signal", ExitCategory: emul.ReasonCategoryExit},
+	}
+	b := emul.BreakdownExits(trades)
+	if s := b.ByStopKind["sl"]; s.Trades != 2 || s.NetPnL != -15 || s.WinRate != 0 {
+		t.Fatalf("unexpected sl stats: %+v", s)
+	}
+	if s := b.ByCategory[emul.ReasonCategoryExit]; s.Trades != 2 || s.NetPnL != 34 {
+		t.Fatalf("unexpected exit stats: %+v", s)
+	}
+	if s := emul.ComputeTradeStats(trades); s.ProfitFactor != 34.0/15.0 {
+		t.Fatalf("unexpected profit factor: %v", s.ProfitFactor)
+	}
+}
+
+func TestStreaksAndLossRunProbability(t *testing.T) {
+	pnls := []float64{5, -1, -2, -3, 0, -1, 4, 6, -2}
+	trades := make([]emul.Trade, len(pnls))
+	for i, p := range pnls {
+		trades[i].PnL = p
+	}
+	s := emul.AnalyzeStreaks(trades)
+	if s.MaxLossStreak != 4 || s.MaxWinStreak != 2 {
+		t.Fatalf("unexpected max streaks: %+v", s)
+	}
+	if s.LossStreaks[4] != 1 || s.LossStreaks[1] != 1 || s.WinStreaks[1] != 1 || s.WinStreaks[2] != 1 {
+		t.Fatalf("unexpected distribution: %+v", s)
+	}
+	// Two trades at 50%: only "loss, loss" has a 2-loss run.
+	if p := emul.LossStreakProbability(0.5, 2, 2); p != 0.25 {
+		t.Fatalf("expected 0.25, got %v", p)
+	}
+	// Three trades: LLL, LLW, WLL.
+	if p := emul.LossStreakProbability(0.5, 2, 3); p != 0.375 {
+		t.Fatalf("expected 0.375, got %v", p)
+	}
+}
+
+func TestMartingaleSizing(t *testing.T) {
+	rets := []float64{-0.1, -0.1, 0.1, -0.1}
+	trades := make([]emul.Trade, len(rets))
+	for i, r := range rets {
+		trades[i] = emul.Trade{Qty: 1, EntryPrice: 100, PnL: r * 100}
+	}
+	res, err := emul.SimulateSizing(trades, 1000, emul.SizingPolicy{Kind: emul.SizingMartingale, BaseFraction: 0.25, Multiplier: 2}, 0)
+	if err != nil {
+		t.Fatalf("simulate: %v", err)
+	}
+	wantStakes := []float64{0.25, 0.5, 1, 0.25}
+	for i, s := range wantStakes {
+		if res.Stakes[i] != s {
+			t.Fatalf("stake %d = %v, want %v", i, res.Stakes[i], s)
+		}
+	}
+	if want := 1000 * 0.975 * 0.95 * 1.1 * 0.975; math.Abs(res.FinalEquity-want) > 1e-9 {
+		t.Fatalf("final equity %v, want %v", res.FinalEquity, want)
+	}
+
+	anti, _ := emul.SimulateSizing(trades, 1000, emul.SizingPolicy{Kind: emul.SizingAntiMartingale, BaseFraction: 0.25, Multiplier: 2}, 0)
+	if anti.Stakes[3] != 0.5 {
+		t.Fatalf("anti-martingale should escalate after the win, got %v", anti.Stakes)
+	}
+
+	var losing []emul.Trade
+	for _, pnl := range []float64{-50, -50, -50, 20, 20, 20} {
+		losing = append(losing, emul.Trade{Qty: 1, EntryPrice: 100, PnL: pnl})
+	}
+	fixed, _ := emul.RuinProbability(losing, 1000, emul.SizingPolicy{BaseFraction: 0.1}, 0.5, 500, 1)
+	mart, _ := emul.RuinProbability(losing, 1000, emul.SizingPolicy{Kind: emul.SizingMartingale, BaseFraction: 0.1, Multiplier: 3}, 0.5, 500, 1)
+	if fixed != 0 || mart <= 0 {
+		t.Fatalf("martingale should ruin more often: fixed %v, martingale %v", fixed, mart)
+	}
+}
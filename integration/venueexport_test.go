@@ -0,0 +1,51 @@
+package emul_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestVenueTradeExports(t *testing.T) {
+	bars := flatBars(100, 100, 110, 110)
+	emu, err := emul.NewEmulator(1000, 0.001, 0, 0, bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := emul.Run(emu, fractionStrategy(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	orders := emu.Exchange().Orders()
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders, got %+v", orders)
+	}
+
+	var buf bytes.Buffer
+	if err := emul.WriteBinanceTrades(&buf, orders, bars, "eth", ""); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "Date(UTC),Pair,Side,Price,Executed,Amount,Fee" {
+		t.Fatalf("unexpected binance export:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "2024-01-01 00:00:00,ETHUSDT,BUY,100,") || !strings.Contains(lines[1], "ETH,") || !strings.HasSuffix(lines[1], "USDT") {
+		t.Fatalf("unexpected binance row %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "2024-01-01 02:00:00,ETHUSDT,SELL,110,") {
+		t.Fatalf("unexpected binance row %q", lines[2])
+	}
+
+	buf.Reset()
+	if err := emul.WriteBybitTrades(&buf, orders, bars, "ETH", "usdc"); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "Spot Pairs,Order Type,Direction,") {
+		t.Fatalf("unexpected bybit export:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "ETHUSDC,MARKET,BUY,") || !strings.HasSuffix(lines[2], ",2,2,2024-01-01 02:00:00") {
+		t.Fatalf("unexpected bybit rows %q", lines[1:])
+	}
+}
@@ -0,0 +1,65 @@
+package emul_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestVenuesBasisAndTransfers(t *testing.T) {
+	bars := syntheticBars(5, 100, 1)
+	v, err := emul.NewVenues(bars,
+		emul.VenueConfig{Name: "lead", StartUSD: 1000},
+		emul.VenueConfig{Name: "lag", StartUSD: 1000, BasisPct: 0.01, LagBars: 1, Costs: emul.CostProfile{TakerFee: 0.001}},
+	)
+	if err != nil {
+		t.Fatalf("new venues: %v", err)
+	}
+	first, err := v.Next()
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	second, _ := v.Next()
+	if math.Abs(second[1].Bar.Close-first[0].Bar.Close*1.01) > 1e-9 {
+		t.Fatalf("lagging venue should trade the previous leader close plus basis: %v", second[1].Bar.Close)
+	}
+
+	lead := v.Exchange("lead")
+	if err := lead.SetFlowFee(emul.LedgerTransfer, emul.FlowFee{Fixed: 1, Pct: 0.001}); err != nil {
+		t.Fatal(err)
+	}
+	// The destination's transfer fee does not apply: the source pays its own.
+	if err := v.Exchange("lag").SetFlowFee(emul.LedgerTransfer, emul.FlowFee{Fixed: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Transfer("lead", "lag", 999.5, 0); !errors.Is(err, emul.ErrInsufficientFunds) {
+		t.Fatalf("the fee must fit in the free cash too, got %v", err)
+	}
+	tr, err := v.Transfer("lead", "lag", 500, 1)
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if got := lead.Balance().USD; math.Abs(got-498.5) > 1e-9 || tr.Fee != 1.5 {
+		t.Fatalf("lead usd %v, fee %v", got, tr.Fee)
+	}
+	if fees := lead.LedgerTotals()[emul.LedgerFee]; math.Abs(fees+1.5) > 1e-9 {
+		t.Fatalf("transfer fee must be booked on the source, ledger fees %v", fees)
+	}
+	if _, err := v.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Exchange("lag").Balance().USD; got != 1000 || len(v.InFlight()) != 1 {
+		t.Fatalf("transfer arrived early: %v", got)
+	}
+	if _, err := v.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Exchange("lag").Balance().USD; math.Abs(got-1500) > 1e-9 {
+		t.Fatalf("lag usd after arrival %v", got)
+	}
+	if math.Abs(v.TotalEquity()-1998.5) > 1e-9 {
+		t.Fatalf("total equity %v", v.TotalEquity())
+	}
+}
@@ -0,0 +1,93 @@
+package emul_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestViewerServesRunAndTradeWindow(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 100, 105, 110, 110, 110))
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve, err := emul.Run(emu, fractionStrategy(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(emul.ViewerHandler(emu, curve))
+	defer srv.Close()
+
+	get := func(path string, v any) int {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("index: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var summary struct {
+		Bars   int     `json:"bars"`
+		Trades int     `json:"trades"`
+		NetPnL float64 `json:"net_pnl"`
+	}
+	get("/api/summary", &summary)
+	if summary.Bars != 6 || summary.Trades != 1 || summary.NetPnL <= 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+
+	var bars []struct {
+		Tick  int64   `json:"tick"`
+		Close float64 `json:"close"`
+	}
+	get("/api/bars?from=2&to=3", &bars)
+	if len(bars) != 2 || bars[0].Tick != 2 || bars[1].Close != 105 {
+		t.Fatalf("bars = %+v", bars)
+	}
+
+	var detail struct {
+		Trade struct {
+			EntryTick int64 `json:"entry_tick"`
+			ExitTick  int64 `json:"exit_tick"`
+		} `json:"trade"`
+		Bars   []struct{ Tick int64 } `json:"bars"`
+		Orders []struct{ ID int64 }   `json:"orders"`
+	}
+	get("/api/trades/0?pad=1", &detail)
+	if detail.Trade.EntryTick != 1 || detail.Trade.ExitTick != 4 {
+		t.Fatalf("trade = %+v", detail.Trade)
+	}
+	if len(detail.Bars) != 5 || detail.Bars[0].Tick != 1 || detail.Bars[4].Tick != 5 {
+		t.Fatalf("trade bars = %+v", detail.Bars)
+	}
+	if len(detail.Orders) != 2 {
+		t.Fatalf("trade orders = %+v", detail.Orders)
+	}
+
+	if code := get("/api/trades/5", nil); code != http.StatusNotFound {
+		t.Fatalf("unknown trade status = %d", code)
+	}
+	if code := get("/api/bars?from=x", nil); code != http.StatusBadRequest {
+		t.Fatalf("bad range status = %d", code)
+	}
+}
@@ -0,0 +1,224 @@
+package emul_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	emul "github.com/svanichkin/ExchangeEmulator"
+)
+
+func TestWebhookReceivesFillsAndBreaches(t *testing.T) {
+	var mu sync.Mutex
+	var events []emul.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev emul.Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 70, 65, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures int
+	hook := &emul.Webhook{URL: srv.URL, Headers: map[string]string{"X-Token": "secret"}, OnError: func(emul.Event, error) { failures++ }}
+	s := emul.WithNotifier(emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		if bar.Close == 100 && ex.Balance().Position == 0 && len(ex.Orders()) == 0 {
+			_, err := ex.OpenLong(1)
+			return err
+		}
+		return nil
+	}), hook, emul.DrawdownRule(0.2))
+	if _, err := emul.Run(emu, s); err != nil {
+		t.Fatal(err)
+	}
+	if failures != 0 {
+		t.Fatalf("%d deliveries failed", failures)
+	}
+	if len(events) != 2 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if ev := events[0]; ev.Kind != emul.EventFill || ev.Side != emul.SideBuy || ev.Qty != 10 || ev.OrderID != 1 {
+		t.Fatalf("unexpected fill event %+v", ev)
+	}
+	if ev := events[1]; ev.Kind != emul.EventRiskBreach || ev.Tick != 2 || ev.Equity != 700 {
+		t.Fatalf("breach must fire once when first crossed: %+v", ev)
+	}
+}
+
+func TestChatNotifiersPostSummaries(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string][]map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		got[r.URL.Path] = append(got[r.URL.Path], msg)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	closes := make([]float64, 26)
+	for i := range closes {
+		closes[i] = 100
+	}
+	closes[23], closes[24], closes[25] = 110, 110, 110
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(closes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := &emul.Telegram{Token: "T0K", ChatID: "42", BaseURL: srv.URL, Kinds: []string{emul.EventDailyPnL}}
+	dc := &emul.Discord{WebhookURL: srv.URL + "/discord"}
+	s := emul.StrategyFunc(func(ex *emul.Exchange, bar emul.OHLCBar, _ []emul.Order) error {
+		if len(ex.Orders()) == 0 {
+			_, err := ex.OpenLong(1)
+			return err
+		}
+		return nil
+	})
+	inner := emul.WithNotifier(s, tg)
+	outer := emul.WithNotifier(inner, dc)
+	if _, err := emul.Run(emu, outer); err != nil {
+		t.Fatal(err)
+	}
+	want := "daily pnl 2024-01-01: +100.00, equity 1100.00"
+	if msgs := got["/botT0K/sendMessage"]; len(msgs) != 1 || msgs[0]["chat_id"] != "42" || msgs[0]["text"] != want {
+		t.Fatalf("unexpected telegram messages %+v", msgs)
+	}
+	msgs := got["/discord"]
+	if len(msgs) != 2 || msgs[1]["content"] != want {
+		t.Fatalf("unexpected discord messages %+v", msgs)
+	}
+	if msg := msgs[0]["content"]; msg != "fill #1 buy 10 @ 100.00 fee 0.00, equity 1000.00 (entry-long)" {
+		t.Fatalf("unexpected fill message %q", msg)
+	}
+
+	// The last day is only reported when the wrappers are closed.
+	if err := outer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Close(); err != nil {
+		t.Fatal(err)
+	}
+	last := "daily pnl 2024-01-02: +0.00, equity 1100.00"
+	if msgs := got["/discord"]; len(msgs) != 3 || msgs[2]["content"] != last {
+		t.Fatalf("unexpected discord messages after close %+v", msgs)
+	}
+	if msgs := got["/botT0K/sendMessage"]; len(msgs) != 2 || msgs[1]["text"] != last {
+		t.Fatalf("unexpected telegram messages after close %+v", msgs)
+	}
+	if err := outer.Close(); err != nil || len(got["/discord"]) != 3 {
+		t.Fatalf("a second close must not report the day again")
+	}
+
+	tg.Token, tg.BaseURL = "secret", "http://127.0.0.1:0"
+	if err := tg.Notify(context.Background(), emul.Event{Kind: emul.EventDailyPnL}); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected an error without the token, got %v", err)
+	}
+}
+
+type blockingNotifier struct {
+	release chan struct{}
+	err     error
+	mu      sync.Mutex
+	got     []emul.Event
+}
+
+func (b *blockingNotifier) Notify(ctx context.Context, ev emul.Event) error {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	b.got = append(b.got, ev)
+	b.mu.Unlock()
+	return b.err
+}
+
+func TestAsyncNotifierDoesNotBlockReplay(t *testing.T) {
+	emu, err := emul.NewEmulator(1000, 0, 0, 0, flatBars(100, 70, 65, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow := &blockingNotifier{release: make(chan struct{})}
+	async := emul.NewAsyncNotifier(slow, 2, time.Minute, nil)
+	s := emul.WithNotifier(emul.StrategyFunc(func(ex *emul.Exchange, _ emul.OHLCBar, _ []emul.Order) error {
+		if len(ex.Orders()) == 0 {
+			_, err := ex.OpenLong(1)
+			return err
+		}
+		return nil
+	}), async, emul.DrawdownRule(0.2))
+	if _, err := emul.Run(emu, s); err != nil {
+		t.Fatal(err)
+	}
+	if st := async.Stats(); st.Sent != 0 {
+		t.Fatalf("replay waited for delivery: %+v", st)
+	}
+	close(slow.release)
+	if err := async.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if st := async.Stats(); st.Sent != 2 || len(slow.got) != 2 || slow.got[0].Kind != emul.EventFill || slow.got[1].Kind != emul.EventRiskBreach {
+		t.Fatalf("unexpected delivery %+v %+v", st, slow.got)
+	}
+	if err := async.Notify(context.Background(), emul.Event{}); !errors.Is(err, emul.ErrNotifierClosed) {
+		t.Fatalf("notify after close: %v", err)
+	}
+}
+
+func TestAsyncNotifierReportsDropsAndFailures(t *testing.T) {
+	failing := &blockingNotifier{release: make(chan struct{}), err: errors.New("endpoint down")}
+	var mu sync.Mutex
+	var reported []error
+	var async *emul.AsyncNotifier
+	// The callback reads the stats, which must not deadlock on a dropped event.
+	async = emul.NewAsyncNotifier(failing, 1, time.Minute, func(_ emul.Event, err error) {
+		async.Stats()
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	})
+	// One event is in delivery and one is queued at most, so the third is dropped.
+	var dropped int
+	for i := range 3 {
+		if err := async.Notify(context.Background(), emul.Event{Kind: emul.EventFill, Tick: int64(i)}); errors.Is(err, emul.ErrNotifyQueueFull) {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Fatalf("a full queue must drop events")
+	}
+	close(failing.release)
+	if err := async.Close(); err == nil {
+		t.Fatalf("close must report failed deliveries")
+	}
+	st := async.Stats()
+	if st.Sent != 0 || st.Dropped != dropped || st.Failed != 3-dropped || len(reported) != 3 {
+		t.Fatalf("unexpected stats %+v, reported %v", st, reported)
+	}
+
+	slow := &blockingNotifier{release: make(chan struct{})}
+	async = emul.NewAsyncNotifier(slow, 1, 10*time.Millisecond, nil)
+	async.Notify(context.Background(), emul.Event{})
+	if err := async.Close(); err == nil || async.Stats().Failed != 1 {
+		t.Fatalf("a delivery past the timeout must fail: %v %+v", err, async.Stats())
+	}
+}
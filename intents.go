@@ -0,0 +1,71 @@
+package emul
+
+// Intent actions, one per placement call.
+const (
+	IntentOpenLong     = "open_long"
+	IntentOpenShort    = "open_short"
+	IntentLongLimit    = "long_limit"
+	IntentShortLimit   = "short_limit"
+	IntentClose        = "close"
+	IntentCloseLimit   = "close_limit"
+	IntentScaleIn      = "scale_in"
+	IntentScaleInLimit = "scale_in_limit"
+	IntentReverse      = "reverse"
+	IntentReverseLimit = "reverse_limit"
+	IntentCancel       = "cancel"
+)
+
+// Intent outcomes.
+const (
+	IntentFilled     = "filled"
+	IntentPlaced     = "placed"
+	IntentRejected   = "rejected"
+	IntentCanceled   = "canceled"
+	IntentNotPending = "not_pending"
+)
+
+// Intent is one placement call made on the exchange, whatever came of it. OrderID is set when
+// the call filled at once (including a limit filled on placement) and LimitID when it placed or
+// canceled a limit; Err holds the rejection error.
+type Intent struct {
+	Tick     int64
+	Action   string
+	Price    float64
+	Fraction float64
+	Reason   string
+	StopKind string
+	Outcome  string
+	OrderID  int64
+	LimitID  int64
+	Tag      string
+	Meta     map[string]string
+	Err      error
+}
+
+// Intents returns every placement call in the order it was made, including rejected ones and
+// limits that never filled, so a post-mortem can compare what a strategy tried with Orders.
+func (e *Exchange) Intents() []Intent {
+	return append([]Intent(nil), e.intents...)
+}
+
+func (e *Exchange) recordIntent(in Intent, order *Order, limitID int64, err error) {
+	in.Tick = e.tick
+	in.Tag = e.tag
+	in.Meta = e.meta
+	in.LimitID = limitID
+	in.Err = err
+	if filled, ok := e.executedByID[limitID]; ok && order == nil {
+		order = &filled
+	}
+	switch {
+	case order != nil:
+		in.Outcome, in.OrderID = IntentFilled, order.ID
+	case err != nil:
+		in.Outcome = IntentRejected
+	default:
+		in.Outcome = IntentPlaced
+	}
+	e.intents = append(e.intents, in)
+	// Metadata set with WithMeta belongs to this placement only.
+	e.meta = nil
+}
@@ -0,0 +1,130 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrIntervalMismatch = errors.New("bar interval does not match declared interval")
+
+// ParseInterval normalizes an interval name and reports unknown ones. Besides "d", "h" and "m"
+// it accepts a positive multiple of a unit (m, h, d, w) such as "5m", "15m", "4h" or "1w";
+// "1d", "1h" and "1m" map onto the single-letter names. The result is also the name of the
+// interval directory under a coin.
+func ParseInterval(raw string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	switch value {
+	case intervalDaily, intervalHourly, intervalMinute:
+		return value, nil
+	}
+	if len(value) < 2 {
+		return "", fmt.Errorf("invalid interval %q", raw)
+	}
+	unit := value[len(value)-1:]
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 || intervalUnits[unit] == 0 {
+		return "", fmt.Errorf("invalid interval %q", raw)
+	}
+	if n == 1 && unit != "w" {
+		return unit, nil
+	}
+	return strconv.Itoa(n) + unit, nil
+}
+
+var intervalUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// IntervalDuration returns the bar length of an interval name, or zero when it is unknown.
+func IntervalDuration(interval string) time.Duration {
+	name, err := ParseInterval(interval)
+	if err != nil {
+		return 0
+	}
+	if unit, ok := intervalUnits[name]; ok {
+		return unit
+	}
+	n, _ := strconv.Atoi(name[:len(name)-1])
+	return time.Duration(n) * intervalUnits[name[len(name)-1:]]
+}
+
+// Resample aggregates bars into buckets of the given interval aligned to UTC (weeks start on
+// Monday). Bars must carry times; each output bar is stamped with its bucket start.
+func Resample(bars []OHLCBar, interval string) ([]OHLCBar, error) {
+	d := IntervalDuration(interval)
+	if d == 0 {
+		return nil, fmt.Errorf("invalid interval %q", interval)
+	}
+	out := make([]OHLCBar, 0, len(bars))
+	for i, bar := range bars {
+		if bar.Time.IsZero() {
+			return nil, fmt.Errorf("bar %d has no time", i)
+		}
+		bucket := bar.Time.UTC().Truncate(d)
+		if n := len(out); n > 0 && out[n-1].Time.Equal(bucket) {
+			last := &out[n-1]
+			last.High = math.Max(last.High, bar.High)
+			last.Low = math.Min(last.Low, bar.Low)
+			last.Close = bar.Close
+			last.Volume += bar.Volume
+			continue
+		}
+		if n := len(out); n > 0 && bucket.Before(out[n-1].Time) {
+			return nil, fmt.Errorf("bar %d is out of order", i)
+		}
+		out = append(out, OHLCBar{Time: bucket, Open: bar.Open, High: bar.High, Low: bar.Low, Close: bar.Close, Volume: bar.Volume})
+	}
+	for i := range out {
+		out[i].Average = (out[i].Open + out[i].High + out[i].Low + out[i].Close) / 4
+	}
+	return out, nil
+}
+
+// InferInterval returns the most common positive spacing between consecutive timestamps, which
+// ignores occasional gaps and duplicates. Zero times are skipped; ok is false when fewer than
+// two timestamps are usable.
+func InferInterval(times []time.Time) (time.Duration, bool) {
+	counts := make(map[time.Duration]int)
+	var prev time.Time
+	for _, ts := range times {
+		if ts.IsZero() {
+			continue
+		}
+		if !prev.IsZero() {
+			if d := ts.Sub(prev); d > 0 {
+				counts[d]++
+			}
+		}
+		prev = ts
+	}
+	best, bestCount := time.Duration(0), 0
+	for d, n := range counts {
+		if n > bestCount || (n == bestCount && d < best) {
+			best, bestCount = d, n
+		}
+	}
+	return best, bestCount > 0
+}
+
+// checkInterval compares the inferred spacing of times with the declared interval, so an hourly
+// file placed under m/ is not replayed as minutes unnoticed.
+func checkInterval(dir string, interval string, times []time.Time, opts CSVReaderOptions) error {
+	declared := IntervalDuration(interval)
+	inferred, ok := InferInterval(times)
+	if declared == 0 || !ok || inferred == declared {
+		return nil
+	}
+	err := fmt.Errorf("%w: %s holds %s bars, declared %q (%s)", ErrIntervalMismatch, dir, inferred, interval, declared)
+	if opts.StrictIntervals {
+		return err
+	}
+	opts.warnInterval(err)
+	return nil
+}
@@ -0,0 +1,107 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type InvariantMode uint8
+
+const (
+	InvariantsOff InvariantMode = iota
+	// InvariantsError makes the mutating call (or Next) return an *InvariantError.
+	InvariantsError
+	// InvariantsPanic panics with the *InvariantError; intended for tests and fuzzing.
+	InvariantsPanic
+)
+
+var ErrInvariant = errors.New("exchange invariant violated")
+
+// InvariantError names the violated rule and carries a dump of the exchange state.
+type InvariantError struct {
+	Rule  string
+	Tick  int64
+	State string
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("%v at tick %d: %s\n%s", ErrInvariant, e.Tick, e.Rule, e.State)
+}
+
+func (e *InvariantError) Unwrap() error {
+	return ErrInvariant
+}
+
+// invariantEpsilon absorbs float rounding in balances that are clamped to zero elsewhere.
+const invariantEpsilon = 1e-9
+
+// SetInvariantMode enables accounting checks after every bar and every market order.
+func (e *Exchange) SetInvariantMode(mode InvariantMode) {
+	e.invariants = mode
+}
+
+// CheckInvariants validates the accounting state regardless of the configured mode.
+func (e *Exchange) CheckInvariants() error {
+	bal := e.Balance()
+	fail := func(rule string, args ...any) error {
+		return &InvariantError{
+			Rule:  fmt.Sprintf(rule, args...),
+			Tick:  e.tick,
+			State: e.stateDump(),
+		}
+	}
+	if math.IsNaN(bal.Equity) || math.IsInf(bal.Equity, 0) {
+		return fail("equity is %v", bal.Equity)
+	}
+	for _, v := range []float64{e.usd, e.position, e.entryPrice, e.shortCash, e.shortMargin} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fail("balance field is %v", v)
+		}
+	}
+	if e.usd < -invariantEpsilon {
+		return fail("usd is negative: %.10f", e.usd)
+	}
+	if e.shortCash < -invariantEpsilon {
+		return fail("short cash is negative: %.10f", e.shortCash)
+	}
+	if e.shortMargin < -invariantEpsilon {
+		return fail("short margin is negative: %.10f", e.shortMargin)
+	}
+	if e.position != 0 && e.entryPrice <= 0 {
+		return fail("position %.10f has no entry price", e.position)
+	}
+	if e.position == 0 && e.entryPrice != 0 {
+		return fail("flat position keeps entry price %.10f", e.entryPrice)
+	}
+	if e.position >= 0 && (e.shortCash != 0 || e.shortMargin != 0) {
+		return fail("short balances %.10f/%.10f without a short position", e.shortCash, e.shortMargin)
+	}
+	return nil
+}
+
+// checkInvariants applies the configured mode; it is called at the end of state transitions.
+func (e *Exchange) checkInvariants() error {
+	if e.invariants == InvariantsOff {
+		return nil
+	}
+	err := e.CheckInvariants()
+	if err != nil && e.invariants == InvariantsPanic {
+		panic(err)
+	}
+	return err
+}
+
+func (e *Exchange) stateDump() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  tick=%d lastPrice=%.10f prevPrice=%.10f spreadPct=%.6f\n", e.tick, e.lastPrice, e.prevPrice, e.spreadPct)
+	fmt.Fprintf(&sb, "  usd=%.10f position=%.10f entryPrice=%.10f\n", e.usd, e.position, e.entryPrice)
+	fmt.Fprintf(&sb, "  shortCash=%.10f shortMargin=%.10f\n", e.shortCash, e.shortMargin)
+	fmt.Fprintf(&sb, "  pending=%d orders=%d", len(e.pending), len(e.orders))
+	if n := len(e.orders); n > 0 {
+		o := e.orders[n-1]
+		fmt.Fprintf(&sb, "\n  lastOrder: id=%d side=%s qty=%.10f price=%.10f reason=%s tick=%d", o.ID, o.Side, o.Qty, o.Price, o.Reason, o.Tick)
+	}
+	return sb.String()
+}
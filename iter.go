@@ -0,0 +1,84 @@
+package emul
+
+import (
+	"errors"
+	"iter"
+)
+
+// All replays the remaining bars as an iterator of each bar and the orders filled on it:
+//
+//	for bar, fills := range emu.All() { ... }
+//	if err := emu.Err(); err != nil { ... }
+//
+// Iteration stops at the end of the bars or on the first error, which Err reports. Breaking
+// out of the loop leaves the emulator on the next bar, so a later All resumes from there.
+func (e *Emulator) All() iter.Seq2[OHLCBar, []Order] {
+	return func(yield func(OHLCBar, []Order) bool) {
+		e.setErr(nil)
+		for {
+			bar, executed, err := e.Next()
+			if err != nil {
+				if !errors.Is(err, ErrNoMoreBars) {
+					e.setErr(err)
+				}
+				return
+			}
+			if !yield(bar, executed) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error that stopped the last All iteration, nil when it ran out of bars or
+// was stopped by the loop.
+func (e *Emulator) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.iterErr
+}
+
+func (e *Emulator) setErr(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.iterErr = err
+}
+
+// OrdersSeq iterates the order history, keeping orders for which keep returns true (all when
+// keep is nil). It reads the history live, so orders filled while iterating are included.
+func (e *Exchange) OrdersSeq(keep func(Order) bool) iter.Seq[Order] {
+	return func(yield func(Order) bool) {
+		for i := 0; i < len(e.orders); i++ {
+			if o := e.orders[i]; (keep == nil || keep(o)) && !yield(o) {
+				return
+			}
+		}
+	}
+}
+
+// TradesSeq iterates the round trips paired from the order history (see PairTrades), keeping
+// those for which keep returns true (all when keep is nil).
+func (e *Exchange) TradesSeq(keep func(Trade) bool) iter.Seq[Trade] {
+	return func(yield func(Trade) bool) {
+		for _, t := range PairTrades(e.orders) {
+			if (keep == nil || keep(t)) && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// OrdersOnSide keeps orders filled on side.
+func OrdersOnSide(side OrderSide) func(Order) bool {
+	return func(o Order) bool { return o.Side == side }
+}
+
+// OrdersInCategory keeps orders whose reason falls in c, e.g. ReasonCategoryStop.
+func OrdersInCategory(c ReasonCategory) func(Order) bool {
+	return func(o Order) bool { return o.Category() == c }
+}
+
+// WinningTrades keeps trades with positive PnL.
+func WinningTrades(t Trade) bool {
+	return t.PnL > 0
+}
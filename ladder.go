@@ -0,0 +1,133 @@
+package emul
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidLadder = errors.New("ladder rungs need a positive gain and fractions summing to at most 1")
+
+// TakeProfitRung takes Fraction of the position (as it was when the ladder was set) off once the
+// price has moved Gain in the position's favour from the entry (0.02 for +2%).
+type TakeProfitRung struct {
+	Gain     float64
+	Fraction float64
+}
+
+// LadderLevel is the state of one rung: its price and quantity and, once reached, the ID of the
+// reduce order it produced.
+type LadderLevel struct {
+	Price   float64
+	Qty     float64
+	Filled  bool
+	OrderID int64
+}
+
+// SetTakeProfitLadder attaches reduce-only take-profit rungs to the open position, replacing any
+// ladder already set. The exchange checks them every bar after this one, after the bracket stop
+// and target: each rung reached fills at its price (at the open when the bar gapped past it)
+// with the maker fee, ReasonExit and StopKindTarget, and never more than the position left.
+// Rungs are part of the bracket, so a stop or any other close cancels the rungs not reached.
+func (e *Exchange) SetTakeProfitLadder(rungs ...TakeProfitRung) error {
+	if e.position == 0 {
+		return ErrNoPosition
+	}
+	total := 0.0
+	for _, r := range rungs {
+		if r.Gain <= 0 || r.Fraction <= 0 {
+			return ErrInvalidLadder
+		}
+		total += r.Fraction
+	}
+	if total > 1+1e-9 || len(rungs) == 0 {
+		return ErrInvalidLadder
+	}
+	dir := 1.0
+	if e.position < 0 {
+		dir = -1
+	}
+	size := math.Abs(e.position)
+	ladder := make([]LadderLevel, len(rungs))
+	for i, r := range rungs {
+		ladder[i] = LadderLevel{Price: e.entryPrice * (1 + dir*r.Gain), Qty: size * r.Fraction}
+	}
+	if e.bracket == nil {
+		e.bracket = &activeBracket{}
+	}
+	e.bracket.ladder = ladder
+	e.bracket.setTick = e.tick
+	return nil
+}
+
+// TakeProfitLadder returns the rungs of the open position's ladder, nil when none is set.
+func (e *Exchange) TakeProfitLadder() []LadderLevel {
+	if e.bracket == nil {
+		return nil
+	}
+	return append([]LadderLevel(nil), e.bracket.ladder...)
+}
+
+// checkLadder fills the rungs reached by bar, in order, and returns the first reduce order.
+func (e *Exchange) checkLadder(bar OHLCBar) *Order {
+	b := e.bracket
+	var first *Order
+	for i := 0; b != nil && i < len(b.ladder) && e.position != 0; i++ {
+		rung := &b.ladder[i]
+		if rung.Filled {
+			continue
+		}
+		price, ok := levelReached(bar, rung.Price, e.position < 0)
+		if !ok {
+			continue
+		}
+		var order Order
+		if rung.Qty >= math.Abs(e.position)-1e-12 {
+			order = e.closeAtPrice(price, ReasonExit, StopKindTarget, e.makerFee)
+		} else {
+			order = e.reduceAtPrice(price, rung.Qty, ReasonExit, StopKindTarget, e.makerFee)
+		}
+		rung.Filled, rung.OrderID = true, order.ID
+		if first == nil {
+			first = &order
+		}
+		// A full close removes the bracket with the rest of the ladder.
+		b = e.bracket
+	}
+	return first
+}
+
+// reduceAtPrice closes qty of the open position at price, keeping the rest and its entry price.
+// A short that cannot cover the part from its share of the proceeds and margin is closed whole.
+func (e *Exchange) reduceAtPrice(price float64, qty float64, reason string, stopKind string, fee float64) Order {
+	if e.lotSize > 0 {
+		qty = roundDownToStep(qty, e.lotSize)
+	}
+	if rest := math.Abs(e.position) - qty; qty <= 0 || rest <= 0 || (e.minQty > 0 && rest < e.minQty) {
+		return e.closeAtPrice(price, reason, stopKind, fee)
+	}
+	savedLast := e.lastPrice
+	e.lastPrice = price
+	defer func() { e.lastPrice = savedLast }()
+	equityBefore := e.Balance().Equity
+	if e.position > 0 {
+		execPrice := e.execPrice(SideSell, price, qty*price)
+		revenue := e.roundQuote(qty * execPrice)
+		feeUSD := e.roundQuote(revenue * fee)
+		e.usd += revenue - feeUSD
+		e.position -= qty
+		return e.recordOrder(SideSell, qty, price, execPrice, feeUSD, qty*(execPrice-price), equityBefore, reason, stopKind, e.tick)
+	}
+	execPrice := e.execPrice(SideBuy, price, qty*price)
+	cost := e.roundQuote(qty * execPrice)
+	feeUSD := e.roundQuote(cost * fee)
+	share := qty / -e.position
+	cash, margin := e.shortCash*share, e.shortMargin*share
+	if cash+margin < cost+feeUSD {
+		return e.closeAtPrice(price, reason, stopKind, fee)
+	}
+	e.shortCash -= cash
+	e.shortMargin -= margin
+	e.usd += cash + margin - cost - feeUSD
+	e.position += qty
+	return e.recordOrder(SideBuy, qty, price, execPrice, feeUSD, qty*(price-execPrice), equityBefore, reason, stopKind, e.tick)
+}
@@ -0,0 +1,128 @@
+package emul
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyConfig describes messaging delays for a REST/WS layer built on the emulator. Every
+// response waits Base plus a uniform jitter in [0, Jitter); with DelayProbability a fill
+// notification is held back by an extra Delay, so it can arrive after later ones.
+type LatencyConfig struct {
+	Base             time.Duration
+	Jitter           time.Duration
+	DelayProbability float64
+	Delay            time.Duration
+	Seed             uint64
+}
+
+// LatencyModel draws delays from a LatencyConfig; it is safe for concurrent use.
+type LatencyModel struct {
+	mu  sync.Mutex
+	cfg LatencyConfig
+	rng *rand.Rand
+}
+
+func NewLatencyModel(cfg LatencyConfig) (*LatencyModel, error) {
+	if cfg.Base < 0 || cfg.Jitter < 0 || cfg.Delay < 0 {
+		return nil, fmt.Errorf("latencies must not be negative")
+	}
+	if cfg.DelayProbability < 0 || cfg.DelayProbability > 1 {
+		return nil, fmt.Errorf("delay probability must be within [0, 1]")
+	}
+	return &LatencyModel{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+	}, nil
+}
+
+// ResponseDelay returns how long to hold a request's response.
+func (m *LatencyModel) ResponseDelay() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.responseDelay()
+}
+
+func (m *LatencyModel) responseDelay() time.Duration {
+	d := m.cfg.Base
+	if m.cfg.Jitter > 0 {
+		d += time.Duration(m.rng.Int64N(int64(m.cfg.Jitter)))
+	}
+	return d
+}
+
+func (m *LatencyModel) notificationDelay() (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.responseDelay()
+	if m.cfg.DelayProbability > 0 && m.rng.Float64() < m.cfg.DelayProbability {
+		return d + m.cfg.Delay, true
+	}
+	return d, false
+}
+
+// Notification is a fill event on its way to a client. Seq is the order in which fills
+// happened; clients see them in Deliver order.
+type Notification struct {
+	Seq     int64
+	Order   Order
+	Sent    time.Time
+	Deliver time.Time
+	Delayed bool
+}
+
+// NotificationQueue holds fill notifications until their delivery time. Times are passed in
+// explicitly, typically Exchange.Now, so it follows the same Clock as the exchange.
+type NotificationQueue struct {
+	mu      sync.Mutex
+	model   *LatencyModel
+	seq     int64
+	pending []Notification
+}
+
+func NewNotificationQueue(model *LatencyModel) *NotificationQueue {
+	return &NotificationQueue{model: model}
+}
+
+// Push schedules a notification for each order, e.g. the executed slice of Emulator.Next.
+func (q *NotificationQueue) Push(now time.Time, orders ...Order) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, o := range orders {
+		q.seq++
+		n := Notification{Seq: q.seq, Order: o, Sent: now, Deliver: now}
+		if q.model != nil {
+			d, delayed := q.model.notificationDelay()
+			n.Deliver, n.Delayed = now.Add(d), delayed
+		}
+		q.pending = append(q.pending, n)
+	}
+}
+
+// Due removes and returns the notifications deliverable at now, ordered by delivery time.
+func (q *NotificationQueue) Due(now time.Time) []Notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []Notification
+	kept := q.pending[:0]
+	for _, n := range q.pending {
+		if n.Deliver.After(now) {
+			kept = append(kept, n)
+			continue
+		}
+		due = append(due, n)
+	}
+	q.pending = kept
+	sort.SliceStable(due, func(i, j int) bool { return due[i].Deliver.Before(due[j].Deliver) })
+	return due
+}
+
+// Pending returns how many notifications are still in flight.
+func (q *NotificationQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
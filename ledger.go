@@ -0,0 +1,45 @@
+package emul
+
+// Ledger entry kinds. Amounts are signed USD: positive when the account received cash.
+const (
+	LedgerFee     = "fee"
+	LedgerRebate  = "rebate"
+	LedgerFunding = "funding"
+)
+
+// LedgerEntry is one cash flow that is not a trade's principal: fees paid, maker rebates
+// received, perp funding, borrow interest, staking rewards, transfers and withdrawals. OrderID
+// is 0 for flows without an order (funding, staking, option fees).
+type LedgerEntry struct {
+	Tick    int64
+	Kind    string
+	Amount  float64
+	OrderID int64
+	// Flow is the ledger kind a LedgerFee was charged on for flow fees (see SetFlowFee); it is
+	// empty for order fees.
+	Flow string
+}
+
+// Ledger returns the cash-flow entries in the order they happened.
+func (e *Exchange) Ledger() []LedgerEntry {
+	return append([]LedgerEntry(nil), e.ledger...)
+}
+
+// LedgerTotals sums ledger amounts by kind.
+func (e *Exchange) LedgerTotals() map[string]float64 {
+	out := make(map[string]float64)
+	for _, entry := range e.ledger {
+		out[entry.Kind] += entry.Amount
+	}
+	return out
+}
+
+// recordFee books an order's fee; a negative fee is a rebate.
+func (e *Exchange) recordFee(orderID int64, feeUSD float64) {
+	switch {
+	case feeUSD > 0:
+		e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerFee, Amount: -feeUSD, OrderID: orderID})
+	case feeUSD < 0:
+		e.ledger = append(e.ledger, LedgerEntry{Tick: e.tick, Kind: LedgerRebate, Amount: -feeUSD, OrderID: orderID})
+	}
+}
@@ -0,0 +1,240 @@
+package emul
+
+// LimitMode selects how pending limit orders are matched against bars.
+type LimitMode uint8
+
+const (
+	// LimitsFillOrClose processes the queue in FIFO order on the next bar: a limit whose price
+	// is inside the bar fills there, otherwise it fills at the close and a LimitMiss is logged.
+	LimitsFillOrClose LimitMode = iota
+	// LimitsRest keeps limits resting until a bar trades through their price. Orders that do
+	// not match the position state (an entry while a position is open, a close while flat)
+	// also stay queued, so ladders of entries and exits can be left on the book.
+	LimitsRest
+)
+
+// LimitEligibility selects the first bar a new limit can fill on.
+type LimitEligibility uint8
+
+const (
+	// LimitNextBar matches a limit from the bar after the one it was placed on (the default).
+	LimitNextBar LimitEligibility = iota
+	// LimitSameBar also matches a limit against the range of the bar it was placed on, as if it
+	// had been placed before the rest of that bar traded. It suits tick-like data where a bar is
+	// a single print; on wide OHLC bars it is optimistic. A limit that does not fill at placement
+	// rests as with LimitNextBar. In LimitsFillOrClose only a limit at the head of the queue is
+	// matched, and never at the close.
+	LimitSameBar
+	// LimitImmediateIfMarketable executes a limit at placement when it is marketable, i.e. a buy
+	// at or above the price it would pay now or a sell at or below it, as a venue would. It fills
+	// at the market price (the better of the two) with the taker fee; other limits rest as with
+	// LimitNextBar.
+	LimitImmediateIfMarketable
+)
+
+func (e *Exchange) SetLimitEligibility(mode LimitEligibility) {
+	e.eligibility = mode
+}
+
+// queueLimit assigns p a limit ID, queues it and applies the eligibility mode.
+func (e *Exchange) queueLimit(p pendingOrder) int64 {
+	e.nextLimitID++
+	p.id = e.nextLimitID
+	p.placedAtTick = e.tick
+	p.lastReason = "await_next_candle"
+	p.placedBar = e.lastBar
+	p.tag, p.meta = e.tag, e.meta
+	e.pending = append(e.pending, p)
+	e.trackPlaced(p)
+	switch {
+	case e.eligibility == LimitSameBar && e.hasLastBar:
+		e.fillOnPlacement(len(e.pending) - 1)
+	case e.eligibility == LimitImmediateIfMarketable && e.lastPrice > 0:
+		e.fillMarketable(len(e.pending) - 1)
+	}
+	return p.id
+}
+
+// fillOnPlacement matches the pending order at index i against the bar it was placed on.
+func (e *Exchange) fillOnPlacement(i int) {
+	p := e.pending[i]
+	if e.limitMode == LimitsFillOrClose && i != 0 {
+		return
+	}
+	if !priceInRange(p.price, e.lastBar.Low, e.lastBar.High) || !e.pendingMatchesPosition(p.kind) || !e.queueFilled(p, e.lastBar) {
+		return
+	}
+	e.pending = append(e.pending[:i:i], e.pending[i+1:]...)
+	if executed, err := e.fillPending(p, p.price, e.makerFee); executed == nil {
+		e.limitFailed["open_rejected"]++
+		e.reject(p, "open_rejected", err)
+	}
+}
+
+// fillMarketable executes the pending order at index i at the last price when that price
+// (after spread and slippage) is at least as good as its limit.
+func (e *Exchange) fillMarketable(i int) {
+	p := e.pending[i]
+	side := e.pendingSide(p.kind)
+	exec := e.execPrice(side, e.lastPrice, e.pendingNotional(p, e.lastPrice))
+	if side == SideBuy && exec > p.price || side == SideSell && exec < p.price {
+		return
+	}
+	if !e.pendingMatchesPosition(p.kind) {
+		return
+	}
+	e.pending = append(e.pending[:i:i], e.pending[i+1:]...)
+	if executed, err := e.fillPending(p, e.lastPrice, e.fee); executed == nil {
+		e.limitFailed["open_rejected"]++
+		e.reject(p, "open_rejected", err)
+	}
+}
+
+// LimitState is the lifecycle state of a limit order.
+type LimitState uint8
+
+const (
+	// LimitUnknown is reported for IDs the exchange never issued.
+	LimitUnknown LimitState = iota
+	LimitPending
+	LimitFilled
+	LimitCanceled
+	// LimitExpired marks a limit dropped without filling: it did not match the position state
+	// when it came up in LimitsFillOrClose, or its open was rejected (e.g. below the minimum lot).
+	LimitExpired
+)
+
+func (s LimitState) String() string {
+	switch s {
+	case LimitPending:
+		return "pending"
+	case LimitFilled:
+		return "filled"
+	case LimitCanceled:
+		return "canceled"
+	case LimitExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+func (e *Exchange) SetLimitMode(mode LimitMode) {
+	e.limitMode = mode
+}
+
+func (e *Exchange) LimitMode() LimitMode {
+	return e.limitMode
+}
+
+// CancelLimit removes a pending limit order; it reports false when id is not pending.
+func (e *Exchange) CancelLimit(id int64) bool {
+	in := Intent{Tick: e.tick, Action: IntentCancel, LimitID: id, Outcome: IntentNotPending, Tag: e.tag, Meta: e.meta}
+	for i, p := range e.pending {
+		if p.id == id {
+			e.pending = append(e.pending[:i:i], e.pending[i+1:]...)
+			in.Outcome = IntentCanceled
+			e.limitDone[id] = LimitCanceled
+			e.trackLimit(id, LimitEvent{Kind: LimitEventCanceled})
+			break
+		}
+	}
+	e.intents = append(e.intents, in)
+	e.meta = nil
+	return in.Outcome == IntentCanceled
+}
+
+// LimitStatus reports the state of a limit order and, when it filled, the order it produced.
+func (e *Exchange) LimitStatus(id int64) (LimitState, *Order) {
+	if order, ok := e.executedByID[id]; ok {
+		return LimitFilled, &order
+	}
+	if state, ok := e.limitDone[id]; ok {
+		return state, nil
+	}
+	for _, p := range e.pending {
+		if p.id == id {
+			return LimitPending, nil
+		}
+	}
+	return LimitUnknown, nil
+}
+
+// ExecutedOrder returns the order a filled limit produced.
+func (e *Exchange) ExecutedOrder(id int64) (Order, bool) {
+	order, ok := e.executedByID[id]
+	return order, ok
+}
+
+// Rejection is a pending limit, or a market order deferred to the open (LimitID 0), the exchange
+// dropped without filling while applying a bar. Reason is "position_state_mismatch" or
+// "open_rejected"; Err is the open's error for the latter.
+type Rejection struct {
+	Tick     int64
+	LimitID  int64
+	Kind     string
+	Price    float64
+	Fraction float64
+	Reason   string
+	Err      error
+}
+
+// Rejections returns the limits dropped on the last bar, so a strategy can re-place or adjust
+// them in the same OnBar call.
+func (e *Exchange) Rejections() []Rejection {
+	return append([]Rejection(nil), e.rejected...)
+}
+
+func (e *Exchange) reject(p pendingOrder, reason string, err error) {
+	e.rejected = append(e.rejected, Rejection{
+		Tick:     e.tick,
+		LimitID:  p.id,
+		Kind:     pendingKindName(p.kind),
+		Price:    p.price,
+		Fraction: p.fraction,
+		Reason:   reason,
+		Err:      err,
+	})
+	e.trackLimit(p.id, LimitEvent{Kind: LimitEventExpired, Reason: reason})
+}
+
+// processResting fills every eligible limit touched by bar and passed by the fill model, in
+// queue order, at its own price with the maker fee. Other orders keep their place in the queue.
+func (e *Exchange) processResting(bar OHLCBar) *Order {
+	var firstExecuted *Order
+	kept := e.pending[:0]
+	for _, p := range e.pending {
+		if e.tick <= p.placedAtTick {
+			kept = append(kept, p)
+			continue
+		}
+		if !priceInRange(p.price, bar.Low, bar.High) {
+			p.lastReason = "price_not_touched"
+			e.trackLimit(p.id, LimitEvent{Kind: LimitEventEvaluated, Reason: p.lastReason})
+			kept = append(kept, p)
+			continue
+		}
+		if !e.pendingMatchesPosition(p.kind) {
+			p.lastReason = "position_state_mismatch"
+			e.trackLimit(p.id, LimitEvent{Kind: LimitEventEvaluated, Reason: p.lastReason})
+			kept = append(kept, p)
+			continue
+		}
+		if !e.queueFilled(p, bar) {
+			p.lastReason = "queue_not_filled"
+			e.trackLimit(p.id, LimitEvent{Kind: LimitEventEvaluated, Reason: p.lastReason})
+			kept = append(kept, p)
+			continue
+		}
+		executed, err := e.fillPending(p, p.price, e.makerFee)
+		if executed == nil {
+			e.limitFailed["open_rejected"]++
+			e.reject(p, "open_rejected", err)
+			continue
+		}
+		if firstExecuted == nil {
+			firstExecuted = executed
+		}
+	}
+	e.pending = kept
+	return firstExecuted
+}
@@ -0,0 +1,48 @@
+package emul
+
+import "fmt"
+
+// LiquidationConfig describes the margin terms used to report a theoretical liquidation price
+// the way a futures venue would for an isolated position: Leverage is the initial leverage and
+// MaintenanceMargin the maintenance margin rate (0.005 = 0.5%). It only affects reporting; the
+// exchange itself still trades 1x spot and shorts against posted collateral.
+type LiquidationConfig struct {
+	Leverage          float64
+	MaintenanceMargin float64
+}
+
+// SetLiquidationConfig sets the margin terms of the reported liquidation price; the zero value
+// reports it for 1x collateral. Leverage may not exceed the symbol's MaxLeverage.
+func (e *Exchange) SetLiquidationConfig(cfg LiquidationConfig) error {
+	if cfg.Leverage < 0 || cfg.MaintenanceMargin < 0 || cfg.MaintenanceMargin >= 1 {
+		return fmt.Errorf("leverage must not be negative and maintenance margin must be within [0, 1)")
+	}
+	if cfg.Leverage > 0 && cfg.Leverage < 1 {
+		return fmt.Errorf("leverage must be at least 1")
+	}
+	if e.maxLeverage > 0 && cfg.Leverage > e.maxLeverage {
+		return fmt.Errorf("leverage %g exceeds the symbol's max leverage %g", cfg.Leverage, e.maxLeverage)
+	}
+	e.liquidation = cfg
+	return nil
+}
+
+// liquidationPrice is the price at which the open position would be liquidated, 0 when there
+// is none. Without leverage configured it is where buying back a short, taker fee included,
+// would consume all of its collateral; a 1x long cannot be liquidated.
+func (e *Exchange) liquidationPrice() float64 {
+	if e.position == 0 || e.entryPrice <= 0 {
+		return 0
+	}
+	cfg := e.liquidation
+	if cfg.Leverage > 0 {
+		if e.position > 0 {
+			return max(e.entryPrice*(1-1/cfg.Leverage)/(1-cfg.MaintenanceMargin), 0)
+		}
+		return e.entryPrice * (1 + 1/cfg.Leverage) / (1 + cfg.MaintenanceMargin)
+	}
+	if e.position > 0 {
+		return 0
+	}
+	return (e.shortCash + e.shortMargin) / (-e.position * (1 + e.fee))
+}
@@ -0,0 +1,201 @@
+package emul
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+const modulePath = "github.com/svanichkin/ExchangeEmulator"
+
+// ManifestConfig is the part of a run that determines its result; ConfigHash is taken over it.
+type ManifestConfig struct {
+	StartUSD    float64
+	Costs       CostProfile
+	Symbol      string
+	Bars        int
+	FirstBar    time.Time
+	LastBar     time.Time
+	LimitMode   LimitMode
+	Eligibility LimitEligibility
+	Precision   Precision
+	SizeBuckets []SizeBucket
+	Staking     StakingConfig
+	Timing      DecisionTiming
+	FillTiming  FillTiming
+	TimeExit    TimeExit
+	Invariants  InvariantMode
+	Accounts    []string
+	// Seeds lists the random seeds in use: the run seed (SetSeed) and those of exchange models.
+	Seeds map[string]uint64
+	// SessionLimit is the session loss limit, nil when sessions are not tracked.
+	SessionLimit *SessionLimit
+	// FlowFees and BorrowAPR are the costs of non-fill cash flows (see SetFlowFee).
+	FlowFees  map[string]FlowFee
+	BorrowAPR float64
+}
+
+type ManifestFile struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
+// Manifest describes a run well enough to reproduce it: the configuration and its hash, the data
+// files with checksums and the build that produced it (module version and VCS revision).
+type Manifest struct {
+	Config     ManifestConfig
+	ConfigHash string
+	RunID      string
+	Files      []ManifestFile
+	Module     string
+	Version    string
+	Revision   string
+	GoVersion  string
+	CreatedAt  time.Time
+}
+
+// SetDataFiles records the files the bars were loaded from; NewEmulatorFromCSV sets it itself.
+func (e *Emulator) SetDataFiles(paths ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.files = append([]string(nil), paths...)
+}
+
+// SetSeed records the seed a strategy or scenario used, so it appears in the manifest.
+func (e *Emulator) SetSeed(seed uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seed = &seed
+}
+
+// Manifest builds the run manifest. Data files are read to checksum them.
+func (e *Emulator) Manifest() (Manifest, error) {
+	e.mu.Lock()
+	cfg := ManifestConfig{
+		StartUSD:    e.startUSD,
+		Costs:       e.ex.CostProfile(),
+		Symbol:      e.ex.symbol,
+		Bars:        len(e.bars),
+		LimitMode:   e.ex.limitMode,
+		Eligibility: e.ex.eligibility,
+		Precision:   e.ex.Precision(),
+		SizeBuckets: e.ex.SizeBuckets(),
+		Staking:     e.ex.Staking(),
+		Timing:      e.timing,
+		FillTiming:  e.ex.fillTiming,
+		TimeExit:    e.ex.timeExit,
+		Invariants:  e.ex.invariants,
+		Seeds:       make(map[string]uint64),
+	}
+	cfg.Costs.Name = e.costs.Name
+	if len(e.bars) > 0 {
+		cfg.FirstBar, cfg.LastBar = e.bars[0].Time, e.bars[len(e.bars)-1].Time
+	}
+	for key := range e.accounts {
+		cfg.Accounts = append(cfg.Accounts, key)
+	}
+	sort.Strings(cfg.Accounts)
+	if len(e.ex.flowFees) > 0 {
+		cfg.FlowFees = e.ex.FlowFees()
+	}
+	cfg.BorrowAPR = e.ex.borrowAPR
+	if limit, ok := e.ex.SessionLimit(); ok {
+		cfg.SessionLimit = &limit
+	}
+	if e.seed != nil {
+		cfg.Seeds["run"] = *e.seed
+	}
+	if e.ex.recall != nil {
+		cfg.Seeds["borrow-recall"] = e.ex.recall.cfg.Seed
+	}
+	if e.ex.fillModel != nil {
+		cfg.Seeds["fill-model"] = e.ex.fillModel.cfg.Seed
+	}
+	if e.ex.perp != nil && e.ex.perp.cfg.ADL != nil {
+		cfg.Seeds["perp-adl"] = e.ex.perp.cfg.ADL.Seed
+	}
+	files := append([]string(nil), e.files...)
+	runID := e.runID
+	e.mu.Unlock()
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return Manifest{}, err
+	}
+	sum := sha256.Sum256(data)
+	m := Manifest{
+		Config:     cfg,
+		ConfigHash: hex.EncodeToString(sum[:]),
+		RunID:      runID,
+		Module:     modulePath,
+		GoVersion:  runtime.Version(),
+		CreatedAt:  time.Now().UTC(),
+	}
+	for _, path := range files {
+		f, err := checksumFile(path)
+		if err != nil {
+			return Manifest{}, err
+		}
+		m.Files = append(m.Files, f)
+	}
+	m.Version, m.Revision = buildVersion()
+	return m, nil
+}
+
+func WriteManifest(w io.Writer, m Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+func checksumFile(path string) (ManifestFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Path: path, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// buildVersion returns the package version from the build info and, when the package is the
+// main module, the VCS revision ("-dirty" when built from modified sources).
+func buildVersion() (string, string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+		}
+	}
+	revision, dirty := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if revision != "" && dirty {
+		revision += "-dirty"
+	}
+	return version, revision
+}
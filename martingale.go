@@ -0,0 +1,140 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+type SizingKind uint8
+
+const (
+	// SizingFixed stakes BaseFraction on every trade.
+	SizingFixed SizingKind = iota
+	// SizingMartingale multiplies the stake after each loss and resets it after a win.
+	SizingMartingale
+	// SizingAntiMartingale multiplies the stake after each win and resets it after a loss.
+	SizingAntiMartingale
+)
+
+// SizingPolicy describes a stake-escalation rule. Stakes are fractions of current equity and
+// never exceed 1 (no leverage).
+type SizingPolicy struct {
+	Kind         SizingKind
+	BaseFraction float64
+	Multiplier   float64
+	// MaxSteps caps consecutive escalations; 0 means no cap other than a stake of 1.
+	MaxSteps int
+}
+
+// SizingResult is the equity path of a trade log replayed under a policy. Equity[i] and
+// Stakes[i] belong to trade i. RuinTrade is the index of the trade that breached the ruin
+// level, or -1.
+type SizingResult struct {
+	Equity      []float64
+	Stakes      []float64
+	FinalEquity float64
+	MaxStake    float64
+	MaxDrawdown float64
+	RuinTrade   int
+}
+
+// SimulateSizing replays the trades with the policy's stakes. Each trade contributes its return
+// on notional (PnL over entry value, so fees are included) times the stake; the run stops once
+// equity falls to ruinLevel times startEquity (e.g. 0.5). A ruinLevel of 0 stops only at zero.
+func SimulateSizing(trades []Trade, startEquity float64, policy SizingPolicy, ruinLevel float64) (SizingResult, error) {
+	if err := policy.validate(); err != nil {
+		return SizingResult{}, err
+	}
+	returns := make([]float64, len(trades))
+	for i, t := range trades {
+		returns[i] = notionalReturn(t)
+	}
+	return simulateSizing(returns, startEquity, policy, ruinLevel), nil
+}
+
+// RuinProbability estimates the chance of reaching the ruin level by replaying paths bootstrap
+// resamples of the trade log (same length, drawn with replacement) under the policy.
+func RuinProbability(trades []Trade, startEquity float64, policy SizingPolicy, ruinLevel float64, paths int, seed uint64) (float64, error) {
+	if err := policy.validate(); err != nil {
+		return 0, err
+	}
+	if len(trades) == 0 || paths <= 0 {
+		return 0, fmt.Errorf("need trades and a positive path count")
+	}
+	returns := make([]float64, len(trades))
+	for i, t := range trades {
+		returns[i] = notionalReturn(t)
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	sample := make([]float64, len(returns))
+	ruined := 0
+	for p := 0; p < paths; p++ {
+		for i := range sample {
+			sample[i] = returns[rng.IntN(len(returns))]
+		}
+		if simulateSizing(sample, startEquity, policy, ruinLevel).RuinTrade >= 0 {
+			ruined++
+		}
+	}
+	return float64(ruined) / float64(paths), nil
+}
+
+func (p SizingPolicy) validate() error {
+	if p.BaseFraction <= 0 || p.BaseFraction > 1 {
+		return ErrInvalidFraction
+	}
+	if p.Kind != SizingFixed && p.Multiplier <= 0 {
+		return fmt.Errorf("multiplier must be positive")
+	}
+	if p.Kind > SizingAntiMartingale {
+		return fmt.Errorf("unknown sizing kind %d", p.Kind)
+	}
+	return nil
+}
+
+func simulateSizing(returns []float64, startEquity float64, policy SizingPolicy, ruinLevel float64) SizingResult {
+	res := SizingResult{
+		Equity:    make([]float64, 0, len(returns)),
+		Stakes:    make([]float64, 0, len(returns)),
+		RuinTrade: -1,
+	}
+	equity, peak := startEquity, startEquity
+	steps := 0
+	for i, r := range returns {
+		stake := math.Min(policy.BaseFraction*math.Pow(policy.Multiplier, float64(steps)), 1)
+		if policy.Kind == SizingFixed {
+			stake = policy.BaseFraction
+		}
+		equity = math.Max(equity*(1+stake*r), 0)
+		res.Equity = append(res.Equity, equity)
+		res.Stakes = append(res.Stakes, stake)
+		res.MaxStake = math.Max(res.MaxStake, stake)
+		peak = math.Max(peak, equity)
+		if peak > 0 {
+			res.MaxDrawdown = math.Max(res.MaxDrawdown, (peak-equity)/peak)
+		}
+		if equity <= startEquity*ruinLevel || equity == 0 {
+			res.RuinTrade = i
+			break
+		}
+		escalate := (policy.Kind == SizingMartingale && r < 0) || (policy.Kind == SizingAntiMartingale && r > 0)
+		switch {
+		case r == 0:
+		case escalate && (policy.MaxSteps == 0 || steps < policy.MaxSteps):
+			steps++
+		case !escalate:
+			steps = 0
+		}
+	}
+	res.FinalEquity = equity
+	return res
+}
+
+func notionalReturn(t Trade) float64 {
+	notional := t.Qty * t.EntryPrice
+	if notional <= 0 {
+		return 0
+	}
+	return t.PnL / notional
+}
@@ -0,0 +1,42 @@
+package emul
+
+import (
+	"maps"
+	"sort"
+)
+
+// WithMeta attaches metadata (e.g. a note, the signal strength or an indicator snapshot) to the
+// next placement call and returns the exchange for chaining:
+//
+//	ex.WithMeta(map[string]string{"signal": "breakout", "rsi": "71.2"}).OpenLong(0.5)
+//
+// The metadata is copied and carried to the intent, the pending limit and the orders the
+// placement fills, including fills on later bars, and from there to exports and stored runs.
+func (e *Exchange) WithMeta(meta map[string]string) *Exchange {
+	e.meta = maps.Clone(meta)
+	return e
+}
+
+// MetaGroup holds the statistics of the trades whose entry carried one value of a metadata key.
+type MetaGroup struct {
+	Value  string
+	Trades []Trade
+	Stats  TradeStats
+}
+
+// GroupTradesByMeta groups trades by the entry's value for key, sorted by value, so outcomes can
+// be compared across signal features. Trades without the key fall in the group with the empty
+// value.
+func GroupTradesByMeta(trades []Trade, key string) []MetaGroup {
+	byValue := make(map[string][]Trade)
+	for _, t := range trades {
+		v := t.Entry.Meta[key]
+		byValue[v] = append(byValue[v], t)
+	}
+	out := make([]MetaGroup, 0, len(byValue))
+	for v, ts := range byValue {
+		out = append(out, MetaGroup{Value: v, Trades: ts, Stats: ComputeTradeStats(ts)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Value < out[j].Value })
+	return out
+}
@@ -0,0 +1,86 @@
+package emul
+
+import "fmt"
+
+// MissRetentionMode selects which LimitMiss entries an Exchange keeps.
+type MissRetentionMode uint8
+
+const (
+	// MissKeepAll keeps every miss (the default).
+	MissKeepAll MissRetentionMode = iota
+	// MissKeepFirst keeps the first MaxEntries misses and only counts the rest.
+	MissKeepFirst
+	// MissRingBuffer keeps the latest MaxEntries misses.
+	MissRingBuffer
+	// MissAggregateOnly keeps no miss entries, only the per-reason counters.
+	MissAggregateOnly
+)
+
+// MissRetention bounds the memory used by the limit miss log. A far-off limit on minute data
+// logs a miss on every bar; the bounded modes keep LimitDiagnostics' counters exact while
+// capping the stored entries. MissKeepFirst and MissRingBuffer also keep at most MaxEntries
+// timelines of finished limits (see LimitTimeline).
+type MissRetention struct {
+	Mode       MissRetentionMode
+	MaxEntries int
+}
+
+// SetMissRetention sets the miss-log retention policy. Entries already logged are trimmed to it.
+func (e *Exchange) SetMissRetention(r MissRetention) error {
+	if r.Mode > MissAggregateOnly {
+		return fmt.Errorf("unknown miss retention mode %d", r.Mode)
+	}
+	if (r.Mode == MissKeepFirst || r.Mode == MissRingBuffer) && r.MaxEntries <= 0 {
+		return fmt.Errorf("miss retention needs a positive max entries, got %d", r.MaxEntries)
+	}
+	misses := e.missLog()
+	e.missRetention = r
+	e.misses, e.missStart = nil, 0
+	for _, m := range misses {
+		e.storeMiss(m)
+	}
+	e.trimTimelines()
+	return nil
+}
+
+func (e *Exchange) MissRetention() MissRetention {
+	return e.missRetention
+}
+
+// recordMiss counts m and stores it as the diagnostics level and retention policy allow.
+func (e *Exchange) recordMiss(m LimitMiss) {
+	if e.diagnostics == DiagnosticsOff {
+		return
+	}
+	if e.missCounts == nil {
+		e.missCounts = make(map[string]int)
+	}
+	e.missCounts[m.Reason]++
+	if e.diagnostics == DiagnosticsFull {
+		e.storeMiss(m)
+	}
+}
+
+func (e *Exchange) storeMiss(m LimitMiss) {
+	r := e.missRetention
+	switch {
+	case r.Mode == MissAggregateOnly, r.Mode == MissKeepFirst && len(e.misses) >= r.MaxEntries:
+		e.missDropped++
+	case r.Mode == MissRingBuffer && len(e.misses) >= r.MaxEntries:
+		e.misses[e.missStart] = m
+		e.missStart = (e.missStart + 1) % len(e.misses)
+		e.missDropped++
+	default:
+		e.misses = append(e.misses, m)
+	}
+}
+
+// missLog returns the stored misses oldest first.
+func (e *Exchange) missLog() []LimitMiss {
+	if len(e.misses) == 0 {
+		return nil
+	}
+	out := make([]LimitMiss, 0, len(e.misses))
+	out = append(out, e.misses[e.missStart:]...)
+	return append(out, e.misses[:e.missStart]...)
+}
@@ -0,0 +1,134 @@
+package emul
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Param is a continuous strategy parameter searched within [Min, Max].
+type Param struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// Objective scores a finished job; optimizers maximize it.
+type Objective interface {
+	Score(res BatchResult) float64
+}
+
+type ObjectiveFunc func(res BatchResult) float64
+
+func (f ObjectiveFunc) Score(res BatchResult) float64 {
+	return f(res)
+}
+
+// ObjectiveSharpe scores a run by CurveSharpe.
+var ObjectiveSharpe = ObjectiveFunc(func(res BatchResult) float64 {
+	return CurveSharpe(res.Equity)
+})
+
+// ObjectiveReturn scores a run by its total return.
+var ObjectiveReturn = ObjectiveFunc(func(res BatchResult) float64 {
+	if len(res.Equity) == 0 || res.Equity[0].Equity <= 0 {
+		return 0
+	}
+	return res.Equity[len(res.Equity)-1].Equity/res.Equity[0].Equity - 1
+})
+
+// OptimizeConfig is shared by the optimizers. Job builds a fresh job for a parameter vector (in
+// Params order); the jobs of a generation run in parallel on the optimizer's BatchRunner.
+// Budget is the total number of evaluations (default 100); Population is the number per
+// generation (a default suited to the optimizer when 0).
+type OptimizeConfig struct {
+	Params     []Param
+	Job        func(x []float64) BatchJob
+	Objective  Objective
+	Budget     int
+	Population int
+	Seed       uint64
+}
+
+// Trial is one evaluated parameter vector. Failed and pruned runs score -Inf.
+type Trial struct {
+	Generation  int
+	Params      []float64
+	Score       float64
+	Equity      []EquityPoint
+	PruneReason string
+	Err         error
+}
+
+type OptimizeResult struct {
+	Best   Trial
+	Trials []Trial
+}
+
+// Top returns the n best trials, best first.
+func (r OptimizeResult) Top(n int) []Trial {
+	trials := append([]Trial(nil), r.Trials...)
+	sort.SliceStable(trials, func(i, j int) bool { return trials[i].Score > trials[j].Score })
+	return trials[:min(n, len(trials))]
+}
+
+func (cfg *OptimizeConfig) validate() error {
+	if len(cfg.Params) == 0 {
+		return fmt.Errorf("no parameters to optimize")
+	}
+	for _, p := range cfg.Params {
+		if !(p.Max > p.Min) {
+			return fmt.Errorf("parameter %q: max %v must exceed min %v", p.Name, p.Max, p.Min)
+		}
+	}
+	if cfg.Job == nil {
+		return fmt.Errorf("job builder is nil")
+	}
+	if cfg.Objective == nil {
+		return fmt.Errorf("objective is nil")
+	}
+	if cfg.Budget <= 0 {
+		cfg.Budget = 100
+	}
+	return nil
+}
+
+// denormalize maps a point of the unit cube onto the parameter ranges.
+func (cfg *OptimizeConfig) denormalize(u []float64) []float64 {
+	x := make([]float64, len(u))
+	for i, p := range cfg.Params {
+		x[i] = p.Min + math.Min(math.Max(u[i], 0), 1)*(p.Max-p.Min)
+	}
+	return x
+}
+
+// evaluate runs one generation of unit-cube points and appends the trials to res.
+func (cfg *OptimizeConfig) evaluate(ctx context.Context, r *BatchRunner, gen int, points [][]float64, res *OptimizeResult) ([]float64, error) {
+	jobs := make([]BatchJob, len(points))
+	params := make([][]float64, len(points))
+	for i, u := range points {
+		params[i] = cfg.denormalize(u)
+		jobs[i] = cfg.Job(params[i])
+	}
+	results := r.RunAll(ctx, jobs)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(points))
+	for i, br := range results {
+		t := Trial{Generation: gen, Params: params[i], Equity: br.Equity, PruneReason: br.PruneReason, Err: br.Err}
+		t.Score = math.Inf(-1)
+		if br.Err == nil && br.PruneReason == "" {
+			if s := cfg.Objective.Score(br); !math.IsNaN(s) {
+				t.Score = s
+			}
+		}
+		scores[i] = t.Score
+		if len(res.Trials) == 0 || t.Score > res.Best.Score {
+			res.Best = t
+		}
+		res.Trials = append(res.Trials, t)
+	}
+	return scores, nil
+}
@@ -0,0 +1,265 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+type OptionKind uint8
+
+const (
+	OptionCall OptionKind = iota
+	OptionPut
+)
+
+func (k OptionKind) String() string {
+	if k == OptionPut {
+		return "put"
+	}
+	return "call"
+}
+
+// ReasonOptionAssigned marks a covered long called away by an in-the-money written call.
+const ReasonOptionAssigned = "option-assigned"
+
+var ErrOptionsDisabled = errors.New("options not enabled")
+
+// OptionsConfig sets the Black-Scholes inputs used to price European options on the replayed
+// series: a flat implied volatility and risk-free rate (both annualized) and the number of
+// bars in a year (365 for daily bars, 8760 for hourly). FeePct is charged on premium.
+type OptionsConfig struct {
+	IV          float64
+	Rate        float64
+	BarsPerYear float64
+	FeePct      float64
+}
+
+// OptionPosition is one option line. Qty is in units of the underlying; negative when written.
+// Collateral is the cash set aside for a written put.
+type OptionPosition struct {
+	ID         int64
+	Kind       OptionKind
+	Strike     float64
+	Qty        float64
+	Premium    float64
+	OpenTick   int64
+	ExpiryTick int64
+	Mark       float64
+	Collateral float64
+}
+
+// OptionFill records a premium payment (open) or a settlement (expiry) in USD: positive when
+// cash was received.
+type OptionFill struct {
+	PositionID int64
+	Tick       int64
+	Kind       OptionKind
+	Strike     float64
+	Qty        float64
+	Price      float64
+	Cash       float64
+	Fee        float64
+	Settlement bool
+}
+
+type optionBook struct {
+	cfg        OptionsConfig
+	nextID     int64
+	positions  []OptionPosition
+	fills      []OptionFill
+	collateral float64
+}
+
+// EnableOptions lets the exchange trade European options priced off its own last price.
+func (e *Exchange) EnableOptions(cfg OptionsConfig) error {
+	if cfg.IV <= 0 || cfg.BarsPerYear <= 0 {
+		return fmt.Errorf("options need a positive IV and bars per year")
+	}
+	if cfg.FeePct < 0 {
+		return fmt.Errorf("option fee must not be negative")
+	}
+	e.options = &optionBook{cfg: cfg}
+	return nil
+}
+
+// BuyOption pays the model premium for qty units expiring expiryBars bars from now; a
+// protective put is BuyOption(OptionPut, ...) next to a long.
+func (e *Exchange) BuyOption(kind OptionKind, strike float64, expiryBars int, qty float64) (OptionPosition, error) {
+	return e.tradeOption(kind, strike, expiryBars, qty)
+}
+
+// WriteOption sells qty units and collects the premium. Calls must be covered by the long
+// position (a covered call); puts are cash-secured, so strike times qty of free USD is locked
+// until expiry.
+func (e *Exchange) WriteOption(kind OptionKind, strike float64, expiryBars int, qty float64) (OptionPosition, error) {
+	if e.options != nil && kind == OptionCall && qty > 0 && qty > e.position-e.options.writtenCalls()+invariantEpsilon {
+		return OptionPosition{}, fmt.Errorf("written call of %.8f exceeds the uncovered long", qty)
+	}
+	return e.tradeOption(kind, strike, expiryBars, -qty)
+}
+
+func (e *Exchange) tradeOption(kind OptionKind, strike float64, expiryBars int, qty float64) (OptionPosition, error) {
+	b := e.options
+	if b == nil {
+		return OptionPosition{}, ErrOptionsDisabled
+	}
+	if e.lastPrice <= 0 {
+		return OptionPosition{}, ErrPriceNotSet
+	}
+	if strike <= 0 || expiryBars <= 0 || qty == 0 || math.IsNaN(qty) {
+		return OptionPosition{}, fmt.Errorf("option needs a positive strike, expiry and non-zero quantity")
+	}
+	price := b.price(kind, e.lastPrice, strike, float64(expiryBars))
+	premium := price * math.Abs(qty)
+	fee := premium * b.cfg.FeePct
+	cash := -premium - fee
+	if qty < 0 {
+		cash = premium - fee
+	}
+	collateral := 0.0
+	if qty < 0 && kind == OptionPut {
+		collateral = strike * -qty
+	}
+	if e.usd+cash-collateral < 0 {
+		return OptionPosition{}, ErrInsufficientFunds
+	}
+	e.usd += cash - collateral
+	b.collateral += collateral
+	e.recordFee(0, fee)
+	b.nextID++
+	pos := OptionPosition{
+		ID:         b.nextID,
+		Kind:       kind,
+		Strike:     strike,
+		Qty:        qty,
+		Premium:    price,
+		OpenTick:   e.tick,
+		ExpiryTick: e.tick + int64(expiryBars),
+		Mark:       price,
+		Collateral: collateral,
+	}
+	b.positions = append(b.positions, pos)
+	b.fills = append(b.fills, OptionFill{
+		PositionID: pos.ID,
+		Tick:       e.tick,
+		Kind:       kind,
+		Strike:     strike,
+		Qty:        qty,
+		Price:      price,
+		Cash:       cash,
+		Fee:        fee,
+	})
+	return pos, e.checkInvariants()
+}
+
+// OptionPositions returns open option lines marked at the last price.
+func (e *Exchange) OptionPositions() []OptionPosition {
+	if e.options == nil {
+		return nil
+	}
+	return append([]OptionPosition(nil), e.options.positions...)
+}
+
+func (e *Exchange) OptionFills() []OptionFill {
+	if e.options == nil {
+		return nil
+	}
+	return append([]OptionFill(nil), e.options.fills...)
+}
+
+// settleOptions marks open options at the bar close and cash-settles those expiring on this
+// tick for their intrinsic value. An in-the-money written call first closes the long at the
+// close, so the covered position is called away; a call left uncovered is paid out of free USD
+// and capped at it.
+func (e *Exchange) settleOptions(bar OHLCBar) *Order {
+	b := e.options
+	if b == nil {
+		return nil
+	}
+	var assigned *Order
+	kept := b.positions[:0]
+	for _, p := range b.positions {
+		if e.tick < p.ExpiryTick {
+			p.Mark = b.price(p.Kind, bar.Close, p.Strike, float64(p.ExpiryTick-e.tick))
+			kept = append(kept, p)
+			continue
+		}
+		intrinsic := optionIntrinsic(p.Kind, bar.Close, p.Strike)
+		if p.Qty < 0 && p.Kind == OptionCall && intrinsic > 0 && e.position > 0 {
+			order := e.closeAtPrice(bar.Close, ReasonOptionAssigned, "", e.fee)
+			assigned = &order
+		}
+		cash := intrinsic * p.Qty
+		e.usd += p.Collateral
+		b.collateral -= p.Collateral
+		if e.usd+cash < 0 {
+			cash = -e.usd
+		}
+		e.usd += cash
+		b.fills = append(b.fills, OptionFill{
+			PositionID: p.ID,
+			Tick:       e.tick,
+			Kind:       p.Kind,
+			Strike:     p.Strike,
+			Qty:        p.Qty,
+			Price:      intrinsic,
+			Cash:       cash,
+			Settlement: true,
+		})
+	}
+	b.positions = kept
+	if len(kept) == 0 {
+		b.collateral = 0
+	}
+	return assigned
+}
+
+// value is the mark-to-model value of open options (negative for written ones).
+func (b *optionBook) value() float64 {
+	total := 0.0
+	for _, p := range b.positions {
+		total += p.Mark * p.Qty
+	}
+	return total
+}
+
+func (b *optionBook) writtenCalls() float64 {
+	total := 0.0
+	for _, p := range b.positions {
+		if p.Kind == OptionCall && p.Qty < 0 {
+			total -= p.Qty
+		}
+	}
+	return total
+}
+
+func (b *optionBook) price(kind OptionKind, spot float64, strike float64, bars float64) float64 {
+	return BlackScholes(kind, spot, strike, bars/b.cfg.BarsPerYear, b.cfg.IV, b.cfg.Rate)
+}
+
+// BlackScholes prices a European option; years <= 0 returns the intrinsic value.
+func BlackScholes(kind OptionKind, spot float64, strike float64, years float64, vol float64, rate float64) float64 {
+	if years <= 0 || vol <= 0 {
+		return optionIntrinsic(kind, spot, strike)
+	}
+	sd := vol * math.Sqrt(years)
+	d1 := (math.Log(spot/strike) + (rate+vol*vol/2)*years) / sd
+	d2 := d1 - sd
+	discount := strike * math.Exp(-rate*years)
+	if kind == OptionPut {
+		return discount*normCDF(-d2) - spot*normCDF(-d1)
+	}
+	return spot*normCDF(d1) - discount*normCDF(d2)
+}
+
+func optionIntrinsic(kind OptionKind, spot float64, strike float64) float64 {
+	if kind == OptionPut {
+		return math.Max(strike-spot, 0)
+	}
+	return math.Max(spot-strike, 0)
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
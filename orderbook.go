@@ -0,0 +1,115 @@
+package emul
+
+import (
+	"math"
+	"sort"
+)
+
+// PendingOrder is a resting limit order as seen by the caller.
+type PendingOrder struct {
+	ID         int64
+	Kind       string
+	Side       OrderSide
+	Price      float64
+	Fraction   float64
+	Reason     string
+	StopKind   string
+	PlacedTick int64
+	Tag        string
+	Meta       map[string]string
+}
+
+// PriceLevel groups the pending orders of one side at one price. DistancePct is the signed
+// distance from the last price as a fraction (0.01 = 1% above).
+type PriceLevel struct {
+	Side        OrderSide
+	Price       float64
+	Orders      []PendingOrder
+	Fraction    float64
+	DistancePct float64
+}
+
+// OpenOrdersView is an "open orders" panel of the exchange's own resting limits: buy levels
+// sorted from the highest price down, sell levels from the lowest price up.
+type OpenOrdersView struct {
+	Tick      int64
+	LastPrice float64
+	Buys      []PriceLevel
+	Sells     []PriceLevel
+}
+
+// PendingOrders returns the resting limits in queue order.
+func (e *Exchange) PendingOrders() []PendingOrder {
+	out := make([]PendingOrder, 0, len(e.pending))
+	for _, p := range e.pending {
+		out = append(out, PendingOrder{
+			ID:         p.id,
+			Kind:       pendingKindName(p.kind),
+			Side:       e.pendingSide(p.kind),
+			Price:      p.price,
+			Fraction:   p.fraction,
+			Reason:     p.reason,
+			StopKind:   p.stopKind,
+			PlacedTick: p.placedAtTick,
+			Tag:        p.tag,
+			Meta:       p.meta,
+		})
+	}
+	return out
+}
+
+// OpenOrders groups pending orders by side and price level. It reflects the state after the
+// last bar, so calling it after every Emulator.Next gives a per-bar view. Close orders take the
+// side that would flatten the current position (sells when flat) and scale-ins the side that
+// would grow it (buys when flat).
+func (e *Exchange) OpenOrders() OpenOrdersView {
+	view := OpenOrdersView{Tick: e.tick, LastPrice: e.lastPrice}
+	levels := make(map[OrderSide]map[float64]int)
+	for _, o := range e.PendingOrders() {
+		price := o.Price
+		if e.tickSize > 0 {
+			price = math.Round(price/e.tickSize) * e.tickSize
+		}
+		side := &view.Sells
+		if o.Side == SideBuy {
+			side = &view.Buys
+		}
+		if levels[o.Side] == nil {
+			levels[o.Side] = make(map[float64]int)
+		}
+		i, ok := levels[o.Side][price]
+		if !ok {
+			i = len(*side)
+			levels[o.Side][price] = i
+			level := PriceLevel{Side: o.Side, Price: price}
+			if e.lastPrice > 0 {
+				level.DistancePct = (price - e.lastPrice) / e.lastPrice
+			}
+			*side = append(*side, level)
+		}
+		(*side)[i].Orders = append((*side)[i].Orders, o)
+		(*side)[i].Fraction += o.Fraction
+	}
+	sort.Slice(view.Buys, func(i, j int) bool { return view.Buys[i].Price > view.Buys[j].Price })
+	sort.Slice(view.Sells, func(i, j int) bool { return view.Sells[i].Price < view.Sells[j].Price })
+	return view
+}
+
+func (e *Exchange) pendingSide(kind pendingKind) OrderSide {
+	switch kind {
+	case pendingOpenLong:
+		return SideBuy
+	case pendingClose, pendingReverse:
+		if e.position < 0 {
+			return SideBuy
+		}
+		return SideSell
+	case pendingScaleIn:
+		if e.position < 0 {
+			return SideSell
+		}
+		return SideBuy
+	default:
+		return SideSell
+	}
+}
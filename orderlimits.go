@@ -0,0 +1,44 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTooManyOpenOrders   = errors.New("too many open orders")
+	ErrTooManyOrdersPerBar = errors.New("too many orders placed on this bar")
+)
+
+// OrderLimits caps order placement like a venue would, to catch runaway strategy loops.
+// MaxOpenOrders caps resting limits; MaxOrdersPerBar caps placement calls (market and limit,
+// cancels excluded) between two bars. Zero disables a cap.
+type OrderLimits struct {
+	MaxOpenOrders   int
+	MaxOrdersPerBar int
+}
+
+func (e *Exchange) SetOrderLimits(l OrderLimits) error {
+	if l.MaxOpenOrders < 0 || l.MaxOrdersPerBar < 0 {
+		return fmt.Errorf("order limits must not be negative")
+	}
+	e.orderLimits = l
+	return nil
+}
+
+// admitOrder charges a placement against the limits; resting reports whether it would rest.
+// Rejected placements are not charged.
+func (e *Exchange) admitOrder(resting bool) error {
+	if e.placedTick != e.tick {
+		e.placedTick, e.placedOnBar = e.tick, 0
+	}
+	l := e.orderLimits
+	if l.MaxOrdersPerBar > 0 && e.placedOnBar >= l.MaxOrdersPerBar {
+		return fmt.Errorf("%w: %d", ErrTooManyOrdersPerBar, l.MaxOrdersPerBar)
+	}
+	if resting && l.MaxOpenOrders > 0 && len(e.pending) >= l.MaxOpenOrders {
+		return fmt.Errorf("%w: %d", ErrTooManyOpenOrders, l.MaxOpenOrders)
+	}
+	e.placedOnBar++
+	return nil
+}
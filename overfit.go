@@ -0,0 +1,200 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DeflatedSharpeReport is the deflated Sharpe ratio of an optimizer's best trial (Bailey and
+// López de Prado): the probability that its Sharpe beats Benchmark, the Sharpe expected from the
+// best of Trials unskilled ones, given the non-normality of its returns. Sharpe ratios are per bar.
+type DeflatedSharpeReport struct {
+	Trials       int
+	Observations int
+	Sharpe       float64
+	Benchmark    float64
+	Skew         float64
+	Kurtosis     float64
+	Probability  float64
+}
+
+// DeflatedSharpe deflates the best trial's Sharpe by the number of trials tried and the spread
+// of their Sharpe ratios. Failed and pruned trials count as tried but add no Sharpe.
+func DeflatedSharpe(res OptimizeResult) (DeflatedSharpeReport, error) {
+	var sharpes []float64
+	for _, t := range res.Trials {
+		if t.Err != nil || t.PruneReason != "" {
+			continue
+		}
+		if sr, ok := returnsSharpe(curveReturns(t.Equity)); ok {
+			sharpes = append(sharpes, sr)
+		}
+	}
+	rets := curveReturns(res.Best.Equity)
+	sr, ok := returnsSharpe(rets)
+	if !ok || len(rets) < 3 {
+		return DeflatedSharpeReport{}, fmt.Errorf("best trial has too few returns")
+	}
+	if len(res.Trials) < 2 || len(sharpes) < 2 {
+		return DeflatedSharpeReport{}, fmt.Errorf("need at least 2 completed trials")
+	}
+	n := float64(len(res.Trials))
+	_, variance := meanVariance(sharpes)
+	const euler = 0.5772156649015329
+	benchmark := math.Sqrt(variance) * ((1-euler)*normQuantile(1-1/n) + euler*normQuantile(1-1/(n*math.E)))
+	skew, kurt := moments(rets)
+	r := DeflatedSharpeReport{
+		Trials:       len(res.Trials),
+		Observations: len(rets),
+		Sharpe:       sr,
+		Benchmark:    benchmark,
+		Skew:         skew,
+		Kurtosis:     kurt,
+	}
+	denom := 1 - skew*sr + (kurt-1)/4*sr*sr
+	if denom <= 0 {
+		return r, fmt.Errorf("non-positive Sharpe variance estimate")
+	}
+	r.Probability = normCDF((sr - benchmark) * math.Sqrt(float64(len(rets)-1)) / math.Sqrt(denom))
+	return r, nil
+}
+
+// OverfitReport is the probability of backtest overfitting estimated by combinatorially
+// symmetric cross-validation: the share of splits in which the trial best in-sample ranks in
+// the lower half out-of-sample. Logits holds ln(w/(1-w)) per split for the out-of-sample
+// relative rank w of that trial.
+type OverfitReport struct {
+	Trials       int
+	Blocks       int
+	Combinations int
+	PBO          float64
+	Logits       []float64
+}
+
+// ProbabilityOfOverfitting runs CSCV over the per-bar returns of the completed trials, which
+// must cover the same bars. blocks is the even number of row blocks (default 16); every
+// half of them is tried as in-sample, so the cost grows as C(blocks, blocks/2).
+func ProbabilityOfOverfitting(res OptimizeResult, blocks int) (OverfitReport, error) {
+	if blocks <= 0 {
+		blocks = 16
+	}
+	if blocks%2 != 0 || blocks < 2 {
+		return OverfitReport{}, fmt.Errorf("blocks must be even and at least 2, got %d", blocks)
+	}
+	var matrix [][]float64
+	for _, t := range res.Trials {
+		if t.Err != nil || t.PruneReason != "" {
+			continue
+		}
+		rets := curveReturns(t.Equity)
+		if len(matrix) > 0 && len(rets) != len(matrix[0]) {
+			return OverfitReport{}, fmt.Errorf("trials cover different bars (%d vs %d returns)", len(rets), len(matrix[0]))
+		}
+		matrix = append(matrix, rets)
+	}
+	if len(matrix) < 2 {
+		return OverfitReport{}, fmt.Errorf("need at least 2 completed trials")
+	}
+	rows := len(matrix[0])
+	if rows < 2*blocks {
+		return OverfitReport{}, fmt.Errorf("%d returns are too few for %d blocks", rows, blocks)
+	}
+	r := OverfitReport{Trials: len(matrix), Blocks: blocks}
+	inSample := make([]bool, blocks)
+	var split func(start, picked int)
+	split = func(start, picked int) {
+		if picked == blocks/2 {
+			r.Logits = append(r.Logits, cscvLogit(matrix, inSample))
+			return
+		}
+		for b := start; b <= blocks-(blocks/2-picked); b++ {
+			inSample[b] = true
+			split(b+1, picked+1)
+			inSample[b] = false
+		}
+	}
+	split(0, 0)
+	r.Combinations = len(r.Logits)
+	below := 0
+	for _, l := range r.Logits {
+		if l <= 0 {
+			below++
+		}
+	}
+	r.PBO = float64(below) / float64(r.Combinations)
+	return r, nil
+}
+
+// cscvLogit scores one split: the trial with the best in-sample Sharpe and its relative rank
+// among the out-of-sample Sharpe ratios.
+func cscvLogit(matrix [][]float64, inSample []bool) float64 {
+	rows, blocks := len(matrix[0]), len(inSample)
+	is := make([]float64, len(matrix))
+	oos := make([]float64, len(matrix))
+	for k, rets := range matrix {
+		var in, out []float64
+		for i, v := range rets {
+			if inSample[min(i*blocks/rows, blocks-1)] {
+				in = append(in, v)
+			} else {
+				out = append(out, v)
+			}
+		}
+		is[k], _ = returnsSharpe(in)
+		oos[k], _ = returnsSharpe(out)
+	}
+	best := 0
+	for k := range is {
+		if is[k] > is[best] {
+			best = k
+		}
+	}
+	sorted := append([]float64(nil), oos...)
+	sort.Float64s(sorted)
+	rank := sort.SearchFloat64s(sorted, oos[best]) + 1
+	w := float64(rank) / float64(len(oos)+1)
+	return math.Log(w / (1 - w))
+}
+
+func curveReturns(curve []EquityPoint) []float64 {
+	rets := make([]float64, 0, len(curve))
+	for i := 1; i < len(curve); i++ {
+		if prev := curve[i-1].Equity; prev > 0 {
+			rets = append(rets, curve[i].Equity/prev-1)
+		} else {
+			rets = append(rets, 0)
+		}
+	}
+	return rets
+}
+
+func returnsSharpe(rets []float64) (float64, bool) {
+	mean, variance := meanVariance(rets)
+	if variance <= 0 {
+		return 0, false
+	}
+	return mean / math.Sqrt(variance), true
+}
+
+// moments returns the skewness and (non-excess) kurtosis of values.
+func moments(values []float64) (float64, float64) {
+	mean, _ := meanVariance(values)
+	var m2, m3, m4 float64
+	for _, v := range values {
+		d := v - mean
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	n := float64(len(values))
+	m2, m3, m4 = m2/n, m3/n, m4/n
+	if m2 <= 0 {
+		return 0, 3
+	}
+	return m3 / math.Pow(m2, 1.5), m4 / (m2 * m2)
+}
+
+func normQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
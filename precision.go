@@ -0,0 +1,79 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+)
+
+// Precision rounds executions to a venue's display precision so PnL matches an exchange
+// statement to the cent. PriceDecimals rounds fill prices (buys up, sells down, like TickSize);
+// QuoteDecimals rounds fees and the quote amounts of spot fills, rounding spent amounts down so a
+// fill never costs more than the budget. A nil field leaves that side unrounded, so the zero
+// value rounds nothing (see Decimals). Perp and option legs are not rounded.
+type Precision struct {
+	PriceDecimals *int
+	QuoteDecimals *int
+}
+
+// Decimals returns a pointer to n for the fields of Precision.
+func Decimals(n int) *int {
+	return &n
+}
+
+// NoPrecision disables rounding to decimals (the default).
+var NoPrecision = Precision{}
+
+// SetPrecision enables rounding for the fields set; NoPrecision turns it off again.
+func (e *Exchange) SetPrecision(p Precision) error {
+	for _, d := range []*int{p.PriceDecimals, p.QuoteDecimals} {
+		if d != nil && (*d < 0 || *d > 12) {
+			return fmt.Errorf("precision must be within [0, 12] decimals")
+		}
+	}
+	e.precision = Precision{PriceDecimals: copyDecimals(p.PriceDecimals), QuoteDecimals: copyDecimals(p.QuoteDecimals)}
+	return nil
+}
+
+// Precision returns the rounding in effect; the fields are copies.
+func (e *Exchange) Precision() Precision {
+	return Precision{PriceDecimals: copyDecimals(e.precision.PriceDecimals), QuoteDecimals: copyDecimals(e.precision.QuoteDecimals)}
+}
+
+func copyDecimals(d *int) *int {
+	if d == nil {
+		return nil
+	}
+	return Decimals(*d)
+}
+
+func (e *Exchange) applyPriceDecimals(side OrderSide, price float64) float64 {
+	if e.precision.PriceDecimals == nil || price <= 0 {
+		return price
+	}
+	scale := math.Pow10(*e.precision.PriceDecimals)
+	if side == SideBuy {
+		return math.Ceil(price*scale-1e-9) / scale
+	}
+	return math.Floor(price*scale+1e-9) / scale
+}
+
+func (e *Exchange) roundsQuote() bool {
+	return e.precision.QuoteDecimals != nil
+}
+
+// roundQuote rounds a quote amount to the nearest unit, halves away from zero.
+func (e *Exchange) roundQuote(v float64) float64 {
+	if !e.roundsQuote() {
+		return v
+	}
+	scale := math.Pow10(*e.precision.QuoteDecimals)
+	return math.Round(v*scale) / scale
+}
+
+func (e *Exchange) floorQuote(v float64) float64 {
+	if !e.roundsQuote() {
+		return v
+	}
+	scale := math.Pow10(*e.precision.QuoteDecimals)
+	return math.Floor(v*scale+1e-9) / scale
+}
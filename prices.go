@@ -2,14 +2,19 @@ package emul
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,24 +22,131 @@ const (
 	intervalDaily  = "d"
 	intervalHourly = "h"
 	intervalMinute = "m"
-	minutesPerDay  = 24 * 60
 )
 
 var errNoDataRows = errors.New("no data rows parsed")
 
+const defaultMaxCSVLine = 1024 * 1024
+
+// CSVReaderOptions controls how CSV files are scanned. Loaders without options (LoadBarsFromCSV,
+// LoadSeriesFromDataRoot and friends) use the zero value; the WithOptions variants take one per
+// call, so loaders in the same process never share settings.
+type CSVReaderOptions struct {
+	// MaxLineBytes caps a single line; zero means 1MB.
+	MaxLineBytes int
+	// SkipLongLines drops lines longer than MaxLineBytes (typically metadata or headers)
+	// instead of aborting the load with bufio.ErrTooLong.
+	SkipLongLines bool
+	// Warn is called for each skipped line; nil logs through the standard logger.
+	Warn func(path string, size int)
+	// StrictIntervals fails the load with ErrIntervalMismatch when the bar spacing found in the
+	// files differs from the interval directory they were loaded from; by default it is only
+	// reported through WarnInterval.
+	StrictIntervals bool
+	// WarnInterval is called with the ErrIntervalMismatch of a non-strict load; nil logs it
+	// through the standard logger.
+	WarnInterval func(err error)
+	// EpochUnit fixes the unit of numeric timestamps; EpochAuto infers it from the magnitude.
+	EpochUnit EpochUnit
+}
+
+type EpochUnit uint8
+
+const (
+	EpochAuto EpochUnit = iota
+	EpochSeconds
+	EpochMillis
+	EpochMicros
+	EpochNanos
+)
+
+func newCSVScanner(r io.Reader, path string, opts CSVReaderOptions) *bufio.Scanner {
+	maxLine := opts.maxLineBytes()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(64*1024, maxLine)), maxLine)
+	if opts.SkipLongLines {
+		scanner.Split(skipLongLines(maxLine, func(size int) { opts.warn(path, size) }))
+	}
+	return scanner
+}
+
+func (o CSVReaderOptions) maxLineBytes() int {
+	if o.MaxLineBytes <= 0 {
+		return defaultMaxCSVLine
+	}
+	return o.MaxLineBytes
+}
+
+// warn reports a skipped line through Warn, or the standard logger without one.
+func (o CSVReaderOptions) warn(path string, size int) {
+	if o.Warn != nil {
+		o.Warn(path, size)
+		return
+	}
+	log.Printf("emul: %s: skipped %d-byte line longer than %d bytes", path, size, o.maxLineBytes())
+}
+
+// warnInterval reports a tolerated interval mismatch through WarnInterval, or the standard
+// logger without one.
+func (o CSVReaderOptions) warnInterval(err error) {
+	if o.WarnInterval != nil {
+		o.WarnInterval(err)
+		return
+	}
+	log.Printf("emul: %v", err)
+}
+
+// skipLongLines behaves like bufio.ScanLines, except that a line that does not fit in maxLine
+// bytes is discarded (reporting its size to onSkip) rather than failing the scan.
+func skipLongLines(maxLine int, onSkip func(size int)) bufio.SplitFunc {
+	skipping := false
+	skipped := 0
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			if skipping {
+				skipping = false
+				onSkip(skipped)
+			}
+			return 0, nil, nil
+		}
+		if skipping {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				skipping = false
+				onSkip(skipped + i)
+				skipped = 0
+				return i + 1, nil, nil
+			}
+			skipped += len(data)
+			return len(data), nil, nil
+		}
+		if len(data) >= maxLine && bytes.IndexByte(data, '\n') < 0 {
+			skipping = true
+			skipped = len(data)
+			return len(data), nil, nil
+		}
+		return bufio.ScanLines(data, atEOF)
+	}
+}
+
 type OHLCSeries struct {
+	Time  []time.Time
 	Open  []float64
 	High  []float64
 	Low   []float64
 	Close []float64
+	// Volume is optional in hand-built series.
+	Volume []float64
 }
 
 type OHLCBar struct {
+	Time    time.Time
 	Open    float64
 	High    float64
 	Low     float64
 	Close   float64
 	Average float64
+	// Volume is in base units; 0 when the source has no usable volume column.
+	Volume float64
 }
 
 func BarsFromSeries(values []float64, ohlc OHLCSeries) ([]OHLCBar, error) {
@@ -45,6 +157,9 @@ func BarsFromSeries(values []float64, ohlc OHLCSeries) ([]OHLCBar, error) {
 	if len(ohlc.Open) != n || len(ohlc.High) != n || len(ohlc.Low) != n || len(ohlc.Close) != n {
 		return nil, fmt.Errorf("ohlc length mismatch")
 	}
+	// Time is optional: series built by hand may omit it.
+	withTime := len(ohlc.Time) == n
+	withVolume := len(ohlc.Volume) == n
 	bars := make([]OHLCBar, n)
 	for i := 0; i < n; i++ {
 		bars[i] = OHLCBar{
@@ -54,6 +169,12 @@ func BarsFromSeries(values []float64, ohlc OHLCSeries) ([]OHLCBar, error) {
 			Close:   ohlc.Close[i],
 			Average: values[i],
 		}
+		if withTime {
+			bars[i].Time = ohlc.Time[i]
+		}
+		if withVolume {
+			bars[i].Volume = ohlc.Volume[i]
+		}
 	}
 	return bars, nil
 }
@@ -82,17 +203,14 @@ func IntervalFromFlags(useDaily bool, useHourly bool, useMinute bool) (string, e
 	return interval, nil
 }
 
+// PointsPerDayForInterval returns the number of bars per day, or 0 for unknown intervals and
+// intervals longer than a day.
 func PointsPerDayForInterval(interval string) int {
-	switch interval {
-	case intervalDaily:
-		return 1
-	case intervalHourly:
-		return 24
-	case intervalMinute:
-		return minutesPerDay
-	default:
+	d := IntervalDuration(interval)
+	if d <= 0 || d > 24*time.Hour {
 		return 0
 	}
+	return int(24 * time.Hour / d)
 }
 
 func LoadSeriesFromDataRoot(dataRoot string, coin string, interval string) ([]float64, float64, error) {
@@ -104,11 +222,9 @@ func LoadSeriesFromDataRoot(dataRoot string, coin string, interval string) ([]fl
 	if coin == "" {
 		return nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -124,7 +240,7 @@ func LoadSeriesFromDataRoot(dataRoot string, coin string, interval string) ([]fl
 	if err != nil {
 		return nil, 0, err
 	}
-	return loadSeriesFromFiles(dir, files, nil)
+	return loadSeriesFromFiles(dir, interval, files, nil, CSVReaderOptions{})
 }
 
 func LoadSeriesFromDataRootMonths(dataRoot string, coin string, interval string, months []int) ([]float64, float64, error) {
@@ -136,11 +252,9 @@ func LoadSeriesFromDataRootMonths(dataRoot string, coin string, interval string,
 	if coin == "" {
 		return nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -156,7 +270,7 @@ func LoadSeriesFromDataRootMonths(dataRoot string, coin string, interval string,
 	if err != nil {
 		return nil, 0, err
 	}
-	return loadSeriesFromFiles(dir, files, buildMonthFilter(months))
+	return loadSeriesFromFiles(dir, interval, files, buildMonthFilter(months), CSVReaderOptions{})
 }
 
 func LoadSeriesFromDataRootYears(dataRoot string, coin string, interval string, years []int) ([]float64, float64, error) {
@@ -168,11 +282,9 @@ func LoadSeriesFromDataRootYears(dataRoot string, coin string, interval string,
 	if coin == "" {
 		return nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -188,7 +300,7 @@ func LoadSeriesFromDataRootYears(dataRoot string, coin string, interval string,
 	if err != nil {
 		return nil, 0, err
 	}
-	return loadSeriesFromFiles(dir, files, nil)
+	return loadSeriesFromFiles(dir, interval, files, nil, CSVReaderOptions{})
 }
 
 func LoadSeriesFromDataRootYearsMonths(dataRoot string, coin string, interval string, years []int, months []int) ([]float64, float64, error) {
@@ -200,11 +312,9 @@ func LoadSeriesFromDataRootYearsMonths(dataRoot string, coin string, interval st
 	if coin == "" {
 		return nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -220,7 +330,7 @@ func LoadSeriesFromDataRootYearsMonths(dataRoot string, coin string, interval st
 	if err != nil {
 		return nil, 0, err
 	}
-	return loadSeriesFromFiles(dir, files, buildMonthFilter(months))
+	return loadSeriesFromFiles(dir, interval, files, buildMonthFilter(months), CSVReaderOptions{})
 }
 
 func LoadSeriesWithCloseFromDataRoot(dataRoot string, coin string, interval string) ([]float64, []float64, float64, error) {
@@ -232,11 +342,9 @@ func LoadSeriesWithCloseFromDataRoot(dataRoot string, coin string, interval stri
 	if coin == "" {
 		return nil, nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -252,7 +360,7 @@ func LoadSeriesWithCloseFromDataRoot(dataRoot string, coin string, interval stri
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	return loadSeriesFromFilesWithClose(dir, files, nil)
+	return loadSeriesFromFilesWithClose(dir, interval, files, nil, CSVReaderOptions{})
 }
 
 func LoadSeriesWithCloseFromDataRootMonths(dataRoot string, coin string, interval string, months []int) ([]float64, []float64, float64, error) {
@@ -264,11 +372,9 @@ func LoadSeriesWithCloseFromDataRootMonths(dataRoot string, coin string, interva
 	if coin == "" {
 		return nil, nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -284,7 +390,7 @@ func LoadSeriesWithCloseFromDataRootMonths(dataRoot string, coin string, interva
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	return loadSeriesFromFilesWithClose(dir, files, buildMonthFilter(months))
+	return loadSeriesFromFilesWithClose(dir, interval, files, buildMonthFilter(months), CSVReaderOptions{})
 }
 
 func LoadSeriesWithCloseFromDataRootYears(dataRoot string, coin string, interval string, years []int) ([]float64, []float64, float64, error) {
@@ -296,11 +402,9 @@ func LoadSeriesWithCloseFromDataRootYears(dataRoot string, coin string, interval
 	if coin == "" {
 		return nil, nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -316,7 +420,7 @@ func LoadSeriesWithCloseFromDataRootYears(dataRoot string, coin string, interval
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	return loadSeriesFromFilesWithClose(dir, files, nil)
+	return loadSeriesFromFilesWithClose(dir, interval, files, nil, CSVReaderOptions{})
 }
 
 func LoadSeriesWithCloseFromDataRootYearsMonths(dataRoot string, coin string, interval string, years []int, months []int) ([]float64, []float64, float64, error) {
@@ -328,11 +432,9 @@ func LoadSeriesWithCloseFromDataRootYearsMonths(dataRoot string, coin string, in
 	if coin == "" {
 		return nil, nil, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, nil, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -348,7 +450,7 @@ func LoadSeriesWithCloseFromDataRootYearsMonths(dataRoot string, coin string, in
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	return loadSeriesFromFilesWithClose(dir, files, buildMonthFilter(months))
+	return loadSeriesFromFilesWithClose(dir, interval, files, buildMonthFilter(months), CSVReaderOptions{})
 }
 
 func LoadSeriesWithOHLCFromDataRoot(dataRoot string, coin string, interval string) ([]float64, OHLCSeries, float64, error) {
@@ -360,11 +462,9 @@ func LoadSeriesWithOHLCFromDataRoot(dataRoot string, coin string, interval strin
 	if coin == "" {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, OHLCSeries{}, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, OHLCSeries{}, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -380,7 +480,7 @@ func LoadSeriesWithOHLCFromDataRoot(dataRoot string, coin string, interval strin
 	if err != nil {
 		return nil, OHLCSeries{}, 0, err
 	}
-	return loadSeriesFromFilesWithOHLC(dir, files, nil)
+	return loadSeriesFromFilesWithOHLC(dir, interval, files, nil, CSVReaderOptions{})
 }
 
 func LoadSeriesWithOHLCFromDataRootMonths(dataRoot string, coin string, interval string, months []int) ([]float64, OHLCSeries, float64, error) {
@@ -392,11 +492,9 @@ func LoadSeriesWithOHLCFromDataRootMonths(dataRoot string, coin string, interval
 	if coin == "" {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, OHLCSeries{}, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, OHLCSeries{}, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -412,7 +510,7 @@ func LoadSeriesWithOHLCFromDataRootMonths(dataRoot string, coin string, interval
 	if err != nil {
 		return nil, OHLCSeries{}, 0, err
 	}
-	return loadSeriesFromFilesWithOHLC(dir, files, buildMonthFilter(months))
+	return loadSeriesFromFilesWithOHLC(dir, interval, files, buildMonthFilter(months), CSVReaderOptions{})
 }
 
 func LoadSeriesWithOHLCFromDataRootYears(dataRoot string, coin string, interval string, years []int) ([]float64, OHLCSeries, float64, error) {
@@ -424,11 +522,9 @@ func LoadSeriesWithOHLCFromDataRootYears(dataRoot string, coin string, interval
 	if coin == "" {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, OHLCSeries{}, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, OHLCSeries{}, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -444,10 +540,16 @@ func LoadSeriesWithOHLCFromDataRootYears(dataRoot string, coin string, interval
 	if err != nil {
 		return nil, OHLCSeries{}, 0, err
 	}
-	return loadSeriesFromFilesWithOHLC(dir, files, nil)
+	return loadSeriesFromFilesWithOHLC(dir, interval, files, nil, CSVReaderOptions{})
 }
 
 func LoadSeriesWithOHLCFromDataRootYearsMonths(dataRoot string, coin string, interval string, years []int, months []int) ([]float64, OHLCSeries, float64, error) {
+	return LoadSeriesWithOHLCFromDataRootWithOptions(dataRoot, coin, interval, years, months, CSVReaderOptions{})
+}
+
+// LoadSeriesWithOHLCFromDataRootWithOptions is LoadSeriesWithOHLCFromDataRootYearsMonths with
+// explicit reader options.
+func LoadSeriesWithOHLCFromDataRootWithOptions(dataRoot string, coin string, interval string, years []int, months []int, opts CSVReaderOptions) ([]float64, OHLCSeries, float64, error) {
 	root := strings.TrimSpace(dataRoot)
 	if root == "" {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("data root is empty")
@@ -456,11 +558,9 @@ func LoadSeriesWithOHLCFromDataRootYearsMonths(dataRoot string, coin string, int
 	if coin == "" {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("coin is empty")
 	}
-	interval = strings.ToLower(strings.TrimSpace(interval))
-	switch interval {
-	case intervalDaily, intervalHourly, intervalMinute:
-	default:
-		return nil, OHLCSeries{}, 0, fmt.Errorf("invalid interval %q", interval)
+	interval, err := ParseInterval(interval)
+	if err != nil {
+		return nil, OHLCSeries{}, 0, err
 	}
 
 	dir := filepath.Join(root, coin, interval)
@@ -476,100 +576,92 @@ func LoadSeriesWithOHLCFromDataRootYearsMonths(dataRoot string, coin string, int
 	if err != nil {
 		return nil, OHLCSeries{}, 0, err
 	}
-	return loadSeriesFromFilesWithOHLC(dir, files, buildMonthFilter(months))
+	return loadSeriesFromFilesWithOHLC(dir, interval, files, buildMonthFilter(months), opts)
 }
 
-func loadSeriesFromFiles(dir string, files []string, months map[int]bool) ([]float64, float64, error) {
-	if len(files) == 0 {
-		return nil, 0, fmt.Errorf("no csv files found in %s", dir)
-	}
+func loadSeriesFromFiles(dir string, interval string, files []string, months map[int]bool, opts CSVReaderOptions) ([]float64, float64, error) {
+	values, _, maxValue, err := loadSeriesFromFilesWithOHLC(dir, interval, files, months, opts)
+	return values, maxValue, err
+}
 
-	series := make([]float64, 0, 1024)
-	maxValue := math.Inf(-1)
-	for _, filePath := range files {
-		values, maxLocal, err := loadSeriesFromCSV(filePath, months)
-		if err != nil {
-			if errors.Is(err, errNoDataRows) {
-				continue
-			}
-			return nil, 0, err
-		}
-		series = append(series, values...)
-		if maxLocal > maxValue {
-			maxValue = maxLocal
-		}
-	}
-	if len(series) == 0 {
-		return nil, 0, fmt.Errorf("no data loaded from %s", dir)
-	}
-	if math.IsInf(maxValue, -1) {
-		maxValue = 0
+func loadSeriesFromFilesWithClose(dir string, interval string, files []string, months map[int]bool, opts CSVReaderOptions) ([]float64, []float64, float64, error) {
+	values, ohlc, maxValue, err := loadSeriesFromFilesWithOHLC(dir, interval, files, months, opts)
+	if err != nil {
+		return nil, nil, 0, err
 	}
-	return series, maxValue, nil
+	return values, ohlc.Close, maxValue, nil
 }
 
-func loadSeriesFromFilesWithClose(dir string, files []string, months map[int]bool) ([]float64, []float64, float64, error) {
-	if len(files) == 0 {
-		return nil, nil, 0, fmt.Errorf("no csv files found in %s", dir)
-	}
+type parsedCSVFile struct {
+	values   []float64
+	ohlc     OHLCSeries
+	maxValue float64
+	err      error
+}
 
-	series := make([]float64, 0, 1024)
-	closeSeries := make([]float64, 0, 1024)
-	maxValue := math.Inf(-1)
-	for _, filePath := range files {
-		values, closes, maxLocal, err := loadSeriesFromCSVWithClose(filePath, months)
-		if err != nil {
-			if errors.Is(err, errNoDataRows) {
-				continue
+// parseCSVFiles parses files on up to GOMAXPROCS workers; results keep the order of files.
+func parseCSVFiles(files []string, months map[int]bool, opts CSVReaderOptions) []parsedCSVFile {
+	results := make([]parsedCSVFile, len(files))
+	workers := min(runtime.GOMAXPROCS(0), len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				r := &results[i]
+				r.values, r.ohlc, r.maxValue, r.err = loadSeriesFromCSVWithOHLC(files[i], months, opts)
 			}
-			return nil, nil, 0, err
-		}
-		series = append(series, values...)
-		closeSeries = append(closeSeries, closes...)
-		if maxLocal > maxValue {
-			maxValue = maxLocal
-		}
+		}()
 	}
-	if len(series) == 0 {
-		return nil, nil, 0, fmt.Errorf("no data loaded from %s", dir)
-	}
-	if len(series) != len(closeSeries) {
-		return nil, nil, 0, fmt.Errorf("series length mismatch for %s", dir)
-	}
-	if math.IsInf(maxValue, -1) {
-		maxValue = 0
+	for i := range files {
+		next <- i
 	}
-	return series, closeSeries, maxValue, nil
+	close(next)
+	wg.Wait()
+	return results
 }
 
-func loadSeriesFromFilesWithOHLC(dir string, files []string, months map[int]bool) ([]float64, OHLCSeries, float64, error) {
+func loadSeriesFromFilesWithOHLC(dir string, interval string, files []string, months map[int]bool, opts CSVReaderOptions) ([]float64, OHLCSeries, float64, error) {
 	if len(files) == 0 {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("no csv files found in %s", dir)
 	}
 
-	series := make([]float64, 0, 1024)
+	results := parseCSVFiles(files, months, opts)
+	total := 0
+	for _, r := range results {
+		if r.err != nil {
+			if errors.Is(r.err, errNoDataRows) {
+				continue
+			}
+			return nil, OHLCSeries{}, 0, r.err
+		}
+		total += len(r.values)
+	}
+	series := make([]float64, 0, total)
 	ohlc := OHLCSeries{
-		Open:  make([]float64, 0, 1024),
-		High:  make([]float64, 0, 1024),
-		Low:   make([]float64, 0, 1024),
-		Close: make([]float64, 0, 1024),
+		Time:   make([]time.Time, 0, total),
+		Open:   make([]float64, 0, total),
+		High:   make([]float64, 0, total),
+		Low:    make([]float64, 0, total),
+		Close:  make([]float64, 0, total),
+		Volume: make([]float64, 0, total),
 	}
 	maxValue := math.Inf(-1)
-	for _, filePath := range files {
-		values, fileOHLC, maxLocal, err := loadSeriesFromCSVWithOHLC(filePath, months)
-		if err != nil {
-			if errors.Is(err, errNoDataRows) {
-				continue
-			}
-			return nil, OHLCSeries{}, 0, err
+	for _, r := range results {
+		if r.err != nil {
+			continue
 		}
-		series = append(series, values...)
-		ohlc.Open = append(ohlc.Open, fileOHLC.Open...)
-		ohlc.High = append(ohlc.High, fileOHLC.High...)
-		ohlc.Low = append(ohlc.Low, fileOHLC.Low...)
-		ohlc.Close = append(ohlc.Close, fileOHLC.Close...)
-		if maxLocal > maxValue {
-			maxValue = maxLocal
+		series = append(series, r.values...)
+		ohlc.Time = append(ohlc.Time, r.ohlc.Time...)
+		ohlc.Open = append(ohlc.Open, r.ohlc.Open...)
+		ohlc.High = append(ohlc.High, r.ohlc.High...)
+		ohlc.Low = append(ohlc.Low, r.ohlc.Low...)
+		ohlc.Close = append(ohlc.Close, r.ohlc.Close...)
+		ohlc.Volume = append(ohlc.Volume, r.ohlc.Volume...)
+		if r.maxValue > maxValue {
+			maxValue = r.maxValue
 		}
 	}
 	if len(series) == 0 {
@@ -578,6 +670,9 @@ func loadSeriesFromFilesWithOHLC(dir string, files []string, months map[int]bool
 	if len(series) != len(ohlc.Close) {
 		return nil, OHLCSeries{}, 0, fmt.Errorf("series length mismatch for %s", dir)
 	}
+	if err := checkInterval(dir, interval, ohlc.Time, opts); err != nil {
+		return nil, OHLCSeries{}, 0, err
+	}
 	if math.IsInf(maxValue, -1) {
 		maxValue = 0
 	}
@@ -653,80 +748,40 @@ func resolveYearFile(yearOnly string, coinYear string) (string, bool, error) {
 	return "", false, nil
 }
 
-func loadSeriesFromCSV(path string, months map[int]bool) ([]float64, float64, error) {
-	values, _, maxValue, err := loadSeriesFromCSVWithClose(path, months)
-	return values, maxValue, err
-}
-
-func loadSeriesFromCSVWithClose(path string, months map[int]bool) ([]float64, []float64, float64, error) {
-	values, ohlc, maxValue, err := loadSeriesFromCSVWithOHLC(path, months)
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	return values, ohlc.Close, maxValue, nil
-}
-
-func loadSeriesFromCSVWithOHLC(path string, months map[int]bool) ([]float64, OHLCSeries, float64, error) {
+func loadSeriesFromCSVWithOHLC(path string, months map[int]bool, opts CSVReaderOptions) ([]float64, OHLCSeries, float64, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, OHLCSeries{}, 0, err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	scanner := newCSVScanner(file, path, opts)
 
 	values := make([]float64, 0, 1024)
 	ohlc := OHLCSeries{
-		Open:  make([]float64, 0, 1024),
-		High:  make([]float64, 0, 1024),
-		Low:   make([]float64, 0, 1024),
-		Close: make([]float64, 0, 1024),
+		Time:   make([]time.Time, 0, 1024),
+		Open:   make([]float64, 0, 1024),
+		High:   make([]float64, 0, 1024),
+		Low:    make([]float64, 0, 1024),
+		Close:  make([]float64, 0, 1024),
+		Volume: make([]float64, 0, 1024),
 	}
 	maxValue := math.Inf(-1)
+	rows := newCSVRowParser(opts)
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-		parts := strings.Split(line, ",")
-		if len(parts) < 6 {
-			continue
-		}
-		if months != nil {
-			ts, ok := parseCSVTime(parts[0])
-			if !ok {
-				continue
-			}
-			if !months[int(ts.Month())] {
-				continue
-			}
-		}
-		openValue, ok := parseCSVFloat(parts[1])
+		bar, ok := rows.parse(scanner.Text(), months)
 		if !ok {
 			continue
 		}
-		highValue, ok := parseCSVFloat(parts[2])
-		if !ok {
-			continue
-		}
-		lowValue, ok := parseCSVFloat(parts[3])
-		if !ok {
-			continue
-		}
-		closeValue, ok := parseCSVFloat(parts[4])
-		if !ok {
-			continue
-		}
-		value := (openValue + highValue + lowValue + closeValue) / 4
-		values = append(values, value)
-		ohlc.Open = append(ohlc.Open, openValue)
-		ohlc.High = append(ohlc.High, highValue)
-		ohlc.Low = append(ohlc.Low, lowValue)
-		ohlc.Close = append(ohlc.Close, closeValue)
-		if value > maxValue {
-			maxValue = value
+		values = append(values, bar.Average)
+		ohlc.Time = append(ohlc.Time, bar.Time)
+		ohlc.Open = append(ohlc.Open, bar.Open)
+		ohlc.High = append(ohlc.High, bar.High)
+		ohlc.Low = append(ohlc.Low, bar.Low)
+		ohlc.Close = append(ohlc.Close, bar.Close)
+		ohlc.Volume = append(ohlc.Volume, bar.Volume)
+		if bar.Average > maxValue {
+			maxValue = bar.Average
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -741,6 +796,185 @@ func loadSeriesFromCSVWithOHLC(path string, months map[int]bool) ([]float64, OHL
 	return values, ohlc, maxValue, nil
 }
 
+// csvBarFields is the number of columns a data row must have: timestamp, OHLC and volume.
+const csvBarFields = 6
+
+// maxCSVFields bounds the columns a header may describe.
+const maxCSVFields = 32
+
+// csvLayout maps CSV columns to bar fields; rows need at least fields columns.
+// volume is -1 when a header names no volume column.
+type csvLayout struct {
+	time   int
+	open   int
+	high   int
+	low    int
+	close  int
+	volume int
+	fields int
+	unit   EpochUnit
+}
+
+var defaultCSVLayout = csvLayout{time: 0, open: 1, high: 2, low: 3, close: 4, volume: 5, fields: csvBarFields}
+
+// csvHeaderNames maps lower-cased header names onto layout columns.
+var csvHeaderNames = map[string]string{
+	"time": "time", "timestamp": "time", "date": "time", "datetime": "time", "open_time": "time", "unix": "time",
+	"open": "open", "o": "open",
+	"high": "high", "h": "high",
+	"low": "low", "l": "low",
+	"close": "close", "c": "close",
+	"volume": "volume", "vol": "volume", "v": "volume",
+}
+
+// csvRowParser parses the rows of one file. Until the first data row it also checks each line
+// for a header and, when one names the OHLC columns, uses that column order for later rows
+// (e.g. Coinbase exports "time,low,high,open,close,volume").
+type csvRowParser struct {
+	layout csvLayout
+	data   bool
+}
+
+func newCSVRowParser(opts CSVReaderOptions) *csvRowParser {
+	layout := defaultCSVLayout
+	layout.unit = opts.EpochUnit
+	return &csvRowParser{layout: layout}
+}
+
+func (p *csvRowParser) parse(raw string, months map[int]bool) (OHLCBar, bool) {
+	if !p.data {
+		if layout, ok := detectCSVHeader(raw); ok {
+			layout.unit = p.layout.unit
+			p.layout = layout
+			return OHLCBar{}, false
+		}
+	}
+	bar, ok := parseCSVBarLayout(raw, p.layout, months)
+	if ok {
+		p.data = true
+	}
+	return bar, ok
+}
+
+// detectCSVHeader reports the layout described by a header line. Lines whose first field is a
+// timestamp, or that do not name all of open/high/low/close, are not headers.
+func detectCSVHeader(raw string) (csvLayout, bool) {
+	line := strings.TrimSpace(raw)
+	if line == "" {
+		return csvLayout{}, false
+	}
+	names := strings.Split(line, ",")
+	if len(names) > maxCSVFields {
+		return csvLayout{}, false
+	}
+	if _, ok := parseCSVTime(names[0], EpochAuto); ok {
+		return csvLayout{}, false
+	}
+	found := make(map[string]int, 5)
+	for i, name := range names {
+		name = strings.ToLower(strings.Trim(strings.TrimSpace(name), "\""))
+		if column, ok := csvHeaderNames[name]; ok {
+			if _, dup := found[column]; !dup {
+				found[column] = i
+			}
+		}
+	}
+	for _, column := range []string{"open", "high", "low", "close"} {
+		if _, ok := found[column]; !ok {
+			return csvLayout{}, false
+		}
+	}
+	layout := csvLayout{time: 0, open: found["open"], high: found["high"], low: found["low"], close: found["close"], volume: -1}
+	if i, ok := found["time"]; ok {
+		layout.time = i
+	}
+	if i, ok := found["volume"]; ok {
+		layout.volume = i
+	}
+	// Rows must be as wide as the header, matching the volume requirement of the default layout.
+	layout.fields = len(names)
+	return layout, true
+}
+
+// parseCSVBar parses one "timestamp,open,high,low,close,volume" row; rows that are blank,
+// malformed or outside the month filter are rejected. Fields are sub-strings of raw, so a row
+// is parsed without allocating.
+func parseCSVBar(raw string, months map[int]bool) (OHLCBar, bool) {
+	return parseCSVBarLayout(raw, defaultCSVLayout, months)
+}
+
+func parseCSVBarLayout(raw string, layout csvLayout, months map[int]bool) (OHLCBar, bool) {
+	line := strings.TrimSpace(raw)
+	if line == "" {
+		return OHLCBar{}, false
+	}
+	var buf [maxCSVFields]string
+	parts := buf[:layout.fields]
+	if !splitCSVFields(line, parts) {
+		return OHLCBar{}, false
+	}
+	ts, tsOK := parseCSVTime(parts[layout.time], layout.unit)
+	if months != nil {
+		if !tsOK {
+			return OHLCBar{}, false
+		}
+		if !months[int(ts.Month())] {
+			return OHLCBar{}, false
+		}
+	}
+	openValue, ok := parseCSVFloat(parts[layout.open])
+	if !ok {
+		return OHLCBar{}, false
+	}
+	highValue, ok := parseCSVFloat(parts[layout.high])
+	if !ok {
+		return OHLCBar{}, false
+	}
+	lowValue, ok := parseCSVFloat(parts[layout.low])
+	if !ok {
+		return OHLCBar{}, false
+	}
+	closeValue, ok := parseCSVFloat(parts[layout.close])
+	if !ok {
+		return OHLCBar{}, false
+	}
+	// Volume was never validated, so an unparsable value reads as 0 instead of dropping the row.
+	volume := 0.0
+	if layout.volume >= 0 {
+		volume, _ = parseCSVFloat(parts[layout.volume])
+	}
+	return OHLCBar{
+		Time:    ts,
+		Open:    openValue,
+		High:    highValue,
+		Low:     lowValue,
+		Close:   closeValue,
+		Average: (openValue + highValue + lowValue + closeValue) / 4,
+		Volume:  volume,
+	}, true
+}
+
+// splitCSVFields fills dst with the first len(dst) comma-separated fields of line and reports
+// whether the line had that many. Extra trailing fields are ignored.
+func splitCSVFields(line string, dst []string) bool {
+	for i := range dst {
+		if i == len(dst)-1 {
+			if j := strings.IndexByte(line, ','); j >= 0 {
+				line = line[:j]
+			}
+			dst[i] = line
+			return true
+		}
+		j := strings.IndexByte(line, ',')
+		if j < 0 {
+			return false
+		}
+		dst[i] = line[:j]
+		line = line[j+1:]
+	}
+	return true
+}
+
 func buildMonthFilter(months []int) map[int]bool {
 	if len(months) == 0 {
 		return nil
@@ -757,7 +991,21 @@ func buildMonthFilter(months []int) map[int]bool {
 	return filter
 }
 
-func parseCSVTime(raw string) (time.Time, bool) {
+// csvTimeLayouts are the date-string formats accepted besides numeric epochs.
+var csvTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseCSVTime accepts numeric epochs and ISO-8601 / "YYYY-MM-DD HH:MM:SS" strings; strings
+// without a zone are taken as UTC. With EpochAuto the epoch unit is inferred from the magnitude:
+// any date after 1973 has at least 12 digits in milliseconds, 15 in microseconds and 18 in
+// nanoseconds, while seconds stay below 1e11 until the year 5138.
+func parseCSVTime(raw string, unit EpochUnit) (time.Time, bool) {
 	value := strings.TrimSpace(raw)
 	value = strings.Trim(value, "\"")
 	if value == "" {
@@ -767,18 +1015,48 @@ func parseCSVTime(raw string) (time.Time, bool) {
 	if err != nil {
 		floatVal, err := strconv.ParseFloat(value, 64)
 		if err != nil {
-			return time.Time{}, false
+			return parseCSVDate(value)
 		}
 		parsed = int64(floatVal)
 	}
-	sec := parsed
-	if parsed > 1_000_000_000_000 {
-		sec = parsed / 1000
+	if parsed <= 0 {
+		return time.Time{}, false
+	}
+	if unit == EpochAuto {
+		switch {
+		case parsed < 100_000_000_000:
+			unit = EpochSeconds
+		case parsed < 100_000_000_000_000:
+			unit = EpochMillis
+		case parsed < 100_000_000_000_000_000:
+			unit = EpochMicros
+		default:
+			unit = EpochNanos
+		}
 	}
-	if sec <= 0 {
+	switch unit {
+	case EpochMillis:
+		return time.UnixMilli(parsed).UTC(), true
+	case EpochMicros:
+		return time.UnixMicro(parsed).UTC(), true
+	case EpochNanos:
+		return time.Unix(0, parsed).UTC(), true
+	default:
+		return time.Unix(parsed, 0).UTC(), true
+	}
+}
+
+func parseCSVDate(value string) (time.Time, bool) {
+	// Every accepted layout starts with a digit; skipping the rest keeps header words cheap.
+	if value[0] < '0' || value[0] > '9' {
 		return time.Time{}, false
 	}
-	return time.Unix(sec, 0).UTC(), true
+	for _, layout := range csvTimeLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts.UTC(), true
+		}
+	}
+	return time.Time{}, false
 }
 
 func parseCSVFloat(raw string) (float64, bool) {
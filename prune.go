@@ -0,0 +1,86 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrPruned = errors.New("run pruned")
+
+// PruneFunc is checked after every bar of a run. progress is the fraction of bars replayed
+// (0..1]; returning a non-empty reason stops the run.
+type PruneFunc func(progress float64, curve []EquityPoint) string
+
+// PruneDrawdown stops a run once its drawdown from peak reaches maxDrawdown (0.3 = 30%).
+func PruneDrawdown(maxDrawdown float64) PruneFunc {
+	peak := 0.0
+	return func(_ float64, curve []EquityPoint) string {
+		equity := curve[len(curve)-1].Equity
+		peak = math.Max(peak, equity)
+		if peak > 0 && (peak-equity)/peak >= maxDrawdown {
+			return fmt.Sprintf("drawdown %.2f%% reached %.2f%%", (peak-equity)/peak*100, maxDrawdown*100)
+		}
+		return ""
+	}
+}
+
+// PruneSharpe checks the run once when progress first reaches after (0.2 = 20% of the data) and
+// stops it if CurveSharpe is below minSharpe.
+func PruneSharpe(after float64, minSharpe float64) PruneFunc {
+	checked := false
+	return func(progress float64, curve []EquityPoint) string {
+		if checked || progress < after {
+			return ""
+		}
+		checked = true
+		if s := CurveSharpe(curve); s < minSharpe {
+			return fmt.Sprintf("sharpe %.2f below %.2f after %.0f%% of bars", s, minSharpe, progress*100)
+		}
+		return ""
+	}
+}
+
+// PruneAny stops a run as soon as one of pruners does.
+func PruneAny(pruners ...PruneFunc) PruneFunc {
+	return func(progress float64, curve []EquityPoint) string {
+		for _, p := range pruners {
+			if reason := p(progress, curve); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+}
+
+// RunPruned is Run with early stopping: when prune returns a reason the replay stops and the
+// error wraps ErrPruned. The curve so far is returned either way. A nil prune never stops.
+func RunPruned(emu *Emulator, s Strategy, prune PruneFunc) ([]EquityPoint, error) {
+	return run(emu, s, prune)
+}
+
+// CurveSharpe is the Sharpe ratio of per-bar equity returns, annualized from the bar spacing
+// when the curve has timestamps. It is 0 for fewer than 3 points or flat equity.
+func CurveSharpe(curve []EquityPoint) float64 {
+	if len(curve) < 3 {
+		return 0
+	}
+	rets := make([]float64, 0, len(curve)-1)
+	times := make([]time.Time, len(curve))
+	for i, p := range curve {
+		times[i] = p.Time
+		if i > 0 && curve[i-1].Equity > 0 {
+			rets = append(rets, p.Equity/curve[i-1].Equity-1)
+		}
+	}
+	mean, variance := meanVariance(rets)
+	if variance <= 0 {
+		return 0
+	}
+	sharpe := mean / math.Sqrt(variance)
+	if step, ok := InferInterval(times); ok {
+		sharpe *= math.Sqrt(float64(365*24*time.Hour) / float64(step))
+	}
+	return sharpe
+}
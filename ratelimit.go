@@ -0,0 +1,159 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitError carries what a REST layer needs for Binance-style responses:
+// the used weight (X-MBX-USED-WEIGHT) and how long to back off (Retry-After on 429).
+type RateLimitError struct {
+	Used       int
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: used weight %d of %d, retry after %s", ErrRateLimited, e.Used, e.Limit, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimiter tracks request weight per key over fixed windows (Binance resets weight every minute).
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	now    func() time.Time
+	used   map[string]weightWindow
+}
+
+type weightWindow struct {
+	start time.Time
+	used  int
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		used:   make(map[string]weightWindow),
+	}
+}
+
+// Allow charges weight to key and returns the weight used in the current window.
+// When the charge would exceed the limit nothing is charged and a *RateLimitError is returned.
+func (r *RateLimiter) Allow(key string, weight int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	w := r.used[key]
+	if w.start.IsZero() || now.Sub(w.start) >= r.window {
+		w = weightWindow{start: now.Truncate(r.window)}
+	}
+	if weight < 0 {
+		weight = 0
+	}
+	if r.limit > 0 && w.used+weight > r.limit {
+		r.used[key] = w
+		return w.used, &RateLimitError{
+			Used:       w.used,
+			Limit:      r.limit,
+			RetryAfter: w.start.Add(r.window).Sub(now),
+		}
+	}
+	w.used += weight
+	r.used[key] = w
+	return w.used, nil
+}
+
+// Used returns the weight consumed by key in the current window.
+func (r *RateLimiter) Used(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.used[key]
+	if !ok || r.now().Sub(w.start) >= r.window {
+		return 0
+	}
+	return w.used
+}
+
+// SetRateLimiter enables per-account weight accounting for WithAccountWeight; nil disables it.
+func (e *Emulator) SetRateLimiter(r *RateLimiter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.limiter = r
+}
+
+// WithAccountWeight is WithAccount for authenticated API calls: the key must name a registered
+// account and the call is charged weight against the rate limiter before fn runs.
+func (e *Emulator) WithAccountWeight(key string, weight int, fn func(ex *Exchange) error) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ex, ok := e.accounts[key]
+	if !ok {
+		return 0, ErrUnknownAccount
+	}
+	used := 0
+	if e.limiter != nil {
+		var err error
+		used, err = e.limiter.Allow(key, weight)
+		if err != nil {
+			return used, err
+		}
+	}
+	return used, fn(ex)
+}
+
+// Binance REST headers used by RateLimitHandler.
+const (
+	HeaderAPIKey     = "X-MBX-APIKEY"
+	HeaderUsedWeight = "X-MBX-USED-WEIGHT"
+)
+
+// RateLimitHandler serves next behind r the way Binance's REST API does: each request is charged
+// weight(req) (1 when weight is nil) to the key in its X-MBX-APIKEY header and the weight used in
+// the window is reported in X-MBX-USED-WEIGHT. A request whose key is missing or not accepted by
+// known (e.g. Emulator.HasAccount) gets 401 and is not charged, so made-up keys cannot open new
+// weight windows; one over the limit gets 429 with Retry-After in whole seconds and does not
+// reach next. The request is already charged when next runs: next should reach the account with
+// WithAccount, as WithAccountWeight on the same limiter would charge it a second time.
+func RateLimitHandler(r *RateLimiter, known func(key string) bool, weight func(*http.Request) int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(HeaderAPIKey)
+		if key == "" {
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+		if known == nil || !known(key) {
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+		cost := 1
+		if weight != nil {
+			cost = weight(req)
+		}
+		used, err := r.Allow(key, cost)
+		w.Header().Set(HeaderUsedWeight, strconv.Itoa(used))
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rle.RetryAfter.Seconds()))))
+			http.Error(w, rle.Error(), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
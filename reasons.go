@@ -0,0 +1,88 @@
+package emul
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ReasonCategory groups free-form order reasons so trade pairing and statistics work with
+// custom labels ("flip-close", "tp-2") as well as the built-in ones.
+type ReasonCategory uint8
+
+const (
+	ReasonCategoryUnknown ReasonCategory = iota
+	ReasonCategoryEntry
+	ReasonCategoryExit
+	ReasonCategoryStop
+	ReasonCategoryLiquidation
+)
+
+func (c ReasonCategory) String() string {
+	switch c {
+	case ReasonCategoryEntry:
+		return "entry"
+	case ReasonCategoryExit:
+		return "exit"
+	case ReasonCategoryStop:
+		return "stop"
+	case ReasonCategoryLiquidation:
+		return "liquidation"
+	default:
+		return "unknown"
+	}
+}
+
+var reasonRegistry = struct {
+	sync.RWMutex
+	labels map[string]ReasonCategory
+}{
+	labels: map[string]ReasonCategory{
+		ReasonEntryLong:  ReasonCategoryEntry,
+		ReasonEntryShort: ReasonCategoryEntry,
+		ReasonExit:       ReasonCategoryExit,
+		ReasonStopLoss:   ReasonCategoryStop,
+		ReasonLiquidate:  ReasonCategoryLiquidation,
+		ReasonScaleIn:    ReasonCategoryEntry,
+		ReasonTimeExit:   ReasonCategoryExit,
+	},
+}
+
+// RegisterReason assigns a category to a custom reason label. Built-in labels cannot be remapped.
+func RegisterReason(label string, category ReasonCategory) error {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return fmt.Errorf("reason label is empty")
+	}
+	if category == ReasonCategoryUnknown || category > ReasonCategoryLiquidation {
+		return fmt.Errorf("invalid reason category %d", category)
+	}
+	switch label {
+	case ReasonEntryLong, ReasonEntryShort, ReasonExit, ReasonStopLoss, ReasonLiquidate, ReasonScaleIn, ReasonTimeExit:
+		return fmt.Errorf("reason %q is built in", label)
+	}
+	reasonRegistry.Lock()
+	defer reasonRegistry.Unlock()
+	reasonRegistry.labels[label] = category
+	return nil
+}
+
+// CategoryOf returns the registered category of a reason label.
+func CategoryOf(reason string) ReasonCategory {
+	reasonRegistry.RLock()
+	defer reasonRegistry.RUnlock()
+	return reasonRegistry.labels[reason]
+}
+
+// Category classifies the order: entries are recognized by reason, and any other order that
+// reduces a position counts as an exit unless its reason is registered as stop or liquidation.
+func (o Order) Category() ReasonCategory {
+	c := CategoryOf(o.Reason)
+	if c != ReasonCategoryUnknown {
+		return c
+	}
+	if o.PositionAfter == 0 {
+		return ReasonCategoryExit
+	}
+	return ReasonCategoryUnknown
+}
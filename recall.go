@@ -0,0 +1,55 @@
+package emul
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// BorrowRecallConfig force-closes short positions the way a recalled borrow or a squeeze would.
+// A recall happens on each listed tick and, independently, with Probability on every bar a short
+// is open. The buy-back executes at the bar's high moved up by PenaltyPct, plus the usual costs.
+type BorrowRecallConfig struct {
+	Probability float64
+	Ticks       []int64
+	PenaltyPct  float64
+	Seed        uint64
+}
+
+type borrowRecall struct {
+	cfg   BorrowRecallConfig
+	ticks map[int64]bool
+	rng   *rand.Rand
+}
+
+// SetBorrowRecall enables recall events; a zero config disables them.
+func (e *Exchange) SetBorrowRecall(cfg BorrowRecallConfig) {
+	if cfg.Probability <= 0 && len(cfg.Ticks) == 0 {
+		e.recall = nil
+		return
+	}
+	r := &borrowRecall{
+		cfg:   cfg,
+		ticks: make(map[int64]bool, len(cfg.Ticks)),
+		rng:   rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+	}
+	for _, t := range cfg.Ticks {
+		r.ticks[t] = true
+	}
+	e.recall = r
+}
+
+// applyBorrowRecall runs after pending orders, so a short opened by a limit on this bar can be
+// recalled on the same bar.
+func (e *Exchange) applyBorrowRecall(bar OHLCBar) *Order {
+	if e.recall == nil || e.position >= 0 {
+		return nil
+	}
+	// The draw happens on every short bar so the sequence does not depend on the schedule.
+	hit := e.recall.cfg.Probability > 0 && e.recall.rng.Float64() < e.recall.cfg.Probability
+	if !hit && !e.recall.ticks[e.tick] {
+		return nil
+	}
+	price := math.Max(bar.High, bar.Close) * (1 + math.Max(e.recall.cfg.PenaltyPct, 0))
+	order := e.closeAtPrice(price, ReasonBorrowRecall, "", e.fee)
+	return &order
+}
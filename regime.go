@@ -0,0 +1,180 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Regime labels the market state of a bar (see ClassifyRegimes).
+type Regime uint8
+
+const (
+	// RegimeUnknown marks the warm-up bars before the windows are full.
+	RegimeUnknown Regime = iota
+	RegimeTrendUp
+	RegimeTrendDown
+	RegimeRange
+	RegimeHighVol
+)
+
+func (r Regime) String() string {
+	switch r {
+	case RegimeUnknown:
+		return "unknown"
+	case RegimeTrendUp:
+		return "trend_up"
+	case RegimeTrendDown:
+		return "trend_down"
+	case RegimeRange:
+		return "range"
+	case RegimeHighVol:
+		return "high_vol"
+	}
+	return "invalid"
+}
+
+// RegimeConfig configures ClassifyRegimes. Realized volatility is the standard deviation of
+// log close returns over VolWindow bars (default 24); bars at or above the HighVolQuantile
+// (default 0.8) of the series' volatility are high-vol. Otherwise a bar trends when the moving
+// average of MAWindow closes (default 50) moved by at least TrendSlope (default 0.01, as a
+// fraction) over the last SlopeBars bars (default 10), and ranges when it did not.
+type RegimeConfig struct {
+	VolWindow       int
+	MAWindow        int
+	SlopeBars       int
+	TrendSlope      float64
+	HighVolQuantile float64
+}
+
+// ClassifyRegimes returns one label per bar (index i is tick i+1). The volatility and slope use
+// only bars up to each bar, but the high-vol threshold is a quantile over the whole series, so
+// the labels describe the backtest after the fact and are not a trading signal.
+func ClassifyRegimes(bars []OHLCBar, cfg RegimeConfig) ([]Regime, error) {
+	if cfg.VolWindow == 0 {
+		cfg.VolWindow = 24
+	}
+	if cfg.MAWindow == 0 {
+		cfg.MAWindow = 50
+	}
+	if cfg.SlopeBars == 0 {
+		cfg.SlopeBars = 10
+	}
+	if cfg.TrendSlope == 0 {
+		cfg.TrendSlope = 0.01
+	}
+	if cfg.HighVolQuantile == 0 {
+		cfg.HighVolQuantile = 0.8
+	}
+	if cfg.VolWindow < 2 || cfg.MAWindow < 1 || cfg.SlopeBars < 1 || cfg.TrendSlope < 0 || cfg.HighVolQuantile <= 0 || cfg.HighVolQuantile > 1 {
+		return nil, fmt.Errorf("invalid regime config")
+	}
+	labels := make([]Regime, len(bars))
+	if len(bars) == 0 {
+		return labels, nil
+	}
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		if b.Close <= 0 {
+			return nil, fmt.Errorf("bar %d: close must be positive", i+1)
+		}
+		closes[i] = b.Close
+	}
+	// The first log return is NaN; the volatility of bar i is over the returns ending at bar i.
+	vols := append([]float64{math.NaN()}, RollingStd(nil, LogReturns(nil, closes)[1:], cfg.VolWindow)...)
+	mas := RollingMean(nil, closes, cfg.MAWindow)
+	known := make([]float64, 0, len(vols))
+	for _, v := range vols {
+		if !math.IsNaN(v) {
+			known = append(known, v)
+		}
+	}
+	if len(known) == 0 {
+		return labels, nil
+	}
+	sort.Float64s(known)
+	threshold := quantileSorted(known, cfg.HighVolQuantile)
+	for i := range labels {
+		if math.IsNaN(vols[i]) || i < cfg.SlopeBars || math.IsNaN(mas[i-cfg.SlopeBars]) {
+			continue
+		}
+		slope := mas[i]/mas[i-cfg.SlopeBars] - 1
+		switch {
+		case vols[i] >= threshold && threshold > 0:
+			labels[i] = RegimeHighVol
+		case slope >= cfg.TrendSlope:
+			labels[i] = RegimeTrendUp
+		case slope <= -cfg.TrendSlope:
+			labels[i] = RegimeTrendDown
+		default:
+			labels[i] = RegimeRange
+		}
+	}
+	return labels, nil
+}
+
+// RegimeStats is the strategy's performance while the market was in one regime. Return chains
+// the equity changes over the bars of the regime; Trades groups trades by the regime of their
+// entry bar.
+type RegimeStats struct {
+	Regime Regime
+	Bars   int
+	Share  float64
+	Return float64
+	Trades TradeStats
+}
+
+type RegimeReport struct {
+	Labels   []Regime
+	ByRegime map[Regime]RegimeStats
+}
+
+// RegimeBreakdown labels the bars and splits the run's equity curve and trades by regime, to show
+// in which market states the strategy earns its edge.
+func RegimeBreakdown(bars []OHLCBar, curve []EquityPoint, trades []Trade, cfg RegimeConfig) (RegimeReport, error) {
+	labels, err := ClassifyRegimes(bars, cfg)
+	if err != nil {
+		return RegimeReport{}, err
+	}
+	at := func(tick int64) Regime {
+		if tick < 1 || tick > int64(len(labels)) {
+			return RegimeUnknown
+		}
+		return labels[tick-1]
+	}
+	out := RegimeReport{Labels: labels, ByRegime: make(map[Regime]RegimeStats)}
+	growth := make(map[Regime]float64)
+	for _, r := range labels {
+		s := out.ByRegime[r]
+		s.Regime = r
+		s.Bars++
+		s.Share = float64(s.Bars) / float64(len(labels))
+		out.ByRegime[r] = s
+		growth[r] = 1
+	}
+	for i := 1; i < len(curve); i++ {
+		if prev := curve[i-1].Equity; prev > 0 {
+			r := at(curve[i].Tick)
+			if _, ok := growth[r]; !ok {
+				growth[r] = 1
+			}
+			growth[r] *= curve[i].Equity / prev
+		}
+	}
+	grouped := make(map[Regime][]Trade)
+	for _, t := range trades {
+		r := at(t.EntryTick)
+		grouped[r] = append(grouped[r], t)
+		if _, ok := growth[r]; !ok {
+			growth[r] = 1
+		}
+	}
+	for r, g := range growth {
+		s := out.ByRegime[r]
+		s.Regime = r
+		s.Return = g - 1
+		s.Trades = ComputeTradeStats(grouped[r])
+		out.ByRegime[r] = s
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package emul
+
+import "fmt"
+
+// ReasonReverse marks the closing leg of a Reverse.
+const ReasonReverse = "reverse"
+
+// Reversal is the combined report of a flip: the order that closed the position and the one
+// that opened the opposite side. LimitID is 0 for Reverse.
+type Reversal struct {
+	LimitID int64
+	Close   Order
+	Open    Order
+	// PnL is the closed position's result at the closing price, net of the closing fee.
+	PnL float64
+}
+
+// Reverse closes the open position at the current price and opens the opposite side with
+// fraction of the free USD. Both legs happen or neither does: the open is checked against the
+// cash the close releases, and if it would be rejected nothing is executed and the error returned.
+func (e *Exchange) Reverse(fraction float64) (*Reversal, error) {
+	var r *Reversal
+	err := e.admitOrder(false)
+	if err == nil {
+		r, err = e.reverseAtPrice(e.lastPrice, fraction, e.fee, e.tick)
+	}
+	var order *Order
+	if r != nil {
+		order = &r.Open
+	}
+	e.recordIntent(Intent{Action: IntentReverse, Fraction: fraction}, order, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	e.reversals = append(e.reversals, *r)
+	return r, e.checkInvariants()
+}
+
+// ReverseLimit places a limit that reverses whichever position is open when the price is
+// touched. It is treated like a close limit for matching.
+func (e *Exchange) ReverseLimit(price float64, fraction float64) (int64, error) {
+	id, err := e.reverseLimit(price, fraction)
+	e.recordIntent(Intent{Action: IntentReverseLimit, Price: price, Fraction: fraction}, nil, id, err)
+	return id, err
+}
+
+func (e *Exchange) reverseLimit(price float64, fraction float64) (int64, error) {
+	if err := e.admitOrder(true); err != nil {
+		return 0, err
+	}
+	if price <= 0 {
+		price = e.lastPrice
+	}
+	if price <= 0 {
+		return 0, ErrPriceNotSet
+	}
+	if fraction <= 0 || fraction > 1 {
+		return 0, ErrInvalidFraction
+	}
+	return e.queueLimit(pendingOrder{
+		kind:     pendingReverse,
+		price:    price,
+		fraction: fraction,
+		reason:   ReasonReverse,
+	}), nil
+}
+
+// Reversals returns the flips executed so far, market and limit.
+func (e *Exchange) Reversals() []Reversal {
+	return append([]Reversal(nil), e.reversals...)
+}
+
+func (e *Exchange) reverseAtPrice(price float64, fraction float64, fee float64, placedTick int64) (*Reversal, error) {
+	if e.position == 0 {
+		return nil, ErrNoPosition
+	}
+	if e.lastPrice <= 0 {
+		return nil, ErrPriceNotSet
+	}
+	if price <= 0 {
+		price = e.lastPrice
+	}
+	if fraction <= 0 || fraction > 1 {
+		return nil, ErrInvalidFraction
+	}
+	long := e.position > 0
+	side := SideBuy
+	if long {
+		side = SideSell
+	}
+	// The opening leg is checked against the cash the close will release before anything is
+	// changed, so a rejected flip leaves the position as it was.
+	if e.sessionLocked() {
+		return nil, ErrSessionLossLimit
+	}
+	if _, err := e.sizeOpen(side, e.usdAfterClose(price, fee), price, fraction, fee); err != nil {
+		return nil, err
+	}
+	entry := e.entryPrice
+	closed := e.closeAtPrice(price, ReasonReverse, "", fee)
+	closed.PlacedTick = placedTick
+	e.orders[len(e.orders)-1].PlacedTick = placedTick
+	var opened *Order
+	var err error
+	if long {
+		opened, err = e.openShortAtPrice(price, fraction, fee, placedTick)
+	} else {
+		opened, err = e.openLongAtPrice(price, fraction, fee, placedTick)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reverse open after close: %w", err)
+	}
+	pnl := closed.Qty*(closed.Price-entry) - closed.Fee
+	if !long {
+		pnl = closed.Qty*(entry-closed.Price) - closed.Fee
+	}
+	return &Reversal{Close: closed, Open: *opened, PnL: pnl}, nil
+}
+
+// usdAfterClose is the free USD once the position is closed at price, as closeAtPrice books it.
+func (e *Exchange) usdAfterClose(price float64, fee float64) float64 {
+	if e.position > 0 {
+		qty, dust := e.splitDust(e.position)
+		revenue := e.roundQuote(qty * e.execPrice(SideSell, price, e.position*price))
+		usd := e.usd + revenue - e.roundQuote(revenue*fee)
+		if e.dustPolicy != DustTrack {
+			usd += dust * price
+		}
+		return usd
+	}
+	qty := -e.position
+	cost := e.roundQuote(qty * e.execPrice(SideBuy, price, qty*price))
+	total := cost + e.roundQuote(cost*fee)
+	available := e.shortCash + e.shortMargin
+	if available < total {
+		return 0
+	}
+	return e.usd + available - total
+}
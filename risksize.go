@@ -0,0 +1,92 @@
+package emul
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrInvalidStop = errors.New("stop must be below the entry for longs and above it for shorts")
+
+// RiskSize is an order sized so that being stopped out loses Risk USD. Fraction is the argument
+// to pass to OpenLong/OpenShort (or LongLimit/ShortLimit for a limit entry) to get Qty. Capped is
+// set when the risk budget asked for more than the free USD, in which case Risk is what the
+// smaller position actually risks.
+type RiskSize struct {
+	Qty      float64
+	Notional float64
+	Fraction float64
+	Risk     float64
+	Capped   bool
+}
+
+// RiskSize sizes an entry on side so that a stop at stop loses riskPct of equity (0.01 for 1%).
+// The loss per unit counts the execution prices after spread, slippage and tick rounding, the
+// entry fee and the taker fee of the stop exit. entry <= 0 sizes a market entry at the last
+// price; a positive entry sizes a limit placed there, charged the maker fee. The quantity is
+// rounded down to the lot size.
+func (e *Exchange) RiskSize(side OrderSide, entry float64, stop float64, riskPct float64) (RiskSize, error) {
+	if e.lastPrice <= 0 {
+		return RiskSize{}, ErrPriceNotSet
+	}
+	if riskPct <= 0 || riskPct > 1 {
+		return RiskSize{}, ErrInvalidFraction
+	}
+	fee := e.makerFee
+	if entry <= 0 {
+		entry, fee = e.lastPrice, e.fee
+	}
+	if (side == SideBuy && stop >= entry) || (side == SideSell && stop <= entry) || stop <= 0 {
+		return RiskSize{}, ErrInvalidStop
+	}
+	exit := SideSell
+	if side == SideSell {
+		exit = SideBuy
+	}
+	budget := e.Balance().Equity * riskPct
+	// Size once at the smallest bucket, then again at the resulting notional so size-bucket
+	// costs match the order actually placed.
+	var out RiskSize
+	notional := 0.0
+	for pass := 0; pass < 2; pass++ {
+		entryExec := e.execPrice(side, entry, notional)
+		stopExec := e.execPrice(exit, stop, notional*stop/entry)
+		entryFee := entryExec * max(fee, 0)
+		if side == SideBuy && fee > 0 {
+			// buyQty takes the fee out of the notional, so it is charged on qty*price/(1-fee).
+			entryFee /= 1 - fee
+		}
+		perUnit := math.Abs(entryExec-stopExec) + entryFee + stopExec*e.fee
+		if perUnit <= 0 {
+			return RiskSize{}, ErrInvalidStop
+		}
+		qty := budget / perUnit
+		if e.lotSize > 0 {
+			qty = roundDownToStep(qty, e.lotSize)
+		}
+		out = RiskSize{Qty: qty, Notional: qty * entryExec}
+		if side == SideBuy && fee > 0 {
+			out.Notional /= 1 - fee
+		}
+		if e.usd > 0 {
+			out.Fraction = out.Notional / e.usd
+		}
+		if out.Fraction > 1 || e.usd <= 0 {
+			out.Capped = true
+			out.Fraction = 1
+			out.Notional = max(e.usd, 0)
+			out.Qty = out.Notional / entryExec
+			if side == SideBuy && fee > 0 {
+				out.Qty *= 1 - fee
+			}
+			if e.lotSize > 0 {
+				out.Qty = roundDownToStep(out.Qty, e.lotSize)
+			}
+		}
+		out.Risk = out.Qty * perUnit
+		notional = out.Notional
+	}
+	if out.Qty <= 0 || (e.minQty > 0 && out.Qty < e.minQty) {
+		return out, ErrBelowMinQty
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RobustnessConfig sets the rolling windows of RollingRobustness: WindowDays long (default 90),
+// starting every StepDays (default 30).
+type RobustnessConfig struct {
+	WindowDays int
+	StepDays   int
+}
+
+// RobustnessWindow holds the stats of one window. Sharpe is annualized from daily returns (365
+// days, zero risk-free rate); Trades covers the trades closed inside the window. Losing is set
+// when the window ends below its starting equity.
+type RobustnessWindow struct {
+	Start       time.Time
+	End         time.Time
+	StartEquity float64
+	EndEquity   float64
+	Return      float64
+	Sharpe      float64
+	MaxDrawdown float64
+	Trades      TradeStats
+	Losing      bool
+}
+
+// RobustnessReport summarizes the windows: how many lost money, the share of losing windows and
+// the worst and best window returns. A strategy whose profit comes from a few windows depends
+// on the regime those windows were in.
+type RobustnessReport struct {
+	Windows      []RobustnessWindow
+	Losing       int
+	LosingShare  float64
+	WorstReturn  float64
+	BestReturn   float64
+	MedianReturn float64
+}
+
+// RollingRobustness recomputes return, Sharpe, drawdown and trade stats over rolling windows of
+// the equity curve. The last window ends at the end of the curve when the step does not land
+// there exactly, so the tail of the backtest is always covered.
+func RollingRobustness(curve []EquityPoint, trades []Trade, cfg RobustnessConfig) (RobustnessReport, error) {
+	if err := requireCurveTimes(curve); err != nil {
+		return RobustnessReport{}, err
+	}
+	if cfg.WindowDays == 0 {
+		cfg.WindowDays = 90
+	}
+	if cfg.StepDays == 0 {
+		cfg.StepDays = 30
+	}
+	if cfg.WindowDays < 2 || cfg.StepDays < 1 {
+		return RobustnessReport{}, fmt.Errorf("window must be at least 2 days and step at least 1 day")
+	}
+	window := time.Duration(cfg.WindowDays) * 24 * time.Hour
+	step := time.Duration(cfg.StepDays) * 24 * time.Hour
+	first, last := curve[0].Time, curve[len(curve)-1].Time
+	if last.Sub(first) < window {
+		return RobustnessReport{}, fmt.Errorf("equity curve spans less than one %d-day window", cfg.WindowDays)
+	}
+	var out RobustnessReport
+	for start := first; ; start = start.Add(step) {
+		end := start.Add(window)
+		if end.After(last) {
+			end, start = last, last.Add(-window)
+		}
+		out.Windows = append(out.Windows, robustnessWindow(curve, trades, start, end))
+		if !end.Before(last) {
+			break
+		}
+	}
+	returns := make([]float64, len(out.Windows))
+	for i, w := range out.Windows {
+		returns[i] = w.Return
+		if w.Losing {
+			out.Losing++
+		}
+		if i == 0 || w.Return < out.WorstReturn {
+			out.WorstReturn = w.Return
+		}
+		if i == 0 || w.Return > out.BestReturn {
+			out.BestReturn = w.Return
+		}
+	}
+	out.LosingShare = float64(out.Losing) / float64(len(out.Windows))
+	out.MedianReturn = median(returns)
+	return out, nil
+}
+
+func robustnessWindow(curve []EquityPoint, trades []Trade, start time.Time, end time.Time) RobustnessWindow {
+	lo, hi := -1, -1
+	for i, p := range curve {
+		if p.Time.Before(start) || p.Time.After(end) {
+			continue
+		}
+		if lo < 0 {
+			lo = i
+		}
+		hi = i
+	}
+	w := RobustnessWindow{Start: start, End: end}
+	if lo < 0 {
+		return w
+	}
+	span := curve[lo : hi+1]
+	w.StartEquity, w.EndEquity = span[0].Equity, span[len(span)-1].Equity
+	if w.StartEquity > 0 {
+		w.Return = w.EndEquity/w.StartEquity - 1
+	}
+	w.Losing = w.EndEquity < w.StartEquity
+	w.MaxDrawdown = MaxDrawdown(span)
+	daily := DailyEquity(span)
+	values := make([]float64, len(daily))
+	for i, p := range daily {
+		values[i] = p.Value
+	}
+	if rets := Returns(nil, values)[1:]; len(rets) > 1 {
+		if sharpe, ok := returnsSharpe(rets); ok {
+			w.Sharpe = sharpe * math.Sqrt(365)
+		}
+	}
+	inside := make([]Trade, 0)
+	for _, t := range trades {
+		if t.ExitTick >= span[0].Tick && t.ExitTick <= span[len(span)-1].Tick {
+			inside = append(inside, t)
+		}
+	}
+	w.Trades = ComputeTradeStats(inside)
+	return w
+}
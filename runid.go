@@ -0,0 +1,60 @@
+package emul
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+)
+
+// NewRunID returns a version-4 style UUID drawn from seed, so a rerun with the same seed gets
+// the same ID.
+func NewRunID(seed uint64) string {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	hi, lo := rng.Uint64(), rng.Uint64()
+	hi = hi&^0xf000 | 0x4000
+	lo = lo&^(0xc<<60) | 0x8<<60
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+// SetRunID namespaces the exchange's orders: each order records id as RunID, so orders of runs
+// merged into one store stay distinct by GlobalID. Order IDs themselves still count from 1.
+func (e *Exchange) SetRunID(id string) {
+	e.runID = id
+}
+
+func (e *Exchange) RunID() string {
+	return e.runID
+}
+
+// GlobalID is the order ID qualified by its run ("<run>-<id>"), or the bare ID without a run.
+func (o Order) GlobalID() string {
+	if o.RunID == "" {
+		return strconv.FormatInt(o.ID, 10)
+	}
+	return o.RunID + "-" + strconv.FormatInt(o.ID, 10)
+}
+
+// SetRunID namespaces the orders of the emulator's exchange with id and those of each account
+// with "<id>.<key>", including accounts added later. The ID is recorded in the manifest.
+func (e *Emulator) SetRunID(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runID = id
+	e.ex.SetRunID(id)
+	for key, ex := range e.accounts {
+		ex.SetRunID(accountRunID(id, key))
+	}
+}
+
+func (e *Emulator) RunID() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.runID
+}
+
+func accountRunID(id string, key string) string {
+	if id == "" {
+		return ""
+	}
+	return id + "." + key
+}
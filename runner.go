@@ -0,0 +1,82 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Strategy reacts to every replayed bar. executed holds the orders filled while the bar was applied.
+type Strategy interface {
+	OnBar(ex *Exchange, bar OHLCBar, executed []Order) error
+}
+
+type StrategyFunc func(ex *Exchange, bar OHLCBar, executed []Order) error
+
+func (f StrategyFunc) OnBar(ex *Exchange, bar OHLCBar, executed []Order) error {
+	return f(ex, bar, executed)
+}
+
+type EquityPoint struct {
+	Tick   int64
+	Time   time.Time
+	Equity float64
+}
+
+// Run replays the remaining bars through s and returns the equity after each bar.
+func Run(emu *Emulator, s Strategy) ([]EquityPoint, error) {
+	return run(emu, s, nil)
+}
+
+func run(emu *Emulator, s Strategy, prune PruneFunc) ([]EquityPoint, error) {
+	total := len(emu.bars) - emu.index
+	curve := make([]EquityPoint, 0, total)
+	timing := emu.DecisionTiming()
+	var executed []Order
+	for {
+		if timing == DecideOnOpen {
+			open, err := emu.openNext()
+			if errors.Is(err, ErrNoMoreBars) {
+				return curve, nil
+			}
+			if err != nil {
+				return curve, err
+			}
+			if err := s.OnBar(emu.ex, open, executed); err != nil {
+				return curve, err
+			}
+		}
+		bar, filled, err := emu.Next()
+		if errors.Is(err, ErrNoMoreBars) {
+			return curve, nil
+		}
+		if err != nil {
+			return curve, err
+		}
+		executed = filled
+		if timing == DecideOnCloseFillAtClose {
+			if err := s.OnBar(emu.ex, bar, executed); err != nil {
+				return curve, err
+			}
+		}
+		curve = append(curve, EquityPoint{
+			Tick:   emu.ex.tick,
+			Time:   bar.Time,
+			Equity: emu.ex.Balance().Equity,
+		})
+		if timing == DecideOnCloseFillNextOpen {
+			if _, err := emu.openNext(); err != nil && !errors.Is(err, ErrNoMoreBars) {
+				return curve, err
+			} else if err == nil {
+				if err := s.OnBar(emu.ex, bar, executed); err != nil {
+					return curve, err
+				}
+			}
+		}
+		if prune != nil {
+			if reason := prune(float64(len(curve))/float64(total), curve); reason != "" {
+				return curve, fmt.Errorf("%w: %s", ErrPruned, reason)
+			}
+		}
+	}
+}
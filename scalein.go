@@ -0,0 +1,97 @@
+package emul
+
+// ScaleIn adds to the open position at the current price with fraction of the free USD. The
+// entry price becomes the quantity-weighted average of the old and new fills.
+func (e *Exchange) ScaleIn(fraction float64) (*Order, error) {
+	var order *Order
+	err := e.admitOrder(false)
+	if err == nil {
+		order, err = e.scaleInAtPrice(e.lastPrice, fraction, e.fee, e.tick)
+	}
+	e.recordIntent(Intent{Action: IntentScaleIn, Fraction: fraction}, order, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return order, e.checkInvariants()
+}
+
+// ScaleInLimit places a limit that adds to whichever position is open when it fills.
+func (e *Exchange) ScaleInLimit(price float64, fraction float64) (int64, error) {
+	id, err := e.scaleInLimit(price, fraction)
+	e.recordIntent(Intent{Action: IntentScaleInLimit, Price: price, Fraction: fraction}, nil, id, err)
+	return id, err
+}
+
+func (e *Exchange) scaleInLimit(price float64, fraction float64) (int64, error) {
+	if err := e.admitOrder(true); err != nil {
+		return 0, err
+	}
+	if price <= 0 {
+		price = e.lastPrice
+	}
+	if price <= 0 {
+		return 0, ErrPriceNotSet
+	}
+	if fraction <= 0 || fraction > 1 {
+		return 0, ErrInvalidFraction
+	}
+	return e.queueLimit(pendingOrder{
+		kind:     pendingScaleIn,
+		price:    price,
+		fraction: fraction,
+	}), nil
+}
+
+func (e *Exchange) scaleInAtPrice(price float64, fraction float64, fee float64, placedTick int64) (*Order, error) {
+	if e.position == 0 {
+		return nil, ErrNoPosition
+	}
+	if e.sessionLocked() {
+		return nil, ErrSessionLossLimit
+	}
+	if e.lastPrice <= 0 {
+		return nil, ErrPriceNotSet
+	}
+	if price <= 0 {
+		price = e.lastPrice
+	}
+	if fraction <= 0 || fraction > 1 {
+		return nil, ErrInvalidFraction
+	}
+	equityBefore := e.Balance().Equity
+	mid := price
+	notional := e.capByVolume(e.usd*fraction, price)
+	if notional <= 0 {
+		return nil, ErrInvalidFraction
+	}
+	feeUSD := notional * fee
+	net := notional - feeUSD
+	if net <= 0 {
+		return nil, ErrInvalidFraction
+	}
+	if e.position > 0 {
+		execPrice := e.execPrice(SideBuy, price, notional)
+		qty, notional, feeUSD := e.buyQty(notional, fee, execPrice)
+		if qty <= 0 || (e.minQty > 0 && qty < e.minQty) {
+			return nil, ErrBelowMinQty
+		}
+		e.usd -= notional
+		e.entryPrice = (e.position*e.entryPrice + qty*execPrice) / (e.position + qty)
+		e.position += qty
+		order := e.recordOrder(SideBuy, qty, mid, execPrice, feeUSD, qty*(mid-execPrice), equityBefore, ReasonScaleIn, "", placedTick)
+		return &order, nil
+	}
+	execPrice := e.execPrice(SideSell, price, notional)
+	qty, notional, feeUSD, net := e.sellQty(notional, fee, execPrice)
+	if qty <= 0 || (e.minQty > 0 && qty < e.minQty) {
+		return nil, ErrBelowMinQty
+	}
+	held := -e.position
+	e.usd -= notional
+	e.shortMargin += notional
+	e.shortCash += net
+	e.entryPrice = (held*e.entryPrice + qty*execPrice) / (held + qty)
+	e.position -= qty
+	order := e.recordOrder(SideSell, qty, mid, execPrice, feeUSD, qty*(execPrice-mid), equityBefore, ReasonScaleIn, "", placedTick)
+	return &order, nil
+}
@@ -0,0 +1,155 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type ShockKind uint8
+
+const (
+	// ShockPrice moves the price level by Move (e.g. -0.30) spread geometrically over the window;
+	// bars after the window keep the shifted level. Chain an opposite shock to model a recovery.
+	ShockPrice ShockKind = iota + 1
+	// ShockVolatility scales log-moves inside the window by VolMultiplier; later bars are
+	// rescaled so the series stays continuous.
+	ShockVolatility
+)
+
+// Shock describes one market event injected on top of historical bars.
+// The window starts at the first bar with Time >= At (or at Index when At is zero)
+// and lasts Bars bars, or Duration when Bars is zero.
+type Shock struct {
+	Kind          ShockKind
+	At            time.Time
+	Index         int
+	Bars          int
+	Duration      time.Duration
+	Move          float64
+	VolMultiplier float64
+}
+
+// ApplyScenario returns a modified copy of bars with shocks applied in order; the input is not changed.
+func ApplyScenario(bars []OHLCBar, shocks ...Shock) ([]OHLCBar, error) {
+	out := make([]OHLCBar, len(bars))
+	copy(out, bars)
+	for i, s := range shocks {
+		start, end, err := shockWindow(out, s)
+		if err != nil {
+			return nil, fmt.Errorf("shock %d: %w", i, err)
+		}
+		switch s.Kind {
+		case ShockPrice:
+			if s.Move <= -1 {
+				return nil, fmt.Errorf("shock %d: move must be greater than -1", i)
+			}
+			applyPriceShock(out, start, end, s.Move)
+		case ShockVolatility:
+			if s.VolMultiplier <= 0 {
+				return nil, fmt.Errorf("shock %d: volatility multiplier must be positive", i)
+			}
+			applyVolatilityShock(out, start, end, s.VolMultiplier)
+		default:
+			return nil, fmt.Errorf("shock %d: unknown kind %d", i, s.Kind)
+		}
+	}
+	return out, nil
+}
+
+func shockWindow(bars []OHLCBar, s Shock) (int, int, error) {
+	start := s.Index
+	if !s.At.IsZero() {
+		start = -1
+		for i, bar := range bars {
+			if !bar.Time.IsZero() && !bar.Time.Before(s.At) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return 0, 0, fmt.Errorf("no bar at or after %s", s.At.Format(time.RFC3339))
+		}
+	}
+	if start < 0 || start >= len(bars) {
+		return 0, 0, fmt.Errorf("start index %d out of range", start)
+	}
+	end := start + s.Bars
+	if s.Bars <= 0 {
+		if s.Duration <= 0 {
+			return 0, 0, fmt.Errorf("shock needs bars or duration")
+		}
+		if bars[start].Time.IsZero() {
+			return 0, 0, fmt.Errorf("duration requires bar timestamps")
+		}
+		limit := bars[start].Time.Add(s.Duration)
+		end = start
+		for end < len(bars) && bars[end].Time.Before(limit) {
+			end++
+		}
+	}
+	if end > len(bars) {
+		end = len(bars)
+	}
+	return start, end, nil
+}
+
+func applyPriceShock(bars []OHLCBar, start int, end int, move float64) {
+	n := end - start
+	prev := 1.0
+	for i := start; i < len(bars); i++ {
+		cur := 1 + move
+		if i < end {
+			cur = math.Pow(1+move, float64(i-start+1)/float64(n))
+		}
+		b := bars[i]
+		open := b.Open * prev
+		close := b.Close * cur
+		b.High = math.Max(b.High*cur, math.Max(open, close))
+		b.Low = math.Min(b.Low*cur, math.Min(open, close))
+		b.Average *= (prev + cur) / 2
+		b.Open = open
+		b.Close = close
+		bars[i] = b
+		prev = cur
+	}
+}
+
+func applyVolatilityShock(bars []OHLCBar, start int, end int, k float64) {
+	// Inside the window every price is re-expressed relative to the previous close with its
+	// log distance multiplied by k; afterwards the accumulated level change is carried forward.
+	ref := bars[start].Open
+	if start > 0 {
+		ref = bars[start-1].Close
+	}
+	newRef := ref
+	scale := func(p float64) float64 {
+		if p <= 0 || ref <= 0 {
+			return p
+		}
+		return newRef * math.Pow(p/ref, k)
+	}
+	for i := start; i < end; i++ {
+		b := bars[i]
+		nextRef := b.Close
+		b.Open = scale(b.Open)
+		b.High = scale(b.High)
+		b.Low = scale(b.Low)
+		b.Close = scale(b.Close)
+		b.Average = scale(b.Average)
+		bars[i] = b
+		ref = nextRef
+		newRef = b.Close
+	}
+	if end <= start || ref <= 0 {
+		return
+	}
+	ratio := newRef / ref
+	for i := end; i < len(bars); i++ {
+		bars[i].Open *= ratio
+		bars[i].High *= ratio
+		bars[i].Low *= ratio
+		bars[i].Close *= ratio
+		bars[i].Average *= ratio
+	}
+}
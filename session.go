@@ -0,0 +1,64 @@
+package emul
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SessionStats is a live snapshot of an emulation session for dashboards and alerting.
+// Drawdown is the fraction below PeakEquity, the highest equity seen after a bar. Uptime is
+// measured on the emulator's clock (see SetClock).
+type SessionStats struct {
+	Tick          int64         `json:"tick"`
+	BarTime       time.Time     `json:"bar_time"`
+	Equity        float64       `json:"equity"`
+	PeakEquity    float64       `json:"peak_equity"`
+	Drawdown      float64       `json:"drawdown"`
+	PositionSide  OrderSide     `json:"position_side,omitempty"`
+	PositionQty   float64       `json:"position_qty"`
+	EntryPrice    float64       `json:"entry_price"`
+	Fills         int           `json:"fills"`
+	PendingOrders int           `json:"pending_orders"`
+	StartedAt     time.Time     `json:"started_at"`
+	Uptime        time.Duration `json:"uptime_ns"`
+}
+
+func (e *Emulator) SessionStats() SessionStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	bal := e.ex.Balance()
+	s := SessionStats{
+		Tick:          e.ex.tick,
+		BarTime:       e.ex.lastBar.Time,
+		Equity:        bal.Equity,
+		PeakEquity:    max(e.peak, bal.Equity),
+		Fills:         len(e.ex.orders),
+		PendingOrders: len(e.ex.pending),
+		StartedAt:     e.started,
+		Uptime:        e.now().Sub(e.started),
+	}
+	if s.PeakEquity > 0 {
+		s.Drawdown = (s.PeakEquity - s.Equity) / s.PeakEquity
+	}
+	if positions := e.ex.Wallet().Positions; len(positions) > 0 {
+		s.PositionSide, s.PositionQty, s.EntryPrice = positions[0].Side, positions[0].Qty, positions[0].EntryPrice
+	}
+	return s
+}
+
+// StatsHandler serves SessionStats as JSON on GET, to be mounted at /stats by a server that
+// drives the emulator.
+func StatsHandler(emu *Emulator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(emu.SessionStats()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
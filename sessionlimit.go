@@ -0,0 +1,97 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSessionLossLimit = errors.New("session loss limit reached")
+
+// RuleSessionLoss names the risk events of a SessionLimit.
+const RuleSessionLoss = "session-loss"
+
+// SessionLimit tracks PnL per trading session and, optionally, stops new entries for the rest
+// of a session once it has lost MaxLossPct of its starting equity (0.03 for 3%) or MaxLossUSD;
+// zero disables either threshold. Sessions start daily at ResetAt past UTC midnight (0 for UTC
+// days) and need bar timestamps. Closes, stops and exits keep working while entries are
+// blocked; entries fail with ErrSessionLossLimit, and limits reaching their price are rejected.
+type SessionLimit struct {
+	MaxLossPct float64
+	MaxLossUSD float64
+	ResetAt    time.Duration
+}
+
+// SessionPnL is the result of one session: PnL is the equity change since its start, marked at
+// the last bar seen. Locked is set once the loss limit was hit.
+type SessionPnL struct {
+	Start       time.Time
+	StartEquity float64
+	PnL         float64
+	Locked      bool
+}
+
+// SetSessionLimit starts session tracking; the current session starts at the next bar.
+func (e *Exchange) SetSessionLimit(cfg SessionLimit) error {
+	if cfg.MaxLossPct < 0 || cfg.MaxLossPct > 1 || cfg.MaxLossUSD < 0 || cfg.ResetAt < 0 || cfg.ResetAt >= 24*time.Hour {
+		return fmt.Errorf("session limit needs losses >= 0 (pct at most 1) and a reset within the day")
+	}
+	e.sessionLimit = &cfg
+	return nil
+}
+
+// SessionLimit returns the session limit in force; ok is false when sessions are not tracked.
+func (e *Exchange) SessionLimit() (SessionLimit, bool) {
+	if e.sessionLimit == nil {
+		return SessionLimit{}, false
+	}
+	return *e.sessionLimit, true
+}
+
+// Sessions returns the sessions tracked so far, oldest first, ending with the current one.
+func (e *Exchange) Sessions() []SessionPnL {
+	return append([]SessionPnL(nil), e.sessions...)
+}
+
+// RiskEvents returns the risk breaches raised by the exchange itself (EventRiskBreach events,
+// e.g. RuleSessionLoss); WithNotifier forwards them.
+func (e *Exchange) RiskEvents() []Event {
+	return append([]Event(nil), e.riskEvents...)
+}
+
+// startSession opens a new session when bar is the first of one, from the equity before it.
+func (e *Exchange) startSession(bar OHLCBar) {
+	if e.sessionLimit == nil || bar.Time.IsZero() {
+		return
+	}
+	start := bar.Time.UTC().Add(-e.sessionLimit.ResetAt).Truncate(24 * time.Hour).Add(e.sessionLimit.ResetAt)
+	if n := len(e.sessions); n > 0 && e.sessions[n-1].Start.Equal(start) {
+		return
+	}
+	e.sessions = append(e.sessions, SessionPnL{Start: start, StartEquity: e.Balance().Equity})
+}
+
+// markSession updates the session PnL at the end of a bar and locks entries on a breach.
+func (e *Exchange) markSession(bar OHLCBar) {
+	n := len(e.sessions)
+	if e.sessionLimit == nil || n == 0 {
+		return
+	}
+	s := &e.sessions[n-1]
+	equity := e.Balance().Equity
+	s.PnL = equity - s.StartEquity
+	if s.Locked || s.PnL >= 0 {
+		return
+	}
+	cfg := e.sessionLimit
+	if (cfg.MaxLossPct > 0 && -s.PnL >= cfg.MaxLossPct*s.StartEquity) || (cfg.MaxLossUSD > 0 && -s.PnL >= cfg.MaxLossUSD) {
+		s.Locked = true
+		e.riskEvents = append(e.riskEvents, Event{Kind: EventRiskBreach, Tick: e.tick, BarTime: bar.Time, Equity: equity, Rule: RuleSessionLoss, PnL: s.PnL})
+	}
+}
+
+// sessionLocked reports whether the current session has hit its loss limit.
+func (e *Exchange) sessionLocked() bool {
+	n := len(e.sessions)
+	return e.sessionLimit != nil && n > 0 && e.sessions[n-1].Locked
+}
@@ -0,0 +1,222 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// CompareConfig configures CompareRuns. Confidence defaults to 0.95 and Resamples to 2000.
+// BlockSize > 1 resamples blocks of consecutive periods (circular block bootstrap) to keep
+// autocorrelation; Daily compares daily instead of per-bar returns.
+type CompareConfig struct {
+	Confidence float64
+	Resamples  int
+	BlockSize  int
+	Seed       uint64
+	Daily      bool
+}
+
+// RunComparison tests whether run B earns more per period than run A on the same data. Diff is
+// the mean per-period return of B minus A. The t-test fields come from a paired Student t-test,
+// the bootstrap ones from resampling the paired differences; both p-values are two-sided and the
+// intervals are at the configured confidence.
+type RunComparison struct {
+	Periods    int
+	Diff       float64
+	StdErr     float64
+	TStat      float64
+	PValue     float64
+	CILow      float64
+	CIHigh     float64
+	BootPValue float64
+	BootLow    float64
+	BootHigh   float64
+}
+
+// CompareRuns pairs the returns of two equity curves by tick (by UTC day when Daily) and tests
+// the difference. Periods present in only one curve are skipped.
+func CompareRuns(a []EquityPoint, b []EquityPoint, cfg CompareConfig) (RunComparison, error) {
+	if cfg.Confidence == 0 {
+		cfg.Confidence = 0.95
+	}
+	if cfg.Resamples == 0 {
+		cfg.Resamples = 2000
+	}
+	if cfg.Confidence <= 0 || cfg.Confidence >= 1 || cfg.Resamples < 0 || cfg.BlockSize < 0 {
+		return RunComparison{}, fmt.Errorf("invalid comparison config")
+	}
+	diffs, err := pairedDiffs(a, b, cfg.Daily)
+	if err != nil {
+		return RunComparison{}, err
+	}
+	n := len(diffs)
+	if n < 3 {
+		return RunComparison{}, fmt.Errorf("need at least 3 paired returns, got %d", n)
+	}
+	mean, variance := meanVariance(diffs)
+	r := RunComparison{Periods: n, Diff: mean, StdErr: math.Sqrt(variance / float64(n))}
+	dof := float64(n - 1)
+	tq := studentQuantile(1-(1-cfg.Confidence)/2, dof)
+	r.CILow, r.CIHigh = mean-tq*r.StdErr, mean+tq*r.StdErr
+	switch {
+	case r.StdErr > 0:
+		r.TStat = mean / r.StdErr
+		r.PValue = 2 * (1 - studentCDF(math.Abs(r.TStat), dof))
+	case mean == 0:
+		r.PValue = 1
+	}
+	r.BootPValue, r.BootLow, r.BootHigh = bootstrapDiff(diffs, cfg)
+	return r, nil
+}
+
+func pairedDiffs(a []EquityPoint, b []EquityPoint, daily bool) ([]float64, error) {
+	if daily {
+		if err := requireCurveTimes(a); err != nil {
+			return nil, err
+		}
+		if err := requireCurveTimes(b); err != nil {
+			return nil, err
+		}
+		a, b = dailyCurve(a), dailyCurve(b)
+	}
+	byTick := make(map[int64]float64, len(b))
+	for i := 1; i < len(b); i++ {
+		if prev := b[i-1].Equity; prev > 0 {
+			byTick[b[i].Tick] = b[i].Equity/prev - 1
+		}
+	}
+	diffs := make([]float64, 0, len(a))
+	for i := 1; i < len(a); i++ {
+		prev := a[i-1].Equity
+		rb, ok := byTick[a[i].Tick]
+		if !ok || prev <= 0 {
+			continue
+		}
+		diffs = append(diffs, rb-(a[i].Equity/prev-1))
+	}
+	return diffs, nil
+}
+
+// dailyCurve resamples a curve to UTC days, numbering them by day so two curves pair up.
+func dailyCurve(curve []EquityPoint) []EquityPoint {
+	daily := DailyEquity(curve)
+	out := make([]EquityPoint, len(daily))
+	for i, p := range daily {
+		out[i] = EquityPoint{Tick: p.Time.Unix() / 86400, Time: p.Time, Equity: p.Value}
+	}
+	return out
+}
+
+// bootstrapDiff resamples the differences and returns the two-sided p-value of a zero mean and
+// the percentile interval of the mean.
+func bootstrapDiff(diffs []float64, cfg CompareConfig) (float64, float64, float64) {
+	if cfg.Resamples == 0 {
+		return 0, 0, 0
+	}
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))
+	n := len(diffs)
+	block := max(cfg.BlockSize, 1)
+	mean, _ := meanVariance(diffs)
+	means := make([]float64, cfg.Resamples)
+	extreme := 0
+	for k := range means {
+		sum := 0.0
+		for i := 0; i < n; {
+			start := rng.IntN(n)
+			for j := 0; j < block && i < n; j, i = j+1, i+1 {
+				sum += diffs[(start+j)%n]
+			}
+		}
+		means[k] = sum / float64(n)
+		// Under the null the resampled mean is centred on zero.
+		if math.Abs(means[k]-mean) >= math.Abs(mean) {
+			extreme++
+		}
+	}
+	sort.Float64s(means)
+	alpha := (1 - cfg.Confidence) / 2
+	return float64(extreme+1) / float64(cfg.Resamples+1), quantileSorted(means, alpha), quantileSorted(means, 1-alpha)
+}
+
+func quantileSorted(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// studentCDF is the CDF of Student's t distribution with dof degrees of freedom.
+func studentCDF(t float64, dof float64) float64 {
+	x := dof / (dof + t*t)
+	tail := 0.5 * regIncBeta(dof/2, 0.5, x)
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentQuantile inverts studentCDF by bisection.
+func studentQuantile(p float64, dof float64) float64 {
+	lo, hi := -1e3, 1e3
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		if studentCDF(mid, dof) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b), evaluated with Lentz's
+// continued fraction.
+func regIncBeta(a float64, b float64, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a + b)
+	lb, _ := math.Lgamma(a)
+	lc, _ := math.Lgamma(b)
+	front := math.Exp(la - lb - lc + a*math.Log(x) + b*math.Log(1-x))
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaFraction(b, a, 1-x)/b
+	}
+	return front * betaFraction(a, b, x) / a
+}
+
+func betaFraction(a float64, b float64, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		for i := 0; i < 2; i++ {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+			num = -(a + fm) * (a + b + fm) * x / ((a + fm) * (a + 2*fm + 1))
+		}
+		if math.Abs(d*c-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,152 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+type ActionKind uint8
+
+const (
+	ActionBar ActionKind = iota
+	ActionOpenLong
+	ActionOpenShort
+	ActionClose
+	ActionLongLimit
+	ActionShortLimit
+	ActionCloseLimit
+	actionKinds
+)
+
+// Action is one deterministic simulation step: either a bar to apply or an order call.
+type Action struct {
+	Kind     ActionKind
+	Bar      OHLCBar
+	Fraction float64
+	Price    float64
+}
+
+type SimConfig struct {
+	StartUSD    float64
+	Fee         float64
+	SlippagePct float64
+	SpreadPct   float64
+}
+
+// SimResult holds the final state plus the outcome of every action (nil for success, including
+// expected rejections such as ErrPositionOpen).
+type SimResult struct {
+	Orders  []Order
+	Balance Balance
+	Errors  []error
+}
+
+// Simulate applies an arbitrary action sequence to a fresh Exchange with invariant checks on.
+// Order-level rejections are recorded per action; only an invariant violation or an invalid bar
+// aborts the run and is returned as the error.
+func Simulate(cfg SimConfig, actions []Action) (SimResult, error) {
+	ex := NewExchange(cfg.StartUSD, cfg.Fee, cfg.SlippagePct, cfg.SpreadPct)
+	ex.SetInvariantMode(InvariantsError)
+	res := SimResult{Errors: make([]error, len(actions))}
+	tick := int64(0)
+	for i, a := range actions {
+		var err error
+		switch a.Kind {
+		case ActionBar:
+			tick++
+			_, err = ex.tickBarAt(tick, a.Bar)
+			if err != nil {
+				return res, fmt.Errorf("action %d: %w", i, err)
+			}
+		case ActionOpenLong:
+			_, err = ex.OpenLong(a.Fraction)
+		case ActionOpenShort:
+			_, err = ex.OpenShort(a.Fraction)
+		case ActionClose:
+			_, err = ex.CloseDeal("")
+		case ActionLongLimit:
+			_, err = ex.LongLimit(a.Price, a.Fraction)
+		case ActionShortLimit:
+			_, err = ex.ShortLimit(a.Price, a.Fraction)
+		case ActionCloseLimit:
+			_, err = ex.CloseLimit(a.Price, "", "")
+		default:
+			err = fmt.Errorf("unknown action kind %d", a.Kind)
+		}
+		if _, ok := err.(*InvariantError); ok {
+			return res, fmt.Errorf("action %d: %w", i, err)
+		}
+		res.Errors[i] = err
+	}
+	res.Orders = ex.Orders()
+	res.Balance = ex.Balance()
+	return res, nil
+}
+
+// DecodeActions maps arbitrary bytes onto a valid action sequence (3 bytes per action), so
+// go test -fuzz can explore order/bar interleavings. Prices follow a bounded random walk.
+func DecodeActions(data []byte) []Action {
+	actions := make([]Action, 0, len(data)/3)
+	price := 100.0
+	for i := 0; i+2 < len(data); i += 3 {
+		kind := ActionKind(data[i] % uint8(actionKinds))
+		a := data[i+1]
+		b := data[i+2]
+		switch kind {
+		case ActionBar:
+			move := (float64(a) - 127.5) / 127.5 * 0.2
+			next := math.Max(price*(1+move), 0.01)
+			wick := float64(b) / 255 * 0.05
+			actions = append(actions, Action{Kind: ActionBar, Bar: syntheticBar(price, next, wick)})
+			price = next
+		default:
+			actions = append(actions, Action{
+				Kind:     kind,
+				Fraction: (float64(a) + 1) / 256,
+				Price:    price * (0.9 + float64(b)/255*0.2),
+			})
+		}
+	}
+	return actions
+}
+
+// RandomActions generates n seeded actions, roughly half of them bars.
+func RandomActions(n int, seed uint64) []Action {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	data := make([]byte, 3*n)
+	for i := range data {
+		data[i] = byte(rng.IntN(256))
+	}
+	for i := 0; i < len(data); i += 3 {
+		if rng.IntN(2) == 0 {
+			data[i] = byte(ActionBar)
+		}
+	}
+	return DecodeActions(data)
+}
+
+// RandomBars generates n seeded bars as a random walk with the given per-bar volatility.
+func RandomBars(n int, start float64, vol float64, seed uint64) []OHLCBar {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	bars := make([]OHLCBar, n)
+	price := start
+	for i := range bars {
+		next := price * math.Exp(vol*rng.NormFloat64())
+		bars[i] = syntheticBar(price, next, vol*math.Abs(rng.NormFloat64()))
+		price = next
+	}
+	return bars
+}
+
+func syntheticBar(open float64, close float64, wick float64) OHLCBar {
+	high := math.Max(open, close) * (1 + wick)
+	low := math.Min(open, close) * (1 - wick)
+	return OHLCBar{
+		Open:    open,
+		High:    high,
+		Low:     low,
+		Close:   close,
+		Average: (open + high + low + close) / 4,
+	}
+}
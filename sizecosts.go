@@ -0,0 +1,76 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+)
+
+// SizeBucket sets the spread and slippage of orders whose notional (USD) is at least
+// MinNotional and below the next bucket's. SpreadPct is the full spread, split evenly between
+// the sides as usual; a negative value keeps the exchange spread. Buy and sell slippage are set
+// separately, since impact on the ask and the bid often differs.
+type SizeBucket struct {
+	MinNotional     float64
+	SpreadPct       float64
+	BuySlippagePct  float64
+	SellSlippagePct float64
+}
+
+// SetSizeBuckets makes spread and slippage depend on the order notional, e.g. small, medium
+// and large tiers:
+//
+//	ex.SetSizeBuckets([]emul.SizeBucket{
+//		{MinNotional: 0, SpreadPct: -1, BuySlippagePct: 0.0002, SellSlippagePct: 0.0002},
+//		{MinNotional: 10_000, SpreadPct: -1, BuySlippagePct: 0.0008, SellSlippagePct: 0.0005},
+//		{MinNotional: 100_000, SpreadPct: 0.002, BuySlippagePct: 0.003, SellSlippagePct: 0.002},
+//	})
+//
+// Buckets must be ordered by MinNotional; orders below the first one use the exchange costs.
+// An empty slice restores the flat costs. Fees are not affected.
+func (e *Exchange) SetSizeBuckets(buckets []SizeBucket) error {
+	for i, b := range buckets {
+		if b.MinNotional < 0 || math.IsNaN(b.MinNotional) {
+			return fmt.Errorf("size bucket %d: min notional must not be negative", i)
+		}
+		if i > 0 && b.MinNotional <= buckets[i-1].MinNotional {
+			return fmt.Errorf("size bucket %d: min notional must increase", i)
+		}
+		if b.SpreadPct >= 1 || b.BuySlippagePct < 0 || b.BuySlippagePct >= 1 || b.SellSlippagePct < 0 || b.SellSlippagePct >= 1 {
+			return fmt.Errorf("size bucket %d: spread and slippage must be in [0, 1)", i)
+		}
+	}
+	e.sizeBuckets = append([]SizeBucket(nil), buckets...)
+	return nil
+}
+
+func (e *Exchange) SizeBuckets() []SizeBucket {
+	return append([]SizeBucket(nil), e.sizeBuckets...)
+}
+
+// sizeCosts returns the spread and slippage for an order of notional on side.
+func (e *Exchange) sizeCosts(side OrderSide, notional float64) (float64, float64) {
+	spread, slippage := e.spreadPct, e.slippagePct
+	for i := len(e.sizeBuckets) - 1; i >= 0; i-- {
+		b := e.sizeBuckets[i]
+		if notional < b.MinNotional {
+			continue
+		}
+		if b.SpreadPct >= 0 {
+			spread = b.SpreadPct
+		}
+		slippage = b.BuySlippagePct
+		if side == SideSell {
+			slippage = b.SellSlippagePct
+		}
+		break
+	}
+	return spread, slippage
+}
+
+// pendingNotional estimates the notional a pending order would trade at price.
+func (e *Exchange) pendingNotional(p pendingOrder, price float64) float64 {
+	if p.kind == pendingClose {
+		return math.Abs(e.position) * price
+	}
+	return e.usd * p.fraction
+}
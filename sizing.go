@@ -0,0 +1,91 @@
+package emul
+
+import (
+	"math"
+)
+
+// Sizing helpers translate a signal into the fraction argument of OpenLong/LongLimit and friends.
+// All of them return a value in [0, 1]; 0 means "not enough data to size", not "sell".
+
+// KellyFraction estimates the Kelly fraction W - (1-W)/R from the trailing lookback trades
+// (all trades when lookback <= 0), where R is average win over average loss in trade returns,
+// and scales it (0.5 for half-Kelly).
+func KellyFraction(trades []Trade, lookback int, scale float64) float64 {
+	if lookback > 0 && len(trades) > lookback {
+		trades = trades[len(trades)-lookback:]
+	}
+	wins, losses := 0, 0
+	winSum, lossSum := 0.0, 0.0
+	for _, t := range trades {
+		switch {
+		case t.Return > 0:
+			wins++
+			winSum += t.Return
+		case t.Return < 0:
+			losses++
+			lossSum -= t.Return
+		}
+	}
+	if wins == 0 || losses == 0 {
+		return 0
+	}
+	w := float64(wins) / float64(wins+losses)
+	r := (winSum / float64(wins)) / (lossSum / float64(losses))
+	return clampFraction((w - (1-w)/r) * scale)
+}
+
+// ATR is the simple average true range over the last period bars.
+func ATR(bars []OHLCBar, period int) float64 {
+	if period <= 0 || len(bars) < period+1 {
+		return 0
+	}
+	sum := 0.0
+	for i := len(bars) - period; i < len(bars); i++ {
+		prevClose := bars[i-1].Close
+		tr := math.Max(bars[i].High-bars[i].Low, math.Max(math.Abs(bars[i].High-prevClose), math.Abs(bars[i].Low-prevClose)))
+		sum += tr
+	}
+	return sum / float64(period)
+}
+
+// RealizedVol is the per-bar standard deviation of close log returns over the last lookback bars.
+func RealizedVol(bars []OHLCBar, lookback int) float64 {
+	if lookback < 2 || len(bars) < lookback+1 {
+		return 0
+	}
+	_, variance := meanVariance(logReturns(bars[len(bars)-lookback-1:]))
+	return math.Sqrt(variance)
+}
+
+// VolTargetFraction scales exposure so the position's volatility matches targetVol; both
+// volatilities must be measured over the same horizon.
+func VolTargetFraction(targetVol float64, assetVol float64) float64 {
+	if targetVol <= 0 || assetVol <= 0 {
+		return 0
+	}
+	return clampFraction(targetVol / assetVol)
+}
+
+// ATRRiskFraction sizes the position so that a move of atrMultiple ATRs against it loses
+// riskPct of equity (e.g. 0.01 for 1%).
+func ATRRiskFraction(bars []OHLCBar, period int, atrMultiple float64, riskPct float64) float64 {
+	atr := ATR(bars, period)
+	if atr <= 0 || atrMultiple <= 0 || riskPct <= 0 {
+		return 0
+	}
+	price := bars[len(bars)-1].Close
+	if price <= 0 {
+		return 0
+	}
+	return clampFraction(riskPct / (atrMultiple * atr / price))
+}
+
+func clampFraction(f float64) float64 {
+	if math.IsNaN(f) || f <= 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
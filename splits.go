@@ -0,0 +1,116 @@
+package emul
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	SegmentTrain      = "train"
+	SegmentValidation = "validation"
+	SegmentTest       = "test"
+)
+
+// SplitConfig cuts bars by date. Bars before ValidationStart are train, bars from ValidationStart
+// to TestStart are validation and the rest are test; a zero ValidationStart leaves no validation
+// segment. Embargo drops the bars within that span before each boundary from the earlier segment,
+// so indicators and open trades of one segment cannot see into the next.
+type SplitConfig struct {
+	ValidationStart time.Time
+	TestStart       time.Time
+	Embargo         time.Duration
+}
+
+// Segment is a contiguous run of bars; Bars is a sub-slice of the bars that were split.
+type Segment struct {
+	Name string
+	From time.Time
+	To   time.Time
+	Bars []OHLCBar
+}
+
+type Splits struct {
+	Segments []Segment
+}
+
+// SplitByDate splits time-ordered bars into train, validation and test segments (see SplitConfig).
+func SplitByDate(bars []OHLCBar, cfg SplitConfig) (Splits, error) {
+	if cfg.TestStart.IsZero() {
+		return Splits{}, fmt.Errorf("test start is not set")
+	}
+	if !cfg.ValidationStart.IsZero() && !cfg.ValidationStart.Before(cfg.TestStart) {
+		return Splits{}, fmt.Errorf("validation start %s is not before test start %s", cfg.ValidationStart, cfg.TestStart)
+	}
+	if cfg.Embargo < 0 {
+		return Splits{}, fmt.Errorf("embargo must not be negative")
+	}
+	for i, bar := range bars {
+		if bar.Time.IsZero() {
+			return Splits{}, fmt.Errorf("bar %d has no time", i)
+		}
+		if i > 0 && bar.Time.Before(bars[i-1].Time) {
+			return Splits{}, fmt.Errorf("bar %d is out of order", i)
+		}
+	}
+	names := []string{SegmentTrain}
+	starts := []time.Time{{}}
+	if !cfg.ValidationStart.IsZero() {
+		names, starts = append(names, SegmentValidation), append(starts, cfg.ValidationStart)
+	}
+	names, starts = append(names, SegmentTest), append(starts, cfg.TestStart)
+
+	var splits Splits
+	for k, name := range names {
+		from := firstBarAt(bars, starts[k])
+		to := len(bars)
+		if k+1 < len(starts) {
+			to = firstBarAt(bars, starts[k+1].Add(-cfg.Embargo))
+		}
+		if to <= from {
+			return Splits{}, fmt.Errorf("%s segment is empty", name)
+		}
+		seg := bars[from:to:to]
+		splits.Segments = append(splits.Segments, Segment{Name: name, From: seg[0].Time, To: seg[len(seg)-1].Time, Bars: seg})
+	}
+	return splits, nil
+}
+
+// firstBarAt returns the index of the first bar at or after t.
+func firstBarAt(bars []OHLCBar, t time.Time) int {
+	for i, bar := range bars {
+		if !bar.Time.Before(t) {
+			return i
+		}
+	}
+	return len(bars)
+}
+
+func (s Splits) Segment(name string) (Segment, bool) {
+	for _, seg := range s.Segments {
+		if seg.Name == name {
+			return seg, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Emulator builds an emulator over the named segment only; cfg.Bars and cfg.BarSet are ignored.
+func (s Splits) Emulator(name string, cfg EmulatorConfig) (*Emulator, error) {
+	seg, ok := s.Segment(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown segment %q", name)
+	}
+	cfg.Bars, cfg.BarSet = seg.Bars, nil
+	return NewEmulatorFromConfig(cfg)
+}
+
+// RunSegment replays s on the named segment and returns the emulator with the equity curve, so
+// a strategy tuned on train can be checked on validation or test without touching other bars.
+func (s Splits) RunSegment(name string, cfg EmulatorConfig, st Strategy) (*Emulator, []EquityPoint, error) {
+	emu, err := s.Emulator(name, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	curve, err := Run(emu, st)
+	return emu, curve, err
+}
@@ -0,0 +1,89 @@
+package emul
+
+import (
+	"math"
+)
+
+type TradeStats struct {
+	Trades       int
+	Wins         int
+	Losses       int
+	WinRate      float64
+	NetPnL       float64
+	GrossProfit  float64
+	GrossLoss    float64
+	AvgPnL       float64
+	AvgWin       float64
+	AvgLoss      float64
+	ProfitFactor float64
+	Fees         float64
+}
+
+// ExitBreakdown splits trade statistics by how trades ended, so exit logic (stop-loss vs
+// take-profit vs signal exits) can be tuned with direct feedback. Trades without a StopKind
+// are grouped under "".
+type ExitBreakdown struct {
+	ByCategory map[ReasonCategory]TradeStats
+	ByReason   map[string]TradeStats
+	ByStopKind map[string]TradeStats
+}
+
+func ComputeTradeStats(trades []Trade) TradeStats {
+	var s TradeStats
+	for _, t := range trades {
+		s.Trades++
+		s.NetPnL += t.PnL
+		s.Fees += t.Fees
+		switch {
+		case t.PnL > 0:
+			s.Wins++
+			s.GrossProfit += t.PnL
+		case t.PnL < 0:
+			s.Losses++
+			s.GrossLoss += -t.PnL
+		}
+	}
+	if s.Trades > 0 {
+		s.WinRate = float64(s.Wins) / float64(s.Trades)
+		s.AvgPnL = s.NetPnL / float64(s.Trades)
+	}
+	if s.Wins > 0 {
+		s.AvgWin = s.GrossProfit / float64(s.Wins)
+	}
+	if s.Losses > 0 {
+		s.AvgLoss = s.GrossLoss / float64(s.Losses)
+	}
+	switch {
+	case s.GrossLoss > 0:
+		s.ProfitFactor = s.GrossProfit / s.GrossLoss
+	case s.GrossProfit > 0:
+		s.ProfitFactor = math.Inf(1)
+	}
+	return s
+}
+
+func BreakdownExits(trades []Trade) ExitBreakdown {
+	byCategory := make(map[ReasonCategory][]Trade)
+	byReason := make(map[string][]Trade)
+	byStopKind := make(map[string][]Trade)
+	for _, t := range trades {
+		byCategory[t.ExitCategory] = append(byCategory[t.ExitCategory], t)
+		byReason[t.ExitReason] = append(byReason[t.ExitReason], t)
+		byStopKind[t.StopKind] = append(byStopKind[t.StopKind], t)
+	}
+	out := ExitBreakdown{
+		ByCategory: make(map[ReasonCategory]TradeStats, len(byCategory)),
+		ByReason:   make(map[string]TradeStats, len(byReason)),
+		ByStopKind: make(map[string]TradeStats, len(byStopKind)),
+	}
+	for k, v := range byCategory {
+		out.ByCategory[k] = ComputeTradeStats(v)
+	}
+	for k, v := range byReason {
+		out.ByReason[k] = ComputeTradeStats(v)
+	}
+	for k, v := range byStopKind {
+		out.ByStopKind[k] = ComputeTradeStats(v)
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type BreakKind uint8
+
+const (
+	BreakEquityDrop BreakKind = iota
+	BreakTime
+	BreakRejection
+	BreakCondition
+)
+
+func (k BreakKind) String() string {
+	switch k {
+	case BreakEquityDrop:
+		return "equity-drop"
+	case BreakTime:
+		return "time"
+	case BreakRejection:
+		return "rejection"
+	}
+	return "condition"
+}
+
+// Breakpoints configure a Stepper. EquityDropPct pauses once equity falls that fraction below
+// its running peak (0.1 = 10%) and re-arms at the next peak. OnRejection pauses when the
+// strategy returns an order rejection (which then does not abort the replay) or a pending limit
+// is dropped. When is an arbitrary condition checked after every bar.
+type Breakpoints struct {
+	EquityDropPct float64
+	At            []time.Time
+	OnRejection   bool
+	When          func(ex *Exchange, bar OHLCBar) bool
+	// RecentBars is how many bars the dump includes (default 10).
+	RecentBars int
+}
+
+// BreakEvent is a paused replay: what triggered and the exchange state at that bar.
+type BreakEvent struct {
+	Kind    BreakKind
+	Reason  string
+	Tick    int64
+	Bar     OHLCBar
+	Balance Balance
+	Pending []PendingOrder
+	Recent  []OHLCBar
+	State   string
+}
+
+func (b *BreakEvent) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "break %s at tick %d (%s): %s\n", b.Kind, b.Tick, b.Bar.Time.Format(time.RFC3339), b.Reason)
+	sb.WriteString(b.State)
+	fmt.Fprintf(&sb, "\n  equity=%.10f pending=%d", b.Balance.Equity, len(b.Pending))
+	for _, p := range b.Pending {
+		fmt.Fprintf(&sb, "\n  pending: id=%d kind=%s side=%s price=%.10f", p.ID, p.Kind, p.Side, p.Price)
+	}
+	for _, bar := range b.Recent {
+		fmt.Fprintf(&sb, "\n  bar %s o=%.10f h=%.10f l=%.10f c=%.10f", bar.Time.Format(time.RFC3339), bar.Open, bar.High, bar.Low, bar.Close)
+	}
+	return sb.String()
+}
+
+// Stepper replays a strategy bar by bar and pauses on breakpoints, for diagnosing anomalies.
+type Stepper struct {
+	emu     *Emulator
+	s       Strategy
+	bp      Breakpoints
+	at      map[int64]bool
+	recent  []OHLCBar
+	curve   []EquityPoint
+	peak    float64
+	dropped bool
+	failed  int
+}
+
+func NewStepper(emu *Emulator, s Strategy, bp Breakpoints) *Stepper {
+	if bp.RecentBars <= 0 {
+		bp.RecentBars = 10
+	}
+	st := &Stepper{emu: emu, s: s, bp: bp, at: make(map[int64]bool, len(bp.At))}
+	for _, t := range bp.At {
+		st.at[t.UnixNano()] = true
+	}
+	return st
+}
+
+// Continue runs until a breakpoint hits and returns it; at the end of the bars it returns nil.
+func (st *Stepper) Continue() (*BreakEvent, error) {
+	for {
+		ev, err := st.Step()
+		if errors.Is(err, ErrNoMoreBars) {
+			return nil, nil
+		}
+		if err != nil || ev != nil {
+			return ev, err
+		}
+	}
+}
+
+// Step applies one bar and returns the breakpoint it hit, if any; ErrNoMoreBars ends the replay.
+func (st *Stepper) Step() (*BreakEvent, error) {
+	bar, executed, err := st.emu.Next()
+	if err != nil {
+		return nil, err
+	}
+	ex := st.emu.ex
+	st.recent = append(st.recent, bar)
+	if len(st.recent) > st.bp.RecentBars {
+		st.recent = st.recent[len(st.recent)-st.bp.RecentBars:]
+	}
+	var ev *BreakEvent
+	if err := st.s.OnBar(ex, bar, executed); err != nil {
+		if !st.bp.OnRejection || !isRejection(err) {
+			return nil, err
+		}
+		ev = st.event(BreakRejection, bar, err.Error())
+	}
+	equity := ex.Balance().Equity
+	st.curve = append(st.curve, EquityPoint{Tick: ex.tick, Time: bar.Time, Equity: equity})
+	failed := 0
+	for _, n := range ex.limitFailed {
+		failed += n
+	}
+	if ev == nil && st.bp.OnRejection && failed > st.failed {
+		ev = st.event(BreakRejection, bar, "pending limit dropped")
+	}
+	st.failed = failed
+	if equity > st.peak {
+		st.peak, st.dropped = equity, false
+	}
+	if ev == nil && st.bp.EquityDropPct > 0 && !st.dropped && st.peak > 0 {
+		if drop := (st.peak - equity) / st.peak; drop >= st.bp.EquityDropPct {
+			st.dropped = true
+			ev = st.event(BreakEquityDrop, bar, fmt.Sprintf("equity %.2f is %.2f%% below peak %.2f", equity, drop*100, st.peak))
+		}
+	}
+	if ev == nil && !bar.Time.IsZero() && st.at[bar.Time.UnixNano()] {
+		ev = st.event(BreakTime, bar, "timestamp reached")
+	}
+	if ev == nil && st.bp.When != nil && st.bp.When(ex, bar) {
+		ev = st.event(BreakCondition, bar, "condition met")
+	}
+	return ev, nil
+}
+
+// Curve returns the equity after every bar stepped so far.
+func (st *Stepper) Curve() []EquityPoint {
+	return append([]EquityPoint(nil), st.curve...)
+}
+
+func (st *Stepper) event(kind BreakKind, bar OHLCBar, reason string) *BreakEvent {
+	ex := st.emu.ex
+	return &BreakEvent{
+		Kind:    kind,
+		Reason:  reason,
+		Tick:    ex.tick,
+		Bar:     bar,
+		Balance: ex.Balance(),
+		Pending: ex.PendingOrders(),
+		Recent:  append([]OHLCBar(nil), st.recent...),
+		State:   ex.stateDump(),
+	}
+}
+
+// isRejection reports whether err is an order being refused rather than a replay failure.
+func isRejection(err error) bool {
+	for _, target := range []error{ErrPositionOpen, ErrNoPosition, ErrInvalidFraction, ErrBelowMinQty, ErrPriceNotSet, ErrInsufficientFunds, ErrTooManyOpenOrders, ErrTooManyOrdersPerBar} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package emul
+
+import "errors"
+
+var ErrInvalidStopRules = errors.New("stop rules must not be negative")
+
+// StopRules tighten the stop of the open position's bracket as the trade moves in its favour.
+// The gain is the best price reached since the entry (highest high for longs, lowest low for
+// shorts) relative to the entry price. Once it reaches BreakEvenAt the stop moves to the entry
+// shifted BreakEvenOffset in the trade's favour (e.g. to cover fees); once it reaches TrailAt
+// the stop trails the best price by TrailATR times ATR, an average true range fixed when the
+// rules are set (see ATR). A zero threshold disables that rule. The stop only ever tightens.
+//
+// The exchange applies the rules at the end of every bar after the one they were set on, so a
+// bar that moves the stop cannot also fill it.
+type StopRules struct {
+	BreakEvenAt     float64
+	BreakEvenOffset float64
+	TrailAt         float64
+	TrailATR        float64
+	ATR             float64
+}
+
+// SetStopRules attaches r to the open position, creating a bracket without levels when none is
+// set. Like the bracket, the rules are removed when the position closes.
+func (e *Exchange) SetStopRules(r StopRules) error {
+	if e.position == 0 {
+		return ErrNoPosition
+	}
+	if r.BreakEvenAt < 0 || r.BreakEvenOffset < 0 || r.TrailAt < 0 || r.TrailATR < 0 || r.ATR < 0 {
+		return ErrInvalidStopRules
+	}
+	if e.bracket == nil {
+		e.bracket = &activeBracket{setTick: e.tick}
+	}
+	e.bracket.rules = &r
+	e.bracket.setTick = e.tick
+	if e.bracket.best == 0 {
+		e.bracket.best = e.entryPrice
+	}
+	return nil
+}
+
+// StopRules returns the rules of the open position; ok is false when none are set.
+func (e *Exchange) StopRules() (StopRules, bool) {
+	if e.bracket == nil || e.bracket.rules == nil {
+		return StopRules{}, false
+	}
+	return *e.bracket.rules, true
+}
+
+// applyStopRules tracks the best price of the position through bar and tightens its stop.
+func (e *Exchange) applyStopRules(bar OHLCBar) {
+	b := e.bracket
+	if b == nil || b.rules == nil || e.position == 0 || e.tick <= b.setTick || e.entryPrice <= 0 {
+		return
+	}
+	r, entry := b.rules, e.entryPrice
+	long := e.position > 0
+	dir := 1.0
+	if long {
+		b.best = max(b.best, bar.High)
+	} else {
+		dir = -1
+		if b.best == 0 || bar.Low < b.best {
+			b.best = bar.Low
+		}
+	}
+	gain := dir * (b.best - entry) / entry
+	tighten := func(stop float64) {
+		if stop <= 0 {
+			return
+		}
+		if b.Stop == 0 || dir*(stop-b.Stop) > 0 {
+			b.Stop = stop
+		}
+	}
+	if r.BreakEvenAt > 0 && gain >= r.BreakEvenAt {
+		tighten(entry * (1 + dir*r.BreakEvenOffset))
+	}
+	if r.TrailAt > 0 && gain >= r.TrailAt && r.TrailATR > 0 && r.ATR > 0 {
+		tighten(b.best - dir*r.TrailATR*r.ATR)
+	}
+}
+
+// ManageStops sets r on the open position, taking ATR over the last period bars of the context
+// window when r.ATR is zero.
+func (c *StrategyContext) ManageStops(r StopRules, period int) error {
+	if r.ATR == 0 && r.TrailATR > 0 {
+		r.ATR = ATR(c.history, period)
+	}
+	return c.ex.SetStopRules(r)
+}
@@ -0,0 +1,278 @@
+package emul
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrRunNotFound = errors.New("run not found")
+
+// StoredRun is one archived run: what was run (Manifest, when known), its headline numbers, the
+// trades and the equity curve. Stats is not written (ProfitFactor may be infinite) and is
+// recomputed from Trades on Load.
+type StoredRun struct {
+	RunSummary
+	Manifest *Manifest
+	Stats    TradeStats `json:"-"`
+	Trades   []Trade
+	Equity   []EquityPoint
+}
+
+// RunSummary is the index entry of a stored run, enough to filter and rank runs without loading
+// their trades and curves.
+type RunSummary struct {
+	ID          string
+	Name        string
+	Tags        []string
+	CreatedAt   time.Time
+	ConfigHash  string
+	TradeCount  int
+	NetPnL      float64
+	Return      float64
+	MaxDrawdown float64
+	Sharpe      float64
+	// PruneReason and Err record optimizer trials that did not run to the end.
+	PruneReason string
+	Err         string
+}
+
+// NewStoredRun builds a record of a finished emulator run, manifest included. The run is stored
+// under the emulator's RunID when it has one.
+func NewStoredRun(name string, emu *Emulator, curve []EquityPoint, tags ...string) (StoredRun, error) {
+	manifest, err := emu.Manifest()
+	if err != nil {
+		return StoredRun{}, err
+	}
+	run := storedRun(name, emu.Exchange().Orders(), curve, tags)
+	run.Manifest = &manifest
+	run.ID, run.ConfigHash = manifest.RunID, manifest.ConfigHash
+	return run, nil
+}
+
+// StoredRunFromBatch builds a record of a batch or optimizer result. It has no manifest.
+func StoredRunFromBatch(res BatchResult, tags ...string) StoredRun {
+	run := storedRun(res.Name, res.Orders, res.Equity, tags)
+	run.PruneReason = res.PruneReason
+	if res.Err != nil {
+		run.Err = res.Err.Error()
+	}
+	return run
+}
+
+func storedRun(name string, orders []Order, curve []EquityPoint, tags []string) StoredRun {
+	trades := PairTrades(orders)
+	run := StoredRun{
+		RunSummary: RunSummary{Name: name, Tags: append([]string(nil), tags...)},
+		Stats:      ComputeTradeStats(trades),
+		Trades:     trades,
+		Equity:     append([]EquityPoint(nil), curve...),
+	}
+	run.TradeCount, run.NetPnL = run.Stats.Trades, run.Stats.NetPnL
+	if len(curve) > 0 && curve[0].Equity > 0 {
+		run.Return = curve[len(curve)-1].Equity/curve[0].Equity - 1
+	}
+	run.MaxDrawdown = MaxDrawdown(curve)
+	run.Sharpe = CurveSharpe(curve)
+	return run
+}
+
+// ResultStore archives runs in a directory: runs/<id>.json holds each run and index.jsonl one
+// summary per line, so weeks of optimizer output can be queried without re-running anything.
+// The files are plain JSON and can be loaded into a database or a notebook as they are.
+// A store is safe for concurrent use within one process.
+type ResultStore struct {
+	dir   string
+	mu    sync.Mutex
+	index []RunSummary
+}
+
+// OpenResultStore opens the store in dir, creating it if needed.
+func OpenResultStore(dir string) (*ResultStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "runs"), 0o755); err != nil {
+		return nil, err
+	}
+	s := &ResultStore{dir: dir}
+	f, err := os.Open(filepath.Join(dir, "index.jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var sum RunSummary
+		if err := json.Unmarshal(sc.Bytes(), &sum); err != nil {
+			return nil, fmt.Errorf("index line %d: %w", line, err)
+		}
+		s.index = append(s.index, sum)
+	}
+	return s, sc.Err()
+}
+
+// Save archives run and returns its ID. An empty ID is assigned; CreatedAt defaults to now.
+func (s *ResultStore) Save(run StoredRun) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run.ID == "" {
+		run.ID = fmt.Sprintf("run-%06d", len(s.index)+1)
+	}
+	if strings.ContainsAny(run.ID, `/\`) {
+		return "", fmt.Errorf("invalid run id %q", run.ID)
+	}
+	for _, sum := range s.index {
+		if sum.ID == run.ID {
+			return "", fmt.Errorf("run %q already stored", run.ID)
+		}
+	}
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now().UTC()
+	}
+	if err := writeFile(s.runPath(run.ID), func(w io.Writer) error { return json.NewEncoder(w).Encode(run) }); err != nil {
+		return "", err
+	}
+	line, err := json.Marshal(run.RunSummary)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, "index.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	s.index = append(s.index, run.RunSummary)
+	return run.ID, nil
+}
+
+// SaveBatch archives every result of a sweep or optimizer with the same tags.
+func (s *ResultStore) SaveBatch(results []BatchResult, tags ...string) ([]string, error) {
+	ids := make([]string, 0, len(results))
+	for _, res := range results {
+		id, err := s.Save(StoredRunFromBatch(res, tags...))
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Load reads a stored run with its trades and curve.
+func (s *ResultStore) Load(id string) (StoredRun, error) {
+	f, err := os.Open(s.runPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return StoredRun{}, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	if err != nil {
+		return StoredRun{}, err
+	}
+	defer f.Close()
+	var run StoredRun
+	if err := json.NewDecoder(f).Decode(&run); err != nil {
+		return StoredRun{}, fmt.Errorf("run %s: %w", id, err)
+	}
+	run.Stats = ComputeTradeStats(run.Trades)
+	return run, nil
+}
+
+func (s *ResultStore) runPath(id string) string {
+	return filepath.Join(s.dir, "runs", id+".json")
+}
+
+// Sort keys for RunQuery.
+const (
+	SortCreated     = "created"
+	SortReturn      = "return"
+	SortSharpe      = "sharpe"
+	SortNetPnL      = "net_pnl"
+	SortMaxDrawdown = "max_drawdown"
+)
+
+// RunQuery filters stored runs. Tags must all be present; NamePrefix and ConfigHash match when
+// set; MinReturn and MaxDrawdown apply when non-zero. Runs that failed or were pruned are left
+// out unless Incomplete is set. Results are sorted by SortBy (SortCreated when empty), best
+// first, and cut to Limit when positive.
+type RunQuery struct {
+	Tags        []string
+	NamePrefix  string
+	ConfigHash  string
+	MinReturn   float64
+	MaxDrawdown float64
+	Incomplete  bool
+	SortBy      string
+	Limit       int
+}
+
+// Query returns the summaries of the runs matching q.
+func (s *ResultStore) Query(q RunQuery) ([]RunSummary, error) {
+	var less func(a, b RunSummary) bool
+	switch q.SortBy {
+	case "", SortCreated:
+		less = func(a, b RunSummary) bool { return a.CreatedAt.After(b.CreatedAt) }
+	case SortReturn:
+		less = func(a, b RunSummary) bool { return a.Return > b.Return }
+	case SortSharpe:
+		less = func(a, b RunSummary) bool { return a.Sharpe > b.Sharpe }
+	case SortNetPnL:
+		less = func(a, b RunSummary) bool { return a.NetPnL > b.NetPnL }
+	case SortMaxDrawdown:
+		less = func(a, b RunSummary) bool { return a.MaxDrawdown < b.MaxDrawdown }
+	default:
+		return nil, fmt.Errorf("unknown sort key %q", q.SortBy)
+	}
+	s.mu.Lock()
+	var out []RunSummary
+	for _, sum := range s.index {
+		if q.matches(sum) {
+			out = append(out, sum)
+		}
+	}
+	s.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func (q RunQuery) matches(sum RunSummary) bool {
+	for _, tag := range q.Tags {
+		if !slices.Contains(sum.Tags, tag) {
+			return false
+		}
+	}
+	switch {
+	case !strings.HasPrefix(sum.Name, q.NamePrefix):
+		return false
+	case q.ConfigHash != "" && sum.ConfigHash != q.ConfigHash:
+		return false
+	case q.MinReturn != 0 && sum.Return < q.MinReturn:
+		return false
+	case q.MaxDrawdown != 0 && sum.MaxDrawdown > q.MaxDrawdown:
+		return false
+	case !q.Incomplete && (sum.Err != "" || sum.PruneReason != ""):
+		return false
+	}
+	return true
+}
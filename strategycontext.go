@@ -0,0 +1,203 @@
+package emul
+
+import "math"
+
+// ContextStrategy is a strategy that only sees a StrategyContext, not the Exchange, so it cannot
+// reach balances or history other than through read-only copies and intent helpers.
+type ContextStrategy interface {
+	OnContext(ctx *StrategyContext) error
+}
+
+type ContextStrategyFunc func(ctx *StrategyContext) error
+
+func (f ContextStrategyFunc) OnContext(ctx *StrategyContext) error {
+	return f(ctx)
+}
+
+// WithContext adapts s to Strategy for Run, batches and ensembles. window is the number of
+// bars kept for History and the indicators (at least 1).
+func WithContext(s ContextStrategy, window int) Strategy {
+	return &contextStrategy{s: s, window: max(window, 1)}
+}
+
+type contextStrategy struct {
+	s       ContextStrategy
+	window  int
+	history []OHLCBar
+}
+
+func (c *contextStrategy) OnBar(ex *Exchange, bar OHLCBar, executed []Order) error {
+	c.history = append(c.history, bar)
+	if len(c.history) >= 2*c.window {
+		// Shift down only once the buffer doubles, so appends stay amortized O(1).
+		c.history = append(c.history[:0], c.history[len(c.history)-c.window:]...)
+	}
+	history := c.history
+	if len(history) > c.window {
+		history = history[len(history)-c.window:]
+	}
+	return c.s.OnContext(&StrategyContext{ex: ex, bar: bar, executed: executed, history: history})
+}
+
+// StrategyContext is the state of one replayed bar as seen by a ContextStrategy. Accessors
+// return copies; orders go through intent helpers that are no-ops when the intent already holds.
+type StrategyContext struct {
+	ex       *Exchange
+	bar      OHLCBar
+	executed []Order
+	history  []OHLCBar
+}
+
+func (c *StrategyContext) Bar() OHLCBar {
+	return c.bar
+}
+
+func (c *StrategyContext) Tick() int64 {
+	return c.ex.tick
+}
+
+// Executed returns the orders filled while the bar was applied.
+func (c *StrategyContext) Executed() []Order {
+	return append([]Order(nil), c.executed...)
+}
+
+// History returns up to the last n bars, oldest first and ending with the current bar; n <= 0
+// returns the whole window.
+func (c *StrategyContext) History(n int) []OHLCBar {
+	if n <= 0 || n > len(c.history) {
+		n = len(c.history)
+	}
+	return append([]OHLCBar(nil), c.history[len(c.history)-n:]...)
+}
+
+func (c *StrategyContext) Balance() Balance {
+	return c.ex.Balance()
+}
+
+// Position returns the open position; Qty is 0 when flat.
+func (c *StrategyContext) Position() PositionInfo {
+	if positions := c.ex.Wallet().Positions; len(positions) > 0 {
+		return positions[0]
+	}
+	return PositionInfo{}
+}
+
+func (c *StrategyContext) PendingOrders() []PendingOrder {
+	return c.ex.PendingOrders()
+}
+
+// SMA is the mean close of the last n bars; ok is false until n bars are in the window.
+func (c *StrategyContext) SMA(n int) (float64, bool) {
+	closes, ok := c.closes(n)
+	if !ok {
+		return 0, false
+	}
+	sum := 0.0
+	for _, v := range closes {
+		sum += v
+	}
+	return sum / float64(n), true
+}
+
+// StdDev is the sample standard deviation of the last n closes.
+func (c *StrategyContext) StdDev(n int) (float64, bool) {
+	if n < 2 {
+		return 0, false
+	}
+	mean, ok := c.SMA(n)
+	if !ok {
+		return 0, false
+	}
+	closes, _ := c.closes(n)
+	sum := 0.0
+	for _, v := range closes {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(n-1)), true
+}
+
+// Highest is the highest high of the last n bars.
+func (c *StrategyContext) Highest(n int) (float64, bool) {
+	if n <= 0 || n > len(c.history) {
+		return 0, false
+	}
+	high := math.Inf(-1)
+	for _, b := range c.history[len(c.history)-n:] {
+		high = math.Max(high, b.High)
+	}
+	return high, true
+}
+
+// Lowest is the lowest low of the last n bars.
+func (c *StrategyContext) Lowest(n int) (float64, bool) {
+	if n <= 0 || n > len(c.history) {
+		return 0, false
+	}
+	low := math.Inf(1)
+	for _, b := range c.history[len(c.history)-n:] {
+		low = math.Min(low, b.Low)
+	}
+	return low, true
+}
+
+func (c *StrategyContext) closes(n int) ([]float64, bool) {
+	if n <= 0 || n > len(c.history) {
+		return nil, false
+	}
+	out := make([]float64, n)
+	for i, b := range c.history[len(c.history)-n:] {
+		out[i] = b.Close
+	}
+	return out, true
+}
+
+// GoLong makes the book long: a short is closed first and a long opened with fraction of the
+// free USD. It returns the last order placed, or nil when already long.
+func (c *StrategyContext) GoLong(fraction float64) (*Order, error) {
+	return c.goTo(SideBuy, fraction)
+}
+
+// GoShort is GoLong for the short side.
+func (c *StrategyContext) GoShort(fraction float64) (*Order, error) {
+	return c.goTo(SideSell, fraction)
+}
+
+func (c *StrategyContext) goTo(side OrderSide, fraction float64) (*Order, error) {
+	pos := c.Position()
+	if pos.Qty > 0 && pos.Side == side {
+		return nil, nil
+	}
+	if pos.Qty > 0 {
+		if _, err := c.ex.CloseDeal(ReasonExit); err != nil {
+			return nil, err
+		}
+	}
+	if side == SideSell {
+		return c.ex.OpenShort(fraction)
+	}
+	return c.ex.OpenLong(fraction)
+}
+
+// Flatten closes the open position with reason; it returns nil when already flat.
+func (c *StrategyContext) Flatten(reason string) (*Order, error) {
+	if c.ex.position == 0 {
+		return nil, nil
+	}
+	return c.ex.CloseDeal(reason)
+}
+
+func (c *StrategyContext) LimitLong(price float64, fraction float64) (int64, error) {
+	return c.ex.LongLimit(price, fraction)
+}
+
+func (c *StrategyContext) LimitShort(price float64, fraction float64) (int64, error) {
+	return c.ex.ShortLimit(price, fraction)
+}
+
+func (c *StrategyContext) LimitClose(price float64, reason string) (int64, error) {
+	return c.ex.CloseLimit(price, reason, "")
+}
+
+func (c *StrategyContext) Cancel(id int64) bool {
+	return c.ex.CancelLimit(id)
+}
@@ -0,0 +1,100 @@
+package emul
+
+// StreakStats describes runs of consecutive wins and losses in a trade log. The distribution
+// maps a streak length to how many times a streak of exactly that length occurred.
+// Break-even trades neither extend nor break a streak.
+type StreakStats struct {
+	MaxWinStreak  int
+	MaxLossStreak int
+	AvgWinStreak  float64
+	AvgLossStreak float64
+	WinStreaks    map[int]int
+	LossStreaks   map[int]int
+}
+
+func AnalyzeStreaks(trades []Trade) StreakStats {
+	s := StreakStats{
+		WinStreaks:  make(map[int]int),
+		LossStreaks: make(map[int]int),
+	}
+	run, win := 0, false
+	flush := func() {
+		if run == 0 {
+			return
+		}
+		if win {
+			s.WinStreaks[run]++
+			s.MaxWinStreak = max(s.MaxWinStreak, run)
+		} else {
+			s.LossStreaks[run]++
+			s.MaxLossStreak = max(s.MaxLossStreak, run)
+		}
+	}
+	for _, t := range trades {
+		if t.PnL == 0 {
+			continue
+		}
+		isWin := t.PnL > 0
+		if run > 0 && isWin != win {
+			flush()
+			run = 0
+		}
+		win = isWin
+		run++
+	}
+	flush()
+	s.AvgWinStreak = averageStreak(s.WinStreaks)
+	s.AvgLossStreak = averageStreak(s.LossStreaks)
+	return s
+}
+
+func averageStreak(dist map[int]int) float64 {
+	total, count := 0, 0
+	for length, n := range dist {
+		total += length * n
+		count += n
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(total) / float64(count)
+}
+
+// LossStreakProbability returns the probability of seeing at least one run of n or more
+// consecutive losses within trades independent trades at the given win rate.
+func LossStreakProbability(winRate float64, n int, trades int) float64 {
+	if n <= 0 {
+		return 1
+	}
+	if trades < n || winRate >= 1 {
+		return 0
+	}
+	if winRate <= 0 {
+		return 1
+	}
+	loss := 1 - winRate
+	// state[k] is the probability of ending with exactly k trailing losses and no run of n so far.
+	state := make([]float64, n)
+	next := make([]float64, n)
+	state[0] = 1
+	for i := 0; i < trades; i++ {
+		for k := range next {
+			next[k] = 0
+		}
+		for k, p := range state {
+			if p == 0 {
+				continue
+			}
+			next[0] += p * winRate
+			if k+1 < n {
+				next[k+1] += p * loss
+			}
+		}
+		state, next = next, state
+	}
+	survive := 0.0
+	for _, p := range state {
+		survive += p
+	}
+	return 1 - survive
+}
@@ -0,0 +1,84 @@
+package emul
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// StreamEvent is one replayed bar with the orders filled and the limits dropped on it. The
+// replay waits for Done before moving to the next bar, so the exchange may be used freely by any
+// goroutine between receiving the event and calling Done.
+type StreamEvent struct {
+	Bar        OHLCBar
+	Executed   []Order
+	Rejections []Rejection
+	done       chan struct{}
+	once       sync.Once
+}
+
+// Done releases the replay to the next bar. It is safe to call more than once.
+func (ev *StreamEvent) Done() {
+	ev.once.Do(func() { close(ev.done) })
+}
+
+// Stream is a replay running in its own goroutine; see Emulator.Stream.
+type Stream struct {
+	Events <-chan *StreamEvent
+	mu     sync.Mutex
+	err    error
+}
+
+// Err returns the error that ended the stream once Events is closed: nil at the end of the
+// bars, the context's error when it was canceled.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Stream replays the remaining bars on a goroutine and delivers them on Events, one at a time:
+// the next bar is only replayed after the previous event's Done, which gives goroutine
+// pipelines (a signal worker feeding an execution worker) backpressure and keeps the replay
+// deterministic. Events is closed at the end of the bars, on an error or when ctx is canceled.
+func (e *Emulator) Stream(ctx context.Context) *Stream {
+	events := make(chan *StreamEvent)
+	s := &Stream{Events: events}
+	go func() {
+		defer close(events)
+		for {
+			if err := ctx.Err(); err != nil {
+				s.fail(err)
+				return
+			}
+			bar, executed, rejections, err := e.NextWithRejections()
+			if errors.Is(err, ErrNoMoreBars) {
+				return
+			}
+			if err != nil {
+				s.fail(err)
+				return
+			}
+			ev := &StreamEvent{Bar: bar, Executed: executed, Rejections: rejections, done: make(chan struct{})}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				s.fail(ctx.Err())
+				return
+			}
+			select {
+			case <-ev.done:
+			case <-ctx.Done():
+				s.fail(ctx.Err())
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *Stream) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
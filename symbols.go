@@ -0,0 +1,166 @@
+package emul
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SymbolSpec holds per-symbol venue parameters. TickSize rounds execution prices (buys up,
+// sells down), LotSize rounds order quantities down and MinQty rejects smaller orders.
+// Fees override the cost profile named by Costs. MaxLeverage caps the leverage accepted by
+// SetLiquidationConfig; FundingRate and FundingInterval (in hours) are the defaults of a perp leg
+// enabled without its own funding terms (see EnablePerp). PriceDecimals and
+// QuoteDecimals, when set, enable rounding to the venue's precision (see Precision). StakingAPR
+// accrues a yield in USD on long positions (see StakingConfig).
+type SymbolSpec struct {
+	Symbol          string   `json:"symbol"`
+	TickSize        float64  `json:"tick_size"`
+	LotSize         float64  `json:"lot_size"`
+	MinQty          float64  `json:"min_qty"`
+	Costs           string   `json:"costs"`
+	MakerFee        *float64 `json:"maker_fee"`
+	TakerFee        *float64 `json:"taker_fee"`
+	MaxLeverage     float64  `json:"max_leverage"`
+	FundingInterval int      `json:"funding_interval_hours"`
+	FundingRate     float64  `json:"funding_rate"`
+	PriceDecimals   *int     `json:"price_decimals"`
+	QuoteDecimals   *int     `json:"quote_decimals"`
+	StakingAPR      float64  `json:"staking_apr"`
+}
+
+type SymbolRegistry struct {
+	specs map[string]SymbolSpec
+}
+
+// LoadSymbolRegistry reads a JSON file holding either a list of specs or {"symbols": [...]}.
+func LoadSymbolRegistry(path string) (*SymbolRegistry, error) {
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	var specs []SymbolSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		var wrapped struct {
+			Symbols []SymbolSpec `json:"symbols"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		specs = wrapped.Symbols
+	}
+	return NewSymbolRegistry(specs...)
+}
+
+func NewSymbolRegistry(specs ...SymbolSpec) (*SymbolRegistry, error) {
+	r := &SymbolRegistry{specs: make(map[string]SymbolSpec, len(specs))}
+	for _, spec := range specs {
+		key := strings.ToLower(strings.TrimSpace(spec.Symbol))
+		if key == "" {
+			return nil, fmt.Errorf("symbol spec without symbol")
+		}
+		if spec.TickSize < 0 || spec.LotSize < 0 || spec.MinQty < 0 || spec.StakingAPR < 0 {
+			return nil, fmt.Errorf("symbol %s: sizes and staking apr must not be negative", spec.Symbol)
+		}
+		if spec.MaxLeverage != 0 && spec.MaxLeverage < 1 {
+			return nil, fmt.Errorf("symbol %s: max leverage must be at least 1", spec.Symbol)
+		}
+		if spec.FundingInterval < 0 {
+			return nil, fmt.Errorf("symbol %s: funding interval must not be negative", spec.Symbol)
+		}
+		for _, d := range []*int{spec.PriceDecimals, spec.QuoteDecimals} {
+			if d != nil && (*d < 0 || *d > 12) {
+				return nil, fmt.Errorf("symbol %s: precision must be within [0, 12] decimals", spec.Symbol)
+			}
+		}
+		if _, ok := r.specs[key]; ok {
+			return nil, fmt.Errorf("duplicate symbol %s", spec.Symbol)
+		}
+		if spec.Costs != "" {
+			if _, err := CostProfileByName(spec.Costs); err != nil {
+				return nil, fmt.Errorf("symbol %s: %w", spec.Symbol, err)
+			}
+		}
+		spec.Symbol = key
+		r.specs[key] = spec
+	}
+	return r, nil
+}
+
+func (r *SymbolRegistry) Lookup(symbol string) (SymbolSpec, bool) {
+	spec, ok := r.specs[strings.ToLower(strings.TrimSpace(symbol))]
+	return spec, ok
+}
+
+func (r *SymbolRegistry) Symbols() []string {
+	out := make([]string, 0, len(r.specs))
+	for k := range r.specs {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CostProfile resolves the spec's fees on top of base (or of the named Costs profile).
+func (spec SymbolSpec) CostProfile(base CostProfile) CostProfile {
+	costs := base
+	if spec.Costs != "" {
+		if p, err := CostProfileByName(spec.Costs); err == nil {
+			costs = p
+		}
+	}
+	if spec.MakerFee != nil {
+		costs.MakerFee = *spec.MakerFee
+	}
+	if spec.TakerFee != nil {
+		costs.TakerFee = *spec.TakerFee
+	}
+	return costs
+}
+
+// SetSymbol applies the spec's symbol name, tick size, lot rules, precision, leverage cap and perp
+// funding defaults; fees are set via the cost profile. A liquidation leverage above the new cap
+// is lowered to it.
+func (e *Exchange) SetSymbol(spec SymbolSpec) {
+	e.symbol = spec.Symbol
+	e.tickSize = spec.TickSize
+	e.lotSize = spec.LotSize
+	e.minQty = spec.MinQty
+	e.maxLeverage = spec.MaxLeverage
+	if e.maxLeverage > 0 && e.liquidation.Leverage > e.maxLeverage {
+		e.liquidation.Leverage = e.maxLeverage
+	}
+	e.fundingRate = spec.FundingRate
+	e.fundingPeriod = time.Duration(spec.FundingInterval) * time.Hour
+	if spec.PriceDecimals != nil || spec.QuoteDecimals != nil {
+		e.precision = Precision{PriceDecimals: copyDecimals(spec.PriceDecimals), QuoteDecimals: copyDecimals(spec.QuoteDecimals)}
+	}
+	if spec.StakingAPR > 0 {
+		e.staking = StakingConfig{APR: spec.StakingAPR}
+	}
+}
+
+func (e *Exchange) applyTickSize(side OrderSide, price float64) float64 {
+	if e.tickSize <= 0 || price <= 0 {
+		return price
+	}
+	steps := price / e.tickSize
+	switch side {
+	case SideBuy:
+		steps = math.Ceil(steps - 1e-9)
+	default:
+		steps = math.Floor(steps + 1e-9)
+	}
+	if steps < 1 {
+		steps = 1
+	}
+	return steps * e.tickSize
+}
+
+func roundDownToStep(qty float64, step float64) float64 {
+	return math.Floor(qty/step+1e-9) * step
+}
@@ -0,0 +1,529 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// BlockBootstrap synthesizes an alternative history of length bars by stitching randomly chosen
+// contiguous blocks of blockSize source bars (moving-block bootstrap). Bars are re-based on the
+// previous synthetic close so the path stays continuous while short-range autocorrelation inside
+// each block is preserved. The same seed always yields the same path.
+func BlockBootstrap(bars []OHLCBar, blockSize int, length int, seed uint64) ([]OHLCBar, error) {
+	n := len(bars)
+	if n < 2 {
+		return nil, fmt.Errorf("need at least 2 bars, got %d", n)
+	}
+	if blockSize <= 0 || blockSize > n {
+		return nil, fmt.Errorf("block size must be in [1, %d]", n)
+	}
+	if length <= 0 {
+		length = n
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	times := syntheticTimes(bars, length)
+	out := make([]OHLCBar, 0, length)
+	prevClose := bars[0].Open
+	for len(out) < length {
+		start := rng.IntN(n - blockSize + 1)
+		for j := start; j < start+blockSize && len(out) < length; j++ {
+			ref := bars[j].Open
+			if j > 0 {
+				ref = bars[j-1].Close
+			}
+			bar := rebaseBar(bars[j], ref, prevClose)
+			bar.Time = times[len(out)]
+			out = append(out, bar)
+			prevClose = bar.Close
+		}
+	}
+	return out, nil
+}
+
+// BlockBootstrapPaths draws count independent bootstrap paths for Monte Carlo backtests;
+// path i uses seed+i.
+func BlockBootstrapPaths(bars []OHLCBar, blockSize int, length int, count int, seed uint64) ([][]OHLCBar, error) {
+	paths := make([][]OHLCBar, 0, count)
+	for i := 0; i < count; i++ {
+		path, err := BlockBootstrap(bars, blockSize, length, seed+uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func rebaseBar(bar OHLCBar, ref float64, base float64) OHLCBar {
+	if ref <= 0 {
+		return bar
+	}
+	k := base / ref
+	// Volume is a quantity traded, not a price: it is carried over unscaled.
+	return OHLCBar{
+		Open:    bar.Open * k,
+		High:    bar.High * k,
+		Low:     bar.Low * k,
+		Close:   bar.Close * k,
+		Average: bar.Average * k,
+		Volume:  bar.Volume,
+	}
+}
+
+// syntheticTimes keeps the source calendar where possible and extends it with the first bar spacing.
+func syntheticTimes(bars []OHLCBar, length int) []time.Time {
+	times := make([]time.Time, length)
+	if len(bars) < 2 || bars[0].Time.IsZero() {
+		return times
+	}
+	step := bars[1].Time.Sub(bars[0].Time)
+	for i := range times {
+		if i < len(bars) {
+			times[i] = bars[i].Time
+			continue
+		}
+		times[i] = times[i-1].Add(step)
+	}
+	return times
+}
+
+// GARCHParams describes per-bar log returns r = Mu + e, e ~ N(0, s2),
+// s2 = Omega + Alpha*e_prev^2 + Beta*s2_prev.
+type GARCHParams struct {
+	Mu    float64
+	Omega float64
+	Alpha float64
+	Beta  float64
+}
+
+// JumpDiffusionParams describes Merton-style per-bar log returns: a Gaussian diffusion plus
+// Poisson jumps (Lambda jumps per bar on average) with normally distributed sizes.
+type JumpDiffusionParams struct {
+	Mu       float64
+	Sigma    float64
+	Lambda   float64
+	JumpMean float64
+	JumpStd  float64
+}
+
+// CalibrateGARCH fits GARCH(1,1) to close-to-close log returns by maximizing the Gaussian
+// likelihood over a grid of (Alpha, Beta) with variance targeting for Omega.
+func CalibrateGARCH(bars []OHLCBar) (GARCHParams, error) {
+	rets := logReturns(bars)
+	if len(rets) < 10 {
+		return GARCHParams{}, fmt.Errorf("need at least 10 returns, got %d", len(rets))
+	}
+	mu, variance := meanVariance(rets)
+	if variance <= 0 {
+		return GARCHParams{}, fmt.Errorf("returns have zero variance")
+	}
+	best := GARCHParams{Mu: mu, Omega: variance}
+	bestLL := garchLogLikelihood(rets, best, variance)
+	for alpha := 0.01; alpha < 0.5; alpha += 0.01 {
+		for beta := 0.0; alpha+beta < 0.999; beta += 0.01 {
+			p := GARCHParams{Mu: mu, Omega: variance * (1 - alpha - beta), Alpha: alpha, Beta: beta}
+			if ll := garchLogLikelihood(rets, p, variance); ll > bestLL {
+				best, bestLL = p, ll
+			}
+		}
+	}
+	return best, nil
+}
+
+func garchLogLikelihood(rets []float64, p GARCHParams, s2 float64) float64 {
+	ll := 0.0
+	for _, r := range rets {
+		if s2 <= 0 {
+			return math.Inf(-1)
+		}
+		e := r - p.Mu
+		ll += -0.5 * (math.Log(2*math.Pi*s2) + e*e/s2)
+		s2 = p.Omega + p.Alpha*e*e + p.Beta*s2
+	}
+	return ll
+}
+
+// CalibrateJumpDiffusion separates jumps from diffusion with a 3-sigma threshold on a robust
+// (median absolute deviation) volatility estimate.
+func CalibrateJumpDiffusion(bars []OHLCBar) (JumpDiffusionParams, error) {
+	rets := logReturns(bars)
+	if len(rets) < 10 {
+		return JumpDiffusionParams{}, fmt.Errorf("need at least 10 returns, got %d", len(rets))
+	}
+	med := median(rets)
+	dev := make([]float64, len(rets))
+	for i, r := range rets {
+		dev[i] = math.Abs(r - med)
+	}
+	robust := 1.4826 * median(dev)
+	if robust <= 0 {
+		return JumpDiffusionParams{}, fmt.Errorf("returns have zero dispersion")
+	}
+	normal := make([]float64, 0, len(rets))
+	jumps := make([]float64, 0)
+	for _, r := range rets {
+		if math.Abs(r-med) > 3*robust {
+			jumps = append(jumps, r-med)
+			continue
+		}
+		normal = append(normal, r)
+	}
+	mu, variance := meanVariance(normal)
+	p := JumpDiffusionParams{
+		Mu:     mu,
+		Sigma:  math.Sqrt(variance),
+		Lambda: float64(len(jumps)) / float64(len(rets)),
+	}
+	if len(jumps) > 0 {
+		jm, jv := meanVariance(jumps)
+		p.JumpMean = jm
+		p.JumpStd = math.Sqrt(jv)
+	}
+	return p, nil
+}
+
+// GenerateGARCH simulates length bars starting at startPrice.
+func GenerateGARCH(p GARCHParams, startPrice float64, length int, seed uint64) ([]OHLCBar, error) {
+	if startPrice <= 0 || length <= 0 {
+		return nil, fmt.Errorf("start price and length must be positive")
+	}
+	if p.Alpha < 0 || p.Beta < 0 || p.Alpha+p.Beta >= 1 || p.Omega <= 0 {
+		return nil, fmt.Errorf("garch params are not stationary")
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	rets := make([]float64, length)
+	sigmas := make([]float64, length)
+	s2 := p.Omega / (1 - p.Alpha - p.Beta)
+	for i := range rets {
+		e := math.Sqrt(s2) * rng.NormFloat64()
+		rets[i] = p.Mu + e
+		sigmas[i] = math.Sqrt(s2)
+		s2 = p.Omega + p.Alpha*e*e + p.Beta*s2
+	}
+	return barsFromReturns(rets, sigmas, startPrice, rng), nil
+}
+
+// GenerateJumpDiffusion simulates length bars starting at startPrice.
+func GenerateJumpDiffusion(p JumpDiffusionParams, startPrice float64, length int, seed uint64) ([]OHLCBar, error) {
+	if startPrice <= 0 || length <= 0 {
+		return nil, fmt.Errorf("start price and length must be positive")
+	}
+	if p.Sigma < 0 || p.Lambda < 0 || p.JumpStd < 0 {
+		return nil, fmt.Errorf("jump diffusion params must be non-negative")
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	rets := make([]float64, length)
+	sigmas := make([]float64, length)
+	for i := range rets {
+		r := p.Mu + p.Sigma*rng.NormFloat64()
+		for k := poisson(rng, p.Lambda); k > 0; k-- {
+			r += p.JumpMean + p.JumpStd*rng.NormFloat64()
+		}
+		rets[i] = r
+		sigmas[i] = p.Sigma
+	}
+	return barsFromReturns(rets, sigmas, startPrice, rng), nil
+}
+
+// SyntheticGARCH calibrates on bars and generates a path of the same calendar, starting at the first open.
+func SyntheticGARCH(bars []OHLCBar, length int, seed uint64) ([]OHLCBar, error) {
+	p, err := CalibrateGARCH(bars)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		length = len(bars)
+	}
+	out, err := GenerateGARCH(p, bars[0].Open, length, seed)
+	if err != nil {
+		return nil, err
+	}
+	applyTimes(out, syntheticTimes(bars, length))
+	return out, nil
+}
+
+// SyntheticJumpDiffusion calibrates on bars and generates a path of the same calendar, starting at the first open.
+func SyntheticJumpDiffusion(bars []OHLCBar, length int, seed uint64) ([]OHLCBar, error) {
+	p, err := CalibrateJumpDiffusion(bars)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		length = len(bars)
+	}
+	out, err := GenerateJumpDiffusion(p, bars[0].Open, length, seed)
+	if err != nil {
+		return nil, err
+	}
+	applyTimes(out, syntheticTimes(bars, length))
+	return out, nil
+}
+
+// barsFromReturns turns close-to-close log returns into OHLC bars; wicks extend beyond the body
+// by a half-normal draw scaled with the bar's volatility.
+func barsFromReturns(rets []float64, sigmas []float64, startPrice float64, rng *rand.Rand) []OHLCBar {
+	out := make([]OHLCBar, len(rets))
+	prev := startPrice
+	for i, r := range rets {
+		open := prev
+		close := open * math.Exp(r)
+		high := math.Max(open, close) * math.Exp(0.5*sigmas[i]*math.Abs(rng.NormFloat64()))
+		low := math.Min(open, close) * math.Exp(-0.5*sigmas[i]*math.Abs(rng.NormFloat64()))
+		out[i] = OHLCBar{
+			Open:    open,
+			High:    high,
+			Low:     low,
+			Close:   close,
+			Average: (open + high + low + close) / 4,
+		}
+		prev = close
+	}
+	return out
+}
+
+func applyTimes(bars []OHLCBar, times []time.Time) {
+	for i := range bars {
+		if i < len(times) {
+			bars[i].Time = times[i]
+		}
+	}
+}
+
+func logReturns(bars []OHLCBar) []float64 {
+	rets := make([]float64, 0, len(bars))
+	for i := 1; i < len(bars); i++ {
+		if bars[i-1].Close <= 0 || bars[i].Close <= 0 {
+			continue
+		}
+		rets = append(rets, math.Log(bars[i].Close/bars[i-1].Close))
+	}
+	return rets
+}
+
+func meanVariance(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func poisson(rng *rand.Rand, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	limit := math.Exp(-lambda)
+	k := 0
+	p := rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+// EstimateCorrelation returns the correlation matrix of close log returns across several coins.
+// Series with timestamps are aligned on common bar times; otherwise the trailing common length is used.
+func EstimateCorrelation(series ...[]OHLCBar) ([][]float64, error) {
+	rets, err := alignedReturns(series)
+	if err != nil {
+		return nil, err
+	}
+	k := len(rets)
+	means := make([]float64, k)
+	stds := make([]float64, k)
+	for i, r := range rets {
+		mean, variance := meanVariance(r)
+		if variance <= 0 {
+			return nil, fmt.Errorf("series %d has zero variance", i)
+		}
+		means[i] = mean
+		stds[i] = math.Sqrt(variance)
+	}
+	n := len(rets[0])
+	corr := make([][]float64, k)
+	for i := range corr {
+		corr[i] = make([]float64, k)
+		for j := range corr[i] {
+			if i == j {
+				corr[i][j] = 1
+				continue
+			}
+			cov := 0.0
+			for t := 0; t < n; t++ {
+				cov += (rets[i][t] - means[i]) * (rets[j][t] - means[j])
+			}
+			corr[i][j] = cov / float64(n-1) / (stds[i] * stds[j])
+		}
+	}
+	return corr, nil
+}
+
+// GenerateCorrelated simulates joint paths: each asset keeps its own drift and volatility
+// estimated from its series, and shocks are correlated via the Cholesky factor of corr
+// (pass nil to estimate it from the same series).
+func GenerateCorrelated(series [][]OHLCBar, corr [][]float64, length int, seed uint64) ([][]OHLCBar, error) {
+	rets, err := alignedReturns(series)
+	if err != nil {
+		return nil, err
+	}
+	if corr == nil {
+		if corr, err = EstimateCorrelation(series...); err != nil {
+			return nil, err
+		}
+	}
+	k := len(series)
+	if len(corr) != k {
+		return nil, fmt.Errorf("correlation matrix must be %dx%d", k, k)
+	}
+	chol, err := cholesky(corr)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		length = len(rets[0]) + 1
+	}
+	mus := make([]float64, k)
+	sigmas := make([]float64, k)
+	for i, r := range rets {
+		mean, variance := meanVariance(r)
+		mus[i] = mean
+		sigmas[i] = math.Sqrt(variance)
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	paths := make([][]float64, k)
+	for i := range paths {
+		paths[i] = make([]float64, length)
+	}
+	z := make([]float64, k)
+	for t := 0; t < length; t++ {
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		for i := 0; i < k; i++ {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += chol[i][j] * z[j]
+			}
+			paths[i][t] = mus[i] + sigmas[i]*shock
+		}
+	}
+	out := make([][]OHLCBar, k)
+	for i := range out {
+		vols := make([]float64, length)
+		for t := range vols {
+			vols[t] = sigmas[i]
+		}
+		out[i] = barsFromReturns(paths[i], vols, series[i][0].Open, rng)
+		applyTimes(out[i], syntheticTimes(series[i], length))
+	}
+	return out, nil
+}
+
+func alignedReturns(series [][]OHLCBar) ([][]float64, error) {
+	if len(series) < 2 {
+		return nil, fmt.Errorf("need at least 2 series, got %d", len(series))
+	}
+	withTime := true
+	for _, s := range series {
+		if len(s) == 0 || s[0].Time.IsZero() {
+			withTime = false
+			break
+		}
+	}
+	aligned := make([][]OHLCBar, len(series))
+	if withTime {
+		common := make(map[int64]int)
+		for _, s := range series {
+			for _, bar := range s {
+				common[bar.Time.Unix()]++
+			}
+		}
+		for i, s := range series {
+			for _, bar := range s {
+				if common[bar.Time.Unix()] == len(series) {
+					aligned[i] = append(aligned[i], bar)
+				}
+			}
+		}
+	} else {
+		n := len(series[0])
+		for _, s := range series {
+			n = min(n, len(s))
+		}
+		for i, s := range series {
+			aligned[i] = s[len(s)-n:]
+		}
+	}
+	rets := make([][]float64, len(aligned))
+	for i, s := range aligned {
+		rets[i] = make([]float64, 0, len(s))
+		for t := 1; t < len(s); t++ {
+			r := 0.0
+			if s[t-1].Close > 0 && s[t].Close > 0 {
+				r = math.Log(s[t].Close / s[t-1].Close)
+			}
+			rets[i] = append(rets[i], r)
+		}
+		if len(rets[i]) < 2 || len(rets[i]) != len(rets[0]) {
+			return nil, fmt.Errorf("not enough overlapping bars")
+		}
+	}
+	return rets, nil
+}
+
+func cholesky(m [][]float64) ([][]float64, error) {
+	n := len(m)
+	l := make([][]float64, n)
+	for i := range l {
+		if len(m[i]) != n {
+			return nil, fmt.Errorf("matrix must be square")
+		}
+		l[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := m[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("correlation matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(sum)
+				continue
+			}
+			l[i][j] = sum / l[j][j]
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,77 @@
+package emul
+
+import (
+	"math"
+	"sort"
+)
+
+// SetTag labels every order placed from now on, including limits that fill later, with tag
+// (e.g. the strategy or signal name); an empty tag clears it.
+func (e *Exchange) SetTag(tag string) {
+	e.tag = tag
+}
+
+func (e *Exchange) Tag() string {
+	return e.tag
+}
+
+// WithTag labels the orders s places with tag, so several strategies can share one account
+// and be told apart by AttributeByTag. The previous tag is restored after each bar.
+func WithTag(s Strategy, tag string) Strategy {
+	return StrategyFunc(func(ex *Exchange, bar OHLCBar, executed []Order) error {
+		saved := ex.tag
+		ex.tag = tag
+		defer func() { ex.tag = saved }()
+		return s.OnBar(ex, bar, executed)
+	})
+}
+
+// TagAttribution is the share of a run's closed trades that belongs to one tag. A trade is
+// attributed to the tag of its entry, even when another tag closed it. Turnover is the traded
+// notional of entries and exits; ExposureBars counts the bars positions were held.
+type TagAttribution struct {
+	Tag          string
+	Trades       int
+	Wins         int
+	PnL          float64
+	Fees         float64
+	Turnover     float64
+	ExposureBars int64
+	// Share is the tag's PnL as a fraction of the gross PnL, the sum of every tag's absolute PnL,
+	// so it stays within [-1, 1] when winning and losing tags offset each other.
+	Share float64
+}
+
+// AttributeByTag splits the closed trades of orders by tag, sorted by tag. Untagged trades
+// are reported under the empty tag.
+func AttributeByTag(orders []Order) []TagAttribution {
+	byTag := make(map[string]*TagAttribution)
+	for _, t := range PairTrades(orders) {
+		a, ok := byTag[t.Entry.Tag]
+		if !ok {
+			a = &TagAttribution{Tag: t.Entry.Tag}
+			byTag[t.Entry.Tag] = a
+		}
+		a.Trades++
+		if t.PnL > 0 {
+			a.Wins++
+		}
+		a.PnL += t.PnL
+		a.Fees += t.Fees
+		a.Turnover += t.Qty * (t.EntryPrice + t.ExitPrice)
+		a.ExposureBars += t.ExitTick - t.EntryTick
+	}
+	gross := 0.0
+	for _, a := range byTag {
+		gross += math.Abs(a.PnL)
+	}
+	out := make([]TagAttribution, 0, len(byTag))
+	for _, a := range byTag {
+		if gross != 0 {
+			a.Share = a.PnL / gross
+		}
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out
+}
@@ -0,0 +1,148 @@
+package emul
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CSVTail follows a growing CSV file (e.g. the current year file updated daily) and returns
+// only rows appended since the previous Poll. A trailing line without a newline is held back
+// until it is complete. Lines are scanned with the same limits as the loaders (see
+// CSVReaderOptions): an over-long line fails the Poll, or is skipped with SkipLongLines.
+type CSVTail struct {
+	mu       sync.Mutex
+	path     string
+	opts     CSVReaderOptions
+	offset   int64
+	partial  []byte
+	skipping bool
+	rows     *csvRowParser
+}
+
+// NewCSVTail starts reading at the beginning of path.
+func NewCSVTail(path string) *CSVTail {
+	return NewCSVTailWithOptions(path, CSVReaderOptions{})
+}
+
+// NewCSVTailWithOptions is NewCSVTail with explicit reader options.
+func NewCSVTailWithOptions(path string, opts CSVReaderOptions) *CSVTail {
+	return &CSVTail{path: strings.TrimSpace(path), opts: opts, rows: newCSVRowParser(opts)}
+}
+
+// LoadBarsAndTail loads every complete row of path and returns a tail positioned right after them.
+func LoadBarsAndTail(csvPath string) ([]OHLCBar, *CSVTail, error) {
+	tail := NewCSVTail(csvPath)
+	bars, err := tail.Poll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bars) == 0 {
+		return nil, nil, fmt.Errorf("%s: %w", csvPath, errNoDataRows)
+	}
+	return bars, tail, nil
+}
+
+// Poll returns bars for rows appended since the last call. A file that shrank (rewritten or
+// truncated) is reported as an error rather than silently replayed twice.
+func (t *CSVTail) Poll() ([]OHLCBar, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < t.offset {
+		return nil, fmt.Errorf("csv file shrank from %d to %d bytes: %s", t.offset, info.Size(), t.path)
+	}
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	chunk, err := io.ReadAll(io.LimitReader(file, info.Size()-t.offset))
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(chunk))
+	if t.skipping {
+		// The rest of a line already found too long.
+		i := bytes.IndexByte(chunk, '\n')
+		if i < 0 {
+			return nil, nil
+		}
+		t.skipping, chunk = false, chunk[i+1:]
+	}
+	data := append(t.partial, chunk...)
+	last := bytes.LastIndexByte(data, '\n')
+	t.partial = append([]byte(nil), data[last+1:]...)
+	if maxLine := t.opts.maxLineBytes(); len(t.partial) > maxLine {
+		if !t.opts.SkipLongLines {
+			return nil, fmt.Errorf("%s: %w", t.path, bufio.ErrTooLong)
+		}
+		t.opts.warn(t.path, len(t.partial))
+		t.partial, t.skipping = nil, true
+	}
+	if last < 0 {
+		return nil, nil
+	}
+	scanner := newCSVScanner(bytes.NewReader(data[:last+1]), t.path, t.opts)
+	bars := make([]OHLCBar, 0)
+	for scanner.Scan() {
+		if bar, ok := t.rows.parse(scanner.Text(), nil); ok {
+			bars = append(bars, bar)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return bars, nil
+}
+
+// AppendBars extends the replay with newly arrived bars (e.g. from CSVTail.Poll). It is not
+// allowed on emulators backed by a shared BarSet. With a perp leg the perp series must be
+// extended first (see AppendPerpBars) so every spot bar has its perp bar.
+func (e *Emulator) AppendBars(bars ...OHLCBar) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.set != nil {
+		return fmt.Errorf("cannot append to a shared bar set")
+	}
+	if p := e.ex.perp; p != nil && len(e.bars)+len(bars) > len(p.cfg.Bars) {
+		return fmt.Errorf("perp series has %d bars, spot would have %d", len(p.cfg.Bars), len(e.bars)+len(bars))
+	}
+	e.bars = appendOwned(e.bars, &e.ownsBars, bars)
+	return nil
+}
+
+// AppendPerpBars extends the perp series of the perp leg ahead of the matching AppendBars.
+func (e *Emulator) AppendPerpBars(bars ...OHLCBar) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p := e.ex.perp
+	if p == nil {
+		return ErrPerpDisabled
+	}
+	p.cfg.Bars = appendOwned(p.cfg.Bars, &p.ownsBars, bars)
+	return nil
+}
+
+// appendOwned appends more to bars. The caller's slice may back other emulators (see
+// NewEmulator), so the first append copies it and sets owned; later appends grow the copy.
+func appendOwned(bars []OHLCBar, owned *bool, more []OHLCBar) []OHLCBar {
+	if !*owned {
+		bars = append(make([]OHLCBar, 0, 2*(len(bars)+len(more))), bars...)
+		*owned = true
+	}
+	return append(bars, more...)
+}
@@ -0,0 +1,186 @@
+package emul
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+type LotMethod uint8
+
+const (
+	LotsFIFO LotMethod = iota
+	LotsLIFO
+	// LotsHIFO disposes of the lot with the highest cost first (the lowest-priced short first),
+	// which minimizes the realized gain.
+	LotsHIFO
+)
+
+func (m LotMethod) String() string {
+	switch m {
+	case LotsLIFO:
+		return "lifo"
+	case LotsHIFO:
+		return "hifo"
+	}
+	return "fifo"
+}
+
+// longTermHolding is the holding period after which a gain counts as long-term.
+const longTermHolding = 365 * 24 * time.Hour
+
+// TaxLot is an open acquisition. Price includes the entry fee per unit; for a short lot it is
+// the sale price net of the fee.
+type TaxLot struct {
+	Side    OrderSide
+	Qty     float64
+	Price   float64
+	OrderID int64
+	Tick    int64
+	Time    time.Time
+}
+
+// Disposal is the part of one lot closed by one fill.
+type Disposal struct {
+	Side      OrderSide
+	Qty       float64
+	Proceeds  float64
+	Cost      float64
+	Gain      float64
+	OpenTime  time.Time
+	CloseTime time.Time
+	LongTerm  bool
+}
+
+// YearGains sums disposals by the calendar year (UTC) they were closed in.
+type YearGains struct {
+	Year      int
+	Proceeds  float64
+	Cost      float64
+	Gain      float64
+	ShortTerm float64
+	LongTerm  float64
+	Disposals int
+}
+
+type TaxReport struct {
+	Method    LotMethod
+	Disposals []Disposal
+	Years     []YearGains
+	OpenLots  []TaxLot
+}
+
+// ComputeTaxLots matches fills into lots with the given method and reports realized gains per
+// calendar year. Bars date the order ticks (tick k is bars[k-1]); fees are added to the cost of
+// acquisitions and deducted from proceeds of disposals.
+func ComputeTaxLots(orders []Order, bars []OHLCBar, method LotMethod) (TaxReport, error) {
+	if method > LotsHIFO {
+		return TaxReport{}, fmt.Errorf("unknown lot method %d", method)
+	}
+	report := TaxReport{Method: method}
+	var lots []TaxLot
+	years := make(map[int]*YearGains)
+	position := 0.0
+	for _, o := range orders {
+		delta := o.PositionAfter - position
+		position = o.PositionAfter
+		if delta == 0 || o.Qty <= 0 {
+			continue
+		}
+		at, err := barTime(bars, o.Tick)
+		if err != nil {
+			return TaxReport{}, err
+		}
+		qty := math.Abs(delta)
+		feePerUnit := o.Fee / o.Qty
+		if len(lots) > 0 && lots[0].Side == o.Side {
+			lots = append(lots, newTaxLot(o, qty, feePerUnit, at))
+			continue
+		}
+		sortLots(lots, method)
+		for qty > invariantEpsilon && len(lots) > 0 {
+			lot := &lots[0]
+			take := math.Min(qty, lot.Qty)
+			d := Disposal{Side: lot.Side, Qty: take, OpenTime: lot.Time, CloseTime: at}
+			if lot.Side == SideBuy {
+				d.Proceeds = take * (o.Price - feePerUnit)
+				d.Cost = take * lot.Price
+			} else {
+				d.Proceeds = take * lot.Price
+				d.Cost = take * (o.Price + feePerUnit)
+			}
+			d.Gain = d.Proceeds - d.Cost
+			d.LongTerm = at.Sub(lot.Time) > longTermHolding
+			report.Disposals = append(report.Disposals, d)
+			addYearGains(years, d)
+			lot.Qty -= take
+			qty -= take
+			if lot.Qty <= invariantEpsilon {
+				lots = lots[1:]
+			}
+		}
+		if qty > invariantEpsilon {
+			// The fill flipped the position: the rest opens a lot on the other side.
+			lots = append(lots, newTaxLot(o, qty, feePerUnit, at))
+		}
+	}
+	report.OpenLots = append([]TaxLot(nil), lots...)
+	for _, y := range years {
+		report.Years = append(report.Years, *y)
+	}
+	sort.Slice(report.Years, func(i, j int) bool { return report.Years[i].Year < report.Years[j].Year })
+	return report, nil
+}
+
+func newTaxLot(o Order, qty float64, feePerUnit float64, at time.Time) TaxLot {
+	price := o.Price + feePerUnit
+	if o.Side == SideSell {
+		price = o.Price - feePerUnit
+	}
+	return TaxLot{Side: o.Side, Qty: qty, Price: price, OrderID: o.ID, Tick: o.Tick, Time: at}
+}
+
+// sortLots puts the next lot to dispose of first; lots are kept in acquisition order otherwise.
+func sortLots(lots []TaxLot, method LotMethod) {
+	switch method {
+	case LotsFIFO:
+		sort.SliceStable(lots, func(i, j int) bool { return lots[i].Tick < lots[j].Tick })
+	case LotsLIFO:
+		sort.SliceStable(lots, func(i, j int) bool { return lots[i].Tick > lots[j].Tick })
+	case LotsHIFO:
+		sort.SliceStable(lots, func(i, j int) bool {
+			if lots[i].Side == SideSell {
+				return lots[i].Price < lots[j].Price
+			}
+			return lots[i].Price > lots[j].Price
+		})
+	}
+}
+
+func addYearGains(years map[int]*YearGains, d Disposal) {
+	year := d.CloseTime.UTC().Year()
+	y := years[year]
+	if y == nil {
+		y = &YearGains{Year: year}
+		years[year] = y
+	}
+	y.Proceeds += d.Proceeds
+	y.Cost += d.Cost
+	y.Gain += d.Gain
+	if d.LongTerm {
+		y.LongTerm += d.Gain
+	} else {
+		y.ShortTerm += d.Gain
+	}
+	y.Disposals++
+}
+
+// barTime dates an order tick with the bars it was replayed on (tick k is bars[k-1], as in
+// Emulator.Next).
+func barTime(bars []OHLCBar, tick int64) (time.Time, error) {
+	if tick < 1 || tick > int64(len(bars)) || bars[tick-1].Time.IsZero() {
+		return time.Time{}, fmt.Errorf("no bar time for tick %d", tick)
+	}
+	return bars[tick-1].Time, nil
+}
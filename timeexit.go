@@ -0,0 +1,67 @@
+package emul
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stop kinds of the orders closed by a TimeExit.
+const (
+	StopKindMaxBars   = "max-bars"
+	StopKindTimeOfDay = "time-of-day"
+)
+
+// TimeExit closes positions on time rather than price, with ReasonTimeExit. MaxBars closes a
+// position at the close of the MaxBars-th bar after the one it was opened on. TimeOfDay closes
+// it at the open of the first bar starting at or after that offset from UTC midnight (e.g.
+// 21*time.Hour; 24*time.Hour is the next midnight), i.e. the first bar whose span from the
+// previous bar crosses it. Zero disables either rule. Both use the taker fee.
+type TimeExit struct {
+	MaxBars   int
+	TimeOfDay time.Duration
+}
+
+// SetTimeExit applies cfg to every position from now on, including the one open.
+func (e *Exchange) SetTimeExit(cfg TimeExit) error {
+	if cfg.MaxBars < 0 || cfg.TimeOfDay < 0 || cfg.TimeOfDay > 24*time.Hour {
+		return fmt.Errorf("time exit needs max bars >= 0 and a time of day within 24h")
+	}
+	e.timeExit = cfg
+	return nil
+}
+
+func (e *Exchange) TimeExit() TimeExit {
+	return e.timeExit
+}
+
+// checkTimeOfDay closes a position held into the bar that starts at or after the daily time.
+func (e *Exchange) checkTimeOfDay(bar OHLCBar) *Order {
+	at := e.timeExit.TimeOfDay
+	if at <= 0 || e.position == 0 || e.openedTick >= e.tick || !e.hasLastBar || bar.Time.IsZero() || e.lastBar.Time.IsZero() {
+		return nil
+	}
+	prev := e.lastBar.Time.UTC()
+	day := time.Date(prev.Year(), prev.Month(), prev.Day(), 0, 0, 0, 0, time.UTC)
+	mark := day.Add(at)
+	if !mark.After(prev) {
+		mark = mark.AddDate(0, 0, 1)
+	}
+	if bar.Time.Before(mark) {
+		return nil
+	}
+	price := bar.Open
+	if price <= 0 {
+		price = bar.Close
+	}
+	order := e.closeAtPrice(price, ReasonTimeExit, StopKindTimeOfDay, e.fee)
+	return &order
+}
+
+// checkMaxBars closes a position at the close of its MaxBars-th bar.
+func (e *Exchange) checkMaxBars() *Order {
+	if e.timeExit.MaxBars <= 0 || e.position == 0 || e.tick-e.openedTick < int64(e.timeExit.MaxBars) {
+		return nil
+	}
+	order := e.closeAtPrice(e.lastPrice, ReasonTimeExit, StopKindMaxBars, e.fee)
+	return &order
+}
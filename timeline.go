@@ -0,0 +1,121 @@
+package emul
+
+import "sort"
+
+// Limit timeline event kinds.
+const (
+	LimitEventPlaced    = "placed"
+	LimitEventEvaluated = "evaluated"
+	LimitEventFilled    = "filled"
+	LimitEventCanceled  = "canceled"
+	LimitEventExpired   = "expired"
+)
+
+// LimitEvent is one step in the life of a limit order. Evaluated events carry the reason the
+// bar did not fill it at its price (the LimitMiss or pending reason); filled events carry the
+// order and its price, expired events the rejection reason. Only changes are recorded: later
+// bars evaluated for the same reason are folded into the event, Repeats counting them and
+// LastTick holding the last one.
+type LimitEvent struct {
+	Tick     int64   `json:"tick"`
+	Kind     string  `json:"kind"`
+	Reason   string  `json:"reason,omitempty"`
+	Price    float64 `json:"price,omitempty"`
+	OrderID  int64   `json:"order_id,omitempty"`
+	Repeats  int     `json:"repeats,omitempty"`
+	LastTick int64   `json:"last_tick,omitempty"`
+}
+
+// LimitTimeline is the lifecycle of one limit order from placement to its final state.
+type LimitTimeline struct {
+	ID         int64        `json:"id"`
+	Kind       string       `json:"kind"`
+	LimitPrice float64      `json:"limit_price"`
+	PlacedTick int64        `json:"placed_tick"`
+	State      string       `json:"state"`
+	Events     []LimitEvent `json:"events"`
+}
+
+// LimitTimeline returns the lifecycle of the limit order id.
+func (e *Exchange) LimitTimeline(id int64) (LimitTimeline, bool) {
+	t, ok := e.timelines[id]
+	if !ok {
+		return LimitTimeline{}, false
+	}
+	return e.timelineView(t), true
+}
+
+// LimitTimelines returns the lifecycle of every limit order in ID order.
+func (e *Exchange) LimitTimelines() []LimitTimeline {
+	out := make([]LimitTimeline, 0, len(e.timelines))
+	for _, t := range e.timelines {
+		out = append(out, e.timelineView(t))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (e *Exchange) timelineView(t *LimitTimeline) LimitTimeline {
+	out := *t
+	state, _ := e.LimitStatus(t.ID)
+	out.State = state.String()
+	out.Events = append([]LimitEvent(nil), t.Events...)
+	return out
+}
+
+func (e *Exchange) trackPlaced(p pendingOrder) {
+	if e.diagnostics != DiagnosticsFull {
+		return
+	}
+	if e.timelines == nil {
+		e.timelines = make(map[int64]*LimitTimeline)
+	}
+	e.timelines[p.id] = &LimitTimeline{
+		ID:         p.id,
+		Kind:       pendingKindName(p.kind),
+		LimitPrice: p.price,
+		PlacedTick: p.placedAtTick,
+		Events:     []LimitEvent{{Tick: e.tick, Kind: LimitEventPlaced, Price: p.price}},
+	}
+}
+
+func (e *Exchange) trackLimit(id int64, ev LimitEvent) {
+	t, ok := e.timelines[id]
+	if !ok || e.diagnostics != DiagnosticsFull {
+		return
+	}
+	ev.Tick = e.tick
+	if n := len(t.Events); n > 0 && ev.Kind == LimitEventEvaluated {
+		last := &t.Events[n-1]
+		if last.Kind == ev.Kind && last.Reason == ev.Reason {
+			last.Repeats++
+			last.LastTick = ev.Tick
+			return
+		}
+	}
+	t.Events = append(t.Events, ev)
+	if ev.Kind != LimitEventEvaluated {
+		e.timelineDone = append(e.timelineDone, id)
+		e.trimTimelines()
+	}
+}
+
+// trimTimelines keeps the timelines of finished limits within MaxEntries of a bounded
+// MissRetention: the first ones with MissKeepFirst, the latest with MissRingBuffer. Timelines
+// of limits still pending are always kept.
+func (e *Exchange) trimTimelines() {
+	r := e.missRetention
+	if r.Mode != MissKeepFirst && r.Mode != MissRingBuffer || len(e.timelineDone) <= r.MaxEntries {
+		return
+	}
+	drop := e.timelineDone[r.MaxEntries:]
+	keep := e.timelineDone[:r.MaxEntries]
+	if r.Mode == MissRingBuffer {
+		drop = e.timelineDone[:len(e.timelineDone)-r.MaxEntries]
+		keep = e.timelineDone[len(e.timelineDone)-r.MaxEntries:]
+	}
+	for _, id := range drop {
+		delete(e.timelines, id)
+	}
+	e.timelineDone = append([]int64(nil), keep...)
+}
@@ -0,0 +1,180 @@
+package emul
+
+import "fmt"
+
+// DecisionTiming selects when the Runner calls a strategy relative to the bar it acts on.
+type DecisionTiming uint8
+
+const (
+	// DecideOnCloseFillAtClose calls OnBar once the bar is applied and fills market orders at
+	// its close (the default). The strategy decides and trades on the same close.
+	DecideOnCloseFillAtClose DecisionTiming = iota
+	// DecideOnCloseFillNextOpen calls OnBar with the finished bar but with the exchange already
+	// moved to the next bar's open, so market orders fill there. OnBar is not called for the
+	// last bar, which has no next open to trade at.
+	DecideOnCloseFillNextOpen
+	// DecideOnOpen calls OnBar at the open of each bar, before its high, low and close are
+	// known: the bar passed carries only its time and open, and market orders fill at the open.
+	DecideOnOpen
+)
+
+func (t DecisionTiming) String() string {
+	switch t {
+	case DecideOnCloseFillAtClose:
+		return "close"
+	case DecideOnCloseFillNextOpen:
+		return "close-next-open"
+	case DecideOnOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// SetDecisionTiming selects how Run schedules the strategy; it must be called before the replay
+// starts. Limits placed at an open rest until the next bar, as limits placed at a close do.
+func (e *Emulator) SetDecisionTiming(t DecisionTiming) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if t > DecideOnOpen {
+		return fmt.Errorf("unknown decision timing %d", t)
+	}
+	if e.index > 0 {
+		return fmt.Errorf("decision timing must be set before the replay starts")
+	}
+	e.timing = t
+	return nil
+}
+
+func (e *Emulator) DecisionTiming() DecisionTiming {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.timing
+}
+
+// openNext moves every account to the open of the next bar without applying the bar, and
+// returns the bar reduced to what is known at its open.
+func (e *Emulator) openNext() (OHLCBar, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.index >= len(e.bars) {
+		return OHLCBar{}, ErrNoMoreBars
+	}
+	bar := e.bars[e.index]
+	tick := int64(e.index + 1)
+	if err := e.ex.openAt(tick, bar); err != nil {
+		return OHLCBar{}, err
+	}
+	for _, ex := range e.accounts {
+		if err := ex.openAt(tick, bar); err != nil {
+			return OHLCBar{}, err
+		}
+	}
+	return OHLCBar{Time: bar.Time, Open: bar.Open, High: bar.Open, Low: bar.Open, Close: bar.Open, Average: bar.Open}, nil
+}
+
+// openAt moves the exchange to the open of bar at tick so market orders placed now fill there.
+// Pending limits, stops and marks are left to tickBarAt.
+func (e *Exchange) openAt(tick int64, bar OHLCBar) error {
+	price := bar.Open
+	if e.impact != nil {
+		price *= 1 + e.impact.offset
+	}
+	if price <= 0 {
+		return fmt.Errorf("bar open must be positive")
+	}
+	e.tick = max(tick, 0)
+	e.lastPrice = price
+	return nil
+}
+
+// FillTiming selects the price market orders placed on the exchange fill at.
+type FillTiming uint8
+
+const (
+	// FillAtClose fills OpenLong, OpenShort and CloseDeal at once at the current close (the
+	// default).
+	FillAtClose FillTiming = iota
+	// FillAtNextOpen queues them and fills them at the open of the next bar, with the taker fee
+	// and spread/slippage as usual, before pending limits are matched. The calls return a nil
+	// order; the fill appears in the next bar's executed orders. An order that no longer fits
+	// the position by then is reported in Rejections. Do not combine it with
+	// DecideOnCloseFillNextOpen, which already trades at the next open.
+	FillAtNextOpen
+)
+
+func (e *Exchange) SetFillTiming(t FillTiming) error {
+	if t > FillAtNextOpen {
+		return fmt.Errorf("unknown fill timing %d", t)
+	}
+	e.fillTiming = t
+	return nil
+}
+
+func (e *Exchange) FillTiming() FillTiming {
+	return e.fillTiming
+}
+
+// deferMarket queues a market order for the next open after the checks the immediate fill
+// would make.
+func (e *Exchange) deferMarket(p pendingOrder) error {
+	if e.lastPrice <= 0 {
+		return ErrPriceNotSet
+	}
+	if p.kind == pendingClose {
+		if e.position == 0 {
+			return ErrNoPosition
+		}
+	} else {
+		if e.position != 0 {
+			return ErrPositionOpen
+		}
+		if p.fraction <= 0 || p.fraction > 1 {
+			return ErrInvalidFraction
+		}
+	}
+	p.placedAtTick = e.tick
+	p.tag, p.meta = e.tag, e.meta
+	e.deferred = append(e.deferred, p)
+	return nil
+}
+
+// fillDeferred fills the queued market orders at the open of bar and returns the first fill.
+func (e *Exchange) fillDeferred(bar OHLCBar) *Order {
+	var first *Order
+	e.lastPrice = bar.Open
+	savedTag, savedMeta := e.tag, e.meta
+	defer func() { e.tag, e.meta = savedTag, savedMeta }()
+	for _, p := range e.deferred {
+		e.tag, e.meta = p.tag, p.meta
+		var executed *Order
+		var err error
+		switch p.kind {
+		case pendingOpenLong:
+			executed, err = e.openLongAtPrice(bar.Open, p.fraction, e.fee, p.placedAtTick)
+		case pendingOpenShort:
+			executed, err = e.openShortAtPrice(bar.Open, p.fraction, e.fee, p.placedAtTick)
+		case pendingClose:
+			if e.position == 0 {
+				err = ErrNoPosition
+				break
+			}
+			order := e.closeAtPrice(bar.Open, p.reason, "", e.fee)
+			e.orders[len(e.orders)-1].PlacedTick = p.placedAtTick
+			order.PlacedTick = p.placedAtTick
+			executed = &order
+		}
+		if err != nil {
+			reason := "open_rejected"
+			if !e.pendingMatchesPosition(p.kind) {
+				reason = "position_state_mismatch"
+			}
+			e.reject(p, reason, err)
+			continue
+		}
+		if first == nil {
+			first = executed
+		}
+	}
+	e.deferred = e.deferred[:0]
+	return first
+}
@@ -0,0 +1,83 @@
+package emul
+
+// Trade is a round trip: the entry fill and the order that flattened it.
+type Trade struct {
+	Side         OrderSide
+	Qty          float64
+	EntryPrice   float64
+	ExitPrice    float64
+	EntryTick    int64
+	ExitTick     int64
+	Fees         float64
+	PnL          float64
+	Return       float64
+	ExitReason   string
+	ExitCategory ReasonCategory
+	StopKind     string
+	Entry        Order
+	Exit         Order
+}
+
+// PairTrades matches entries with the orders that closed them using reason categories, so custom
+// exit labels pair the same way as the built-in ones. PnL is the equity change from before the
+// entry to after the exit, which includes fees, spread and liquidation losses. An entry left open
+// at the end of the history is not reported. When a position was scaled into, Entry is the first
+// fill, Qty the total closed and EntryPrice the average entry; when it was closed in parts
+// (e.g. by a take-profit ladder), ExitPrice is the average exit and Fees include every part.
+func PairTrades(orders []Order) []Trade {
+	trades := make([]Trade, 0, len(orders)/2)
+	var entry *Order
+	entryPrice, entryFees := 0.0, 0.0
+	partQty, partValue, partFees := 0.0, 0.0, 0.0
+	for i := range orders {
+		o := orders[i]
+		category := o.Category()
+		if category == ReasonCategoryEntry {
+			if entry == nil || o.Reason != ReasonScaleIn {
+				entry = &orders[i]
+				entryFees = 0
+				partQty, partValue, partFees = 0, 0, 0
+			}
+			entryPrice = o.EntryPrice
+			entryFees += o.Fee
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		if o.PositionAfter != 0 {
+			// A partial exit: the trade closes with the order that flattens the position.
+			if category == ReasonCategoryExit || category == ReasonCategoryStop {
+				partQty += o.Qty
+				partValue += o.Qty * o.Price
+				partFees += o.Fee
+			}
+			continue
+		}
+		qty, exitPrice := o.Qty+partQty, o.Price
+		if partQty > 0 {
+			exitPrice = (o.Qty*o.Price + partValue) / qty
+		}
+		t := Trade{
+			Side:         entry.Side,
+			Qty:          qty,
+			EntryPrice:   entryPrice,
+			ExitPrice:    exitPrice,
+			EntryTick:    entry.Tick,
+			ExitTick:     o.Tick,
+			Fees:         entryFees + partFees + o.Fee,
+			PnL:          o.Equity - entry.EquityBefore,
+			ExitReason:   o.Reason,
+			ExitCategory: o.Category(),
+			StopKind:     o.StopKind,
+			Entry:        *entry,
+			Exit:         o,
+		}
+		if entry.EquityBefore > 0 {
+			t.Return = t.PnL / entry.EquityBefore
+		}
+		trades = append(trades, t)
+		entry = nil
+	}
+	return trades
+}
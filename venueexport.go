@@ -0,0 +1,103 @@
+package emul
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteBinanceTrades writes orders in the layout of Binance's spot "Trade History" export:
+// Date(UTC),Pair,Side,Price,Executed,Amount,Fee, with the asset suffixed to each amount as Binance
+// does. Fill times are those of the bars the orders filled on. base names the traded asset of
+// orders without a Symbol; quote is the quote asset (USDT when empty) and the emulator's USD fees
+// are reported in it.
+func WriteBinanceTrades(w io.Writer, orders []Order, bars []OHLCBar, base string, quote string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Date(UTC)", "Pair", "Side", "Price", "Executed", "Amount", "Fee"}); err != nil {
+		return err
+	}
+	for _, o := range orders {
+		base, quote := venueAssets(o.Symbol, base, quote)
+		row := []string{
+			venueTime(bars, o.Tick),
+			base + quote,
+			strings.ToUpper(string(o.Side)),
+			strconv.FormatFloat(o.Price, 'f', -1, 64),
+			venueAmount(o.Qty) + base,
+			venueAmount(o.Qty*o.Price) + quote,
+			venueAmount(o.Fee) + quote,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteBybitTrades writes orders in the layout of Bybit's spot trade history export: Spot
+// Pairs,Order Type,Direction,Filled Value,Filled Price,Filled Quantity,Fees,Transaction ID,Order
+// No.,Timestamp (UTC). IDs are GlobalID and orders filled on a later bar than placed are reported
+// as limit orders. Times, assets and fees are handled as in WriteBinanceTrades.
+func WriteBybitTrades(w io.Writer, orders []Order, bars []OHLCBar, base string, quote string) error {
+	cw := csv.NewWriter(w)
+	header := []string{"Spot Pairs", "Order Type", "Direction", "Filled Value", "Filled Price", "Filled Quantity", "Fees", "Transaction ID", "Order No.", "Timestamp (UTC)"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, o := range orders {
+		base, quote := venueAssets(o.Symbol, base, quote)
+		orderType := "MARKET"
+		if o.PlacedTick < o.Tick {
+			orderType = "LIMIT"
+		}
+		id := o.GlobalID()
+		row := []string{
+			base + quote,
+			orderType,
+			strings.ToUpper(string(o.Side)),
+			venueAmount(o.Qty * o.Price),
+			strconv.FormatFloat(o.Price, 'f', -1, 64),
+			venueAmount(o.Qty),
+			venueAmount(o.Fee),
+			id,
+			id,
+			venueTime(bars, o.Tick),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// venueAssets splits a symbol such as "btc", "BTCUSDT" or "BTC/USDT" into upper-case base and
+// quote assets, falling back to base when the symbol is empty.
+func venueAssets(symbol string, base string, quote string) (string, string) {
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if quote == "" {
+		quote = "USDT"
+	}
+	if strings.TrimSpace(symbol) == "" {
+		symbol = base
+	}
+	asset := strings.ToUpper(strings.TrimSpace(symbol))
+	asset = strings.TrimSuffix(strings.TrimSuffix(asset, quote), "/")
+	return asset, quote
+}
+
+// venueTime formats the fill time of tick, empty when the bars carry none.
+func venueTime(bars []OHLCBar, tick int64) string {
+	ts, err := barTime(bars, tick)
+	if err != nil {
+		return ""
+	}
+	return ts.UTC().Format(time.DateTime)
+}
+
+func venueAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 8, 64)
+}
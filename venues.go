@@ -0,0 +1,201 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInsufficientFunds = errors.New("insufficient free USD")
+
+// VenueConfig describes one exchange in a multi-venue replay. Without Bars the venue trades the
+// shared series shifted by BasisPct (0.001 = 10bp richer) and delayed by LagBars, which models
+// a venue whose quotes trail the leader.
+type VenueConfig struct {
+	Name     string
+	StartUSD float64
+	Costs    CostProfile
+	BasisPct float64
+	LagBars  int
+	// Bars, when set, is the venue's own series and must be as long as the shared one.
+	Bars []OHLCBar
+}
+
+// VenueBar is one venue's bar and the orders executed on it during Venues.Next; Executed is
+// read-only, as with Emulator.Next.
+type VenueBar struct {
+	Venue    string
+	Bar      OHLCBar
+	Executed []Order
+}
+
+// Transfer is USD in flight between venues.
+type Transfer struct {
+	From       string
+	To         string
+	Sent       float64
+	Fee        float64
+	SentTick   int64
+	ArriveTick int64
+}
+
+// Venues replays one timeline on several exchanges with their own costs and price series, and
+// moves USD between them with a delay and a transfer fee, for studying cross-venue arbitrage.
+type Venues struct {
+	index     int
+	length    int
+	names     []string
+	venues    map[string]*venueState
+	transfers []Transfer
+}
+
+type venueState struct {
+	cfg VenueConfig
+	ex  *Exchange
+}
+
+func NewVenues(bars []OHLCBar, venues ...VenueConfig) (*Venues, error) {
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("bars are empty")
+	}
+	if len(venues) < 2 {
+		return nil, fmt.Errorf("need at least two venues")
+	}
+	v := &Venues{length: len(bars), venues: make(map[string]*venueState, len(venues))}
+	for _, cfg := range venues {
+		cfg.Name = strings.TrimSpace(cfg.Name)
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("venue name is empty")
+		}
+		if _, ok := v.venues[cfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate venue %q", cfg.Name)
+		}
+		if cfg.LagBars < 0 {
+			return nil, fmt.Errorf("venue %q: lag must not be negative", cfg.Name)
+		}
+		if cfg.Bars == nil {
+			cfg.Bars = offsetBars(bars, cfg.BasisPct, cfg.LagBars)
+		} else if len(cfg.Bars) != len(bars) {
+			return nil, fmt.Errorf("venue %q: %d bars, want %d", cfg.Name, len(cfg.Bars), len(bars))
+		}
+		v.names = append(v.names, cfg.Name)
+		v.venues[cfg.Name] = &venueState{cfg: cfg, ex: NewExchangeFromProfile(cfg.StartUSD, cfg.Costs)}
+	}
+	return v, nil
+}
+
+// Exchange returns the named venue's exchange, or nil.
+func (v *Venues) Exchange(name string) *Exchange {
+	if s, ok := v.venues[name]; ok {
+		return s.ex
+	}
+	return nil
+}
+
+// Names returns venue names in configuration order.
+func (v *Venues) Names() []string {
+	return append([]string(nil), v.names...)
+}
+
+// Next advances every venue by one bar, credits transfers that arrive on it, and returns the
+// bars in configuration order.
+func (v *Venues) Next() ([]VenueBar, error) {
+	if v.index >= v.length {
+		return nil, ErrNoMoreBars
+	}
+	tick := int64(v.index + 1)
+	out := make([]VenueBar, 0, len(v.names))
+	for _, name := range v.names {
+		s := v.venues[name]
+		bar := s.cfg.Bars[v.index]
+		before := len(s.ex.orders)
+		if _, err := s.ex.tickBarAt(tick, bar); err != nil {
+			return nil, fmt.Errorf("venue %q: %w", name, err)
+		}
+		after := len(s.ex.orders)
+		out = append(out, VenueBar{Venue: name, Bar: bar, Executed: s.ex.orders[before:after:after]})
+	}
+	pending := v.transfers[:0]
+	for _, t := range v.transfers {
+		if t.ArriveTick <= tick {
+			dst := v.venues[t.To].ex
+			dst.usd += t.Sent
+			dst.ledger = append(dst.ledger, LedgerEntry{Tick: dst.tick, Kind: LedgerTransfer, Amount: t.Sent})
+			continue
+		}
+		pending = append(pending, t)
+	}
+	v.transfers = pending
+	v.index++
+	return out, nil
+}
+
+// Transfer withdraws usd of free cash from one venue now and credits it to the other after
+// delayBars bars (0 means on the next bar). The transfer fee is the source exchange's
+// LedgerTransfer flow fee (see SetFlowFee), paid by the source on top of usd as for a transfer
+// between wallets. Both legs are booked in the venues' ledgers.
+func (v *Venues) Transfer(from string, to string, usd float64, delayBars int) (Transfer, error) {
+	src, ok := v.venues[from]
+	if !ok {
+		return Transfer{}, fmt.Errorf("unknown venue %q", from)
+	}
+	if _, ok := v.venues[to]; !ok || from == to {
+		return Transfer{}, fmt.Errorf("invalid destination venue %q", to)
+	}
+	if usd <= 0 || delayBars < 0 {
+		return Transfer{}, fmt.Errorf("transfer needs a positive amount and non-negative delay")
+	}
+	fee := src.ex.flowFee(LedgerTransfer, usd)
+	if usd+fee > src.ex.usd {
+		return Transfer{}, ErrInsufficientFunds
+	}
+	tick := int64(v.index)
+	t := Transfer{
+		From:       from,
+		To:         to,
+		Sent:       usd,
+		Fee:        fee,
+		SentTick:   tick,
+		ArriveTick: tick + int64(delayBars) + 1,
+	}
+	src.ex.usd -= usd + fee
+	src.ex.ledger = append(src.ex.ledger, LedgerEntry{Tick: src.ex.tick, Kind: LedgerTransfer, Amount: -usd})
+	src.ex.bookFlowFee(LedgerTransfer, fee)
+	v.transfers = append(v.transfers, t)
+	return t, nil
+}
+
+// InFlight returns transfers that have not arrived yet.
+func (v *Venues) InFlight() []Transfer {
+	return append([]Transfer(nil), v.transfers...)
+}
+
+// TotalEquity sums venue equity and USD in flight.
+func (v *Venues) TotalEquity() float64 {
+	total := 0.0
+	for _, s := range v.venues {
+		total += s.ex.Balance().Equity
+	}
+	for _, t := range v.transfers {
+		total += t.Sent
+	}
+	return total
+}
+
+func offsetBars(bars []OHLCBar, basisPct float64, lag int) []OHLCBar {
+	out := make([]OHLCBar, len(bars))
+	scale := 1 + basisPct
+	for i := range bars {
+		src := bars[max(i-lag, 0)]
+		out[i] = OHLCBar{
+			Time:    bars[i].Time,
+			Open:    src.Open * scale,
+			High:    src.High * scale,
+			Low:     src.Low * scale,
+			Close:   src.Close * scale,
+			Average: src.Average * scale,
+			Volume:  src.Volume,
+		}
+	}
+	return out
+}
@@ -0,0 +1,246 @@
+package emul
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//go:embed viewer/index.html
+var viewerPage []byte
+
+type viewerBar struct {
+	Tick   int64     `json:"tick"`
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+type viewerOrder struct {
+	ID         int64             `json:"id"`
+	Tick       int64             `json:"tick"`
+	PlacedTick int64             `json:"placed_tick"`
+	Side       OrderSide         `json:"side"`
+	Qty        float64           `json:"qty"`
+	Price      float64           `json:"price"`
+	Fee        float64           `json:"fee"`
+	Reason     string            `json:"reason"`
+	Equity     float64           `json:"equity"`
+	Tag        string            `json:"tag,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+}
+
+type viewerTrade struct {
+	Index      int       `json:"index"`
+	Side       OrderSide `json:"side"`
+	Qty        float64   `json:"qty"`
+	EntryTick  int64     `json:"entry_tick"`
+	ExitTick   int64     `json:"exit_tick"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitPrice  float64   `json:"exit_price"`
+	PnL        float64   `json:"pnl"`
+	Return     float64   `json:"return"`
+	Fees       float64   `json:"fees"`
+	ExitReason string    `json:"exit_reason"`
+}
+
+type viewerLimit struct {
+	ID         int64        `json:"id"`
+	Action     string       `json:"action"`
+	Price      float64      `json:"price"`
+	Fraction   float64      `json:"fraction"`
+	PlacedTick int64        `json:"placed_tick"`
+	State      string       `json:"state"`
+	OrderID    int64        `json:"order_id,omitempty"`
+	FillTick   int64        `json:"fill_tick,omitempty"`
+	Events     []LimitEvent `json:"events"`
+}
+
+type viewerSummary struct {
+	Bars        int     `json:"bars"`
+	Orders      int     `json:"orders"`
+	Trades      int     `json:"trades"`
+	Limits      int     `json:"limits"`
+	StartEquity float64 `json:"start_equity"`
+	FinalEquity float64 `json:"final_equity"`
+	NetPnL      float64 `json:"net_pnl"`
+	WinRate     float64 `json:"win_rate"`
+	MaxDrawdown float64 `json:"max_drawdown"`
+}
+
+// viewer is a snapshot of a finished run served as JSON.
+type viewer struct {
+	bars   []viewerBar
+	orders []viewerOrder
+	trades []viewerTrade
+	limits []viewerLimit
+	curve  []EquityPoint
+	sum    viewerSummary
+}
+
+// ViewerHandler serves a browsable replay of a finished run: a single-page app at / and JSON
+// endpoints under /api/ for the summary, bars (?from=&to= ticks), equity, trades, one trade with
+// the bars around it (/api/trades/{i}?pad=) and the limit orders with their timelines
+// (/api/limits/{id}). The run is captured when the handler is built.
+func ViewerHandler(emu *Emulator, curve []EquityPoint) http.Handler {
+	v := newViewer(emu, curve)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(viewerPage)
+	})
+	mux.HandleFunc("GET /api/summary", func(w http.ResponseWriter, r *http.Request) { writeJSON(w, v.sum) })
+	mux.HandleFunc("GET /api/equity", func(w http.ResponseWriter, r *http.Request) { writeJSON(w, v.curve) })
+	mux.HandleFunc("GET /api/trades", func(w http.ResponseWriter, r *http.Request) { writeJSON(w, v.trades) })
+	mux.HandleFunc("GET /api/limits", func(w http.ResponseWriter, r *http.Request) { writeJSON(w, v.limits) })
+	mux.HandleFunc("GET /api/limits/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		for _, l := range v.limits {
+			if l.ID == id {
+				writeJSON(w, l)
+				return
+			}
+		}
+		http.Error(w, "unknown limit", http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /api/bars", func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := tickRange(r, 1, int64(len(v.bars)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, v.barRange(from, to))
+	})
+	mux.HandleFunc("GET /api/trades/{index}", func(w http.ResponseWriter, r *http.Request) {
+		i, err := strconv.Atoi(r.PathValue("index"))
+		if err != nil || i < 0 || i >= len(v.trades) {
+			http.Error(w, "unknown trade", http.StatusNotFound)
+			return
+		}
+		pad := int64(20)
+		if raw := r.URL.Query().Get("pad"); raw != "" {
+			if pad, err = strconv.ParseInt(raw, 10, 64); err != nil || pad < 0 {
+				http.Error(w, "invalid pad", http.StatusBadRequest)
+				return
+			}
+		}
+		t := v.trades[i]
+		from, to := t.EntryTick-pad, t.ExitTick+pad
+		var orders []viewerOrder
+		for _, o := range v.orders {
+			if o.Tick >= from && o.Tick <= to {
+				orders = append(orders, o)
+			}
+		}
+		var limits []viewerLimit
+		for _, l := range v.limits {
+			if l.PlacedTick <= to && (l.FillTick == 0 || l.FillTick >= from) {
+				limits = append(limits, l)
+			}
+		}
+		writeJSON(w, struct {
+			Trade  viewerTrade   `json:"trade"`
+			Bars   []viewerBar   `json:"bars"`
+			Orders []viewerOrder `json:"orders"`
+			Limits []viewerLimit `json:"limits"`
+		}{t, v.barRange(from, to), orders, limits})
+	})
+	return mux
+}
+
+// ServeViewer serves ViewerHandler on addr until ctx is canceled.
+func ServeViewer(ctx context.Context, addr string, emu *Emulator, curve []EquityPoint) error {
+	srv := &http.Server{Addr: addr, Handler: ViewerHandler(emu, curve), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func newViewer(emu *Emulator, curve []EquityPoint) *viewer {
+	bars := emu.Bars()
+	ex := emu.Exchange()
+	orders := ex.Orders()
+	v := &viewer{curve: append([]EquityPoint(nil), curve...)}
+	for i, b := range bars {
+		v.bars = append(v.bars, viewerBar{Tick: int64(i + 1), Time: b.Time, Open: b.Open, High: b.High, Low: b.Low, Close: b.Close, Volume: b.Volume})
+	}
+	for _, o := range orders {
+		v.orders = append(v.orders, viewerOrder{ID: o.ID, Tick: o.Tick, PlacedTick: o.PlacedTick, Side: o.Side, Qty: o.Qty, Price: o.Price, Fee: o.Fee, Reason: o.Reason, Equity: o.Equity, Tag: o.Tag, Meta: o.Meta})
+	}
+	trades := PairTrades(orders)
+	for i, t := range trades {
+		v.trades = append(v.trades, viewerTrade{
+			Index: i, Side: t.Side, Qty: t.Qty, EntryTick: t.EntryTick, ExitTick: t.ExitTick,
+			EntryPrice: t.EntryPrice, ExitPrice: t.ExitPrice, PnL: t.PnL, Return: t.Return, Fees: t.Fees, ExitReason: t.ExitReason,
+		})
+	}
+	for _, in := range ex.Intents() {
+		if in.LimitID == 0 || in.Action == IntentCancel {
+			continue
+		}
+		state, order := ex.LimitStatus(in.LimitID)
+		l := viewerLimit{ID: in.LimitID, Action: in.Action, Price: in.Price, Fraction: in.Fraction, PlacedTick: in.Tick, State: state.String()}
+		if order != nil {
+			l.OrderID, l.FillTick = order.ID, order.Tick
+		}
+		if t, ok := ex.LimitTimeline(in.LimitID); ok {
+			l.Events = t.Events
+		}
+		v.limits = append(v.limits, l)
+	}
+	stats := ComputeTradeStats(trades)
+	v.sum = viewerSummary{
+		Bars: len(bars), Orders: len(orders), Trades: len(trades), Limits: len(v.limits),
+		NetPnL: stats.NetPnL, WinRate: stats.WinRate, MaxDrawdown: MaxDrawdown(curve),
+	}
+	v.sum.StartEquity = emu.startUSD
+	v.sum.FinalEquity = ex.Balance().Equity
+	return v
+}
+
+func (v *viewer) barRange(from int64, to int64) []viewerBar {
+	from, to = max(from, 1), min(to, int64(len(v.bars)))
+	if from > to {
+		return []viewerBar{}
+	}
+	return v.bars[from-1 : to]
+}
+
+// tickRange reads the optional from/to query parameters.
+func tickRange(r *http.Request, from int64, to int64) (int64, int64, error) {
+	q := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"from", &from}, {"to", &to}} {
+		if raw := q.Get(p.name); raw != "" {
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return 0, 0, errors.New("invalid " + p.name)
+			}
+			*p.dst = v
+		}
+	}
+	return from, to, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
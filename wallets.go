@@ -0,0 +1,137 @@
+package emul
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// WalletID names one of the exchange's wallets.
+type WalletID string
+
+const (
+	// WalletSpot holds the USD traded by spot orders and options.
+	WalletSpot WalletID = "spot"
+	// WalletMargin holds the USD backing the perp leg once EnableMarginWallet is called.
+	WalletMargin WalletID = "margin"
+)
+
+// LedgerTransfer books a transfer between wallets: negative out of a wallet, positive into it.
+// Transfers net to zero in equity.
+const LedgerTransfer = "transfer"
+
+// ErrMarginWalletDisabled is returned for margin wallet operations before EnableMarginWallet.
+var ErrMarginWalletDisabled = errors.New("margin wallet not enabled")
+
+// WalletBalance is one wallet's free cash and equity. The spot wallet's equity values the spot
+// position, dust and options; the margin wallet's adds the perp margin and unrealized PnL.
+type WalletBalance struct {
+	Cash   float64
+	Equity float64
+}
+
+// EnableMarginWallet splits a margin wallet off the spot wallet and moves initial USD into it.
+// From then on the perp leg posts margin from, and settles funding and PnL into, the margin
+// wallet, which is cross-margined: a perp loss beyond the posted margin is taken from the
+// wallet's free cash, and the perp is liquidated when the wallet equity reaches zero. The spot
+// wallet is never touched by the perp. The perp position must be flat.
+func (e *Exchange) EnableMarginWallet(initial float64) error {
+	if e.perp == nil {
+		return ErrPerpDisabled
+	}
+	if e.perp.qty != 0 {
+		return ErrPositionOpen
+	}
+	if e.marginWallet {
+		return fmt.Errorf("margin wallet already enabled")
+	}
+	e.marginWallet = true
+	if initial == 0 {
+		return nil
+	}
+	return e.Transfer(WalletSpot, WalletMargin, initial)
+}
+
+// Transfer moves amount USD of free cash between the spot and margin wallets; the transfer fee,
+// if any (see SetFlowFee), is paid by the source wallet on top of amount.
+func (e *Exchange) Transfer(from WalletID, to WalletID, amount float64) error {
+	if !e.marginWallet {
+		return ErrMarginWalletDisabled
+	}
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return fmt.Errorf("transfer amount must be positive")
+	}
+	src, dst, err := e.walletCash(from), e.walletCash(to), error(nil)
+	fee := e.flowFee(LedgerTransfer, amount)
+	if src == nil || dst == nil || from == to {
+		err = fmt.Errorf("invalid transfer from %q to %q", from, to)
+	} else if *src < amount+fee {
+		err = fmt.Errorf("%w: %s wallet holds %.2f", ErrInsufficientFunds, from, *src)
+	}
+	if err != nil {
+		return err
+	}
+	*src -= amount + fee
+	*dst += amount
+	e.ledger = append(e.ledger,
+		LedgerEntry{Tick: e.tick, Kind: LedgerTransfer, Amount: -amount},
+		LedgerEntry{Tick: e.tick, Kind: LedgerTransfer, Amount: amount},
+	)
+	e.bookFlowFee(LedgerTransfer, fee)
+	return e.checkInvariants()
+}
+
+// WalletBalance reports a wallet's cash and equity. Without a margin wallet the spot wallet
+// holds everything, including the perp leg.
+func (e *Exchange) WalletBalance(w WalletID) (WalletBalance, error) {
+	bal := e.Balance()
+	perp := bal.PerpMargin + bal.PerpPnL
+	switch {
+	case w == WalletSpot && e.marginWallet:
+		return WalletBalance{Cash: e.usd, Equity: bal.Equity - e.marginUSD - perp}, nil
+	case w == WalletSpot:
+		return WalletBalance{Cash: e.usd, Equity: bal.Equity}, nil
+	case w == WalletMargin && e.marginWallet:
+		return WalletBalance{Cash: e.marginUSD, Equity: e.marginUSD + perp}, nil
+	case w == WalletMargin:
+		return WalletBalance{}, ErrMarginWalletDisabled
+	}
+	return WalletBalance{}, fmt.Errorf("unknown wallet %q", w)
+}
+
+func (e *Exchange) walletCash(w WalletID) *float64 {
+	switch w {
+	case WalletSpot:
+		return &e.usd
+	case WalletMargin:
+		return &e.marginUSD
+	}
+	return nil
+}
+
+// perpCash is the wallet cash the perp leg draws on.
+func (e *Exchange) perpCash() *float64 {
+	if e.marginWallet {
+		return &e.marginUSD
+	}
+	return &e.usd
+}
+
+// liquidateMarginWallet closes the perp at the mark when the margin wallet's equity is gone;
+// the wallet is left empty.
+func (e *Exchange) liquidateMarginWallet() {
+	p := e.perp
+	if !e.marginWallet || p.qty == 0 || e.marginUSD+p.margin+p.unrealized() > 0 {
+		return
+	}
+	equityBefore := e.Balance().Equity
+	side := SideBuy
+	if p.qty > 0 {
+		side = SideSell
+	}
+	qty := math.Abs(p.qty)
+	p.realized -= p.margin + e.marginUSD
+	e.marginUSD = 0
+	p.qty, p.entry, p.margin = 0, 0, 0
+	e.recordPerpOrder(side, qty, 0, equityBefore, ReasonLiquidate)
+}
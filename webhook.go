@@ -0,0 +1,308 @@
+package emul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotifyQueueFull = errors.New("notification queue full")
+	ErrNotifierClosed  = errors.New("notifier closed")
+)
+
+// Event kinds sent to notifiers.
+const (
+	EventFill        = "fill"
+	EventLiquidation = "liquidation"
+	EventRiskBreach  = "risk_breach"
+	EventDailyPnL    = "daily_pnl"
+)
+
+// Event is one notification. Order fields are set for fills and liquidations, Rule for risk
+// breaches and PnL for daily summaries, which report the UTC day that just ended.
+type Event struct {
+	Kind    string    `json:"kind"`
+	Tick    int64     `json:"tick"`
+	BarTime time.Time `json:"bar_time"`
+	Equity  float64   `json:"equity"`
+	OrderID int64     `json:"order_id,omitempty"`
+	Side    OrderSide `json:"side,omitempty"`
+	Qty     float64   `json:"qty,omitempty"`
+	Price   float64   `json:"price,omitempty"`
+	Fee     float64   `json:"fee,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Rule    string    `json:"rule,omitempty"`
+	PnL     float64   `json:"pnl,omitempty"`
+}
+
+// Notifier delivers events, e.g. to a webhook or a chat.
+type Notifier interface {
+	Notify(ctx context.Context, ev Event) error
+}
+
+// Webhook POSTs events as JSON to URL. Kinds filters the events sent (all when empty); a failed
+// delivery is passed to OnError, if set, and never stops the replay.
+type Webhook struct {
+	URL     string
+	Kinds   []string
+	Headers map[string]string
+	Timeout time.Duration
+	Client  *http.Client
+	OnError func(ev Event, err error)
+}
+
+func (w *Webhook) Notify(ctx context.Context, ev Event) error {
+	if len(w.Kinds) > 0 && !slices.Contains(w.Kinds, ev.Kind) {
+		return nil
+	}
+	err := w.post(ctx, ev)
+	if err != nil && w.OnError != nil {
+		w.OnError(ev, err)
+	}
+	return err
+}
+
+func (w *Webhook) post(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, w.Client, w.URL, w.Headers, w.Timeout, body)
+}
+
+// postJSON POSTs body to url, failing on a non-2xx status; timeout defaults to 5s.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, timeout time.Duration, body []byte) error {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post to %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// NotifyStats counts the events an AsyncNotifier handled: Sent were delivered, Failed were
+// rejected by the notifier and Dropped did not fit in the queue.
+type NotifyStats struct {
+	Sent    int
+	Failed  int
+	Dropped int
+}
+
+// AsyncNotifier delivers events to a Notifier from a background goroutine, so a slow or
+// unreachable endpoint does not hold up the replay. Notify only queues the event; when the queue
+// is full the event is dropped. Failed and dropped events are passed to onError, which runs on
+// the delivery goroutine for failures. Close delivers what is still queued.
+type AsyncNotifier struct {
+	n       Notifier
+	timeout time.Duration
+	onError func(ev Event, err error)
+	queue   chan Event
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+	stats   NotifyStats
+}
+
+// NewAsyncNotifier starts delivering to n. queue bounds the events waiting for delivery
+// (default 64) and timeout bounds each delivery (default 10s).
+func NewAsyncNotifier(n Notifier, queue int, timeout time.Duration, onError func(ev Event, err error)) *AsyncNotifier {
+	if queue <= 0 {
+		queue = 64
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	a := &AsyncNotifier{n: n, timeout: timeout, onError: onError, queue: make(chan Event, queue), done: make(chan struct{})}
+	go a.deliver()
+	return a
+}
+
+// Notify queues ev without waiting for its delivery; ctx is not used by the delivery.
+func (a *AsyncNotifier) Notify(_ context.Context, ev Event) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrNotifierClosed
+	}
+	select {
+	case a.queue <- ev:
+		a.mu.Unlock()
+		return nil
+	default:
+	}
+	a.stats.Dropped++
+	a.mu.Unlock()
+	// The callback runs unlocked so it may call Stats or Notify.
+	if a.onError != nil {
+		a.onError(ev, ErrNotifyQueueFull)
+	}
+	return ErrNotifyQueueFull
+}
+
+func (a *AsyncNotifier) deliver() {
+	defer close(a.done)
+	for ev := range a.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+		err := a.n.Notify(ctx, ev)
+		cancel()
+		a.mu.Lock()
+		if err != nil {
+			a.stats.Failed++
+		} else {
+			a.stats.Sent++
+		}
+		a.mu.Unlock()
+		if err != nil && a.onError != nil {
+			a.onError(ev, err)
+		}
+	}
+}
+
+// Stats returns the delivery counts so far.
+func (a *AsyncNotifier) Stats() NotifyStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// Close stops accepting events and waits until the queued ones are delivered. It reports an
+// error if any event failed or was dropped.
+func (a *AsyncNotifier) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.done
+	st := a.Stats()
+	if st.Failed > 0 || st.Dropped > 0 {
+		return fmt.Errorf("%d notifications failed, %d dropped", st.Failed, st.Dropped)
+	}
+	return nil
+}
+
+// RiskRule is a condition checked after every bar; a breach is notified once and again only
+// after the rule has cleared.
+type RiskRule struct {
+	Name     string
+	Breached func(ex *Exchange) bool
+}
+
+// DrawdownRule breaches while equity is maxDrawdown (0.2 = 20%) or more below its peak.
+func DrawdownRule(maxDrawdown float64) RiskRule {
+	peak := 0.0
+	return RiskRule{
+		Name: fmt.Sprintf("drawdown>=%.2f%%", maxDrawdown*100),
+		Breached: func(ex *Exchange) bool {
+			equity := ex.Balance().Equity
+			peak = max(peak, equity)
+			return peak > 0 && (peak-equity)/peak >= maxDrawdown
+		},
+	}
+}
+
+// WithNotifier wraps s so every fill (including orders s places) and liquidation, every breach
+// of rules or of the exchange's own limits (see RiskEvents) and a PnL summary at each UTC day
+// boundary are sent to n; Close sends the summary of the last day. Delivery errors are left to
+// the notifier and do not stop s. n is called inline, so wrap network notifiers in an
+// AsyncNotifier to keep slow deliveries out of the replay.
+func WithNotifier(s Strategy, n Notifier, rules ...RiskRule) *NotifyingStrategy {
+	return &NotifyingStrategy{s: s, n: n, rules: rules, breached: make([]bool, len(rules))}
+}
+
+// NotifyingStrategy is the Strategy returned by WithNotifier.
+type NotifyingStrategy struct {
+	s        Strategy
+	n        Notifier
+	rules    []RiskRule
+	breached []bool
+	seen     int
+	seenRisk int
+	day      time.Time
+	dayStart float64
+	equity   float64
+	tick     int64
+}
+
+// Close sends the PnL summary of the day in progress, which no later bar will report, and
+// returns the notifier's error. A wrapped NotifyingStrategy is not closed.
+func (ns *NotifyingStrategy) Close() error {
+	if ns.day.IsZero() {
+		return nil
+	}
+	ev := Event{Kind: EventDailyPnL, Tick: ns.tick, BarTime: ns.day, Equity: ns.equity, PnL: ns.equity - ns.dayStart}
+	ns.day = time.Time{}
+	return ns.n.Notify(context.Background(), ev)
+}
+
+func (ns *NotifyingStrategy) OnBar(ex *Exchange, bar OHLCBar, executed []Order) error {
+	ctx := context.Background()
+	day := bar.Time.UTC().Truncate(24 * time.Hour)
+	switch {
+	case ns.day.IsZero():
+		ns.day, ns.dayStart = day, ex.Balance().Equity
+	case day.After(ns.day):
+		ns.n.Notify(ctx, Event{Kind: EventDailyPnL, Tick: ex.tick, BarTime: ns.day, Equity: ns.equity, PnL: ns.equity - ns.dayStart})
+		ns.day, ns.dayStart = day, ns.equity
+	}
+	err := ns.s.OnBar(ex, bar, executed)
+	for _, o := range ex.orders[ns.seen:] {
+		kind := EventFill
+		if o.Reason == ReasonLiquidate {
+			kind = EventLiquidation
+		}
+		ns.n.Notify(ctx, Event{
+			Kind:    kind,
+			Tick:    o.Tick,
+			BarTime: bar.Time,
+			Equity:  o.Equity,
+			OrderID: o.ID,
+			Side:    o.Side,
+			Qty:     o.Qty,
+			Price:   o.Price,
+			Fee:     o.Fee,
+			Reason:  o.Reason,
+		})
+	}
+	ns.seen = len(ex.orders)
+	for _, ev := range ex.riskEvents[ns.seenRisk:] {
+		ns.n.Notify(ctx, ev)
+	}
+	ns.seenRisk = len(ex.riskEvents)
+	for i, rule := range ns.rules {
+		breached := rule.Breached(ex)
+		if breached && !ns.breached[i] {
+			ns.n.Notify(ctx, Event{Kind: EventRiskBreach, Tick: ex.tick, BarTime: bar.Time, Equity: ex.Balance().Equity, Rule: rule.Name})
+		}
+		ns.breached[i] = breached
+	}
+	ns.equity, ns.tick = ex.Balance().Equity, ex.tick
+	return err
+}